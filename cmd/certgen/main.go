@@ -10,7 +10,31 @@ import (
 	"os"
 )
 
+// usage:
+//
+//	certgen [-out private.pem] [-bits 2048]   JWT signing key
+//	certgen ca     [flags]                    local certificate authority
+//	certgen server [flags]                    TLS server certificate
+//	certgen client [flags]                    mutual TLS client certificate
+//	certgen rotate [flags]                    new key in a JWT key ring
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "ca":
+			runCA(os.Args[2:])
+			return
+		case "server":
+			runLeaf("server", os.Args[2:])
+			return
+		case "client":
+			runLeaf("client", os.Args[2:])
+			return
+		case "rotate":
+			runRotate(os.Args[2:])
+			return
+		}
+	}
+
 	var (
 		outPath string
 		bits    int
@@ -43,3 +67,87 @@ func main() {
 
 	fmt.Println("private key generated:", outPath)
 }
+
+func runCA(args []string) {
+	fs := flag.NewFlagSet("ca", flag.ExitOnError)
+	certPath := fs.String("cert", "ca.pem", "output path for CA certificate")
+	keyPath := fs.String("key", "ca-key.pem", "output path for CA private key")
+	name := fs.String("cn", "GophKeeper local CA", "CA common name")
+	days := fs.Int("days", 3650, "validity in days")
+	_ = fs.Parse(args)
+
+	if err := writeCA(*certPath, *keyPath, *name, *days); err != nil {
+		fmt.Fprintln(os.Stderr, "generate CA:", err)
+		os.Exit(1)
+	}
+	fmt.Println("CA certificate generated:", *certPath)
+}
+
+func runLeaf(kind string, args []string) {
+	fs := flag.NewFlagSet(kind, flag.ExitOnError)
+	caCert := fs.String("ca", "ca.pem", "CA certificate path")
+	caKey := fs.String("ca-key", "ca-key.pem", "CA private key path")
+	certPath := fs.String("cert", kind+".pem", "output path for certificate")
+	keyPath := fs.String("key", kind+"-key.pem", "output path for private key")
+	days := fs.Int("days", 825, "validity in days")
+	hosts := fs.String("hosts", "localhost,127.0.0.1", "comma separated server host names and IPs")
+	email := fs.String("email", "", "user e-mail the client certificate is mapped to")
+	_ = fs.Parse(args)
+
+	req := certRequest{days: *days}
+	usage := x509.ExtKeyUsageServerAuth
+	if kind == "server" {
+		req.hosts = splitHosts(*hosts)
+		if len(req.hosts) > 0 {
+			req.commonName = req.hosts[0]
+		}
+	} else {
+		if *email == "" {
+			fmt.Fprintln(os.Stderr, "email is required for a client certificate")
+			os.Exit(1)
+		}
+		req.commonName = *email
+		req.email = *email
+		usage = x509.ExtKeyUsageClientAuth
+	}
+
+	if err := writeLeaf(*caCert, *caKey, *certPath, *keyPath, req, usage); err != nil {
+		fmt.Fprintln(os.Stderr, "generate certificate:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s certificate generated: %s\n", kind, *certPath)
+}
+
+func runRotate(args []string) {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	dir := fs.String("dir", "keys", "key ring directory")
+	bits := fs.Int("bits", 2048, "RSA key size in bits")
+	keep := fs.Int("keep", 0, "number of newest keys to keep, 0 keeps all")
+	legacy := fs.String("import", "", "single-file signing key to add to the ring before rotating")
+	_ = fs.Parse(args)
+
+	if *bits < 2048 {
+		fmt.Fprintln(os.Stderr, "bits must be >= 2048")
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		fmt.Fprintln(os.Stderr, "create key ring:", err)
+		os.Exit(1)
+	}
+	if *legacy != "" {
+		kid, err := importKey(*dir, *legacy)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "import key:", err)
+			os.Exit(1)
+		}
+		fmt.Println("key imported:", kid)
+	}
+
+	kid, err := rotateKey(*dir, *bits, *keep)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rotate key:", err)
+		os.Exit(1)
+	}
+	fmt.Println("active signing key:", kid)
+	fmt.Println("send SIGHUP to the server to reload the key ring")
+}
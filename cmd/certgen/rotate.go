@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/auth"
+)
+
+// importKey copies a single-file signing key into the ring under its
+// fingerprint, the kid the server used for it before the ring existed.
+func importKey(dir, keyPath string) (string, error) {
+	buf, err := os.ReadFile(keyPath)
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return "", auth.ErrUnsupportedKey
+	}
+	var key *rsa.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var parsed any
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if rsaKey, ok := parsed.(*rsa.PrivateKey); ok {
+			key = rsaKey
+		} else if err == nil {
+			err = auth.ErrUnsupportedKey
+		}
+	default:
+		err = auth.ErrUnsupportedKey
+	}
+	if err != nil {
+		return "", err
+	}
+	kid := auth.KeyID(&key.PublicKey)
+	return kid, os.WriteFile(filepath.Join(dir, kid+".pem"), buf, 0o600)
+}
+
+// rotateKey generates a new signing key in dir and makes it active. Retired
+// keys stay in the ring to verify tokens they signed; keep > 0 limits the
+// ring to the newest keep keys.
+func rotateKey(dir string, bits, keep int) (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return "", err
+	}
+	kid := time.Now().UTC().Format("20060102T150405.000000")
+	pemBlock := &pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}
+	if err = os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(pemBlock), 0o600); err != nil {
+		return "", err
+	}
+
+	// the server may reload at any moment, so the active file is replaced
+	// atomically
+	tmp := filepath.Join(dir, auth.ActiveKeyFile+".tmp")
+	if err = os.WriteFile(tmp, []byte(kid+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp, filepath.Join(dir, auth.ActiveKeyFile)); err != nil {
+		return "", err
+	}
+	if keep > 0 {
+		err = pruneKeys(dir, kid, keep)
+	}
+	return kid, err
+}
+
+// pruneKeys removes the oldest retired keys so that keep keys, the active
+// one included, remain.
+func pruneKeys(dir, activeID string, keep int) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	retired := make([]string, 0, len(files))
+	modTime := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		if strings.TrimSuffix(filepath.Base(file), ".pem") == activeID {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		retired = append(retired, file)
+		modTime[file] = info.ModTime()
+	}
+	sort.Slice(retired, func(i, j int) bool { return modTime[retired[i]].After(modTime[retired[j]]) })
+	for i := keep - 1; i < len(retired); i++ {
+		if err = os.Remove(retired[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
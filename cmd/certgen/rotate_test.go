@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/auth"
+)
+
+func TestRotateKey(t *testing.T) {
+	dir := t.TempDir()
+
+	legacy, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyPath := filepath.Join(t.TempDir(), "private.pem")
+	pemBlock := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(legacy)}
+	if err = os.WriteFile(legacyPath, pem.EncodeToMemory(pemBlock), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	legacyID, err := importKey(dir, legacyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacyID != auth.KeyID(&legacy.PublicKey) {
+		t.Fatalf("imported kid = %s", legacyID)
+	}
+
+	first, err := rotateKey(dir, 2048, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := auth.LoadKeyRing(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid, _ := ring.Signing(); kid != first {
+		t.Fatalf("active kid = %s, want %s", kid, first)
+	}
+	if _, err = ring.Verifying(legacyID); err != nil {
+		t.Fatalf("imported key is not in the ring: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	second, err := rotateKey(dir, 2048, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ring.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if kid, _ := ring.Signing(); kid != second {
+		t.Fatalf("active kid = %s, want %s", kid, second)
+	}
+	if _, err = ring.Verifying(first); err != nil {
+		t.Fatalf("retired key was pruned: %v", err)
+	}
+	if _, err = ring.Verifying(legacyID); err == nil {
+		t.Fatal("oldest key was not pruned")
+	}
+}
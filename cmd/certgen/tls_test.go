@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
+)
+
+func TestWriteLeaf(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	if err := writeCA(path("ca.pem"), path("ca-key.pem"), "test CA", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeLeaf(path("ca.pem"), path("ca-key.pem"), path("server.pem"), path("server-key.pem"),
+		certRequest{commonName: "localhost", hosts: []string{"localhost", "127.0.0.1"}, days: 1}, x509.ExtKeyUsageServerAuth); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeLeaf(path("ca.pem"), path("ca-key.pem"), path("client.pem"), path("client-key.pem"),
+		certRequest{commonName: "user1@test.com", email: "user1@test.com", days: 1}, x509.ExtKeyUsageClientAuth); err != nil {
+		t.Fatal(err)
+	}
+
+	serverCfg, err := util.ServerTLSConfig(path("server.pem"), path("server-key.pem"), path("ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCfg, err := util.ClientTLSConfig(path("ca.pem"), path("client.pem"), path("client-key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCfg.ServerName = "localhost"
+
+	c, s := net.Pipe()
+	defer c.Close()
+	defer s.Close()
+	srv := tls.Server(s, serverCfg)
+	done := make(chan error, 1)
+	go func() { done <- srv.Handshake() }()
+	if err = tls.Client(c, clientCfg).Handshake(); err != nil {
+		t.Fatalf("client handshake: %v", err)
+	}
+	if err = <-done; err != nil {
+		t.Fatalf("server handshake: %v", err)
+	}
+
+	chains := srv.ConnectionState().VerifiedChains
+	if len(chains) == 0 {
+		t.Fatal("client certificate was not verified")
+	}
+	if got := util.CertIdentity(chains[0][0]); got != "user1@test.com" {
+		t.Errorf("CertIdentity() got = %v, want %v", got, "user1@test.com")
+	}
+}
+
+func TestSplitHosts(t *testing.T) {
+	got := splitHosts(" localhost, ,127.0.0.1 ")
+	if len(got) != 2 || got[0] != "localhost" || got[1] != "127.0.0.1" {
+		t.Errorf("splitHosts() got = %v", got)
+	}
+}
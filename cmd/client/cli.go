@@ -0,0 +1,867 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/exporters"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/importers"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/ui"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
+	"golang.org/x/term"
+)
+
+const (
+	envSession  = "GOPHKEEPER_SESSION"
+	envPassword = "GOPHKEEPER_PASSWORD"
+	envExport   = "GOPHKEEPER_EXPORT_PASSPHRASE"
+)
+
+var (
+	errUsage = errors.New(`usage: client [flags] <command> [args], commands:
+  login [-email e] [-code c]                   sign in and print the session key
+  logout                                       sign out and remove the session file
+  ls [-type t]                                 list the notes
+  get <name|id> [-field f] [-out path]         show a note, one field or the attached file
+  add credential|text|card|binary -name n ...  add a note
+  edit <name|id> ...                           change the given fields of a note
+  rm <name|id>                                 move a note to the trash
+  export -out path                             write the vault to an encrypted file
+  export -out path -format keepass|bitwarden   write the vault unencrypted for another manager
+  import -in path [-policy skip|overwrite|duplicate]
+                                               add the notes of an export
+  import -in path -format keepass|bitwarden|csv [-dry-run]
+                                               add the entries of another manager
+every command takes -json`)
+	errNoSession = errors.New("not signed in, run the login command first")
+)
+
+// vault is the part of ui.Service the commands use.
+type vault interface {
+	Login(user *pb.User) error
+	VerifyMfa(code string) error
+	Logout() error
+	SaveSession(sessionKey []byte) (*ui.SavedSession, error)
+	ResumeSession(saved ui.SavedSession, sessionKey []byte, password string) error
+	LoadNote() (*[]models.Noteable, error)
+	AddNote(note models.Noteable) (*[]models.Noteable, error)
+	DeleteNote(id uuid.UUID) (*[]models.Noteable, error)
+	UploadFile(path string) (*models.BlobRef, error)
+	UploadBlob(ref *models.BlobRef, r io.ReadSeeker) error
+	DownloadFile(ref models.BlobRef, path string) error
+	DownloadBlob(ref models.BlobRef, w io.Writer) error
+	ExportVaultFile(path, passphrase string) (int, error)
+	ImportVaultFile(path, passphrase string, policy ui.ImportPolicy) (*ui.ImportResult, error)
+}
+
+// cli runs one command of the non-interactive client. login keeps the
+// session in sessionFile with the vault key sealed by a session key that it
+// prints; the other commands read that key from GOPHKEEPER_SESSION, or unlock
+// the vault with the master password from GOPHKEEPER_PASSWORD or the
+// terminal instead.
+type cli struct {
+	v           vault
+	sessionFile string
+	in          *bufio.Reader
+	out         io.Writer
+	// terminal is set when stdin is a terminal; secrets are then read
+	// without echo.
+	terminal bool
+	getenv   func(string) string
+	// saved is the session a command resumed; it is written back afterwards
+	// because the tokens may have been refreshed.
+	saved *ui.SavedSession
+}
+
+func newCLI(v vault, sessionFile string) *cli {
+	return &cli{
+		v:           v,
+		sessionFile: sessionFile,
+		in:          bufio.NewReader(os.Stdin),
+		out:         os.Stdout,
+		terminal:    term.IsTerminal(int(os.Stdin.Fd())),
+		getenv:      os.Getenv,
+	}
+}
+
+func (c *cli) run(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	commands := map[string]func([]string) error{
+		"login":  c.login,
+		"logout": c.logout,
+		"ls":     c.list,
+		"get":    c.get,
+		"add":    c.add,
+		"edit":   c.edit,
+		"rm":     c.remove,
+		"export": c.exportVault,
+		"import": c.importVault,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return errUsage
+	}
+	err := command(args[1:])
+	if c.saved != nil {
+		if saveErr := c.persist(); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+func (c *cli) login(args []string) error {
+	fs := newFlagSet("login")
+	email := fs.String("email", "", "account e-mail")
+	code := fs.String("code", "", "second factor code")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if rest, err := parseArgs(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+
+	var err error
+	if *email == "" {
+		if *email, err = c.prompt("E-mail: ", false); err != nil {
+			return err
+		}
+	}
+	password, err := c.password()
+	if err != nil {
+		return err
+	}
+	err = c.v.Login(&pb.User{Email: *email, Password: password})
+	if errors.Is(err, ui.ErrMfaRequired) {
+		if *code == "" {
+			if *code, err = c.prompt("Code: ", false); err != nil {
+				return err
+			}
+		}
+		err = c.v.VerifyMfa(*code)
+	}
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 32)
+	if _, err = rand.Read(key); err != nil {
+		return err
+	}
+	saved, err := c.v.SaveSession(key)
+	if err != nil {
+		return err
+	}
+	if err = c.writeSession(saved); err != nil {
+		return err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(key)
+	if *asJSON {
+		return c.printJSON(map[string]string{"email": saved.Email, "session": encoded})
+	}
+	_, err = fmt.Fprintf(c.out, "export %s=%s\n", envSession, encoded)
+	return err
+}
+
+func (c *cli) logout(args []string) error {
+	fs := newFlagSet("logout")
+	fs.Bool("json", false, "print JSON")
+	if rest, err := parseArgs(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+	if err := c.resume(false); err != nil {
+		return err
+	}
+	c.saved = nil
+	err := c.v.Logout()
+	if removeErr := os.Remove(c.sessionFile); removeErr != nil && err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// noteView is a note as the commands print it. Fields are keyed by
+// fieldKey of their form label.
+type noteView struct {
+	ID     string            `json:"id"`
+	Type   string            `json:"type"`
+	Name   string            `json:"name"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func (c *cli) list(args []string) error {
+	fs := newFlagSet("ls")
+	typeNote := fs.String("type", "", "only notes of this type: credential, text, card or binary")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if rest, err := parseArgs(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+	notes, err := c.notes()
+	if err != nil {
+		return err
+	}
+
+	views := make([]noteView, 0, len(notes))
+	for _, note := range notes {
+		if *typeNote != "" && note.GetType() != noteTypes[*typeNote] {
+			continue
+		}
+		views = append(views, noteView{ID: note.GetID().String(), Type: note.GetType().String(), Name: note.GetName()})
+	}
+	if *asJSON {
+		return c.printJSON(views)
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tNAME")
+	for _, view := range views {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", view.ID, view.Type, view.Name)
+	}
+	return w.Flush()
+}
+
+func (c *cli) get(args []string) error {
+	fs := newFlagSet("get")
+	field := fs.String("field", "", "print only this field, e.g. password")
+	out := fs.String("out", "", "write the data or file of a binary note to this path")
+	asJSON := fs.Bool("json", false, "print JSON")
+	rest, err := parseArgs(fs, args)
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+	note, err := c.find(rest[0])
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		binary, ok := note.(*models.BinaryNote)
+		switch {
+		case !ok:
+			return fmt.Errorf("note %q is not a binary note", note.GetName())
+		case binary.Blob != nil:
+			return c.v.DownloadFile(*binary.Blob, *out)
+		}
+		return os.WriteFile(*out, binary.Binary, 0o600)
+	}
+
+	fields := models.Fields(note)
+	if *field != "" {
+		key := fieldKey(*field)
+		for _, f := range fields {
+			if fieldKey(f.Name) == key {
+				if *asJSON {
+					return c.printJSON(map[string]string{key: f.Value})
+				}
+				_, err = fmt.Fprintln(c.out, f.Value)
+				return err
+			}
+		}
+		keys := make([]string, 0, len(fields))
+		for _, f := range fields {
+			keys = append(keys, fieldKey(f.Name))
+		}
+		return fmt.Errorf("note %q has no field %q, it has %s", note.GetName(), *field, strings.Join(keys, ", "))
+	}
+
+	if *asJSON {
+		view := noteView{ID: note.GetID().String(), Type: note.GetType().String(), Name: note.GetName(), Fields: map[string]string{}}
+		for _, f := range fields {
+			view.Fields[fieldKey(f.Name)] = f.Value
+		}
+		return c.printJSON(view)
+	}
+	fmt.Fprintf(c.out, "ID: %s\nType: %s\n", note.GetID(), note.GetType())
+	for _, f := range fields {
+		fmt.Fprintf(c.out, "%s: %s\n", f.Name, f.Value)
+	}
+	return nil
+}
+
+// noteTypes maps the type names of the commands to note types.
+var noteTypes = map[string]models.TypeNote{
+	"credential": models.CREDENTIAL,
+	"text":       models.TEXT,
+	"card":       models.CARD,
+	"binary":     models.BINARY,
+}
+
+func (c *cli) add(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	var note models.Noteable
+	base := models.BaseNote{Id: uuid.New(), Created: time.Now().Unix(), Type: noteTypes[args[0]]}
+	switch base.Type {
+	case models.CREDENTIAL:
+		note = &models.CredentialNote{BaseNote: base}
+	case models.TEXT:
+		note = &models.TextNote{BaseNote: base}
+	case models.CARD:
+		note = &models.BankCardNote{BaseNote: base}
+	case models.BINARY:
+		note = &models.BinaryNote{BaseNote: base}
+	default:
+		return errUsage
+	}
+	return c.save(note, "add", args[1:])
+}
+
+func (c *cli) edit(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	note, err := c.find(args[0])
+	if err != nil {
+		return err
+	}
+	return c.save(copyNote(note), "edit", args[1:])
+}
+
+// save sets the fields given by the flags in args on note and stores it.
+func (c *cli) save(note models.Noteable, command string, args []string) error {
+	fs := newFlagSet(command)
+	asJSON := fs.Bool("json", false, "print JSON")
+	values := noteFlags(fs, note)
+	if rest, err := parseArgs(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+	if err := c.resume(true); err != nil {
+		return err
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if value, ok := values[f.Name]; ok && err == nil {
+			err = c.setField(note, f.Name, *value)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if note.GetName() == "" {
+		return errors.New("the note needs a -name")
+	}
+	if _, err = c.v.AddNote(note); err != nil {
+		return err
+	}
+	return c.printID(note, *asJSON)
+}
+
+func (c *cli) remove(args []string) error {
+	fs := newFlagSet("rm")
+	asJSON := fs.Bool("json", false, "print JSON")
+	rest, err := parseArgs(fs, args)
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+	note, err := c.find(rest[0])
+	if err != nil {
+		return err
+	}
+	if _, err = c.v.DeleteNote(note.GetID()); err != nil {
+		return err
+	}
+	return c.printID(note, *asJSON)
+}
+
+func (c *cli) exportVault(args []string) error {
+	fs := newFlagSet("export")
+	out := fs.String("out", "", "export file")
+	format := fs.String("format", formatGophKeeper, "gophkeeper, keepass or bitwarden")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if rest, err := parseArgs(fs, args); err != nil || len(rest) > 0 || *out == "" {
+		return errUsage
+	}
+	if *format != formatGophKeeper {
+		return c.exportForeign(*out, *format, *asJSON)
+	}
+	if err := c.resume(true); err != nil {
+		return err
+	}
+	passphrase, err := c.exportPassphrase()
+	if err != nil {
+		return err
+	}
+	count, err := c.v.ExportVaultFile(*out, passphrase)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return c.printJSON(map[string]interface{}{"file": *out, "notes": count})
+	}
+	_, err = fmt.Fprintf(c.out, "%d notes exported to %s\n", count, *out)
+	return err
+}
+
+// exportForeign writes the vault, with its uploaded files, in the format of
+// another manager. The file is not encrypted.
+func (c *cli) exportForeign(path, formatName string, asJSON bool) error {
+	format, err := exporters.ParseFormat(formatName)
+	if err != nil {
+		return err
+	}
+	notes, err := c.notes()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = exporters.Write(format, &buf, notes, func(ref *models.BlobRef) ([]byte, error) {
+		var data bytes.Buffer
+		err := c.v.DownloadBlob(*ref, &data)
+		return data.Bytes(), err
+	})
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if asJSON {
+		return c.printJSON(map[string]interface{}{"file": path, "notes": len(notes), "format": format})
+	}
+	_, err = fmt.Fprintf(c.out, "%d notes exported to %s, unencrypted: keep it safe and delete it after the import\n", len(notes), path)
+	return err
+}
+
+func (c *cli) importVault(args []string) error {
+	fs := newFlagSet("import")
+	in := fs.String("in", "", "export file")
+	format := fs.String("format", formatGophKeeper, "gophkeeper, keepass, bitwarden or csv")
+	policyName := fs.String("policy", string(ui.ImportSkip), "notes the vault already has: skip, overwrite or duplicate")
+	dryRun := fs.Bool("dry-run", false, "only report what a keepass, bitwarden or csv import would create")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if rest, err := parseArgs(fs, args); err != nil || len(rest) > 0 || *in == "" {
+		return errUsage
+	}
+	if *format != formatGophKeeper {
+		return c.importForeign(*in, *format, *dryRun, *asJSON)
+	}
+	if *dryRun {
+		return errors.New("-dry-run needs -format keepass, bitwarden or csv")
+	}
+	policy, err := ui.ParseImportPolicy(*policyName)
+	if err != nil {
+		return err
+	}
+	if err = c.resume(true); err != nil {
+		return err
+	}
+	passphrase, err := c.exportPassphrase()
+	if err != nil {
+		return err
+	}
+	result, err := c.v.ImportVaultFile(*in, passphrase, policy)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return c.printJSON(result)
+	}
+	_, err = fmt.Fprintln(c.out, result)
+	return err
+}
+
+// formatGophKeeper is the import format of files written by export.
+const formatGophKeeper = "gophkeeper"
+
+// inlineLimit is the largest attachment of a foreign export that is kept
+// inside its note; larger ones are uploaded as files.
+const inlineLimit = 64 << 10
+
+// importForeign creates the notes of a KeePass, Bitwarden or browser export,
+// or with dryRun only reports them.
+func (c *cli) importForeign(path, formatName string, dryRun, asJSON bool) error {
+	format, err := importers.ParseFormat(formatName)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	result, err := importers.Parse(format, file)
+	if err != nil {
+		return err
+	}
+
+	if !dryRun {
+		if err = c.resume(true); err != nil {
+			return err
+		}
+		for i, note := range result.Notes {
+			if err = c.attachLarge(note); err != nil {
+				return fmt.Errorf("importing %q: %w", note.GetName(), err)
+			}
+			if _, err = c.v.AddNote(note); err != nil {
+				return fmt.Errorf("importing %q after %d notes: %w", note.GetName(), i, err)
+			}
+		}
+	}
+	if asJSON {
+		report := struct {
+			DryRun  bool                `json:"dry_run"`
+			Created []noteView          `json:"created"`
+			Skipped []importers.Skipped `json:"skipped"`
+		}{DryRun: dryRun, Created: make([]noteView, 0, len(result.Notes)), Skipped: result.Skipped}
+		for _, note := range result.Notes {
+			report.Created = append(report.Created, noteView{ID: note.GetID().String(), Type: note.GetType().String(), Name: note.GetName()})
+		}
+		return c.printJSON(report)
+	}
+	if dryRun {
+		fmt.Fprintln(c.out, "Dry run, nothing is imported:")
+	}
+	return result.Report(c.out)
+}
+
+// attachLarge moves an attachment too large for a note into a file.
+func (c *cli) attachLarge(note models.Noteable) error {
+	binary, ok := note.(*models.BinaryNote)
+	if !ok || len(binary.Binary) <= inlineLimit {
+		return nil
+	}
+	key, err := util.NewDataKey()
+	if err != nil {
+		return err
+	}
+	name := binary.NameRecord
+	for _, line := range binary.MetaInfo {
+		if strings.HasPrefix(line, "File: ") {
+			name = strings.TrimPrefix(line, "File: ")
+		}
+	}
+	ref := &models.BlobRef{ID: uuid.New(), FileName: name, Size: int64(len(binary.Binary)), Key: key}
+	if err = c.v.UploadBlob(ref, bytes.NewReader(binary.Binary)); err != nil {
+		return err
+	}
+	binary.Blob, binary.Binary = ref, nil
+	return nil
+}
+
+func (c *cli) exportPassphrase() (string, error) {
+	if passphrase := c.getenv(envExport); passphrase != "" {
+		return passphrase, nil
+	}
+	return c.prompt("Export passphrase: ", true)
+}
+
+// noteFlags registers the flags that set the fields of note.
+func noteFlags(fs *flag.FlagSet, note models.Noteable) map[string]*string {
+	values := make(map[string]*string)
+	add := func(name, usage string) {
+		values[name] = fs.String(name, "", usage)
+	}
+	add("name", "note name")
+	add("meta", "additional information, one entry per line")
+	switch note.(type) {
+	case *models.CredentialNote:
+		add("username", "username")
+		add("password", "password, - asks for it")
+	case *models.TextNote:
+		add("text", "text, - reads it from stdin")
+	case *models.BankCardNote:
+		add("bank", "bank name")
+		add("number", "card number")
+		add("expiry", "card expiration, MM/YY")
+		add("holder", "cardholder name")
+		add("cvv", "security code, - asks for it")
+	case *models.BinaryNote:
+		add("data", "inline data")
+		add("file", "file to encrypt and attach")
+	}
+	return values
+}
+
+func (c *cli) setField(note models.Noteable, name, value string) error {
+	var err error
+	switch {
+	case value == "-" && name == "text":
+		var data []byte
+		if data, err = io.ReadAll(c.in); err != nil {
+			return err
+		}
+		value = strings.TrimSuffix(string(data), "\n")
+	case value == "-" && (name == "password" || name == "cvv"):
+		if value, err = c.prompt(name+": ", true); err != nil {
+			return err
+		}
+	}
+
+	switch n := note.(type) {
+	case *models.CredentialNote:
+		switch name {
+		case "username":
+			n.Username = value
+		case "password":
+			n.Password = value
+		}
+	case *models.TextNote:
+		if name == "text" {
+			n.Text = value
+		}
+	case *models.BankCardNote:
+		switch name {
+		case "bank":
+			n.Bank = value
+		case "number":
+			n.Number = value
+		case "expiry":
+			n.Expiration = value
+		case "holder":
+			n.Cardholder = value
+		case "cvv":
+			n.SecurityCode = value
+		}
+	case *models.BinaryNote:
+		switch name {
+		case "data":
+			n.Binary = []byte(value)
+		case "file":
+			ref, err := c.v.UploadFile(value)
+			if err != nil {
+				return err
+			}
+			n.Blob = ref
+		}
+	}
+
+	base := baseOf(note)
+	switch name {
+	case "name":
+		base.NameRecord = value
+	case "meta":
+		base.MetaInfo = nil
+		if value != "" {
+			base.MetaInfo = strings.Split(value, "\n")
+		}
+	}
+	return nil
+}
+
+func baseOf(note models.Noteable) *models.BaseNote {
+	switch n := note.(type) {
+	case *models.CredentialNote:
+		return &n.BaseNote
+	case *models.TextNote:
+		return &n.BaseNote
+	case *models.BankCardNote:
+		return &n.BaseNote
+	case *models.BinaryNote:
+		return &n.BaseNote
+	}
+	return &models.BaseNote{}
+}
+
+// copyNote returns a copy of note to edit, so a failed save leaves the
+// loaded note as it is.
+func copyNote(note models.Noteable) models.Noteable {
+	switch n := note.(type) {
+	case *models.CredentialNote:
+		edited := *n
+		return &edited
+	case *models.TextNote:
+		edited := *n
+		return &edited
+	case *models.BankCardNote:
+		edited := *n
+		return &edited
+	case *models.BinaryNote:
+		edited := *n
+		return &edited
+	}
+	return note
+}
+
+// fieldKey names a field on the command line: "Card number" is card_number.
+func fieldKey(name string) string {
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// find returns the note with id or name ref. A name that several notes share
+// has to be given as an id.
+func (c *cli) find(ref string) (models.Noteable, error) {
+	notes, err := c.notes()
+	if err != nil {
+		return nil, err
+	}
+	id, idErr := uuid.Parse(ref)
+	var found []models.Noteable
+	for _, note := range notes {
+		if (idErr == nil && note.GetID() == id) || note.GetName() == ref {
+			found = append(found, note)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no note %q", ref)
+	case 1:
+		return found[0], nil
+	}
+	ids := make([]string, 0, len(found))
+	for _, note := range found {
+		ids = append(ids, note.GetID().String())
+	}
+	return nil, fmt.Errorf("%d notes are named %q, use one of the ids: %s", len(found), ref, strings.Join(ids, ", "))
+}
+
+// notes loads the vault, sorted by name.
+func (c *cli) notes() ([]models.Noteable, error) {
+	if err := c.resume(true); err != nil {
+		return nil, err
+	}
+	loaded, err := c.v.LoadNote()
+	if err != nil {
+		return nil, err
+	}
+	notes := *loaded
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].GetName() != notes[j].GetName() {
+			return notes[i].GetName() < notes[j].GetName()
+		}
+		return notes[i].GetID().String() < notes[j].GetID().String()
+	})
+	return notes, nil
+}
+
+// resume continues the saved session once per command. unlock also unlocks
+// the vault.
+func (c *cli) resume(unlock bool) error {
+	if c.saved != nil {
+		return nil
+	}
+	buf, err := os.ReadFile(c.sessionFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return errNoSession
+	}
+	if err != nil {
+		return err
+	}
+	var saved ui.SavedSession
+	if err = json.Unmarshal(buf, &saved); err != nil {
+		return fmt.Errorf("session file %s: %w", c.sessionFile, err)
+	}
+
+	var key []byte
+	var password string
+	if unlock {
+		if encoded := c.getenv(envSession); encoded != "" {
+			if key, err = base64.RawURLEncoding.DecodeString(encoded); err != nil {
+				return ui.ErrSessionKey
+			}
+		} else if password, err = c.password(); err != nil {
+			return err
+		}
+	}
+	if err = c.v.ResumeSession(saved, key, password); err != nil {
+		return err
+	}
+	c.saved = &saved
+	return nil
+}
+
+// persist writes the refreshed tokens back to the session file.
+func (c *cli) persist() error {
+	saved, err := c.v.SaveSession(nil)
+	if err != nil {
+		return err
+	}
+	saved.SealedKey = c.saved.SealedKey
+	return c.writeSession(saved)
+}
+
+// writeSession replaces the session file in one step, readable only by the
+// user.
+func (c *cli) writeSession(saved *ui.SavedSession) error {
+	buf, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.sessionFile + ".tmp"
+	if err = os.WriteFile(tmp, buf, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.sessionFile)
+}
+
+func (c *cli) password() (string, error) {
+	if password := c.getenv(envPassword); password != "" {
+		return password, nil
+	}
+	return c.prompt("Master password: ", true)
+}
+
+// prompt asks for a value on the terminal, without echo for a secret.
+// Without a terminal it reads a line from stdin.
+func (c *cli) prompt(label string, secret bool) (string, error) {
+	if c.terminal {
+		fmt.Fprint(os.Stderr, label)
+		if secret {
+			value, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Fprintln(os.Stderr)
+			return string(value), err
+		}
+	}
+	line, err := c.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", fmt.Errorf("reading %s%w", strings.ToLower(label), err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (c *cli) printID(note models.Noteable, asJSON bool) error {
+	if asJSON {
+		return c.printJSON(noteView{ID: note.GetID().String(), Type: note.GetType().String(), Name: note.GetName()})
+	}
+	_, err := fmt.Fprintln(c.out, note.GetID())
+	return err
+}
+
+func (c *cli) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseArgs parses flags that may also follow the positional arguments, as
+// in "get mail -field password", and returns the positional ones.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -4,31 +4,58 @@ import (
 	"encoding/json"
 	"errors"
 	"flag"
+	"log"
 	"os"
 	"strings"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
 )
 
 const defaultClientConfigPath = "config_c.json"
 
 var (
-	connAddr string
-	logLevel string
-	logFile  string
-	confFile string
+	connAddr    string
+	logLevel    string
+	logFile     string
+	confFile    string
+	kdfTime     uint
+	kdfMemory   uint
+	kdfThreads  uint
+	zeroKnow    bool
+	legacyLogin bool
+	caFile      string
+	tlsCert     string
+	tlsKey      string
+	cacheFile   string
+	sessionFile string
 )
 
 type clientConfig struct {
-	ConnAddr string `json:"conn_addr"`
-	LogLevel string `json:"log_level"`
-	LogFile  string `json:"log_file"`
+	ConnAddr    string `json:"conn_addr"`
+	LogLevel    string `json:"log_level"`
+	LogFile     string `json:"log_file"`
+	KdfTime     uint   `json:"kdf_time,omitempty"`
+	KdfMemory   uint   `json:"kdf_memory,omitempty"`
+	KdfThreads  uint   `json:"kdf_threads,omitempty"`
+	ZeroKnow    bool   `json:"zero_knowledge,omitempty"`
+	CAFile      string `json:"ca_file,omitempty"`
+	TLSCert     string `json:"tls_cert,omitempty"`
+	TLSKey      string `json:"tls_key,omitempty"`
+	CacheFile   string `json:"cache_file,omitempty"`
+	SessionFile string `json:"session_file,omitempty"`
 }
 
 func parseFlags() {
 	confFile = resolveConfigPath(defaultClientConfigPath)
 	defaults := clientConfig{
-		ConnAddr: "localhost:3200",
-		LogLevel: "info",
-		LogFile:  "logs.log",
+		ConnAddr:    "localhost:3200",
+		LogLevel:    "info",
+		LogFile:     "logs.log",
+		KdfTime:     uint(util.DefaultKdfTime),
+		KdfMemory:   uint(util.DefaultKdfMemory),
+		KdfThreads:  uint(util.DefaultKdfThreads),
+		CacheFile:   "vault_cache.db",
+		SessionFile: "session.json",
 	}
 
 	if cfg, err := loadClientConfig(confFile); err == nil {
@@ -41,18 +68,62 @@ func parseFlags() {
 		if cfg.LogFile != "" {
 			defaults.LogFile = cfg.LogFile
 		}
+		if cfg.KdfTime != 0 {
+			defaults.KdfTime = cfg.KdfTime
+		}
+		if cfg.KdfMemory != 0 {
+			defaults.KdfMemory = cfg.KdfMemory
+		}
+		if cfg.KdfThreads != 0 {
+			defaults.KdfThreads = cfg.KdfThreads
+		}
+		defaults.ZeroKnow = cfg.ZeroKnow
+		defaults.CAFile = cfg.CAFile
+		defaults.TLSCert = cfg.TLSCert
+		defaults.TLSKey = cfg.TLSKey
+		if cfg.CacheFile != "" {
+			defaults.CacheFile = cfg.CacheFile
+		}
+		if cfg.SessionFile != "" {
+			defaults.SessionFile = cfg.SessionFile
+		}
 	}
 
 	flag.StringVar(&confFile, "cfg", confFile, "config file path")
 	flag.StringVar(&connAddr, "a", defaults.ConnAddr, "server connection address")
 	flag.StringVar(&logLevel, "ll", defaults.LogLevel, "log level")
 	flag.StringVar(&logFile, "lf", defaults.LogFile, "log path")
+	flag.UintVar(&kdfTime, "kt", defaults.KdfTime, "argon2id iterations")
+	flag.UintVar(&kdfMemory, "km", defaults.KdfMemory, "argon2id memory in KiB")
+	flag.UintVar(&kdfThreads, "kp", defaults.KdfThreads, "argon2id parallelism")
+	flag.StringVar(&caFile, "ca", defaults.CAFile, "server CA path, enables TLS")
+	flag.StringVar(&tlsCert, "tc", defaults.TLSCert, "client certificate path for mutual TLS")
+	flag.StringVar(&tlsKey, "tk", defaults.TLSKey, "client private key path for mutual TLS")
+	flag.StringVar(&cacheFile, "vc", defaults.CacheFile, "local encrypted cache of synced notes and offline edits, empty disables it")
+	flag.StringVar(&sessionFile, "sf", defaults.SessionFile, "session file of the command line client")
+	flag.BoolVar(&zeroKnow, "zk", defaults.ZeroKnow, "keep note names and types encrypted on the server")
+	// not saved: the password is sent only for runs that ask for it
+	flag.BoolVar(&legacyLogin, "legacy-login", false, "send the password when the server has no SRP verifier for the account")
 	flag.Parse()
+	// the values are narrowed to the Argon2id parameter types; keys derived
+	// above the maximum could not be opened again
+	if kdfTime > uint(util.MaxKdfTime) || kdfMemory > uint(util.MaxKdfMemory) || kdfThreads > uint(util.MaxKdfThreads) {
+		log.Fatalf("argon2id cost must not exceed -kt %d -km %d -kp %d", util.MaxKdfTime, util.MaxKdfMemory, util.MaxKdfThreads)
+	}
 
 	_ = saveClientConfig(confFile, &clientConfig{
-		ConnAddr: connAddr,
-		LogLevel: logLevel,
-		LogFile:  logFile,
+		ConnAddr:    connAddr,
+		LogLevel:    logLevel,
+		LogFile:     logFile,
+		KdfTime:     kdfTime,
+		KdfMemory:   kdfMemory,
+		KdfThreads:  kdfThreads,
+		ZeroKnow:    zeroKnow,
+		CAFile:      caFile,
+		TLSCert:     tlsCert,
+		TLSKey:      tlsKey,
+		CacheFile:   cacheFile,
+		SessionFile: sessionFile,
 	})
 }
 
@@ -1,15 +1,17 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
 
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/logger"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/mvc"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/ui"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 var appLogger *logger.Logger
@@ -18,7 +20,11 @@ func main() {
 	parseFlags()
 	initLogger()
 
-	conn, err := grpc.NewClient(connAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds, err := util.ClientCredentials(caFile, tlsCert, tlsKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn, err := grpc.NewClient(connAddr, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -29,6 +35,21 @@ func main() {
 	}(conn)
 
 	uiService := ui.NewUIService(appLogger, conn)
+	uiService.SetKdfCost(uint32(kdfTime), uint32(kdfMemory), uint8(kdfThreads))
+	uiService.SetZeroKnowledge(zeroKnow)
+	uiService.SetPasswordLogin(legacyLogin)
+	if cacheFile != "" {
+		if err = uiService.SetCache(cacheFile); err != nil {
+			appLogger.WithError(err).Warning("vault cache disabled")
+		}
+	}
+	if args := flag.Args(); len(args) > 0 {
+		if err = newCLI(uiService, sessionFile).run(args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	controller := mvc.NewUIController(appLogger, uiService)
 	controller.AddItemInfoList("The application started successfully. Welcome!")
 	if err = controller.Run(); err != nil {
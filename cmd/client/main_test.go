@@ -1,8 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/importers"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/ui"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestInitLogger(t *testing.T) {
@@ -14,3 +29,340 @@ func TestInitLogger(t *testing.T) {
 		t.Fatal("expected app logger to be initialized")
 	}
 }
+
+type fakeVault struct {
+	notes    map[uuid.UUID]models.Noteable
+	mfa      bool
+	password string
+	resumed  []byte
+	files    map[string][]byte
+}
+
+func (v *fakeVault) Login(user *pb.User) error {
+	if user.Password != "master" {
+		return errors.New("wrong password")
+	}
+	if v.mfa {
+		return ui.ErrMfaRequired
+	}
+	return nil
+}
+
+func (v *fakeVault) VerifyMfa(code string) error {
+	if code != "123456" {
+		return errors.New("wrong code")
+	}
+	return nil
+}
+
+func (v *fakeVault) Logout() error { return nil }
+
+func (v *fakeVault) SaveSession(sessionKey []byte) (*ui.SavedSession, error) {
+	return &ui.SavedSession{Email: "cli@test.com", Token: "jwt", SealedKey: sessionKey}, nil
+}
+
+func (v *fakeVault) ResumeSession(saved ui.SavedSession, sessionKey []byte, password string) error {
+	switch {
+	case sessionKey != nil && !bytes.Equal(sessionKey, saved.SealedKey):
+		return ui.ErrSessionKey
+	case sessionKey == nil && password != "" && password != "master":
+		return errors.New("wrong password")
+	}
+	v.resumed, v.password = sessionKey, password
+	return nil
+}
+
+func (v *fakeVault) LoadNote() (*[]models.Noteable, error) {
+	notes := make([]models.Noteable, 0, len(v.notes))
+	for _, note := range v.notes {
+		notes = append(notes, note)
+	}
+	return &notes, nil
+}
+
+func (v *fakeVault) AddNote(note models.Noteable) (*[]models.Noteable, error) {
+	v.notes[note.GetID()] = note
+	return v.LoadNote()
+}
+
+func (v *fakeVault) DeleteNote(id uuid.UUID) (*[]models.Noteable, error) {
+	delete(v.notes, id)
+	return v.LoadNote()
+}
+
+func (v *fakeVault) UploadFile(path string) (*models.BlobRef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ref := &models.BlobRef{ID: uuid.New(), FileName: filepath.Base(path), Size: int64(len(data))}
+	v.files[ref.ID.String()] = data
+	return ref, nil
+}
+
+func (v *fakeVault) DownloadFile(ref models.BlobRef, path string) error {
+	return os.WriteFile(path, v.files[ref.ID.String()], 0o600)
+}
+
+func (v *fakeVault) DownloadBlob(ref models.BlobRef, w io.Writer) error {
+	_, err := w.Write(v.files[ref.ID.String()])
+	return err
+}
+
+func (v *fakeVault) ExportVaultFile(path, passphrase string) (int, error) {
+	return len(v.notes), os.WriteFile(path, []byte(passphrase), 0o600)
+}
+
+func (v *fakeVault) ImportVaultFile(path, passphrase string, policy ui.ImportPolicy) (*ui.ImportResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) != passphrase {
+		return nil, ui.ErrExportPassphrase
+	}
+	if policy == ui.ImportSkip {
+		return &ui.ImportResult{Skipped: len(v.notes)}, nil
+	}
+	return &ui.ImportResult{Overwritten: len(v.notes)}, nil
+}
+
+func (v *fakeVault) UploadBlob(ref *models.BlobRef, r io.ReadSeeker) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	v.files[ref.ID.String()] = data
+	return nil
+}
+
+func newTestCLI(t *testing.T, v vault, env map[string]string, stdin string) (*cli, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &cli{
+		v:           v,
+		sessionFile: filepath.Join(t.TempDir(), "session.json"),
+		in:          bufio.NewReader(strings.NewReader(stdin)),
+		out:         out,
+		getenv:      func(key string) string { return env[key] },
+	}, out
+}
+
+func TestCLI_login(t *testing.T) {
+	v := &fakeVault{mfa: true}
+	c, out := newTestCLI(t, v, nil, "master\n123456\n")
+
+	assert.NoError(t, c.run([]string{"login", "-email", "cli@test.com"}))
+	assert.True(t, strings.HasPrefix(out.String(), "export "+envSession+"="), out.String())
+	info, err := os.Stat(c.sessionFile)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+	var saved ui.SavedSession
+	buf, _ := os.ReadFile(c.sessionFile)
+	assert.NoError(t, json.Unmarshal(buf, &saved))
+	key := strings.TrimSpace(strings.SplitN(out.String(), "=", 2)[1])
+	assert.Equal(t, key, base64.RawURLEncoding.EncodeToString(saved.SealedKey))
+
+	c.in = bufio.NewReader(strings.NewReader("master\n000000\n"))
+	assert.EqualError(t, c.run([]string{"login", "-email", "cli@test.com"}), "wrong code")
+}
+
+func TestCLI_notes(t *testing.T) {
+	v := &fakeVault{notes: map[uuid.UUID]models.Noteable{}, files: map[string][]byte{}}
+	c, out := newTestCLI(t, v, nil, "master\n")
+	assert.ErrorIs(t, c.run([]string{"ls"}), errNoSession)
+	assert.NoError(t, c.run([]string{"login", "-email", "cli@test.com"}))
+	env := map[string]string{envSession: strings.TrimSpace(strings.SplitN(out.String(), "=", 2)[1])}
+
+	run := func(stdin string, args ...string) (string, error) {
+		out.Reset()
+		c.saved = nil
+		c.in = bufio.NewReader(strings.NewReader(stdin))
+		c.getenv = func(key string) string { return env[key] }
+		err := c.run(args)
+		return out.String(), err
+	}
+
+	got, err := run("s3cret\n", "add", "credential", "-name", "mail", "-username", "me", "-password", "-")
+	assert.NoError(t, err)
+	id := strings.TrimSpace(got)
+	assert.Equal(t, v.resumed, mustDecode(t, env[envSession]), "unlocked with the session key")
+
+	got, err = run("", "get", "mail", "-field", "password")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret\n", got)
+
+	got, err = run("", "edit", "mail", "-password", "n3w", "-meta", "work")
+	assert.NoError(t, err)
+	assert.Equal(t, id+"\n", got)
+	got, err = run("", "get", id, "-json")
+	assert.NoError(t, err)
+	var view noteView
+	assert.NoError(t, json.Unmarshal([]byte(got), &view))
+	assert.Equal(t, noteView{ID: id, Type: models.CREDENTIAL.String(), Name: "mail", Fields: map[string]string{
+		"note": "mail", "username": "me", "password": "n3w", "additional_information": "work",
+	}}, view)
+	_, err = run("", "get", "mail", "-field", "pin")
+	assert.EqualError(t, err, `note "mail" has no field "pin", it has note, username, password, additional_information`)
+
+	file := filepath.Join(t.TempDir(), "scan.pdf")
+	assert.NoError(t, os.WriteFile(file, []byte("%PDF"), 0o600))
+	_, err = run("", "add", "binary", "-name", "scan", "-file", file)
+	assert.NoError(t, err)
+	copied := filepath.Join(t.TempDir(), "copy.pdf")
+	_, err = run("", "get", "scan", "-out", copied)
+	assert.NoError(t, err)
+	data, _ := os.ReadFile(copied)
+	assert.Equal(t, []byte("%PDF"), data)
+
+	_, err = run("", "add", "text", "-name", "mail", "-text", "second")
+	assert.NoError(t, err)
+	got, err = run("", "ls", "-type", "credential")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(got, "\n"), got)
+	_, err = run("", "rm", "mail")
+	assert.ErrorContains(t, err, `2 notes are named "mail"`)
+
+	env = map[string]string{envPassword: "master"}
+	_, err = run("", "rm", id)
+	assert.NoError(t, err)
+	assert.Nil(t, v.resumed)
+	assert.Equal(t, "master", v.password)
+	assert.Len(t, v.notes, 2)
+
+	env = map[string]string{envSession: base64.RawURLEncoding.EncodeToString([]byte("other"))}
+	_, err = run("", "ls")
+	assert.ErrorIs(t, err, ui.ErrSessionKey)
+
+	env = map[string]string{envPassword: "master"}
+	export := filepath.Join(t.TempDir(), "vault.json")
+	got, err = run("correct horse\n", "export", "-out", export)
+	assert.NoError(t, err)
+	assert.Equal(t, "2 notes exported to "+export+"\n", got)
+	env[envExport] = "correct horse"
+	got, err = run("", "import", "-in", export, "-policy", "overwrite", "-json")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"added":0,"overwritten":2,"duplicated":0,"skipped":0}`, got)
+	_, err = run("", "import", "-in", export, "-policy", "merge")
+	assert.EqualError(t, err, `unknown import policy "merge", use skip, overwrite or duplicate`)
+	env[envExport] = "wrong horse"
+	_, err = run("", "import", "-in", export)
+	assert.ErrorIs(t, err, ui.ErrExportPassphrase)
+
+	_, err = run("", "add", "note", "-name", "x")
+	assert.ErrorIs(t, err, errUsage)
+	_, err = run("", "add", "text", "-text", "nameless")
+	assert.EqualError(t, err, "the note needs a -name")
+}
+
+func TestCLI_importForeign(t *testing.T) {
+	v := &fakeVault{notes: map[uuid.UUID]models.Noteable{}, files: map[string][]byte{}}
+	c, out := newTestCLI(t, v, map[string]string{envPassword: "master"}, "master\n")
+	assert.NoError(t, c.run([]string{"login", "-email", "cli@test.com"}))
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "passwords.csv")
+	assert.NoError(t, os.WriteFile(csvFile, []byte("name,url,username,password\nMail,,me,pw\nNone,,,\n"), 0o600))
+
+	run := func(args ...string) (string, error) {
+		out.Reset()
+		c.saved = nil
+		err := c.run(args)
+		return out.String(), err
+	}
+	got, err := run("import", "-in", csvFile, "-format", "csv", "-dry-run")
+	assert.NoError(t, err)
+	assert.Equal(t, "Dry run, nothing is imported:\ncreate  credential  Mail\nskip    None        no username or password\n1 to create, 1 skipped\n", got)
+	assert.Empty(t, v.notes)
+
+	got, err = run("import", "-in", csvFile, "-format", "csv", "-json")
+	assert.NoError(t, err)
+	assert.Len(t, v.notes, 1)
+	var report struct {
+		DryRun  bool       `json:"dry_run"`
+		Created []noteView `json:"created"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(got), &report))
+	assert.False(t, report.DryRun)
+	if assert.Len(t, report.Created, 1) {
+		assert.Equal(t, "me", v.notes[uuid.MustParse(report.Created[0].ID)].(*models.CredentialNote).Username)
+	}
+
+	large := bytes.Repeat([]byte("k"), inlineLimit+1)
+	keepass := filepath.Join(dir, "keepass.xml")
+	assert.NoError(t, os.WriteFile(keepass, []byte(`<KeePassFile><Root><Group><Name>Root</Name><Entry>
+<String><Key>Title</Key><Value>Backup</Value></String>
+<Binary><Key>disk.img</Key><Value>`+base64.StdEncoding.EncodeToString(large)+`</Value></Binary>
+</Entry></Group></Root></KeePassFile>`), 0o600))
+	_, err = run("import", "-in", keepass, "-format", "keepass")
+	assert.NoError(t, err)
+	assert.Len(t, v.notes, 2)
+	for _, note := range v.notes {
+		if binary, ok := note.(*models.BinaryNote); ok && assert.NotNil(t, binary.Blob) {
+			assert.Nil(t, binary.Binary)
+			assert.Equal(t, "disk.img", binary.Blob.FileName)
+			assert.Equal(t, large, v.files[binary.Blob.ID.String()])
+		}
+	}
+
+	for _, format := range []string{"keepass", "bitwarden"} {
+		exported := filepath.Join(dir, "export."+format)
+		got, err = run("export", "-out", exported, "-format", format)
+		assert.NoError(t, err)
+		assert.Equal(t, "2 notes exported to "+exported+", unencrypted: keep it safe and delete it after the import\n", got)
+		file, err := os.Open(exported)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		result, err := importers.Parse(importers.Format(format), file)
+		file.Close()
+		if assert.NoError(t, err) && assert.Len(t, result.Notes, 2) {
+			names := map[string][]byte{}
+			for _, note := range result.Notes {
+				if binary, ok := note.(*models.BinaryNote); ok {
+					names[note.GetName()] = binary.Binary
+				} else {
+					names[note.GetName()] = nil
+				}
+			}
+			assert.Equal(t, map[string][]byte{"Mail": nil, "Backup": large}, names)
+		}
+	}
+	_, err = run("export", "-out", filepath.Join(dir, "x"), "-format", "csv")
+	assert.EqualError(t, err, `unknown export format "csv", use keepass or bitwarden`)
+
+	_, err = run("import", "-in", csvFile, "-format", "1password")
+	assert.EqualError(t, err, `unknown import format "1password", use keepass, bitwarden or csv`)
+	_, err = run("import", "-in", csvFile, "-dry-run")
+	assert.EqualError(t, err, "-dry-run needs -format keepass, bitwarden or csv")
+}
+
+func mustDecode(t *testing.T, s string) []byte {
+	key, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		want  []string
+		field string
+	}{
+		{name: "flags first", args: []string{"-field", "password", "mail"}, want: []string{"mail"}, field: "password"},
+		{name: "flags last", args: []string{"mail", "-field", "password"}, want: []string{"mail"}, field: "password"},
+		{name: "no flags", args: []string{"mail", "work"}, want: []string{"mail", "work"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFlagSet("get")
+			field := fs.String("field", "", "")
+			got, err := parseArgs(fs, tt.args)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.field, *field)
+		})
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -10,11 +9,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces"
 	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -24,15 +23,25 @@ func main() {
 		username string
 		email    string
 		password string
+		caFile   string
+		tlsCert  string
+		tlsKey   string
 	)
 
 	flag.StringVar(&addr, "addr", "localhost:3200", "gRPC server address")
 	flag.StringVar(&username, "username", "demo-user", "demo username")
 	flag.StringVar(&email, "email", "demo@example.com", "demo email")
 	flag.StringVar(&password, "password", "DemoPass123!", "demo password")
+	flag.StringVar(&caFile, "ca", "", "server CA path, enables TLS")
+	flag.StringVar(&tlsCert, "tc", "", "client certificate path for mutual TLS")
+	flag.StringVar(&tlsKey, "tk", "", "client private key path for mutual TLS")
 	flag.Parse()
 
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds, err := util.ClientCredentials(caFile, tlsCert, tlsKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -46,10 +55,29 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	jwtToken, err := userClient.Register(ctx, &pb.User{
-		Username: username,
-		Email:    email,
-		Password: password,
+	params, err := util.NewKdfParams(0, 0, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	kek, err := util.DeriveKey(password, params)
+	if err != nil {
+		log.Fatal(err)
+	}
+	key, err := util.NewDataKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	wrapped, err := util.WrapKey(ctx, kek, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	jwtToken, err := userClient.RegisterSrp(ctx, &pb.SrpRegister{
+		Username:   username,
+		Email:      email,
+		Kdf:        interfaces.KdfToDto(params),
+		WrappedKey: wrapped,
+		Verifier:   util.SrpVerifier(util.SrpX(kek)),
 	})
 	if err != nil {
 		log.Fatalf("register failed: %v", err)
@@ -93,7 +121,6 @@ func main() {
 		},
 	}
 
-	key := getHash(email, password)
 	for _, note := range notes {
 		if err = addNote(context.Background(), noteClient, jwtToken.Token, key, note); err != nil {
 			log.Fatalf("seed note failed: %v", err)
@@ -109,7 +136,7 @@ func addNote(ctx context.Context, noteClient pb.NoteServicesClient, jwtToken str
 		return err
 	}
 
-	encrypted, err := util.Encrypt(ctx, key, payload)
+	encrypted, err := util.Seal(ctx, key, util.KdfVersionNone, util.NoteAAD(note.GetID().String(), note.GetType().String()), payload)
 	if err != nil {
 		return err
 	}
@@ -127,8 +154,3 @@ func addNote(ctx context.Context, noteClient pb.NoteServicesClient, jwtToken str
 	_, err = noteClient.AddNote(ctx, req)
 	return err
 }
-
-func getHash(email, password string) []byte {
-	sum := sha256.Sum256([]byte(email + password))
-	return sum[:]
-}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/database"
+)
+
+var (
+	errBackupUsage  = errors.New("usage: server [flags] backup [directory]")
+	errRestoreUsage = errors.New("usage: server [flags] restore <backup directory>")
+)
+
+// runBackup runs the backup subcommand: it writes a backup below the given
+// directory, the configured backup directory by default.
+func runBackup(store database.DataStorable, args []string, out io.Writer) error {
+	if len(args) > 1 {
+		return errBackupUsage
+	}
+	root := backupDir
+	if len(args) == 1 {
+		root = args[0]
+	}
+	dir, err := store.Backup(context.Background(), root)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "backup written to %s\n", dir)
+	return err
+}
+
+// runRestore runs the restore subcommand. It verifies the backup before it
+// replaces anything and refuses while a server holds the database lock.
+func runRestore(driver, dsn string, blobs database.BlobStore, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errRestoreUsage
+	}
+	if driver != database.DriverSQLite {
+		return database.ErrBackupDriver
+	}
+	previous, err := database.RestoreBackup(context.Background(), args[0], dsn, blobs)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "database restored from %s\n", args[0])
+	if previous != "" {
+		_, err = fmt.Fprintf(out, "the replaced database is kept at %s\n", previous)
+	}
+	return err
+}
+
+// backupPeriodically writes a backup every interval and keeps the newest
+// keep of them.
+func backupPeriodically(store database.DataStorable, root string, interval time.Duration, keep int) {
+	for range time.Tick(interval) {
+		if _, err := store.Backup(context.Background(), root); err != nil {
+			appLogger.WithError(err).Error("failed to back up the database")
+			continue
+		}
+		if _, err := database.PruneBackups(root, keep); err != nil {
+			appLogger.WithError(err).Error("failed to delete old backups")
+		}
+	}
+}
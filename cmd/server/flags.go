@@ -4,36 +4,84 @@ import (
 	"encoding/json"
 	"errors"
 	"flag"
+	"io/fs"
+	"log"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/database"
 )
 
 const defaultServerConfigPath = "config_s.json"
 
 var (
-	srvAddr  string
-	logLevel string
-	dbFile   string
-	crtFile  string
-	confFile string
+	srvAddr        string
+	logLevel       string
+	dbDriver       string
+	dbDSN          string
+	crtFile        string
+	confFile       string
+	tlsCert        string
+	tlsKey         string
+	clientCA       string
+	legacyAuth     bool
+	blobStore      string
+	blobDir        string
+	s3             database.S3Config
+	historySize    int
+	trashRetention time.Duration
+	backupDir      string
+	backupInterval time.Duration
+	backupKeep     int
 )
 
 type serverConfig struct {
-	SrvAddr       string `json:"conn_addr"`
-	LogLevel      string `json:"log_level"`
-	CrtFile       string `json:"crt_file"`
-	DBFile        string `json:"db_file"`
-	LegacyDBField string `json:"log_file,omitempty"`
+	SrvAddr        string `json:"conn_addr"`
+	LogLevel       string `json:"log_level"`
+	CrtFile        string `json:"crt_file"`
+	DBFile         string `json:"db_file,omitempty"`
+	DBDriver       string `json:"db_driver,omitempty"`
+	DBDSN          string `json:"db_dsn,omitempty"`
+	TLSCert        string `json:"tls_cert,omitempty"`
+	TLSKey         string `json:"tls_key,omitempty"`
+	ClientCA       string `json:"client_ca,omitempty"`
+	LegacyAuth     bool   `json:"legacy_auth,omitempty"`
+	BlobStore      string `json:"blob_store,omitempty"`
+	BlobDir        string `json:"blob_dir,omitempty"`
+	S3Endpoint     string `json:"s3_endpoint,omitempty"`
+	S3Region       string `json:"s3_region,omitempty"`
+	S3Bucket       string `json:"s3_bucket,omitempty"`
+	S3Prefix       string `json:"s3_prefix,omitempty"`
+	S3AccessKey    string `json:"s3_access_key,omitempty"`
+	S3SecretKey    string `json:"s3_secret_key,omitempty"`
+	S3SSL          bool   `json:"s3_ssl,omitempty"`
+	HistorySize    *int   `json:"history_size,omitempty"`
+	TrashRetention string `json:"trash_retention,omitempty"`
+	BackupDir      string `json:"backup_dir,omitempty"`
+	BackupInterval string `json:"backup_interval,omitempty"`
+	BackupKeep     *int   `json:"backup_keep,omitempty"`
+	LegacyDBField  string `json:"log_file,omitempty"`
 }
 
 func parseFlags() {
 	confFile = resolveConfigPath(defaultServerConfigPath)
 	defaults := serverConfig{
-		SrvAddr:  "localhost:3200",
-		LogLevel: "info",
-		DBFile:   "test.db",
-		CrtFile:  "private.pem",
+		SrvAddr:   "localhost:3200",
+		LogLevel:  "info",
+		DBDriver:  database.DriverSQLite,
+		DBDSN:     "test.db",
+		CrtFile:   "private.pem",
+		BlobStore: "fs",
+		BlobDir:   "blobs",
+		BackupDir: "backups",
 	}
+	keep := 10
+	defaults.HistorySize = &keep
+	retention := 30 * 24 * time.Hour
+	keepBackups := 7
+	defaults.BackupKeep = &keepBackups
+	var interval time.Duration
 
 	if cfg, err := loadServerConfig(confFile); err == nil {
 		if cfg.SrvAddr != "" {
@@ -45,26 +93,116 @@ func parseFlags() {
 		if cfg.CrtFile != "" {
 			defaults.CrtFile = cfg.CrtFile
 		}
-		if cfg.DBFile != "" {
-			defaults.DBFile = cfg.DBFile
+		if cfg.DBDriver != "" {
+			defaults.DBDriver = cfg.DBDriver
+		}
+		if cfg.DBDSN != "" {
+			defaults.DBDSN = cfg.DBDSN
+		}
+		defaults.TLSCert = cfg.TLSCert
+		defaults.TLSKey = cfg.TLSKey
+		defaults.ClientCA = cfg.ClientCA
+		defaults.LegacyAuth = cfg.LegacyAuth
+		if cfg.BlobStore != "" {
+			defaults.BlobStore = cfg.BlobStore
+		}
+		if cfg.BlobDir != "" {
+			defaults.BlobDir = cfg.BlobDir
+		}
+		defaults.S3Endpoint = cfg.S3Endpoint
+		defaults.S3Region = cfg.S3Region
+		defaults.S3Bucket = cfg.S3Bucket
+		defaults.S3Prefix = cfg.S3Prefix
+		defaults.S3AccessKey = cfg.S3AccessKey
+		defaults.S3SecretKey = cfg.S3SecretKey
+		defaults.S3SSL = cfg.S3SSL
+		if cfg.HistorySize != nil {
+			defaults.HistorySize = cfg.HistorySize
+		}
+		if cfg.TrashRetention != "" {
+			d, err := time.ParseDuration(cfg.TrashRetention)
+			if err != nil {
+				log.Fatalf("invalid trash_retention in %s: %v", confFile, err)
+			}
+			retention = d
+		}
+		if cfg.BackupDir != "" {
+			defaults.BackupDir = cfg.BackupDir
+		}
+		if cfg.BackupInterval != "" {
+			d, err := time.ParseDuration(cfg.BackupInterval)
+			if err != nil {
+				log.Fatalf("invalid backup_interval in %s: %v", confFile, err)
+			}
+			interval = d
+		}
+		if cfg.BackupKeep != nil {
+			defaults.BackupKeep = cfg.BackupKeep
 		}
 	}
 
 	flag.StringVar(&confFile, "cfg", confFile, "config file path")
 	flag.StringVar(&srvAddr, "a", defaults.SrvAddr, "server address")
 	flag.StringVar(&logLevel, "ll", defaults.LogLevel, "log level")
-	flag.StringVar(&dbFile, "db", defaults.DBFile, "db path")
-	flag.StringVar(&crtFile, "crt", defaults.CrtFile, "certificate x509 path")
+	flag.StringVar(&dbDriver, "dd", defaults.DBDriver, "database driver: sqlite or postgres")
+	flag.StringVar(&dbDSN, "db", defaults.DBDSN, "database file (sqlite) or connection string (postgres)")
+	flag.StringVar(&crtFile, "crt", defaults.CrtFile, "JWT signing key file or key ring directory")
+	flag.StringVar(&tlsCert, "tc", defaults.TLSCert, "TLS certificate path, enables TLS")
+	flag.StringVar(&tlsKey, "tk", defaults.TLSKey, "TLS private key path")
+	flag.StringVar(&clientCA, "ca", defaults.ClientCA, "client CA path, enables mutual TLS")
+	flag.BoolVar(&legacyAuth, "legacy-auth", defaults.LegacyAuth, "accept password login besides SRP")
+	flag.StringVar(&blobStore, "bs", defaults.BlobStore, "blob store: fs or s3")
+	flag.StringVar(&blobDir, "bd", defaults.BlobDir, "blob directory of the fs store")
+	flag.StringVar(&s3.Endpoint, "s3", defaults.S3Endpoint, "S3 endpoint host:port")
+	flag.StringVar(&s3.Bucket, "s3-bucket", defaults.S3Bucket, "S3 bucket")
+	flag.BoolVar(&s3.UseSSL, "s3-ssl", defaults.S3SSL, "connect to S3 over TLS")
+	flag.IntVar(&historySize, "hs", *defaults.HistorySize, "revisions kept per note, 0 keeps no history")
+	flag.DurationVar(&trashRetention, "tr", retention, "how long deleted notes stay in the trash, 0 keeps them")
+	flag.StringVar(&backupDir, "bkd", defaults.BackupDir, "directory of database backups")
+	flag.DurationVar(&backupInterval, "bki", interval, "time between scheduled backups, 0 disables them")
+	flag.IntVar(&backupKeep, "bkk", *defaults.BackupKeep, "scheduled backups kept, 0 keeps all")
 	flag.Parse()
+	s3.Region = defaults.S3Region
+	s3.Prefix = defaults.S3Prefix
+	s3.AccessKey = envOr("AWS_ACCESS_KEY_ID", defaults.S3AccessKey)
+	s3.SecretKey = envOr("AWS_SECRET_ACCESS_KEY", defaults.S3SecretKey)
 
 	_ = saveServerConfig(confFile, &serverConfig{
-		SrvAddr:  srvAddr,
-		LogLevel: logLevel,
-		DBFile:   dbFile,
-		CrtFile:  crtFile,
+		SrvAddr:        srvAddr,
+		LogLevel:       logLevel,
+		DBDriver:       dbDriver,
+		DBDSN:          dbDSN,
+		CrtFile:        crtFile,
+		TLSCert:        tlsCert,
+		TLSKey:         tlsKey,
+		ClientCA:       clientCA,
+		LegacyAuth:     legacyAuth,
+		BlobStore:      blobStore,
+		BlobDir:        blobDir,
+		S3Endpoint:     s3.Endpoint,
+		S3Region:       s3.Region,
+		S3Bucket:       s3.Bucket,
+		S3Prefix:       s3.Prefix,
+		S3AccessKey:    defaults.S3AccessKey,
+		S3SecretKey:    defaults.S3SecretKey,
+		S3SSL:          s3.UseSSL,
+		HistorySize:    &historySize,
+		TrashRetention: trashRetention.String(),
+		BackupDir:      backupDir,
+		BackupInterval: backupInterval.String(),
+		BackupKeep:     &backupKeep,
 	})
 }
 
+// envOr returns the environment variable name, or fallback if it is unset.
+// S3 credentials taken from the environment are not written to the config.
+func envOr(name, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
+
 func resolveConfigPath(defaultPath string) string {
 	args := os.Args[1:]
 	for i := 0; i < len(args); i++ {
@@ -85,12 +223,18 @@ func resolveConfigPath(defaultPath string) string {
 	return defaultPath
 }
 
+// saveServerConfig writes cfg readable by the owner only: db_dsn and
+// s3_secret_key may hold credentials. A config written before with wider
+// permissions is narrowed first.
 func saveServerConfig(path string, cfg *serverConfig) error {
 	bytes, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, bytes, 0o644)
+	if err = os.Chmod(path, 0o600); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.WriteFile(path, bytes, 0o600)
 }
 
 func loadServerConfig(path string) (*serverConfig, error) {
@@ -107,8 +251,12 @@ func loadServerConfig(path string) (*serverConfig, error) {
 	if cfg.DBFile == "" && cfg.LegacyDBField != "" {
 		cfg.DBFile = cfg.LegacyDBField
 	}
+	// db_file is the SQLite database of configs written before db_dsn
+	if cfg.DBDSN == "" && cfg.DBFile != "" && (cfg.DBDriver == "" || cfg.DBDriver == database.DriverSQLite) {
+		cfg.DBDSN = cfg.DBFile
+	}
 
-	if cfg.SrvAddr == "" && cfg.LogLevel == "" && cfg.CrtFile == "" && cfg.DBFile == "" {
+	if cfg.SrvAddr == "" && cfg.LogLevel == "" && cfg.CrtFile == "" && cfg.DBDSN == "" {
 		return nil, errors.New("empty config")
 	}
 
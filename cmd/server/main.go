@@ -1,49 +1,128 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/database"
 	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/server"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/logger"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/auth"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
+	"google.golang.org/grpc/credentials"
 )
 
 var appLogger *logger.Logger
 
+var errCommandUsage = errors.New("usage: server [flags] [migrate|backup|restore ...]")
+
 func main() {
 	parseFlags()
 	initLogger()
 
-	db, err := gorm.Open(sqlite.Open(dbFile), &gorm.Config{})
+	blobs, err := openBlobStore()
+	if err != nil {
+		log.Fatal("failed to open blob store", err)
+	}
+	args := flag.Args()
+	// restore replaces the database file, so it must not be open here
+	if len(args) > 0 && args[0] == "restore" {
+		if err = runRestore(dbDriver, dbDSN, blobs, args[1:], os.Stdout); err != nil {
+			log.Fatal(args[0]+": ", err)
+		}
+		return
+	}
+	if len(args) == 0 && dbDriver == database.DriverSQLite {
+		// held until the server exits, see database.LockSQLite
+		unlock, err := database.LockSQLite(dbDSN)
+		if err != nil {
+			log.Fatal("failed to lock database", err)
+		}
+		defer unlock()
+	}
+
+	db, err := database.OpenDB(dbDriver, dbDSN)
 	if err != nil {
 		log.Fatal("failed to connect database", err)
 	}
 
-	store := *database.NewDataStore(appLogger, db)
+	store := *database.NewDataStore(appLogger, db, blobs)
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			err = runMigrate(store, args[1:], os.Stdout)
+		case "backup":
+			err = runBackup(store, args[1:], os.Stdout)
+		default:
+			log.Fatal(errCommandUsage)
+		}
+		if err != nil {
+			log.Fatal(args[0]+": ", err)
+		}
+		return
+	}
+	// a schema newer than the binary is refused, see database.ErrSchemaTooNew
 	if err = store.Migrate(); err != nil {
 		log.Fatal("failed to migrate database", err)
 	}
+	database.KeepRevisions(historySize)
+	go collectBlobs(store)
+	if trashRetention > 0 {
+		go purgeTrash(store, trashRetention)
+	}
+	if backupInterval > 0 {
+		if dbDriver == database.DriverSQLite {
+			go backupPeriodically(store, backupDir, backupInterval, backupKeep)
+		} else {
+			appLogger.Warn("scheduled backups need the sqlite driver, back up PostgreSQL with pg_dump")
+		}
+	}
 
 	authService, err := auth.NewAuthService(appLogger, crtFile)
 	if err != nil {
 		log.Fatal("failed to initialize auth service", err)
 	}
 
+	go reloadKeysOnHangup(authService)
+
 	controller := server.NewController(appLogger, store, *authService)
 	listener, err := net.Listen("tcp", srvAddr)
 	if err != nil {
 		log.Fatal("failed to start listener", err)
 	}
 
-	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(server.TokenInterceptor))
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(server.TokenInterceptor), grpc.StreamInterceptor(server.TokenStreamInterceptor)}
+	if tlsCert != "" {
+		tlsConfig, err := util.ServerTLSConfig(tlsCert, tlsKey, clientCA)
+		if err != nil {
+			log.Fatal("failed to load TLS certificate", err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		server.RequireCertIdentity(clientCA != "")
+	} else {
+		if clientCA != "" {
+			log.Fatal("client CA requires a TLS certificate")
+		}
+		appLogger.Warn("TLS is disabled, traffic is sent in plaintext")
+	}
+
+	server.AllowPasswordAuth(legacyAuth)
+	if legacyAuth {
+		appLogger.Warn("password login is enabled, clients send passwords to the server")
+	}
+
+	grpcServer := grpc.NewServer(opts...)
 	pb.RegisterNoteServicesServer(grpcServer, controller)
 	pb.RegisterUserServicesServer(grpcServer, controller)
 
@@ -53,6 +132,59 @@ func main() {
 	}
 }
 
+// Orphan blobs are looked for every blobCollectInterval. A blob is only
+// collected once it is older than blobCollectGrace, which leaves a client
+// time to save the note a new upload belongs to.
+const (
+	blobCollectInterval = time.Hour
+	blobCollectGrace    = 24 * time.Hour
+	trashPurgeInterval  = time.Hour
+)
+
+func openBlobStore() (database.BlobStore, error) {
+	switch blobStore {
+	case "fs":
+		return database.NewFileBlobStore(blobDir)
+	case "s3":
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return database.NewS3BlobStore(ctx, s3)
+	}
+	return nil, fmt.Errorf("unknown blob store %q", blobStore)
+}
+
+// collectBlobs deletes orphan blobs, the files of purged notes whose
+// deletion failed and uploads that were never attached to a note.
+func collectBlobs(store database.DataStorable) {
+	for ; ; time.Sleep(blobCollectInterval) {
+		if _, err := store.CollectBlobs(context.Background(), time.Now().Add(-blobCollectGrace)); err != nil {
+			appLogger.WithError(err).Error("failed to collect orphan blobs")
+		}
+	}
+}
+
+// purgeTrash deletes the notes that have been in the trash for longer than
+// retention.
+func purgeTrash(store database.DataStorable, retention time.Duration) {
+	for ; ; time.Sleep(trashPurgeInterval) {
+		if _, err := store.PurgeTrash(context.Background(), time.Now().Add(-retention)); err != nil {
+			appLogger.WithError(err).Error("failed to purge the trash")
+		}
+	}
+}
+
+// reloadKeysOnHangup re-reads the JWT signing keys on SIGHUP, so a rotated key
+// ring is picked up without a restart.
+func reloadKeysOnHangup(authService *auth.Service) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := authService.Reload(); err != nil {
+			appLogger.WithError(err).Error("failed to reload signing keys, keeping the previous ones")
+		}
+	}
+}
+
 func initLogger() {
 	level, err := logrus.ParseLevel(logLevel)
 	if err != nil {
@@ -1,6 +1,18 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/database"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/mocks"
+)
 
 func TestInitLogger(t *testing.T) {
 	logLevel = "info"
@@ -10,3 +22,204 @@ func TestInitLogger(t *testing.T) {
 		t.Fatal("expected app logger to be initialized")
 	}
 }
+
+func TestLoadServerConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		wantDriver string
+		wantDSN    string
+	}{
+		{
+			name:    "db_file",
+			config:  `{"conn_addr": "localhost:3200", "db_file": "demo.db"}`,
+			wantDSN: "demo.db",
+		},
+		{
+			name:    "log_file of old configs",
+			config:  `{"conn_addr": "localhost:3200", "log_file": "old.db"}`,
+			wantDSN: "old.db",
+		},
+		{
+			name:       "postgres",
+			config:     `{"db_driver": "postgres", "db_dsn": "host=db user=keeper", "db_file": "demo.db"}`,
+			wantDriver: database.DriverPostgres,
+			wantDSN:    "host=db user=keeper",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := loadServerConfig(path)
+			if err != nil {
+				t.Fatalf("loadServerConfig() error = %v", err)
+			}
+			if cfg.DBDriver != tt.wantDriver || cfg.DBDSN != tt.wantDSN {
+				t.Errorf("loadServerConfig() driver = %q, dsn = %q, want %q, %q", cfg.DBDriver, cfg.DBDSN, tt.wantDriver, tt.wantDSN)
+			}
+		})
+	}
+}
+
+func TestSaveServerConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool
+	}{
+		{name: "new config"},
+		{name: "config readable by others", existing: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if tt.existing {
+				if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := saveServerConfig(path, &serverConfig{DBDSN: "host=db user=keeper password=secret", S3SecretKey: "secret"}); err != nil {
+				t.Fatalf("saveServerConfig() error = %v", err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if perm := info.Mode().Perm(); perm != 0o600 {
+				t.Errorf("saveServerConfig() permissions = %o, want 600", perm)
+			}
+		})
+	}
+}
+
+func TestRunMigrate(t *testing.T) {
+	applied := time.Date(2026, 10, 1, 12, 0, 0, 0, time.Local)
+	states := []database.MigrationState{
+		{Version: 1, Name: "initial schema", AppliedAt: &applied, Known: true},
+		{Version: 2, Name: "blob chunks in the blob store", Known: true},
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		prepare func(store *mocks.MockDataStorable)
+		wantErr error
+	}{
+		{name: "no command", wantErr: errMigrateUsage},
+		{name: "unknown command", args: []string{"sideways"}, wantErr: errMigrateUsage},
+		{name: "steps of up", args: []string{"up", "2"}, wantErr: errMigrateUsage},
+		{name: "bad steps", args: []string{"down", "0"}, wantErr: errMigrateUsage},
+		{name: "status", args: []string{"status"}},
+		{
+			name: "up",
+			args: []string{"up"},
+			prepare: func(store *mocks.MockDataStorable) {
+				store.EXPECT().Migrate().Return(nil)
+			},
+		},
+		{
+			name: "down",
+			args: []string{"down"},
+			prepare: func(store *mocks.MockDataStorable) {
+				store.EXPECT().MigrateDown(1).Return(nil)
+			},
+		},
+		{
+			name: "down 2",
+			args: []string{"down", "2"},
+			prepare: func(store *mocks.MockDataStorable) {
+				store.EXPECT().MigrateDown(2).Return(nil)
+			},
+		},
+		{
+			name: "schema too new",
+			args: []string{"up"},
+			prepare: func(store *mocks.MockDataStorable) {
+				store.EXPECT().Migrate().Return(database.ErrSchemaTooNew)
+			},
+			wantErr: database.ErrSchemaTooNew,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mocks.NewMockDataStorable(ctrl)
+			if tt.prepare != nil {
+				tt.prepare(store)
+			}
+			if tt.wantErr == nil {
+				store.EXPECT().MigrationStatus().Return(states, nil)
+			}
+			var out bytes.Buffer
+			err := runMigrate(store, tt.args, &out)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("runMigrate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (!strings.Contains(out.String(), "2026-10-01 12:00:00") || !strings.Contains(out.String(), "pending")) {
+				t.Errorf("runMigrate() output = %q", out.String())
+			}
+		})
+	}
+}
+
+func TestRunBackup(t *testing.T) {
+	backupDir = "backups"
+	tests := []struct {
+		name    string
+		args    []string
+		root    string
+		err     error
+		wantErr error
+	}{
+		{name: "configured directory", root: "backups"},
+		{name: "given directory", args: []string{"/srv/backups"}, root: "/srv/backups"},
+		{name: "postgres", root: "backups", err: database.ErrBackupDriver, wantErr: database.ErrBackupDriver},
+		{name: "too many arguments", args: []string{"a", "b"}, wantErr: errBackupUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mocks.NewMockDataStorable(ctrl)
+			if tt.root != "" {
+				store.EXPECT().Backup(gomock.Any(), tt.root).Return(filepath.Join(tt.root, "20261017T120000Z"), tt.err)
+			}
+			var out bytes.Buffer
+			err := runBackup(store, tt.args, &out)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("runBackup() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && out.String() != "backup written to "+filepath.Join(tt.root, "20261017T120000Z")+"\n" {
+				t.Errorf("runBackup() output = %q", out.String())
+			}
+		})
+	}
+}
+
+func TestRunRestore(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  string
+		args    []string
+		wantErr error
+	}{
+		{name: "no backup", driver: database.DriverSQLite, wantErr: errRestoreUsage},
+		{name: "postgres", driver: database.DriverPostgres, args: []string{"backup"}, wantErr: database.ErrBackupDriver},
+		{name: "not a backup", driver: database.DriverSQLite, args: []string{t.TempDir()}, wantErr: database.ErrBackupCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dsn := filepath.Join(t.TempDir(), "server.db")
+			err := runRestore(tt.driver, dsn, nil, tt.args, &bytes.Buffer{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("runRestore() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, err = os.Stat(dsn); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("runRestore() touched the database: %v", err)
+			}
+		})
+	}
+}
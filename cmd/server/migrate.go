@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/database"
+)
+
+var errMigrateUsage = errors.New("usage: server [flags] migrate status|up|down [steps]")
+
+// runMigrate runs the migrate subcommand: status lists the migrations, up
+// applies the pending ones and down rolls back the last steps (one by
+// default).
+func runMigrate(store database.DataStorable, args []string, out io.Writer) error {
+	if len(args) == 0 || len(args) > 2 || (len(args) == 2 && args[0] != "down") {
+		return errMigrateUsage
+	}
+	switch args[0] {
+	case "status":
+	case "up":
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return errMigrateUsage
+			}
+			steps = n
+		}
+		if err := store.MigrateDown(steps); err != nil {
+			return err
+		}
+	default:
+		return errMigrateUsage
+	}
+	return printMigrations(store, out)
+}
+
+func printMigrations(store database.DataStorable, out io.Writer) error {
+	states, err := store.MigrationStatus()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tAPPLIED\tNAME")
+	for _, state := range states {
+		applied := "pending"
+		if state.AppliedAt != nil {
+			applied = state.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		name := state.Name
+		if !state.Known {
+			name += " (unknown to this server)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", state.Version, applied, name)
+	}
+	return w.Flush()
+}
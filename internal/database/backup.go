@@ -0,0 +1,350 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A backup is a directory holding a SQLite snapshot of the database, the blob
+// objects the snapshot refers to, and a manifest with their checksums. The
+// objects are part of it because the blob collector deletes the objects no
+// row refers to any more, which a restored database may still need.
+const (
+	backupFormat   = "gophkeeper-backup"
+	backupVersion  = 1
+	backupManifest = "manifest.json"
+	backupDatabase = "gophkeeper.db"
+	backupObjects  = "objects"
+	// backupName names backup directories by their UTC time down to the
+	// nanosecond, so two backups in the same second get different names.
+	// Names of older backups stop at the second; both parse with it.
+	backupName = "20060102T150405.000000000Z"
+)
+
+// BackupManifest describes a backup. Objects are the blob store keys, each the
+// SHA-256 of its file.
+type BackupManifest struct {
+	Format    string     `json:"format"`
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	Driver    string     `json:"driver"`
+	Schema    int        `json:"schema_version"`
+	Database  BackupFile `json:"database"`
+	Objects   []string   `json:"objects"`
+}
+
+type BackupFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Backup writes a consistent snapshot of a SQLite database to a new directory
+// below root, while the server keeps running, and returns its path. The
+// directory only appears once the backup is complete.
+func (ds *DataStore) Backup(ctx context.Context, root string) (string, error) {
+	if ds.db.Dialector.Name() != DriverSQLite {
+		return "", ErrBackupDriver
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return "", err
+	}
+	tmp, err := os.MkdirTemp(root, ".backup-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	created := time.Now().UTC()
+	snapshot := filepath.Join(tmp, backupDatabase)
+	if err = ds.db.WithContext(ctx).Exec("VACUUM INTO ?", snapshot).Error; err != nil {
+		return "", fmt.Errorf("snapshot of the database: %w", err)
+	}
+	manifest := &BackupManifest{Format: backupFormat, Version: backupVersion, CreatedAt: created, Driver: DriverSQLite}
+	var keys []string
+	if manifest.Schema, keys, err = readSnapshot(snapshot); err != nil {
+		return "", err
+	}
+
+	// The objects are read after the snapshot; one deleted in between fails
+	// the backup rather than leaving it incomplete.
+	if err = os.Mkdir(filepath.Join(tmp, backupObjects), 0o700); err != nil {
+		return "", err
+	}
+	for _, key := range keys {
+		data, err := ds.blobs.Get(ctx, key)
+		if err != nil {
+			return "", fmt.Errorf("blob object %s: %w", key, err)
+		}
+		if err = os.WriteFile(filepath.Join(tmp, backupObjects, key), data, 0o600); err != nil {
+			return "", err
+		}
+	}
+	manifest.Objects = keys
+	if manifest.Database, err = describeFile(snapshot); err != nil {
+		return "", err
+	}
+	buf, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(filepath.Join(tmp, backupManifest), buf, 0o600); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(root, created.Format(backupName))
+	if err = os.Rename(tmp, dir); err != nil {
+		return "", err
+	}
+	log.Infof("backup written to %s: schema %d, %d blob objects", dir, manifest.Schema, len(keys))
+	return dir, nil
+}
+
+// readSnapshot returns the schema version of a database file and the blob
+// objects it refers to.
+func readSnapshot(path string) (int, []string, error) {
+	db, err := OpenDB(DriverSQLite, path)
+	if err != nil {
+		return 0, nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer sqlDB.Close()
+
+	var check string
+	if err = db.Raw("PRAGMA integrity_check").Scan(&check).Error; err != nil {
+		return 0, nil, err
+	}
+	if check != "ok" {
+		return 0, nil, fmt.Errorf("%w: %s", ErrBackupCorrupt, check)
+	}
+	var schema int
+	if err = db.Model(&schemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&schema).Error; err != nil {
+		return 0, nil, fmt.Errorf("%w: no schema version: %v", ErrBackupCorrupt, err)
+	}
+	var keys []string
+	if db.Migrator().HasTable("blob_chunks") && db.Migrator().HasColumn("blob_chunks", "key") {
+		err = db.Table("blob_chunks").Distinct("key").Where("key <> ''").Order("key").Pluck("key", &keys).Error
+	}
+	return schema, keys, err
+}
+
+func describeFile(path string) (BackupFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return BackupFile{}, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return BackupFile{}, err
+	}
+	return BackupFile{Name: filepath.Base(path), Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// VerifyBackup reads the manifest of the backup in dir and checks the
+// database and every object against their checksums.
+func VerifyBackup(dir string) (*BackupManifest, error) {
+	buf, err := os.ReadFile(filepath.Join(dir, backupManifest))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBackupCorrupt, err)
+	}
+	var manifest BackupManifest
+	if err = json.Unmarshal(buf, &manifest); err != nil || manifest.Format != backupFormat {
+		return nil, fmt.Errorf("%w: not a backup manifest", ErrBackupCorrupt)
+	}
+	if manifest.Version != backupVersion || manifest.Driver != DriverSQLite {
+		return nil, fmt.Errorf("%w: version %d of driver %q", ErrBackupVersion, manifest.Version, manifest.Driver)
+	}
+	if manifest.Schema > LatestSchema() {
+		return nil, fmt.Errorf("%w: backup is at version %d, this server knows %d", ErrSchemaTooNew, manifest.Schema, LatestSchema())
+	}
+
+	if manifest.Database.Name != backupDatabase {
+		return nil, fmt.Errorf("%w: unexpected database %q", ErrBackupCorrupt, manifest.Database.Name)
+	}
+	got, err := describeFile(filepath.Join(dir, backupDatabase))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBackupCorrupt, err)
+	}
+	if got != manifest.Database {
+		return nil, fmt.Errorf("%w: %s does not match its checksum", ErrBackupCorrupt, backupDatabase)
+	}
+	for _, key := range manifest.Objects {
+		if _, err = objectPath(key); err != nil {
+			return nil, fmt.Errorf("%w: object %q", ErrBackupCorrupt, key)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, backupObjects, key))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBackupCorrupt, err)
+		}
+		if _, err = checkObject(key, data); err != nil {
+			return nil, fmt.Errorf("%w: object %s: %v", ErrBackupCorrupt, key, err)
+		}
+	}
+	schema, _, err := readSnapshot(filepath.Join(dir, backupDatabase))
+	if err != nil {
+		return nil, err
+	}
+	if schema != manifest.Schema {
+		return nil, fmt.Errorf("%w: database is at version %d, the manifest says %d", ErrBackupCorrupt, schema, manifest.Schema)
+	}
+	return &manifest, nil
+}
+
+// RestoreBackup verifies the backup in dir, puts its objects back into blobs
+// and swaps its database in for the SQLite database at dsn. The replaced file
+// is kept next to it; its path is returned. While a server holds the lock of
+// the database, see LockSQLite, ErrDatabaseInUse is returned.
+func RestoreBackup(ctx context.Context, dir, dsn string, blobs BlobStore) (string, error) {
+	manifest, err := VerifyBackup(dir)
+	if err != nil {
+		return "", err
+	}
+	unlock, err := LockSQLite(dsn)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	for _, key := range manifest.Objects {
+		data, err := os.ReadFile(filepath.Join(dir, backupObjects, key))
+		if err != nil {
+			return "", err
+		}
+		if _, err = blobs.Put(ctx, data); err != nil {
+			return "", fmt.Errorf("blob object %s: %w", key, err)
+		}
+	}
+
+	target := sqliteFile(dsn)
+	tmp := target + ".restore"
+	if err = copyFile(filepath.Join(dir, backupDatabase), tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	previous := ""
+	// moved are the files of the replaced database already renamed; a
+	// failure puts them back, so the database is left as it was.
+	var moved []string
+	rollback := func() {
+		os.Remove(tmp)
+		for i := len(moved) - 1; i >= 0; i-- {
+			if err := os.Rename(previous+moved[i], target+moved[i]); err != nil {
+				log.WithError(err).Errorf("%s not moved back to %s", previous+moved[i], target+moved[i])
+			}
+		}
+	}
+	if _, err = os.Stat(target); err == nil {
+		previous = target + ".before-restore-" + time.Now().UTC().Format(backupName)
+		// the journal files belong to the replaced database
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			err = rename(target+suffix, previous+suffix)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				rollback()
+				return "", err
+			}
+			moved = append(moved, suffix)
+		}
+	}
+	if err = rename(tmp, target); err != nil {
+		rollback()
+		return "", err
+	}
+	log.Infof("restored %s from the backup of %s", target, manifest.CreatedAt.Format(time.RFC3339))
+	return previous, nil
+}
+
+// rename moves the database files during a restore; tests make it fail.
+var rename = os.Rename
+
+// sqliteFile is the file of a SQLite DSN, which may be a URI with parameters.
+func sqliteFile(dsn string) string {
+	dsn = strings.TrimPrefix(dsn, "file:")
+	if i := strings.IndexByte(dsn, '?'); i >= 0 {
+		dsn = dsn[:i]
+	}
+	return dsn
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// PruneBackups deletes all but the keep newest backups below root and
+// returns the deleted directories. A keep below one deletes nothing.
+func PruneBackups(root string, keep int) ([]string, error) {
+	if keep < 1 {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	type backup struct {
+		name    string
+		created time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		if created, err := parseBackupName(entry.Name()); err == nil && entry.IsDir() {
+			backups = append(backups, backup{name: entry.Name(), created: created})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].created.Before(backups[j].created) })
+	var deleted []string
+	for i := 0; i < len(backups)-keep; i++ {
+		dir := filepath.Join(root, backups[i].name)
+		if err = os.RemoveAll(dir); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, dir)
+	}
+	return deleted, nil
+}
+
+// parseBackupName returns the time a backup directory is named after. The
+// fraction of the second is optional: backups written before it was added
+// have none.
+func parseBackupName(name string) (time.Time, error) {
+	return time.Parse("20060102T150405Z", name)
+}
+
+var (
+	ErrBackupDriver  = errors.New("backups need the sqlite driver, back up PostgreSQL with pg_dump")
+	ErrBackupCorrupt = errors.New("backup is damaged or incomplete")
+	ErrBackupVersion = errors.New("unsupported backup")
+)
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataStore_Backup(t *testing.T) {
+	dir := t.TempDir()
+	dsn := filepath.Join(dir, "server.db")
+	db, err := OpenDB(DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs, err := NewFileBlobStore(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &DataStore{db: db, broker: NewBroker(), blobs: blobs}
+	if err = store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	user := uuid.New()
+	ctx := addContext(context.Background(), user)
+	_, err = store.AddUser(ctx, &models.User{ID: user, Username: "backup", Email: "backup@test.com", Password: []byte("pw")})
+	assert.NoError(t, err)
+	_, err = store.AddBlobChunk(ctx, uuid.New(), 0, []byte("chunk"), true)
+	assert.NoError(t, err)
+
+	backups := filepath.Join(dir, "backups")
+	backup, err := store.Backup(context.Background(), backups)
+	if !assert.NoError(t, err) {
+		return
+	}
+	manifest, err := VerifyBackup(backup)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, LatestSchema(), manifest.Schema)
+	assert.Equal(t, []string{objectKey([]byte("chunk"))}, manifest.Objects)
+
+	// changes after the backup are undone by the restore, and the collected
+	// object comes back
+	assert.NoError(t, db.Model(&models.User{}).Where("id = ?", user).Update("username", "changed").Error)
+	assert.NoError(t, blobs.Delete(ctx, objectKey([]byte("chunk"))))
+	sqlDB, _ := db.DB()
+	assert.NoError(t, sqlDB.Close())
+
+	previous, err := RestoreBackup(context.Background(), backup, "file:"+dsn+"?_busy_timeout=5000", blobs)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.FileExists(t, previous)
+	restored, err := OpenDB(DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	assert.NoError(t, restored.Model(&models.User{}).Where("id = ?", user).Pluck("username", &name).Error)
+	assert.Equal(t, "backup", name)
+	data, err := blobs.Get(ctx, objectKey([]byte("chunk")))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("chunk"), data)
+
+	database := filepath.Join(backup, backupDatabase)
+	assert.NoError(t, os.WriteFile(database, append([]byte("x"), mustRead(t, database)...), 0o600))
+	_, err = VerifyBackup(backup)
+	assert.ErrorIs(t, err, ErrBackupCorrupt)
+	_, err = RestoreBackup(context.Background(), backup, dsn, blobs)
+	assert.ErrorIs(t, err, ErrBackupCorrupt)
+}
+
+func TestPruneBackups(t *testing.T) {
+	root := t.TempDir()
+	names := []string{"20260101T000000Z", "20260102T000000.500000000Z", "20260102T000000Z", "20260103T000000.000000001Z", "notes", ".backup-1"}
+	for _, name := range names {
+		assert.NoError(t, os.Mkdir(filepath.Join(root, name), 0o700))
+	}
+	deleted, err := PruneBackups(root, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "20260101T000000Z"), filepath.Join(root, "20260102T000000Z")}, deleted)
+	entries, err := os.ReadDir(root)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 4)
+
+	deleted, err = PruneBackups(root, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, deleted)
+}
+
+func TestRestoreBackupSwap(t *testing.T) {
+	dir := t.TempDir()
+	dsn := filepath.Join(dir, "server.db")
+	db, err := OpenDB(DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs, err := NewFileBlobStore(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &DataStore{db: db, broker: NewBroker(), blobs: blobs}
+	if err = store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	first, err := store.Backup(context.Background(), dir)
+	assert.NoError(t, err)
+	second, err := store.Backup(context.Background(), dir)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second, "backups of the same second share a directory")
+	sqlDB, _ := db.DB()
+	assert.NoError(t, sqlDB.Close())
+	assert.NoError(t, os.WriteFile(dsn+"-wal", []byte("wal"), 0o600))
+	database := mustRead(t, dsn)
+
+	unlock, err := LockSQLite(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = RestoreBackup(context.Background(), second, dsn, blobs)
+	assert.ErrorIs(t, err, ErrDatabaseInUse, "RestoreBackup() while a server holds the lock")
+	assert.Equal(t, database, mustRead(t, dsn), "database replaced while locked")
+	assert.NoError(t, unlock())
+
+	defer func() { rename = os.Rename }()
+	for _, failing := range []string{dsn + "-wal", dsn + ".restore"} {
+		rename = func(from, to string) error {
+			if from == failing {
+				return errors.New("rename failed")
+			}
+			return os.Rename(from, to)
+		}
+		_, err = RestoreBackup(context.Background(), second, dsn, blobs)
+		assert.Error(t, err, "RestoreBackup() with %s not renamed", failing)
+		assert.Equal(t, database, mustRead(t, dsn), "database not moved back")
+		assert.Equal(t, []byte("wal"), mustRead(t, dsn+"-wal"), "journal not moved back")
+		assert.NoFileExists(t, dsn+".restore")
+		left, _ := filepath.Glob(dsn + ".before-restore-*")
+		assert.Empty(t, left, "replaced files left behind")
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileBlobStore keeps objects as files below a root directory.
+type FileBlobStore struct {
+	root string
+}
+
+func NewFileBlobStore(root string) (*FileBlobStore, error) {
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileBlobStore{root: root}, nil
+}
+
+// Put writes the object to a temporary file first and renames it into place,
+// so a reader never sees a partly written object.
+func (s *FileBlobStore) Put(_ context.Context, data []byte) (string, error) {
+	key := objectKey(data)
+	name, err := s.file(key)
+	if err != nil {
+		return "", err
+	}
+	if _, err = os.Stat(name); err == nil {
+		return key, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".put-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func (s *FileBlobStore) Get(_ context.Context, key string) ([]byte, error) {
+	name, err := s.file(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return checkObject(key, data)
+}
+
+func (s *FileBlobStore) Delete(_ context.Context, key string) error {
+	name, err := s.file(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileBlobStore) file(key string) (string, error) {
+	p, err := objectPath(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(p)), nil
+}
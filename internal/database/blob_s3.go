@@ -0,0 +1,107 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config locates a bucket of an S3-compatible service such as MinIO.
+// Endpoint is host[:port]; objects are stored below Prefix.
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// S3BlobStore keeps objects in an S3 bucket.
+type S3BlobStore struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3BlobStore connects to the service and creates the bucket if it does
+// not exist yet.
+func NewS3BlobStore(ctx context.Context, cfg S3Config) (*S3BlobStore, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err = client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+			return nil, err
+		}
+	}
+	return &S3BlobStore{client: client, bucket: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, data []byte) (string, error) {
+	key := objectKey(data)
+	name, err := s.object(key)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.client.PutObject(ctx, s.bucket, name, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: "application/octet-stream"})
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	name, err := s.object(key)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := s.client.GetObject(ctx, s.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	defer obj.Close()
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	return checkObject(key, data)
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	name, err := s.object(key)
+	if err != nil {
+		return err
+	}
+	return s.client.RemoveObject(ctx, s.bucket, name, minio.RemoveObjectOptions{})
+}
+
+func (s *S3BlobStore) object(key string) (string, error) {
+	p, err := objectPath(key)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(s.prefix, p), nil
+}
+
+func s3Error(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrObjectNotFound
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path"
+)
+
+// BlobStore keeps the encrypted chunks of blobs outside the database.
+// Objects are content addressed: the key of an object is the hex SHA-256 of
+// its bytes, so storing the same bytes twice keeps one copy and a damaged
+// object is detected when it is read.
+type BlobStore interface {
+	// Put stores data and returns its key.
+	Put(ctx context.Context, data []byte) (string, error)
+	// Get returns the object stored under key, or ErrObjectNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the object stored under key. A missing object is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+// objectKey is the key data is stored under.
+func objectKey(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// objectPath spreads objects over two levels of directories named after the
+// first bytes of their key, e.g. ab/cd/abcd….
+func objectPath(key string) (string, error) {
+	if _, err := hex.DecodeString(key); err != nil || len(key) != 2*sha256.Size {
+		return "", ErrInvalidObjectKey
+	}
+	return path.Join(key[:2], key[2:4], key), nil
+}
+
+// checkObject verifies that data is the object stored under key.
+func checkObject(key string, data []byte) ([]byte, error) {
+	if objectKey(data) != key {
+		return nil, ErrObjectCorrupt
+	}
+	return data, nil
+}
+
+var (
+	ErrObjectNotFound   = errors.New("blob object not found")
+	ErrObjectCorrupt    = errors.New("blob object does not match its key")
+	ErrInvalidObjectKey = errors.New("invalid blob object key")
+)
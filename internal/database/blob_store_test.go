@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// testBlobStore checks the behaviour every BlobStore shares.
+func testBlobStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+	data := []byte("encrypted chunk " + uuid.NewString())
+
+	key, err := store.Put(ctx, data)
+	assert.NoError(t, err)
+	assert.Equal(t, objectKey(data), key, "Put() key")
+	again, err := store.Put(ctx, data)
+	assert.NoError(t, err)
+	assert.Equal(t, key, again, "Put() of the same bytes")
+
+	got, err := store.Get(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	assert.NoError(t, store.Delete(ctx, key))
+	_, err = store.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrObjectNotFound, "Get() after Delete()")
+	assert.NoError(t, store.Delete(ctx, key), "Delete() of a missing object")
+
+	_, err = store.Get(ctx, "../../etc/passwd")
+	assert.ErrorIs(t, err, ErrInvalidObjectKey)
+}
+
+func TestFileBlobStore(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewFileBlobStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlobStore(t, store)
+
+	ctx := context.Background()
+	key, err := store.Put(ctx, []byte("chunk"))
+	assert.NoError(t, err)
+	name := filepath.Join(root, key[:2], key[2:4], key)
+	assert.FileExists(t, name, "Put() layout")
+	assert.NoError(t, os.WriteFile(name, []byte("tampered"), 0o600))
+	_, err = store.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrObjectCorrupt, "Get() of an altered object")
+}
+
+// TestS3BlobStore runs against an S3-compatible service, e.g. a local MinIO:
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	GOPHKEEPER_TEST_S3=localhost:9000 AWS_ACCESS_KEY_ID=minioadmin AWS_SECRET_ACCESS_KEY=minioadmin go test ./internal/database
+func TestS3BlobStore(t *testing.T) {
+	endpoint := os.Getenv("GOPHKEEPER_TEST_S3")
+	if endpoint == "" {
+		t.Skip("GOPHKEEPER_TEST_S3 is not set")
+	}
+	store, err := NewS3BlobStore(context.Background(), S3Config{
+		Endpoint:  endpoint,
+		Bucket:    "gophkeeper-test",
+		Prefix:    "blobs",
+		AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlobStore(t, store)
+}
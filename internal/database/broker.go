@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+)
+
+// watchBuffer is how many changes a subscriber may fall behind before it is
+// dropped.
+const watchBuffer = 64
+
+type ChangeKind int
+
+const (
+	SecretCreated ChangeKind = iota
+	SecretUpdated
+	SecretDeleted
+)
+
+// Change is a committed write of a secret. Session is the session that made
+// it, so a subscriber can skip its own writes.
+type Change struct {
+	Kind    ChangeKind
+	Secret  models.SecretData
+	Session uuid.UUID
+}
+
+// Broker fans committed secret writes out to the subscribers of the user
+// that owns the secret. It lives in the process: subscribers of another
+// server instance do not see the change until they sync.
+type Broker struct {
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[chan Change]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[uuid.UUID]map[chan Change]struct{})}
+}
+
+// Subscribe returns the changes of user's secrets until ctx is done; the
+// channel is closed then. A subscriber that falls more than watchBuffer
+// changes behind is dropped and its channel closed early, so it has to sync
+// and subscribe again.
+func (b *Broker) Subscribe(ctx context.Context, user uuid.UUID) <-chan Change {
+	ch := make(chan Change, watchBuffer)
+	b.mu.Lock()
+	if b.subs[user] == nil {
+		b.subs[user] = make(map[chan Change]struct{})
+	}
+	b.subs[user][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.drop(user, ch)
+	}()
+	return ch
+}
+
+func (b *Broker) publish(user uuid.UUID, changes ...Change) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[user] {
+		for _, change := range changes {
+			select {
+			case ch <- change:
+			default:
+				b.drop(user, ch)
+			}
+			if _, ok := b.subs[user][ch]; !ok {
+				break
+			}
+		}
+	}
+}
+
+// drop removes and closes a subscription. It is a no-op for one already
+// dropped. b.mu must be held.
+func (b *Broker) drop(user uuid.UUID, ch chan Change) {
+	if _, ok := b.subs[user][ch]; !ok {
+		return
+	}
+	delete(b.subs[user], ch)
+	close(ch)
+	if len(b.subs[user]) == 0 {
+		delete(b.subs, user)
+	}
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker_Subscribe(t *testing.T) {
+	user := uuid.New()
+	change := Change{Kind: SecretUpdated, Secret: models.SecretData{ID: uuid.New()}}
+
+	tests := []struct {
+		name      string
+		publish   int
+		wantCount int
+		wantOpen  bool
+	}{
+		{name: "changes delivered", publish: 2, wantCount: 2, wantOpen: true},
+		{name: "full buffer", publish: watchBuffer, wantCount: watchBuffer, wantOpen: true},
+		{name: "slow subscriber dropped", publish: watchBuffer + 1, wantCount: watchBuffer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBroker()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ch := b.Subscribe(ctx, user)
+			for i := 0; i < tt.publish; i++ {
+				b.publish(user, change)
+			}
+			b.publish(uuid.New(), change)
+
+			assert.Equal(t, tt.wantCount, len(ch), "Subscribe() buffered changes")
+			for i := 0; i < tt.wantCount; i++ {
+				assert.Equal(t, change, <-ch)
+			}
+			open := true
+			select {
+			case _, open = <-ch:
+			default:
+			}
+			assert.Equal(t, tt.wantOpen, open, "Subscribe() subscribed")
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"errors"
+	"os"
+)
+
+// LockSQLite takes an exclusive lock on a file next to the SQLite database at
+// dsn and returns the function that releases it. A running server holds the
+// lock, so a restore cannot replace the database under its open handle; a
+// second holder gets ErrDatabaseInUse. The operating system releases the lock
+// when the process exits.
+func LockSQLite(dsn string) (func() error, error) {
+	f, err := os.OpenFile(sqliteFile(dsn)+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err = lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f.Close, nil
+}
+
+var ErrDatabaseInUse = errors.New("database is in use by a running server")
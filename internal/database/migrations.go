@@ -0,0 +1,362 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned step of the schema. Up moves the schema from
+// Version-1 to Version, Down moves it back. Steps use their own snapshot of
+// the tables they touch, never the models, so that replaying old steps on an
+// empty database gives the same schema as it gave then.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ds *DataStore, tx *gorm.DB) error
+	Down    func(ds *DataStore, tx *gorm.DB) error
+}
+
+// MigrationState is a migration known to the binary or recorded in the
+// database. AppliedAt is nil for a pending migration; Known is false for one
+// applied by a newer binary.
+type MigrationState struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Known     bool
+}
+
+// schemaVersion records an applied migration.
+type schemaVersion struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaVersion) TableName() string { return "schema_version" }
+
+// migrations is the schema history, in order. Append new steps; never edit a
+// released one.
+var migrations = []Migration{
+	{Version: 1, Name: "initial schema", Up: upInitialSchema, Down: downInitialSchema},
+	{Version: 2, Name: "blob chunks in the blob store", Up: upChunksInStore, Down: downChunksInStore},
+	{Version: 3, Name: "secret revisions", Up: upSecretRevisions, Down: downSecretRevisions},
+	{Version: 4, Name: "purged tombstones", Up: upPurgedSeq, Down: downPurgedSeq},
+}
+
+// migrationLock serializes the migrations of servers sharing a PostgreSQL
+// database.
+const migrationLock = 0x6b656570
+
+// LatestSchema is the schema version this binary expects.
+func LatestSchema() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrate applies the pending migrations, each in its own transaction. It
+// refuses to touch a database whose schema is newer than the binary.
+func (ds *DataStore) Migrate() error {
+	if err := ds.db.AutoMigrate(&schemaVersion{}); err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		err := ds.db.Transaction(func(tx *gorm.DB) error {
+			current, err := lockSchema(tx)
+			if err != nil || current >= m.Version {
+				return err
+			}
+			log.Infof("applying migration %d: %s", m.Version, m.Name)
+			if err = m.Up(ds, tx); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+			}
+			return tx.Create(&schemaVersion{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			log.Error(err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateDown rolls back the last steps migrations.
+func (ds *DataStore) MigrateDown(steps int) error {
+	if err := ds.db.AutoMigrate(&schemaVersion{}); err != nil {
+		return err
+	}
+	for i := 0; i < steps; i++ {
+		err := ds.db.Transaction(func(tx *gorm.DB) error {
+			current, err := lockSchema(tx)
+			if err != nil {
+				return err
+			}
+			if current == 0 {
+				return ErrNoMigration
+			}
+			m := migrations[current-1]
+			log.Infof("rolling back migration %d: %s", m.Version, m.Name)
+			if err = m.Down(ds, tx); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+			}
+			return tx.Delete(&schemaVersion{Version: m.Version}).Error
+		})
+		if err != nil {
+			log.Error(err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrationStatus lists the known migrations and any newer ones recorded in
+// the database, by version.
+func (ds *DataStore) MigrationStatus() ([]MigrationState, error) {
+	var applied []schemaVersion
+	if ds.db.Migrator().HasTable(&schemaVersion{}) {
+		if err := ds.db.Order("version").Find(&applied).Error; err != nil {
+			return nil, err
+		}
+	}
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		states = append(states, MigrationState{Version: m.Version, Name: m.Name, Known: true})
+	}
+	for _, row := range applied {
+		row := row
+		if row.Version <= len(migrations) {
+			states[row.Version-1].AppliedAt = &row.AppliedAt
+			continue
+		}
+		states = append(states, MigrationState{Version: row.Version, Name: row.Name, AppliedAt: &row.AppliedAt})
+	}
+	return states, nil
+}
+
+// lockSchema returns the current schema version, holding the migration lock
+// until tx ends on PostgreSQL. SQLite serializes writers anyway.
+func lockSchema(tx *gorm.DB) (int, error) {
+	if tx.Dialector.Name() == DriverPostgres {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLock).Error; err != nil {
+			return 0, err
+		}
+	}
+	var current int
+	if err := tx.Model(&schemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&current).Error; err != nil {
+		return 0, err
+	}
+	if current > LatestSchema() {
+		return 0, fmt.Errorf("%w: database is at version %d, this server knows %d", ErrSchemaTooNew, current, LatestSchema())
+	}
+	return current, nil
+}
+
+var (
+	ErrSchemaTooNew = errors.New("database schema is newer than the server")
+	ErrNoMigration  = errors.New("no migration to roll back")
+)
+
+// Version 1 is the schema as AutoMigrate left it. On a database that
+// AutoMigrate created it only adds what an older server had not yet.
+
+type v1Kdf struct {
+	Algorithm string `gorm:"size:32"`
+	Salt      []byte `gorm:"size:64"`
+	Time      uint32
+	Memory    uint32
+	Threads   uint8
+}
+
+type v1User struct {
+	ID            uuid.UUID  `gorm:"primary_key;type:uuid"`
+	Username      string     `gorm:"size:255;not null"`
+	Password      []byte     `gorm:"size:255;not null"`
+	Email         string     `gorm:"size:255;not null;unique;index:idx_email"`
+	Kdf           v1Kdf      `gorm:"embedded;embeddedPrefix:kdf_"`
+	WrappedKey    []byte     `gorm:"size:128"`
+	Verifier      []byte     `gorm:"size:256"`
+	TotpSecret    string     `gorm:"size:64"`
+	TotpEnabled   bool       `gorm:"not null;default:false"`
+	TotpLastStep  int64      `gorm:"not null;default:0"`
+	RecoveryCodes [][]byte   `gorm:"serializer:json"`
+	CreatedAt     *time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     *time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v1User) TableName() string { return "users" }
+
+type v1SecretData struct {
+	ID        uuid.UUID      `gorm:"primary_key;type:uuid"`
+	UserID    uuid.UUID      `gorm:"type:uuid;not null"`
+	Type      string         `gorm:"size:255;not null"`
+	Name      string         `gorm:"size:255;not null"`
+	Secret    []byte         `gorm:"type:bytes;size:20480;not null"`
+	Sealed    bool           `gorm:"not null;default:false"`
+	Revision  int64          `gorm:"not null;default:1"`
+	ChangeSeq int64          `gorm:"not null;default:0;index"`
+	BlobID    *uuid.UUID     `gorm:"type:uuid;index"`
+	UpdatedAt *time.Time     `gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1SecretData) TableName() string { return "secret_data" }
+
+type v1ChangeCounter struct {
+	UserID uuid.UUID `gorm:"primary_key;type:uuid"`
+	Seq    int64     `gorm:"not null;default:0"`
+}
+
+func (v1ChangeCounter) TableName() string { return "change_counters" }
+
+type v1Blob struct {
+	ID        uuid.UUID  `gorm:"primary_key;type:uuid"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Chunks    int64      `gorm:"not null;default:0"`
+	Size      int64      `gorm:"not null;default:0"`
+	Complete  bool       `gorm:"not null;default:false"`
+	CreatedAt *time.Time `gorm:"autoCreateTime"`
+	UpdatedAt *time.Time `gorm:"autoUpdateTime"`
+}
+
+func (v1Blob) TableName() string { return "blobs" }
+
+type v1BlobChunk struct {
+	BlobID uuid.UUID `gorm:"primary_key;type:uuid"`
+	Number int64     `gorm:"primary_key;autoIncrement:false"`
+	Key    string    `gorm:"size:64;not null;default:'';index"`
+	Size   int64     `gorm:"not null;default:0"`
+}
+
+func (v1BlobChunk) TableName() string { return "blob_chunks" }
+
+type v1Session struct {
+	ID          uuid.UUID  `gorm:"primary_key;type:uuid"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index"`
+	RefreshHash []byte     `gorm:"size:64;not null"`
+	Device      string     `gorm:"size:255"`
+	CreatedAt   *time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   *time.Time `gorm:"autoUpdateTime"`
+	ExpiresAt   time.Time  `gorm:"not null"`
+	RevokedAt   *time.Time
+}
+
+func (v1Session) TableName() string { return "sessions" }
+
+func v1Tables() []interface{} {
+	return []interface{}{&v1User{}, &v1SecretData{}, &v1Session{}, &v1ChangeCounter{}, &v1Blob{}, &v1BlobChunk{}}
+}
+
+func upInitialSchema(_ *DataStore, tx *gorm.DB) error {
+	return tx.AutoMigrate(v1Tables()...)
+}
+
+func downInitialSchema(_ *DataStore, tx *gorm.DB) error {
+	return tx.Migrator().DropTable(v1Tables()...)
+}
+
+// Version 2 moves chunks that were kept in the blob_chunks table to the
+// BlobStore and drops the column.
+
+type v2LegacyChunk struct {
+	BlobID uuid.UUID
+	Number int64
+	Data   []byte
+}
+
+func (v2LegacyChunk) TableName() string { return "blob_chunks" }
+
+func upChunksInStore(ds *DataStore, tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&v2LegacyChunk{}, "data") {
+		return nil
+	}
+	var chunks []v2LegacyChunk
+	if err := tx.Where("key = ''").Find(&chunks).Error; err != nil {
+		return err
+	}
+	log.Infof("moving %d blob chunks to the blob store", len(chunks))
+	for _, chunk := range chunks {
+		key, err := ds.blobs.Put(context.Background(), chunk.Data)
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&v1BlobChunk{}).Where("blob_id = ?", chunk.BlobID).Where("number = ?", chunk.Number).
+			Updates(map[string]interface{}{"key": key, "size": len(chunk.Data)}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Migrator().DropColumn(&v2LegacyChunk{}, "data")
+}
+
+// downChunksInStore copies the chunks back into the table. The objects stay
+// in the store.
+func downChunksInStore(ds *DataStore, tx *gorm.DB) error {
+	if err := tx.Migrator().AddColumn(&v2LegacyChunk{}, "Data"); err != nil {
+		return err
+	}
+	var chunks []v1BlobChunk
+	if err := tx.Find(&chunks).Error; err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		data, err := ds.blobs.Get(context.Background(), chunk.Key)
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&v2LegacyChunk{}).Where("blob_id = ?", chunk.BlobID).Where("number = ?", chunk.Number).
+			Updates(map[string]interface{}{"data": data, "key": ""}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Version 3 keeps the replaced revisions of secrets.
+
+type v3SecretRevision struct {
+	SecretID  uuid.UUID  `gorm:"primary_key;type:uuid"`
+	Revision  int64      `gorm:"primary_key;autoIncrement:false"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Type      string     `gorm:"size:255;not null"`
+	Name      string     `gorm:"size:255;not null"`
+	Secret    []byte     `gorm:"type:bytes;size:20480;not null"`
+	Sealed    bool       `gorm:"not null;default:false"`
+	BlobID    *uuid.UUID `gorm:"type:uuid;index"`
+	WrittenAt *time.Time
+}
+
+func (v3SecretRevision) TableName() string { return "secret_revisions" }
+
+func upSecretRevisions(_ *DataStore, tx *gorm.DB) error {
+	return tx.AutoMigrate(&v3SecretRevision{})
+}
+
+func downSecretRevisions(_ *DataStore, tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&v3SecretRevision{})
+}
+
+// Version 4 records the newest tombstone purged from the trash of each user.
+
+type v4ChangeCounter struct {
+	UserID    uuid.UUID `gorm:"primary_key;type:uuid"`
+	Seq       int64     `gorm:"not null;default:0"`
+	PurgedSeq int64     `gorm:"not null;default:0"`
+}
+
+func (v4ChangeCounter) TableName() string { return "change_counters" }
+
+func upPurgedSeq(_ *DataStore, tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&v4ChangeCounter{}, "purged_seq") {
+		return nil
+	}
+	return tx.Migrator().AddColumn(&v4ChangeCounter{}, "PurgedSeq")
+}
+
+func downPurgedSeq(_ *DataStore, tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&v4ChangeCounter{}, "purged_seq")
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newMigrationStore(t *testing.T) (*DataStore, *gorm.DB) {
+	db, err := OpenDB(DriverSQLite, filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &DataStore{db: db, broker: NewBroker(), blobs: testBlobs}, db
+}
+
+var liveModels = []interface{}{&models.User{}, &models.SecretData{}, &models.Session{}, &models.ChangeCounter{}, &models.Blob{}, &models.BlobChunk{}, &models.SecretRevision{}}
+
+// assertSchemaMatchesModels checks that the migrated tables have exactly the
+// columns of the models, so a model change without a migration fails here.
+func assertSchemaMatchesModels(t *testing.T, db *gorm.DB) {
+	for _, model := range liveModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		table := stmt.Schema.Table
+		if !assert.True(t, db.Migrator().HasTable(table), "table %s", table) {
+			continue
+		}
+		want := map[string]bool{}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" {
+				want[field.DBName] = true
+			}
+		}
+		columns, err := db.Migrator().ColumnTypes(table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]bool{}
+		for _, column := range columns {
+			got[column.Name()] = true
+		}
+		assert.Equal(t, want, got, "columns of %s", table)
+	}
+}
+
+func TestDataStore_Migrate(t *testing.T) {
+	store, db := newMigrationStore(t)
+
+	states, err := store.MigrationStatus()
+	assert.NoError(t, err)
+	assert.Len(t, states, LatestSchema())
+	for _, state := range states {
+		assert.Nil(t, state.AppliedAt, "migration %d applied on an empty database", state.Version)
+	}
+
+	assert.NoError(t, store.Migrate())
+	assertSchemaMatchesModels(t, db)
+	states, err = store.MigrationStatus()
+	assert.NoError(t, err)
+	for _, state := range states {
+		assert.True(t, state.Known)
+		assert.NotNil(t, state.AppliedAt, "migration %d pending", state.Version)
+	}
+	assert.NoError(t, store.Migrate(), "Migrate() of an up-to-date database")
+
+	assert.NoError(t, store.MigrateDown(LatestSchema()))
+	for _, model := range liveModels {
+		assert.False(t, db.Migrator().HasTable(model), "%T kept after rolling back", model)
+	}
+	assert.ErrorIs(t, store.MigrateDown(1), ErrNoMigration)
+
+	assert.NoError(t, store.Migrate(), "Migrate() after rolling back")
+	assertSchemaMatchesModels(t, db)
+}
+
+func TestDataStore_Migrate_schemaTooNew(t *testing.T) {
+	store, db := newMigrationStore(t)
+	assert.NoError(t, store.Migrate())
+	next := LatestSchema() + 1
+	assert.NoError(t, db.Create(&schemaVersion{Version: next, Name: "from the future", AppliedAt: time.Now()}).Error)
+
+	assert.ErrorIs(t, store.Migrate(), ErrSchemaTooNew)
+	assert.ErrorIs(t, store.MigrateDown(1), ErrSchemaTooNew)
+	states, err := store.MigrationStatus()
+	assert.NoError(t, err)
+	if assert.Len(t, states, next) {
+		assert.Equal(t, MigrationState{Version: next, Name: "from the future", AppliedAt: states[next-1].AppliedAt}, states[next-1])
+		assert.NotNil(t, states[next-1].AppliedAt)
+	}
+}
+
+// TestDataStore_Migrate_autoMigrated adopts a database created by a server
+// that still used AutoMigrate.
+func TestDataStore_Migrate_autoMigrated(t *testing.T) {
+	store, db := newMigrationStore(t)
+	assert.NoError(t, db.AutoMigrate(liveModels...))
+	user := models.User{ID: uuid.New(), Username: "A", Password: []byte("a"), Email: "adopted@test.com"}
+	assert.NoError(t, db.Create(&user).Error)
+
+	assert.NoError(t, store.Migrate())
+	assertSchemaMatchesModels(t, db)
+	var got models.User
+	assert.NoError(t, db.Take(&got, "id = ?", user.ID).Error, "user lost")
+}
+
+func TestDataStore_Migrate_chunkData(t *testing.T) {
+	store, db := newMigrationStore(t)
+	type BlobChunk struct {
+		BlobID uuid.UUID `gorm:"primary_key;type:uuid"`
+		Number int64     `gorm:"primary_key;autoIncrement:false"`
+		Data   []byte    `gorm:"not null"`
+	}
+	assert.NoError(t, db.AutoMigrate(&BlobChunk{}))
+	id := uuid.New()
+	assert.NoError(t, db.Create(&BlobChunk{BlobID: id, Number: 0, Data: []byte("legacy chunk")}).Error)
+
+	assert.NoError(t, store.Migrate())
+	assert.False(t, db.Migrator().HasColumn(&models.BlobChunk{}, "data"), "data column kept")
+	var chunk models.BlobChunk
+	assert.NoError(t, db.Where("blob_id = ?", id).Take(&chunk).Error)
+	assert.Equal(t, objectKey([]byte("legacy chunk")), chunk.Key)
+	assert.Equal(t, int64(len("legacy chunk")), chunk.Size)
+	data, err := testBlobs.Get(context.Background(), chunk.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("legacy chunk"), data)
+
+	assert.NoError(t, store.MigrateDown(LatestSchema()-1), "MigrateDown() to version 1")
+	var legacy BlobChunk
+	assert.NoError(t, db.Where("blob_id = ?", id).Take(&legacy).Error)
+	assert.Equal(t, []byte("legacy chunk"), legacy.Data, "MigrateDown() data")
+}
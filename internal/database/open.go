@@ -0,0 +1,37 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Supported values of the db_driver option.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// OpenDB connects to the database the DataStore runs on. dsn is a file path
+// for SQLite and a connection string for PostgreSQL, e.g.
+// "host=localhost user=gophkeeper dbname=gophkeeper sslmode=disable" or
+// "postgres://gophkeeper@localhost/gophkeeper". Several server instances can
+// share one PostgreSQL database if the calls of a client stick to one
+// instance: pending logins are kept in memory, see the server package.
+// Driver errors are translated, so a duplicate key is gorm.ErrDuplicatedKey
+// on both. Foreign keys are left out of the schema, as SQLite never enforced
+// them: both backends keep the same rows.
+func OpenDB(driver, dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case DriverSQLite:
+		dialector = sqlite.Open(dsn)
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+	return gorm.Open(dialector, &gorm.Config{TranslateError: true, DisableForeignKeyConstraintWhenMigrating: true})
+}
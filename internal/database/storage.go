@@ -1,9 +1,13 @@
 package database
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
 	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/logger"
@@ -22,8 +26,37 @@ type DataStorable interface {
 	AddSecretData(ctx context.Context, data models.SecretData) (*models.SecretData, error)
 	GetSecretData(ctx context.Context) (*[]models.SecretData, error)
 	UpdateSecretData(ctx context.Context, data models.SecretData) (*models.SecretData, error)
-	DeleteSecretData(ctx context.Context, idSecretData uuid.UUID) (bool, error)
+	DeleteSecretData(ctx context.Context, idSecretData uuid.UUID, revision int64) (bool, error)
+	ListTrash(ctx context.Context) (*[]models.SecretData, error)
+	RestoreSecretData(ctx context.Context, id uuid.UUID) (*models.SecretData, error)
+	EmptyTrash(ctx context.Context) (int, error)
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+	ListSecretRevisions(ctx context.Context, id uuid.UUID) (*[]models.SecretRevision, error)
+	RestoreSecretRevision(ctx context.Context, id uuid.UUID, revision int64, current int64) (*models.SecretData, error)
+	SyncSecretData(ctx context.Context, cursor int64) (*Changes, error)
+	WatchSecretData(ctx context.Context) (<-chan Change, error)
+	RekeyVault(ctx context.Context, user models.User, data []models.SecretData, revisions []models.SecretRevision) error
+
+	AddBlobChunk(ctx context.Context, id uuid.UUID, index int64, data []byte, last bool) (*models.Blob, error)
+	GetBlob(ctx context.Context, id uuid.UUID) (*models.Blob, error)
+	GetBlobChunk(ctx context.Context, id uuid.UUID, index int64) ([]byte, error)
+	DeleteBlob(ctx context.Context, id uuid.UUID) (bool, error)
+	CollectBlobs(ctx context.Context, before time.Time) (int, error)
+
+	AddSession(ctx context.Context, session models.Session) error
+	RefreshSession(ctx context.Context, id uuid.UUID, refreshHash []byte, newHash []byte, expiresAt time.Time) (*models.User, error)
+	IsSessionActive(ctx context.Context, id uuid.UUID) (bool, error)
+	ListSessions(ctx context.Context) (*[]models.Session, error)
+	RevokeSession(ctx context.Context, id uuid.UUID) (bool, error)
+
+	SetTotp(ctx context.Context, user models.User) error
+	UseTotpStep(ctx context.Context, id uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, id uuid.UUID, hash []byte) (bool, error)
+
 	Migrate() error
+	MigrateDown(steps int) error
+	MigrationStatus() ([]MigrationState, error)
+	Backup(ctx context.Context, root string) (string, error)
 }
 
 var (
@@ -34,21 +67,25 @@ var (
 
 type DataStore struct {
 	db *gorm.DB
+	// broker receives every committed secret write
+	broker *Broker
+	// blobs keeps the bytes of blob chunks
+	blobs BlobStore
+	// objects is held shared from storing an object until its chunk row is
+	// committed and exclusively while releaseObjects counts the rows of a key
+	// and deletes the object, so an object stored again for a new chunk is
+	// not deleted in between.
+	objects sync.RWMutex
 }
 
-func NewDataStore(logger *logger.Logger, db *gorm.DB) *DataStorable {
+func NewDataStore(logger *logger.Logger, db *gorm.DB, blobs BlobStore) *DataStorable {
 	once.Do(func() {
 		log = logger
-		ds = &DataStore{db: db}
+		ds = &DataStore{db: db, broker: NewBroker(), blobs: blobs}
 	})
 	return &ds
 }
 
-func (ds *DataStore) Migrate() error {
-	log.Info("migrating database schema")
-	return ds.db.AutoMigrate(&models.User{}, &models.SecretData{})
-}
-
 func (ds *DataStore) AddUser(ctx context.Context, user *models.User) (*models.User, error) {
 	log := log.WithFields(logrus.Fields{
 		"method": "AddUser",
@@ -112,6 +149,9 @@ func (ds *DataStore) UpdateUser(ctx context.Context, user models.User) (*models.
 	if user.Password != nil {
 		param["password"] = user.Password
 	}
+	if user.Verifier != nil {
+		param["verifier"] = user.Verifier
+	}
 
 	log.Info("updating user")
 	tx := ds.db.Model(&user).Clauses(clause.Returning{}).Updates(param).First(&user)
@@ -132,12 +172,24 @@ func (ds *DataStore) AddSecretData(ctx context.Context, data models.SecretData)
 		"user":   userCtx.Email,
 	})
 	data.UserID = userCtx.Id
+	data.Revision = 1
 	log.Info("adding secret data")
-	tx := ds.db.Create(&data)
-	if err := tx.Error; err != nil {
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkBlob(tx, userCtx.Id, data.BlobID); err != nil {
+			return err
+		}
+		seq, err := nextChangeSeq(tx, userCtx.Id)
+		if err != nil {
+			return err
+		}
+		data.ChangeSeq = seq
+		return tx.Create(&data).Error
+	})
+	if err != nil {
 		log.Error(err.Error())
 		return nil, err
 	}
+	ds.broker.publish(userCtx.Id, Change{Kind: SecretCreated, Secret: data, Session: userCtx.SessionID})
 	return &data, nil
 }
 
@@ -161,6 +213,12 @@ func (ds *DataStore) GetSecretData(ctx context.Context) (*[]models.SecretData, e
 	return &dataList, nil
 }
 
+// UpdateSecretData writes data if the stored secret is still at
+// data.Revision and returns it with the next revision. Otherwise a
+// *ConflictError with the stored copy is returned. The replaced revision is
+// kept in the history. data.BlobID replaces the attachment, so an update
+// without it detaches the file. A blob neither the secret nor its history
+// refers to any more is deleted unless another secret does.
 func (ds *DataStore) UpdateSecretData(ctx context.Context, data models.SecretData) (*models.SecretData, error) {
 	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
 	if !ok {
@@ -171,26 +229,198 @@ func (ds *DataStore) UpdateSecretData(ctx context.Context, data models.SecretDat
 		"user":   userCtx.Email,
 	})
 
-	param := make(map[string]interface{})
+	param := map[string]interface{}{}
 
 	if data.Name != "" {
 		param["name"] = data.Name
 	}
+	if data.Sealed {
+		param["name"] = ""
+		param["type"] = ""
+		param["sealed"] = true
+	}
 	if data.Secret != nil {
 		param["secret"] = data.Secret
 	}
+	param["blob_id"] = data.BlobID
 
 	log.Info("updating secret data")
-	tx := ds.db.Model(&data).Clauses(clause.Returning{}).Where("id = ?", data.ID).Where("user_id = ?", userCtx.Id).Updates(param).First(&data)
-	if err := tx.Error; err != nil {
+	stored, released, err := ds.replaceSecret(userCtx.Id, data.ID, data.Revision, func(tx *gorm.DB) (map[string]interface{}, error) {
+		return param, checkBlob(tx, userCtx.Id, data.BlobID)
+	})
+	if err != nil {
+		logWriteError(log, err)
+		return nil, err
+	}
+	ds.broker.publish(userCtx.Id, Change{Kind: SecretUpdated, Secret: *stored, Session: userCtx.SessionID})
+	for _, id := range released {
+		ds.collectBlob(ctx, userCtx.Id, id)
+	}
+	return stored, nil
+}
+
+// ListSecretRevisions returns the kept revisions of a secret of the user,
+// newest first.
+func (ds *DataStore) ListSecretRevisions(ctx context.Context, id uuid.UUID) (*[]models.SecretRevision, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "ListSecretRevisions",
+		"user":   userCtx.Email,
+	})
+
+	log.Info("listing secret revisions")
+	var revisions []models.SecretRevision
+	err := ds.db.Where("secret_id = ?", id).Where("user_id = ?", userCtx.Id).Order("revision DESC").Find(&revisions).Error
+	if err != nil {
 		log.Error(err.Error())
 		return nil, err
 	}
+	return &revisions, nil
+}
 
-	return &data, nil
+// RestoreSecretRevision writes a kept revision of a secret back as its next
+// revision, if the secret is still at current. The replaced revision goes to
+// the history like any other write; a missing revision is
+// gorm.ErrRecordNotFound.
+func (ds *DataStore) RestoreSecretRevision(ctx context.Context, id uuid.UUID, revision int64, current int64) (*models.SecretData, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "RestoreSecretRevision",
+		"user":   userCtx.Email,
+	})
+
+	log.WithField("revision", revision).Info("restoring secret revision")
+	stored, released, err := ds.replaceSecret(userCtx.Id, id, current, func(tx *gorm.DB) (map[string]interface{}, error) {
+		var old models.SecretRevision
+		err := tx.Where("secret_id = ?", id).Where("user_id = ?", userCtx.Id).Where("revision = ?", revision).Take(&old).Error
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"name":    old.Name,
+			"type":    old.Type,
+			"secret":  old.Secret,
+			"sealed":  old.Sealed,
+			"blob_id": old.BlobID,
+		}, nil
+	})
+	if err != nil {
+		logWriteError(log, err)
+		return nil, err
+	}
+	ds.broker.publish(userCtx.Id, Change{Kind: SecretUpdated, Secret: *stored, Session: userCtx.SessionID})
+	for _, id := range released {
+		ds.collectBlob(ctx, userCtx.Id, id)
+	}
+	return stored, nil
+}
+
+// replaceSecret writes the columns returned by param to secret id of user if
+// the secret is still at revision, and moves the replaced revision to the
+// history. It returns the stored secret and the blobs the write may have
+// released: the previous blob of the secret and those of pruned revisions.
+func (ds *DataStore) replaceSecret(user uuid.UUID, id uuid.UUID, revision int64,
+	param func(tx *gorm.DB) (map[string]interface{}, error)) (*models.SecretData, []uuid.UUID, error) {
+	var data models.SecretData
+	var released []uuid.UUID
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		// the change counter is locked from here on, so the secret cannot
+		// change before the update
+		seq, err := nextChangeSeq(tx, user)
+		if err != nil {
+			return err
+		}
+		if err = tx.Where("id = ?", id).Where("user_id = ?", user).Take(&data).Error; err != nil {
+			return err
+		}
+		if data.Revision != revision {
+			return &ConflictError{Current: data}
+		}
+		values, err := param(tx)
+		if err != nil {
+			return err
+		}
+		if err = archiveSecret(tx, data); err != nil {
+			return err
+		}
+		values["revision"] = gorm.Expr("revision + 1")
+		values["change_seq"] = seq
+		if err = tx.Model(&models.SecretData{}).Where("id = ?", id).Where("user_id = ?", user).Updates(values).Error; err != nil {
+			return err
+		}
+		if data.BlobID != nil {
+			released = append(released, *data.BlobID)
+		}
+		pruned, err := pruneRevisions(tx, user, id)
+		if err != nil {
+			return err
+		}
+		released = append(released, pruned...)
+		return tx.Where("id = ?", id).Where("user_id = ?", user).Take(&data).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &data, released, nil
+}
+
+// historySize is how many replaced revisions of each secret are kept.
+var historySize = 10
+
+// KeepRevisions sets how many replaced revisions of each secret are kept;
+// 0 keeps none. Surplus revisions are pruned with the next write.
+func KeepRevisions(n int) {
+	historySize = n
 }
 
-func (ds *DataStore) DeleteSecretData(ctx context.Context, idSecretData uuid.UUID) (bool, error) {
+func archiveSecret(tx *gorm.DB, data models.SecretData) error {
+	if historySize <= 0 {
+		return nil
+	}
+	return tx.Create(&models.SecretRevision{
+		SecretID:  data.ID,
+		Revision:  data.Revision,
+		UserID:    data.UserID,
+		Type:      data.Type,
+		Name:      data.Name,
+		Secret:    data.Secret,
+		Sealed:    data.Sealed,
+		BlobID:    data.BlobID,
+		WrittenAt: data.UpdatedAt,
+	}).Error
+}
+
+// pruneRevisions deletes the revisions of a secret beyond historySize and
+// returns the blobs they referred to.
+func pruneRevisions(tx *gorm.DB, user uuid.UUID, id uuid.UUID) ([]uuid.UUID, error) {
+	var kept []int64
+	err := tx.Model(&models.SecretRevision{}).Where("secret_id = ?", id).Where("user_id = ?", user).
+		Order("revision DESC").Pluck("revision", &kept).Error
+	if err != nil || len(kept) <= historySize {
+		return nil, err
+	}
+	oldest := kept[max(historySize, 0)]
+	var blobs []uuid.UUID
+	err = tx.Model(&models.SecretRevision{}).Where("secret_id = ?", id).Where("user_id = ?", user).Where("revision <= ?", oldest).
+		Where("blob_id IS NOT NULL").Pluck("blob_id", &blobs).Error
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Where("secret_id = ?", id).Where("user_id = ?", user).Where("revision <= ?", oldest).Delete(&models.SecretRevision{}).Error
+	return blobs, err
+}
+
+// DeleteSecretData moves a secret that is still at revision to the trash. The
+// row stays as a tombstone for sync, with its history and attached blob,
+// until the trash is emptied or purged. A secret that changed since returns a
+// *ConflictError with the stored copy.
+func (ds *DataStore) DeleteSecretData(ctx context.Context, idSecretData uuid.UUID, revision int64) (bool, error) {
 	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
 	if !ok {
 		return false, ErrUserNotFound
@@ -201,12 +431,926 @@ func (ds *DataStore) DeleteSecretData(ctx context.Context, idSecretData uuid.UUI
 	})
 
 	log.Info("deleting secret data")
-	tx := ds.db.Where("id = ?", idSecretData).Where("user_id = ?", userCtx.Id).First(&models.SecretData{}).Delete(&models.SecretData{})
+	deleted := models.SecretData{ID: idSecretData, UserID: userCtx.Id, Revision: revision + 1}
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextChangeSeq(tx, userCtx.Id)
+		if err != nil {
+			return err
+		}
+		deleted.ChangeSeq = seq
+		res := tx.Model(&models.SecretData{}).Where("id = ?", idSecretData).Where("user_id = ?", userCtx.Id).Where("revision = ?", revision).
+			Updates(map[string]interface{}{"deleted_at": time.Now(), "change_seq": seq, "revision": gorm.Expr("revision + 1")})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 1 {
+			return nil
+		}
+		var current models.SecretData
+		if err = tx.Where("id = ?", idSecretData).Where("user_id = ?", userCtx.Id).Take(&current).Error; err != nil {
+			return err
+		}
+		return &ConflictError{Current: current}
+	})
+	if err != nil {
+		logWriteError(log, err)
+		return false, err
+	}
+	ds.broker.publish(userCtx.Id, Change{Kind: SecretDeleted, Secret: deleted, Session: userCtx.SessionID})
+	return true, nil
+}
+
+// ListTrash returns the deleted secrets of the user that are not purged yet,
+// most recently deleted first.
+func (ds *DataStore) ListTrash(ctx context.Context) (*[]models.SecretData, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "ListTrash",
+		"user":   userCtx.Email,
+	})
+
+	log.Info("listing trash")
+	var trash []models.SecretData
+	err := ds.db.Unscoped().Where("user_id = ?", userCtx.Id).Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").Find(&trash).Error
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return &trash, nil
+}
+
+// RestoreSecretData takes a secret out of the trash. It comes back at the
+// next revision, so every device syncs it again. A secret that is not in the
+// trash returns gorm.ErrRecordNotFound.
+func (ds *DataStore) RestoreSecretData(ctx context.Context, id uuid.UUID) (*models.SecretData, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "RestoreSecretData",
+		"user":   userCtx.Email,
+	})
+
+	log.Info("restoring secret data")
+	var restored models.SecretData
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextChangeSeq(tx, userCtx.Id)
+		if err != nil {
+			return err
+		}
+		res := tx.Unscoped().Model(&models.SecretData{}).Where("id = ?", id).Where("user_id = ?", userCtx.Id).Where("deleted_at IS NOT NULL").
+			Updates(map[string]interface{}{"deleted_at": nil, "change_seq": seq, "revision": gorm.Expr("revision + 1")})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("id = ?", id).Where("user_id = ?", userCtx.Id).Take(&restored).Error
+	})
+	if err != nil {
+		logWriteError(log, err)
+		return nil, err
+	}
+	ds.broker.publish(userCtx.Id, Change{Kind: SecretCreated, Secret: restored, Session: userCtx.SessionID})
+	return &restored, nil
+}
+
+// EmptyTrash deletes the secrets in the user's trash for good and returns how
+// many there were.
+func (ds *DataStore) EmptyTrash(ctx context.Context) (int, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return 0, ErrUserNotFound
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "EmptyTrash",
+		"user":   userCtx.Email,
+	})
+
+	log.Info("emptying trash")
+	var purged int
+	var released []uuid.UUID
+	err := ds.db.Transaction(func(tx *gorm.DB) (err error) {
+		purged, released, err = purgeTrash(tx, userCtx.Id, nil)
+		return err
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return 0, err
+	}
+	for _, id := range released {
+		ds.collectBlob(ctx, userCtx.Id, id)
+	}
+	return purged, nil
+}
+
+// PurgeTrash deletes the secrets of every user that were moved to the trash
+// before that, and returns how many it deleted.
+func (ds *DataStore) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "PurgeTrash",
+	})
+
+	var users []uuid.UUID
+	err := ds.db.Unscoped().Model(&models.SecretData{}).Where("deleted_at < ?", before).Distinct().Pluck("user_id", &users).Error
+	if err != nil {
+		log.Error(err.Error())
+		return 0, err
+	}
+	total := 0
+	for _, user := range users {
+		var purged int
+		var released []uuid.UUID
+		err = ds.db.Transaction(func(tx *gorm.DB) (err error) {
+			purged, released, err = purgeTrash(tx, user, &before)
+			return err
+		})
+		if err != nil {
+			log.Error(err.Error())
+			return total, err
+		}
+		total += purged
+		for _, id := range released {
+			ds.collectBlob(ctx, user, id)
+		}
+	}
+	if total > 0 {
+		log.Infof("purged %d secrets from the trash", total)
+	}
+	return total, nil
+}
+
+// purgeTrash deletes the user's secrets trashed before that, or the whole
+// trash for a nil before, with their history. It returns how many it deleted
+// and the blobs they referred to. Their tombstones are gone, so it moves the
+// user's PurgedSeq past them and clients that missed a delete sync in full.
+func purgeTrash(tx *gorm.DB, user uuid.UUID, before *time.Time) (int, []uuid.UUID, error) {
+	// the counter lock keeps writes of the user out until the purge commits
+	var counter models.ChangeCounter
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", user).Take(&counter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	trashed := tx.Unscoped().Where("user_id = ?", user).Where("deleted_at IS NOT NULL")
+	if before != nil {
+		trashed = trashed.Where("deleted_at < ?", *before)
+	}
+	var secrets []models.SecretData
+	if err = trashed.Select("id", "blob_id", "change_seq").Find(&secrets).Error; err != nil || len(secrets) == 0 {
+		return 0, nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(secrets))
+	var blobs []uuid.UUID
+	horizon := counter.PurgedSeq
+	for _, secret := range secrets {
+		ids = append(ids, secret.ID)
+		if secret.BlobID != nil {
+			blobs = append(blobs, *secret.BlobID)
+		}
+		horizon = max(horizon, secret.ChangeSeq)
+	}
+
+	var history []uuid.UUID
+	err = tx.Model(&models.SecretRevision{}).Where("user_id = ?", user).Where("secret_id IN ?", ids).
+		Where("blob_id IS NOT NULL").Pluck("blob_id", &history).Error
+	if err != nil {
+		return 0, nil, err
+	}
+	if err = tx.Where("user_id = ?", user).Where("secret_id IN ?", ids).Delete(&models.SecretRevision{}).Error; err != nil {
+		return 0, nil, err
+	}
+	res := tx.Unscoped().Where("user_id = ?", user).Where("id IN ?", ids).Delete(&models.SecretData{})
+	if res.Error != nil {
+		return 0, nil, res.Error
+	}
+	err = tx.Model(&models.ChangeCounter{}).Where("user_id = ?", user).Update("purged_seq", horizon).Error
+	if err != nil {
+		return 0, nil, err
+	}
+	return int(res.RowsAffected), append(blobs, history...), nil
+}
+
+// SyncSecretData returns the secrets written after cursor, deleted ones
+// included, and the cursor to continue from. A zero cursor, one the server
+// does not know, or one older than a tombstone purged since gets the full
+// list of live secrets instead.
+func (ds *DataStore) SyncSecretData(ctx context.Context, cursor int64) (*Changes, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "SyncSecretData",
+		"user":   userCtx.Email,
+	})
+
+	log.Info("syncing secret data")
+	changes := Changes{Secrets: make([]models.SecretData, 0)}
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		// Writes take the counter under a row lock, so every secret up to the
+		// committed counter is visible here.
+		var counter models.ChangeCounter
+		if err := tx.Where("user_id = ?", userCtx.Id).Limit(1).Find(&counter).Error; err != nil {
+			return err
+		}
+		changes.Cursor = counter.Seq
+		if cursor == 0 || cursor > changes.Cursor || cursor < counter.PurgedSeq {
+			changes.Full = true
+			return tx.Where("user_id = ?", userCtx.Id).Find(&changes.Secrets).Error
+		}
+		return tx.Unscoped().Where("user_id = ?", userCtx.Id).Where("change_seq > ? AND change_seq <= ?", cursor, changes.Cursor).
+			Order("change_seq").Find(&changes.Secrets).Error
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return &changes, nil
+}
+
+// WatchSecretData returns the writes to the user's secrets committed from
+// now on until ctx is done. The channel is closed early when the subscriber
+// falls behind; a sync then picks up what was missed.
+func (ds *DataStore) WatchSecretData(ctx context.Context) (<-chan Change, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	log.WithFields(logrus.Fields{
+		"method": "WatchSecretData",
+		"user":   userCtx.Email,
+	}).Info("watching secret data")
+	return ds.broker.Subscribe(ctx, userCtx.Id), nil
+}
+
+// nextChangeSeq advances the user's change counter. The write locks the
+// counter row until the transaction ends, so sequence numbers become visible
+// in order.
+func nextChangeSeq(tx *gorm.DB, id uuid.UUID) (int64, error) {
+	counter := models.ChangeCounter{UserID: id, Seq: 1}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"seq": gorm.Expr("change_counters.seq + 1")}),
+	}).Create(&counter).Error
+	if err != nil {
+		return 0, err
+	}
+	var seq int64
+	if err = tx.Model(&models.ChangeCounter{}).Where("user_id = ?", id).Select("seq").Scan(&seq).Error; err != nil {
+		return 0, err
+	}
+	return seq, nil
+}
+
+// logWriteError logs a failed secret write; a revision conflict is expected
+// and only a warning.
+func logWriteError(log *logrus.Entry, err error) {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		log.WithField("revision", conflict.Current.Revision).Warn("secret data changed concurrently")
+		return
+	}
+	log.Error(err.Error())
+}
+
+// RekeyVault replaces the user's key material and every secret of the vault in
+// one transaction. data must contain each stored secret exactly once at its
+// current revision, the ones in the trash included, and revisions each kept
+// revision of the history, otherwise nothing is written and ErrVaultMismatch
+// is returned. Re-encrypted live secrets move to the next revision; trashed
+// secrets and the history keep theirs. A nil data only re-wraps the vault data
+// key and is accepted once the vault already uses one. A new password or
+// verifier revokes every session of the user but the one of ctx.
+func (ds *DataStore) RekeyVault(ctx context.Context, user models.User, data []models.SecretData, revisions []models.SecretRevision) error {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return ErrUserNotFound
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "RekeyVault",
+		"user":   userCtx.Email,
+	})
+
+	log.Info("re-keying vault")
+	var changes []Change
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		var stored models.User
+		if err := tx.Where("id = ?", userCtx.Id).Take(&stored).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if data == nil && stored.WrappedKey != nil && user.WrappedKey != nil {
+			return updateKeyMaterial(tx, userCtx, user)
+		}
+
+		// the counter lock keeps writes of the user out until the vault commits
+		seq, err := nextChangeSeq(tx, userCtx.Id)
+		if err != nil {
+			return err
+		}
+		var count int64
+		if err = tx.Unscoped().Model(&models.SecretData{}).Where("user_id = ?", userCtx.Id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != int64(len(data)) {
+			return ErrVaultMismatch
+		}
+		if err = tx.Model(&models.SecretRevision{}).Where("user_id = ?", userCtx.Id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != int64(len(revisions)) {
+			return ErrVaultMismatch
+		}
+
+		seen := make(map[uuid.UUID]struct{}, len(data))
+		for _, item := range data {
+			if _, dup := seen[item.ID]; dup {
+				return ErrVaultMismatch
+			}
+			seen[item.ID] = struct{}{}
+
+			res := tx.Model(&models.SecretData{}).Where("id = ?", item.ID).Where("user_id = ?", userCtx.Id).Where("revision = ?", item.Revision).
+				Updates(map[string]interface{}{"secret": item.Secret, "revision": gorm.Expr("revision + 1"), "change_seq": seq})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 1 {
+				item.UserID = userCtx.Id
+				item.Revision++
+				item.ChangeSeq = seq
+				changes = append(changes, Change{Kind: SecretUpdated, Secret: item, Session: userCtx.SessionID})
+				continue
+			}
+			// a trashed secret is not synced, so it keeps its revision
+			res = tx.Unscoped().Model(&models.SecretData{}).Where("id = ?", item.ID).Where("user_id = ?", userCtx.Id).
+				Where("revision = ?", item.Revision).Where("deleted_at IS NOT NULL").Update("secret", item.Secret)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected != 1 {
+				return ErrVaultMismatch
+			}
+		}
+
+		type revisionKey struct {
+			id       uuid.UUID
+			revision int64
+		}
+		kept := make(map[revisionKey]struct{}, len(revisions))
+		for _, item := range revisions {
+			key := revisionKey{item.SecretID, item.Revision}
+			if _, dup := kept[key]; dup {
+				return ErrVaultMismatch
+			}
+			kept[key] = struct{}{}
+
+			res := tx.Model(&models.SecretRevision{}).Where("secret_id = ?", item.SecretID).Where("revision = ?", item.Revision).
+				Where("user_id = ?", userCtx.Id).Update("secret", item.Secret)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected != 1 {
+				return ErrVaultMismatch
+			}
+		}
+		return updateKeyMaterial(tx, userCtx, user)
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	ds.broker.publish(userCtx.Id, changes...)
+	return nil
+}
+
+func updateKeyMaterial(tx *gorm.DB, userCtx *models.UserCtx, user models.User) error {
+	param := map[string]interface{}{
+		"kdf_algorithm": user.Kdf.Algorithm,
+		"kdf_salt":      user.Kdf.Salt,
+		"kdf_time":      user.Kdf.Time,
+		"kdf_memory":    user.Kdf.Memory,
+		"kdf_threads":   user.Kdf.Threads,
+		"wrapped_key":   user.WrappedKey,
+	}
+	if user.Password != nil {
+		param["password"] = user.Password
+	}
+	if user.Verifier != nil {
+		param["verifier"] = user.Verifier
+	}
+	res := tx.Model(&models.User{}).Where("id = ?", userCtx.Id).Updates(param)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected != 1 {
+		return ErrUserNotFound
+	}
+	if user.Password == nil && user.Verifier == nil {
+		return nil
+	}
+	// tokens issued for the old password must not outlive it
+	return tx.Model(&models.Session{}).
+		Where("user_id = ?", userCtx.Id).Where("id <> ?", userCtx.SessionID).Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
+
+func (ds *DataStore) AddSession(ctx context.Context, session models.Session) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "AddSession",
+		"user":   session.UserID,
+	})
+
+	log.Info("adding session")
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+	if err := ds.db.Create(&session).Error; err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// RefreshSession replaces the refresh token hash of an active session and
+// returns the session owner. A hash that does not match means an old refresh
+// token was replayed, so the session is revoked and ErrSessionRevoked is
+// returned.
+func (ds *DataStore) RefreshSession(ctx context.Context, id uuid.UUID, refreshHash []byte, newHash []byte, expiresAt time.Time) (*models.User, error) {
+	log := log.WithFields(logrus.Fields{
+		"method":  "RefreshSession",
+		"session": id,
+	})
+
+	log.Info("refreshing session")
+	var (
+		user   models.User
+		reused bool
+	)
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		var session models.Session
+		if err := tx.Where("id = ?", id).Take(&session).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSessionNotFound
+			}
+			return err
+		}
+		now := time.Now()
+		if session.RevokedAt != nil || session.ExpiresAt.Before(now) {
+			return ErrSessionRevoked
+		}
+		if subtle.ConstantTimeCompare(session.RefreshHash, refreshHash) != 1 {
+			reused = true
+			return tx.Model(&session).Update("revoked_at", now).Error
+		}
+		res := tx.Model(&models.Session{}).Where("id = ?", id).Where("refresh_hash = ?", refreshHash).
+			Updates(map[string]interface{}{"refresh_hash": newHash, "expires_at": expiresAt})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return ErrSessionRevoked
+		}
+		return tx.Where("id = ?", session.UserID).Take(&user).Error
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	if reused {
+		log.Warn("refresh token reused, session revoked")
+		return nil, ErrSessionRevoked
+	}
+	return &user, nil
+}
+
+func (ds *DataStore) IsSessionActive(ctx context.Context, id uuid.UUID) (bool, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return false, ErrUserNotFound
+	}
+
+	var count int64
+	tx := ds.db.Model(&models.Session{}).
+		Where("id = ?", id).Where("user_id = ?", userCtx.Id).
+		Where("revoked_at IS NULL").Where("expires_at > ?", time.Now()).
+		Count(&count)
+	if err := tx.Error; err != nil {
+		log.WithField("method", "IsSessionActive").Error(err.Error())
+		return false, err
+	}
+	return count == 1, nil
+}
+
+func (ds *DataStore) ListSessions(ctx context.Context) (*[]models.Session, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "ListSessions",
+		"user":   userCtx.Email,
+	})
+
+	log.Info("listing sessions")
+	var sessions []models.Session
+	tx := ds.db.Where("user_id = ?", userCtx.Id).
+		Where("revoked_at IS NULL").Where("expires_at > ?", time.Now()).
+		Order("created_at").Find(&sessions)
+	if err := tx.Error; err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+	return &sessions, nil
+}
+
+func (ds *DataStore) RevokeSession(ctx context.Context, id uuid.UUID) (bool, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return false, ErrUserNotFound
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "RevokeSession",
+		"user":   userCtx.Email,
+	})
+
+	log.Info("revoking session")
+	tx := ds.db.Model(&models.Session{}).
+		Where("id = ?", id).Where("user_id = ?", userCtx.Id).Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now())
 	if err := tx.Error; err != nil {
 		log.Error(err.Error())
 		return false, err
 	}
-	return true, nil
+	return tx.RowsAffected == 1, nil
+}
+
+// SetTotp replaces the two-factor settings of user.ID with those of user. A
+// zero TotpSecret disables the second factor.
+func (ds *DataStore) SetTotp(ctx context.Context, user models.User) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "SetTotp",
+		"user":   user.ID,
+	})
+
+	log.Info("updating two-factor settings")
+	res := ds.db.Model(&models.User{ID: user.ID}).
+		Select("totp_secret", "totp_enabled", "totp_last_step", "recovery_codes").
+		Updates(&models.User{
+			TotpSecret:    user.TotpSecret,
+			TotpEnabled:   user.TotpEnabled,
+			TotpLastStep:  user.TotpLastStep,
+			RecoveryCodes: user.RecoveryCodes,
+		})
+	if err := res.Error; err != nil {
+		log.Error(err.Error())
+		return err
+	}
+	if res.RowsAffected != 1 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UseTotpStep records that a code of step was accepted. It returns false when
+// a code of this or a later step was accepted before, which means the code is
+// replayed.
+func (ds *DataStore) UseTotpStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	res := ds.db.Model(&models.User{}).
+		Where("id = ?", id).Where("totp_last_step < ?", step).
+		Update("totp_last_step", step)
+	if err := res.Error; err != nil {
+		log.WithFields(logrus.Fields{"method": "UseTotpStep", "user": id}).Error(err.Error())
+		return false, err
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// UseRecoveryCode removes the recovery code hash of user id. It returns false
+// when the code was used already.
+func (ds *DataStore) UseRecoveryCode(ctx context.Context, id uuid.UUID, hash []byte) (bool, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "UseRecoveryCode",
+		"user":   id,
+	})
+
+	var (
+		used bool
+		left int
+	)
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		// the row stays locked until the code is removed, so two servers
+		// cannot accept the same code
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Take(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		rest := make([][]byte, 0, len(user.RecoveryCodes))
+		for _, stored := range user.RecoveryCodes {
+			if !used && bytes.Equal(stored, hash) {
+				used = true
+				continue
+			}
+			rest = append(rest, stored)
+		}
+		if !used {
+			return nil
+		}
+		left = len(rest)
+		return tx.Model(&models.User{ID: id}).Select("recovery_codes").Updates(&models.User{RecoveryCodes: rest}).Error
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return false, err
+	}
+	if used {
+		log.Infof("recovery code used, %d left", left)
+	}
+	return used, nil
 }
 
-var ErrUserNotFound = errors.New("user not found")
+// AddBlobChunk stores chunk index of blob id, creating the blob with its
+// first chunk. Chunks must arrive in order: a chunk that is already stored
+// is ignored, so a resumed upload may resend it, and one after a gap returns
+// ErrChunkOutOfOrder. last completes the blob. The bytes go to the BlobStore,
+// the database keeps only their key.
+func (ds *DataStore) AddBlobChunk(ctx context.Context, id uuid.UUID, index int64, data []byte, last bool) (*models.Blob, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "AddBlobChunk",
+		"user":   userCtx.Email,
+	})
+
+	var blob models.Blob
+	var key string
+	ds.objects.RLock()
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Where("user_id = ?", userCtx.Id).Take(&blob).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound) && index == 0:
+			blob = models.Blob{ID: id, UserID: userCtx.Id}
+			if err = tx.Create(&blob).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		}
+		switch {
+		case index < blob.Chunks:
+			return nil
+		case blob.Complete:
+			return ErrBlobComplete
+		case index > blob.Chunks:
+			return ErrChunkOutOfOrder
+		}
+		if key, err = ds.blobs.Put(ctx, data); err != nil {
+			return err
+		}
+		if err = tx.Create(&models.BlobChunk{BlobID: id, Number: index, Key: key, Size: int64(len(data))}).Error; err != nil {
+			return err
+		}
+		blob.Chunks++
+		blob.Size += int64(len(data))
+		blob.Complete = last
+		return tx.Model(&blob).Updates(map[string]interface{}{"chunks": blob.Chunks, "size": blob.Size, "complete": blob.Complete}).Error
+	})
+	ds.objects.RUnlock()
+	if err != nil {
+		if key != "" {
+			ds.releaseObjects(ctx, key)
+		}
+		log.Error(err.Error())
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// GetBlob returns the state of a blob of the user.
+func (ds *DataStore) GetBlob(ctx context.Context, id uuid.UUID) (*models.Blob, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	var blob models.Blob
+	if err := ds.db.Where("id = ?", id).Where("user_id = ?", userCtx.Id).Take(&blob).Error; err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// GetBlobChunk returns one chunk of a blob of the user. Chunks are read one
+// at a time, so a download never holds the whole blob in memory.
+func (ds *DataStore) GetBlobChunk(ctx context.Context, id uuid.UUID, index int64) ([]byte, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	var chunk models.BlobChunk
+	err := ds.db.Joins("JOIN blobs ON blobs.id = blob_chunks.blob_id").
+		Where("blob_chunks.blob_id = ?", id).Where("blob_chunks.number = ?", index).Where("blobs.user_id = ?", userCtx.Id).
+		Take(&chunk).Error
+	if err != nil {
+		return nil, err
+	}
+	return ds.blobs.Get(ctx, chunk.Key)
+}
+
+// DeleteBlob deletes a blob of the user with all its chunks. A blob that a
+// secret, in the trash or not, or a kept revision refers to returns
+// ErrBlobInUse.
+func (ds *DataStore) DeleteBlob(ctx context.Context, id uuid.UUID) (bool, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return false, ErrUserNotFound
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "DeleteBlob",
+		"user":   userCtx.Email,
+	})
+
+	var keys []string
+	var deleted bool
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		refs, err := blobRefs(tx, userCtx.Id, id)
+		if err != nil {
+			return err
+		}
+		if refs > 0 {
+			return ErrBlobInUse
+		}
+		keys, deleted, err = removeBlob(tx, userCtx.Id, id)
+		return err
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return false, err
+	}
+	ds.releaseObjects(ctx, keys...)
+	return deleted, nil
+}
+
+// CollectBlobs deletes the blobs created before that no secret, trashed ones
+// included, or kept revision of their owner refers to: uploads that were
+// never finished or never attached, and files of notes purged while the
+// collection on purge failed. It returns how many blobs were deleted.
+func (ds *DataStore) CollectBlobs(ctx context.Context, before time.Time) (int, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "CollectBlobs",
+	})
+
+	var orphans []models.Blob
+	err := ds.db.Where("created_at < ?", before).
+		Where("NOT EXISTS (?)", ds.db.Unscoped().Model(&models.SecretData{}).Select("1").
+			Where("secret_data.blob_id = blobs.id").Where("secret_data.user_id = blobs.user_id")).
+		Where("NOT EXISTS (?)", ds.db.Model(&models.SecretRevision{}).Select("1").
+			Where("secret_revisions.blob_id = blobs.id").Where("secret_revisions.user_id = blobs.user_id")).
+		Find(&orphans).Error
+	if err != nil {
+		log.Error(err.Error())
+		return 0, err
+	}
+	collected := 0
+	for _, blob := range orphans {
+		if ds.collectBlob(ctx, blob.UserID, blob.ID) {
+			collected++
+		}
+	}
+	if collected > 0 {
+		log.Infof("collected %d orphan blobs", collected)
+	}
+	return collected, nil
+}
+
+// collectBlob deletes blob id of user unless a secret, in the trash or not,
+// or a kept revision of the user still refers to it, and reports whether it
+// did. Failures are only logged: the blob stays for the next CollectBlobs.
+func (ds *DataStore) collectBlob(ctx context.Context, user uuid.UUID, id uuid.UUID) bool {
+	var keys []string
+	var deleted bool
+	err := ds.db.Transaction(func(tx *gorm.DB) error {
+		refs, err := blobRefs(tx, user, id)
+		if err != nil || refs > 0 {
+			return err
+		}
+		keys, deleted, err = removeBlob(tx, user, id)
+		return err
+	})
+	if err != nil {
+		log.WithField("method", "collectBlob").WithError(err).Errorf("blob %s not collected", id)
+		return false
+	}
+	ds.releaseObjects(ctx, keys...)
+	return deleted
+}
+
+// checkBlob returns ErrBlobUnusable unless blob id, if set, is a complete
+// blob of user. Notes can only refer to files that can be downloaded.
+func checkBlob(tx *gorm.DB, user uuid.UUID, id *uuid.UUID) error {
+	if id == nil {
+		return nil
+	}
+	var count int64
+	err := tx.Model(&models.Blob{}).Where("id = ?", *id).Where("user_id = ?", user).Where("complete = ?", true).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrBlobUnusable
+	}
+	return nil
+}
+
+// blobRefs counts the secrets, trashed ones included, and the kept revisions
+// of user that refer to blob id.
+func blobRefs(tx *gorm.DB, user uuid.UUID, id uuid.UUID) (int64, error) {
+	var secrets, revisions int64
+	err := tx.Unscoped().Model(&models.SecretData{}).Where("user_id = ?", user).Where("blob_id = ?", id).Count(&secrets).Error
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Model(&models.SecretRevision{}).Where("user_id = ?", user).Where("blob_id = ?", id).Count(&revisions).Error
+	return secrets + revisions, err
+}
+
+// removeBlob deletes the rows of a blob and returns the keys of its chunks.
+func removeBlob(tx *gorm.DB, user uuid.UUID, id uuid.UUID) ([]string, bool, error) {
+	res := tx.Where("id = ?", id).Where("user_id = ?", user).Delete(&models.Blob{})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return nil, false, res.Error
+	}
+	var keys []string
+	if err := tx.Model(&models.BlobChunk{}).Where("blob_id = ?", id).Pluck("key", &keys).Error; err != nil {
+		return nil, false, err
+	}
+	if err := tx.Where("blob_id = ?", id).Delete(&models.BlobChunk{}).Error; err != nil {
+		return nil, false, err
+	}
+	return keys, true, nil
+}
+
+// releaseObjects deletes the objects no chunk refers to any more. It runs
+// after the chunk rows are gone, so an object is never missing for a stored
+// chunk; an object left behind by a failure only takes space. The rows are
+// counted and the object deleted under objects, so an upload of the same
+// bytes waits until the object is gone and stores it again.
+func (ds *DataStore) releaseObjects(ctx context.Context, keys ...string) {
+	ds.objects.Lock()
+	defer ds.objects.Unlock()
+	for _, key := range keys {
+		var refs int64
+		if err := ds.db.Model(&models.BlobChunk{}).Where("key = ?", key).Count(&refs).Error; err != nil || refs > 0 {
+			continue
+		}
+		if err := ds.blobs.Delete(ctx, key); err != nil {
+			log.WithField("method", "releaseObjects").WithError(err).Errorf("object %s not deleted", key)
+		}
+	}
+}
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrVaultMismatch   = errors.New("vault content does not match stored secrets")
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session revoked or expired")
+	ErrChunkOutOfOrder = errors.New("blob chunk out of order")
+	ErrBlobComplete    = errors.New("blob is already complete")
+	ErrBlobInUse       = errors.New("blob is attached to a note")
+	ErrBlobUnusable    = errors.New("blob is missing, incomplete or of another user")
+)
+
+// Changes answers a sync request. Full means Secrets is the whole vault and
+// the client drops what it does not contain.
+type Changes struct {
+	Secrets []models.SecretData
+	Cursor  int64
+	Full    bool
+}
+
+// ConflictError reports a write based on an outdated revision. Current is the
+// stored copy.
+type ConflictError struct {
+	Current models.SecretData
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("secret data %s is at revision %d", e.Current.ID, e.Current.Revision)
+}
@@ -2,7 +2,10 @@ package database
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -12,13 +15,13 @@ import (
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 var (
-	testDs DataStorable
-	tnow   time.Time
+	testDs    DataStorable
+	testBlobs *FileBlobStore
+	tnow      time.Time
 
 	uidS1 = uuid.New()
 	uidS2 = uuid.New()
@@ -26,9 +29,9 @@ var (
 	uidU1 = uuid.New()
 	uidU2 = uuid.New()
 
-	secretData1 = models.SecretData{ID: uidS1, UserID: uidU1, Type: "CARD", Name: "Test Secret", Secret: []byte("Test Secret")}
-	secretData2 = models.SecretData{ID: uidS2, UserID: uidU1, Type: "CARD", Name: "Test Secret", Secret: []byte("Test Secret")}
-	secretData3 = models.SecretData{ID: uidS3, UserID: uidU2, Type: "CARD", Name: "Test Secret", Secret: []byte("Test Secret")}
+	secretData1 = models.SecretData{ID: uidS1, UserID: uidU1, Type: "CARD", Name: "Test Secret", Secret: []byte("Test Secret"), Revision: 1}
+	secretData2 = models.SecretData{ID: uidS2, UserID: uidU1, Type: "CARD", Name: "Test Secret", Secret: []byte("Test Secret"), Revision: 1}
+	secretData3 = models.SecretData{ID: uidS3, UserID: uidU2, Type: "CARD", Name: "Test Secret", Secret: []byte("Test Secret"), Revision: 1}
 
 	list1     = []models.SecretData{secretData1, secretData2}
 	list2     = []models.SecretData{secretData3}
@@ -36,16 +39,51 @@ var (
 	testUser2 = models.User{ID: uidU2, Username: "Test User", Password: []byte("Test Password"), Email: "user2@test.com", CreatedAt: &tnow, UpdatedAt: &tnow, SecretData: &list2}
 )
 
+// The suite runs against SQLite, and once more against PostgreSQL when
+// GOPHKEEPER_TEST_PG_DSN names an empty database. scripts/test-postgres.sh
+// starts a local container for it, the CI workflow a service container.
+// Without the variable the PostgreSQL run is skipped with a notice, and fails
+// when CI or GOPHKEEPER_TEST_PG_REQUIRED is set.
 func TestMain(m *testing.M) {
-	testDb := "test.db"
-	db, err := gorm.Open(sqlite.Open(testDb), &gorm.Config{})
-	if err != nil {
-		log.Fatal("failed to connect database", err)
-	}
 	l := logrus.New()
 	log = logger.NewLogger(l)
 	tnow = time.Now()
-	testDs = *NewDataStore(log, db)
+
+	backends := [][2]string{{DriverSQLite, "test.db"}}
+	if dsn := os.Getenv("GOPHKEEPER_TEST_PG_DSN"); dsn != "" {
+		backends = append(backends, [2]string{DriverPostgres, dsn})
+	} else if os.Getenv("CI") != "" || os.Getenv("GOPHKEEPER_TEST_PG_REQUIRED") != "" {
+		fmt.Fprintln(os.Stderr, "FAIL: GOPHKEEPER_TEST_PG_DSN is not set, the storage tests cannot run on PostgreSQL")
+		os.Exit(1)
+	} else {
+		fmt.Fprintln(os.Stderr, "SKIP: storage tests on PostgreSQL, set GOPHKEEPER_TEST_PG_DSN or run scripts/test-postgres.sh")
+	}
+	code := 0
+	for _, backend := range backends {
+		if code = runSuite(m, backend[0], backend[1]); code != 0 {
+			break
+		}
+	}
+	os.Exit(code)
+}
+
+// runSuite runs the tests against a fresh schema of driver and drops it
+// afterwards.
+func runSuite(m *testing.M, driver, dsn string) int {
+	db, err := OpenDB(driver, dsn)
+	if err != nil {
+		log.Fatal("failed to connect database", err)
+	}
+	blobDir, err := os.MkdirTemp("", "blobs")
+	if err != nil {
+		log.Fatal("failed to create blob directory", err)
+	}
+	defer os.RemoveAll(blobDir)
+	testBlobs, err = NewFileBlobStore(blobDir)
+	if err != nil {
+		log.Fatal("failed to open blob store", err)
+	}
+	testDs = &DataStore{db: db, broker: NewBroker(), blobs: testBlobs}
 	err = testDs.Migrate()
 	if err != nil {
 		log.Fatal("failed to migrate database", err)
@@ -54,16 +92,21 @@ func TestMain(m *testing.M) {
 	_, err = testDs.AddUser(addContext(context.Background(), uuid.New()), &testUser1)
 	_, err = testDs.AddUser(addContext(context.Background(), uuid.New()), &testUser2)
 	if err != nil {
-		return
+		return 1
 	}
 
+	log.Infof("running the storage tests on %s", driver)
 	code := m.Run()
 
-	err = os.Remove(testDb)
+	if driver == DriverSQLite {
+		err = os.Remove(dsn)
+	} else {
+		err = db.Migrator().DropTable(&models.User{}, &models.SecretData{}, &models.Session{}, &models.ChangeCounter{}, &models.Blob{}, &models.BlobChunk{}, &models.SecretRevision{}, &schemaVersion{})
+	}
 	if err != nil {
 		log.Fatal("failed to remove test database", err)
 	}
-	os.Exit(code)
+	return code
 }
 
 func TestDataStore_AddSecretData(t *testing.T) {
@@ -92,11 +135,13 @@ func TestDataStore_AddSecretData(t *testing.T) {
 				},
 			},
 			want: &models.SecretData{
-				ID:     id1,
-				UserID: uuid.Nil,
-				Type:   "CARD",
-				Name:   "Test Secret",
-				Secret: []byte("Test Secret"),
+				ID:        id1,
+				UserID:    uuid.Nil,
+				Type:      "CARD",
+				Name:      "Test Secret",
+				Secret:    []byte("Test Secret"),
+				Revision:  1,
+				ChangeSeq: 1,
 			},
 			wantErr: false,
 		},
@@ -123,6 +168,9 @@ func TestDataStore_AddSecretData(t *testing.T) {
 				t.Errorf("AddSecretData() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if got != nil {
+				got.UpdatedAt = nil
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("AddSecretData() got = %v, want %v", got, tt.want)
 			}
@@ -261,6 +309,11 @@ func TestDataStore_GetSecretData(t *testing.T) {
 				t.Errorf("GetSecretData() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if got != nil {
+				for i := range *got {
+					(*got)[i].UpdatedAt = nil
+				}
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("GetSecretData() got = %v, want %v", got, tt.want)
 			}
@@ -334,16 +387,19 @@ func TestDataStore_UpdateSecretData(t *testing.T) {
 			args: args{
 				ctx: addContext(context.Background(), uidU2),
 				data: models.SecretData{
-					ID:   uidS3,
-					Name: "TEST33",
+					ID:       uidS3,
+					Name:     "TEST33",
+					Revision: 1,
 				},
 			},
 			want: &models.SecretData{
-				ID:     uidS3,
-				UserID: uidU2,
-				Type:   "CARD",
-				Name:   "TEST33",
-				Secret: []byte("Test Secret"),
+				ID:        uidS3,
+				UserID:    uidU2,
+				Type:      "CARD",
+				Name:      "TEST33",
+				Secret:    []byte("Test Secret"),
+				Revision:  2,
+				ChangeSeq: 1,
 			},
 			wantErr: false,
 		},
@@ -352,16 +408,19 @@ func TestDataStore_UpdateSecretData(t *testing.T) {
 			args: args{
 				ctx: addContext(context.Background(), uidU2),
 				data: models.SecretData{
-					ID:     uidS3,
-					Secret: []byte("TEST, TEST, TEST"),
+					ID:       uidS3,
+					Secret:   []byte("TEST, TEST, TEST"),
+					Revision: 2,
 				},
 			},
 			want: &models.SecretData{
-				ID:     uidS3,
-				UserID: uidU2,
-				Type:   "CARD",
-				Name:   "TEST33",
-				Secret: []byte("TEST, TEST, TEST"),
+				ID:        uidS3,
+				UserID:    uidU2,
+				Type:      "CARD",
+				Name:      "TEST33",
+				Secret:    []byte("TEST, TEST, TEST"),
+				Revision:  3,
+				ChangeSeq: 2,
 			},
 			wantErr: false,
 		},
@@ -370,19 +429,56 @@ func TestDataStore_UpdateSecretData(t *testing.T) {
 			args: args{
 				ctx: addContext(context.Background(), uidU2),
 				data: models.SecretData{
-					ID:     uidS3,
-					UserID: uidU1,
-					Type:   "TEST3",
-					Name:   "TEST3",
-					Secret: []byte("TEST3, TEST3"),
+					ID:       uidS3,
+					UserID:   uidU1,
+					Type:     "TEST3",
+					Name:     "TEST3",
+					Secret:   []byte("TEST3, TEST3"),
+					Revision: 3,
 				},
 			},
 			want: &models.SecretData{
-				ID:     uidS3,
-				UserID: uidU2,
-				Type:   "CARD",
-				Name:   "TEST3",
-				Secret: []byte("TEST3, TEST3"),
+				ID:        uidS3,
+				UserID:    uidU2,
+				Type:      "CARD",
+				Name:      "TEST3",
+				Secret:    []byte("TEST3, TEST3"),
+				Revision:  4,
+				ChangeSeq: 3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "update outdated revision",
+			args: args{
+				ctx: addContext(context.Background(), uidU2),
+				data: models.SecretData{
+					ID:       uidS3,
+					Name:     "LOST",
+					Revision: 3,
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "seal secret data",
+			args: args{
+				ctx: addContext(context.Background(), uidU2),
+				data: models.SecretData{
+					ID:       uidS3,
+					Secret:   []byte("SEALED"),
+					Sealed:   true,
+					Revision: 4,
+				},
+			},
+			want: &models.SecretData{
+				ID:        uidS3,
+				UserID:    uidU2,
+				Secret:    []byte("SEALED"),
+				Sealed:    true,
+				Revision:  5,
+				ChangeSeq: 4,
 			},
 			wantErr: false,
 		},
@@ -409,11 +505,21 @@ func TestDataStore_UpdateSecretData(t *testing.T) {
 				t.Errorf("UpdateSecretData() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if got != nil {
+				got.UpdatedAt = nil
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("UpdateSecretData() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
+
+	_, err := testDs.UpdateSecretData(addContext(context.Background(), uidU2), models.SecretData{ID: uidS3, Name: "LOST", Revision: 1})
+	var conflict *ConflictError
+	if assert.ErrorAs(t, err, &conflict) {
+		assert.Equal(t, int64(5), conflict.Current.Revision, "UpdateSecretData() current revision")
+		assert.Equal(t, []byte("SEALED"), conflict.Current.Secret, "UpdateSecretData() current secret")
+	}
 }
 
 func TestDataStore_UpdateUser(t *testing.T) {
@@ -483,6 +589,24 @@ func TestDataStore_UpdateUser(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "set verifier",
+			args: args{
+				ctx: addContext(context.Background(), uuid.New()),
+				user: models.User{
+					ID:       uidU2,
+					Verifier: []byte("Test Verifier"),
+				},
+			},
+			want: &models.User{
+				ID:       uidU2,
+				Username: "Test User2",
+				Password: []byte("Test Password2"),
+				Email:    "user2@test.com",
+				Verifier: []byte("Test Verifier"),
+			},
+			wantErr: false,
+		},
 		{
 			name: "update user is not exist",
 			args: args{
@@ -523,6 +647,7 @@ func TestDataStore_DeleteSecretData(t *testing.T) {
 	type args struct {
 		ctx          context.Context
 		idSecretData uuid.UUID
+		revision     int64
 	}
 	tests := []struct {
 		name    string
@@ -539,11 +664,22 @@ func TestDataStore_DeleteSecretData(t *testing.T) {
 			want:    false,
 			wantErr: true,
 		},
+		{
+			name: "delete outdated revision",
+			args: args{
+				ctx:          addContext(context.Background(), uidU1),
+				idSecretData: uidS2,
+				revision:     2,
+			},
+			want:    false,
+			wantErr: true,
+		},
 		{
 			name: "delete secret data is exist",
 			args: args{
 				ctx:          addContext(context.Background(), uidU1),
 				idSecretData: uidS2,
+				revision:     1,
 			},
 			want:    true,
 			wantErr: false,
@@ -551,7 +687,7 @@ func TestDataStore_DeleteSecretData(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := testDs.DeleteSecretData(tt.args.ctx, tt.args.idSecretData)
+			got, err := testDs.DeleteSecretData(tt.args.ctx, tt.args.idSecretData, tt.args.revision)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DeleteSecretData() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -607,6 +743,745 @@ func TestDataStore_DeleteUser(t *testing.T) {
 	}
 }
 
+func TestDataStore_RekeyVault(t *testing.T) {
+	uidU3 := uuid.New()
+	uidS4 := uuid.New()
+	uidS5 := uuid.New()
+	ctx := addContext(context.Background(), uidU3)
+	_, err := testDs.AddUser(ctx, &models.User{ID: uidU3, Username: "Test User", Password: []byte("Test Password"), Email: "user3@test.com"})
+	assert.NoError(t, err)
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: uidS4, Type: "CARD", Name: "Test Secret", Secret: []byte("old 4")})
+	assert.NoError(t, err)
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: uidS5, Type: "CARD", Name: "Test Secret", Secret: []byte("old 5")})
+	assert.NoError(t, err)
+
+	kdf := models.KdfParams{Algorithm: "argon2id", Salt: []byte("salt"), Time: 3, Memory: 65536, Threads: 4}
+
+	type args struct {
+		ctx  context.Context
+		user models.User
+		data []models.SecretData
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantErr      error
+		wantSecret   []byte
+		wantRevision int64
+	}{
+		{
+			name: "missing secret",
+			args: args{
+				ctx:  ctx,
+				user: models.User{Kdf: kdf},
+				data: []models.SecretData{{ID: uidS4, Secret: []byte("new 4"), Revision: 1}},
+			},
+			wantErr:      ErrVaultMismatch,
+			wantSecret:   []byte("old 4"),
+			wantRevision: 1,
+		},
+		{
+			name: "duplicated secret",
+			args: args{
+				ctx:  ctx,
+				user: models.User{Kdf: kdf},
+				data: []models.SecretData{{ID: uidS4, Secret: []byte("new 4"), Revision: 1}, {ID: uidS4, Secret: []byte("new 4"), Revision: 1}},
+			},
+			wantErr:      ErrVaultMismatch,
+			wantSecret:   []byte("old 4"),
+			wantRevision: 1,
+		},
+		{
+			name: "foreign secret",
+			args: args{
+				ctx:  ctx,
+				user: models.User{Kdf: kdf},
+				data: []models.SecretData{{ID: uidS4, Secret: []byte("new 4"), Revision: 1}, {ID: uidS1, Secret: []byte("new 1"), Revision: 1}},
+			},
+			wantErr:      ErrVaultMismatch,
+			wantSecret:   []byte("old 4"),
+			wantRevision: 1,
+		},
+		{
+			name: "outdated revision",
+			args: args{
+				ctx:  ctx,
+				user: models.User{Kdf: kdf},
+				data: []models.SecretData{{ID: uidS4, Secret: []byte("new 4"), Revision: 2}, {ID: uidS5, Secret: []byte("new 5"), Revision: 1}},
+			},
+			wantErr:      ErrVaultMismatch,
+			wantSecret:   []byte("old 4"),
+			wantRevision: 1,
+		},
+		{
+			name: "without user context",
+			args: args{
+				ctx: context.Background(),
+			},
+			wantErr:      ErrUserNotFound,
+			wantSecret:   []byte("old 4"),
+			wantRevision: 1,
+		},
+		{
+			name: "rewrap before vault uses data key",
+			args: args{
+				ctx:  ctx,
+				user: models.User{Kdf: kdf, WrappedKey: []byte("wrapped 1")},
+			},
+			wantErr:      ErrVaultMismatch,
+			wantSecret:   []byte("old 4"),
+			wantRevision: 1,
+		},
+		{
+			name: "success",
+			args: args{
+				ctx:  ctx,
+				user: models.User{Kdf: kdf, WrappedKey: []byte("wrapped 1")},
+				data: []models.SecretData{{ID: uidS4, Secret: []byte("new 4"), Revision: 1}, {ID: uidS5, Secret: []byte("new 5"), Revision: 1}},
+			},
+			wantSecret:   []byte("new 4"),
+			wantRevision: 2,
+		},
+		{
+			name: "rewrap only",
+			args: args{
+				ctx:  ctx,
+				user: models.User{Kdf: kdf, WrappedKey: []byte("wrapped 2")},
+			},
+			wantSecret:   []byte("new 4"),
+			wantRevision: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testDs.RekeyVault(tt.args.ctx, tt.args.user, tt.args.data, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RekeyVault() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			list, err := testDs.GetSecretData(ctx)
+			assert.NoError(t, err)
+			for _, item := range *list {
+				if item.ID == uidS4 {
+					assert.Equal(t, tt.wantSecret, item.Secret, "RekeyVault() secret")
+					assert.Equal(t, tt.wantRevision, item.Revision, "RekeyVault() revision")
+				}
+			}
+		})
+	}
+
+	user, err := testDs.GetUser(ctx, "user3@test.com")
+	assert.NoError(t, err)
+	assert.Equal(t, kdf, user.Kdf, "RekeyVault() kdf")
+	assert.Equal(t, []byte("wrapped 2"), user.WrappedKey, "RekeyVault() wrapped key")
+	assert.Equal(t, []byte("Test Password"), user.Password, "RekeyVault() password must be kept")
+}
+
+func TestDataStore_RekeyVaultHistoryAndTrash(t *testing.T) {
+	uid, live, trashed := uuid.New(), uuid.New(), uuid.New()
+	ctx := addContext(context.Background(), uid)
+	_, err := testDs.AddUser(ctx, &models.User{ID: uid, Username: "Test User", Password: []byte("Test Password"), Email: "history@test.com"})
+	assert.NoError(t, err)
+	for _, id := range []uuid.UUID{live, trashed} {
+		_, err = testDs.AddSecretData(ctx, models.SecretData{ID: id, Type: "TEXT", Name: "Test Secret", Secret: []byte("old 1")})
+		assert.NoError(t, err)
+	}
+	_, err = testDs.UpdateSecretData(ctx, models.SecretData{ID: live, Type: "TEXT", Name: "Test Secret", Secret: []byte("old 2"), Revision: 1})
+	assert.NoError(t, err)
+	_, err = testDs.DeleteSecretData(ctx, trashed, 1)
+	assert.NoError(t, err)
+	trash, err := testDs.ListTrash(ctx)
+	if !assert.NoError(t, err) || !assert.Len(t, *trash, 1) {
+		return
+	}
+	inTrash := (*trash)[0].Revision
+
+	kdf := models.KdfParams{Algorithm: "argon2id", Salt: []byte("salt"), Time: 3, Memory: 65536, Threads: 4}
+	user := models.User{Kdf: kdf, WrappedKey: []byte("wrapped")}
+	data := []models.SecretData{{ID: live, Secret: []byte("new 2"), Revision: 2}, {ID: trashed, Secret: []byte("new trashed"), Revision: inTrash}}
+	history := []models.SecretRevision{{SecretID: live, Revision: 1, Secret: []byte("new 1")}}
+
+	assert.ErrorIs(t, testDs.RekeyVault(ctx, user, data[:1], history), ErrVaultMismatch, "RekeyVault() without the trash")
+	assert.ErrorIs(t, testDs.RekeyVault(ctx, user, data, nil), ErrVaultMismatch, "RekeyVault() without the history")
+	assert.ErrorIs(t, testDs.RekeyVault(ctx, user, data, []models.SecretRevision{{SecretID: live, Revision: 2, Secret: []byte("new 1")}}),
+		ErrVaultMismatch, "RekeyVault() with an unknown revision")
+	if !assert.NoError(t, testDs.RekeyVault(ctx, user, data, history)) {
+		return
+	}
+
+	list, err := testDs.GetSecretData(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, *list, 1) {
+		assert.Equal(t, []byte("new 2"), (*list)[0].Secret)
+		assert.Equal(t, int64(3), (*list)[0].Revision)
+	}
+	trash, err = testDs.ListTrash(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, *trash, 1) {
+		assert.Equal(t, []byte("new trashed"), (*trash)[0].Secret)
+		assert.Equal(t, inTrash, (*trash)[0].Revision)
+	}
+	revisions, err := testDs.ListSecretRevisions(ctx, live)
+	assert.NoError(t, err)
+	if assert.Len(t, *revisions, 1) {
+		assert.Equal(t, []byte("new 1"), (*revisions)[0].Secret)
+	}
+}
+
+func TestDataStore_RekeyVaultRevokesSessions(t *testing.T) {
+	uid := uuid.New()
+	current, other := uuid.New(), uuid.New()
+	ctx := context.WithValue(context.Background(), "UserCtx", &models.UserCtx{Email: "rekey@test.com", Id: uid, SessionID: current})
+	_, err := testDs.AddUser(ctx, &models.User{ID: uid, Username: "Test User", Password: []byte("Test Password"), Email: "rekey@test.com"})
+	assert.NoError(t, err)
+	expires := time.Now().Add(time.Hour)
+	assert.NoError(t, testDs.AddSession(ctx, models.Session{ID: current, UserID: uid, RefreshHash: []byte("hash 1"), ExpiresAt: expires}))
+	assert.NoError(t, testDs.AddSession(ctx, models.Session{ID: other, UserID: uid, RefreshHash: []byte("hash 2"), ExpiresAt: expires}))
+	kdf := models.KdfParams{Algorithm: "argon2id", Salt: []byte("salt"), Time: 3, Memory: 65536, Threads: 4}
+
+	// moving the vault to a data key keeps the password and the sessions
+	assert.NoError(t, testDs.RekeyVault(ctx, models.User{Kdf: kdf, WrappedKey: []byte("wrapped 1")}, []models.SecretData{}, nil))
+	active, err := testDs.IsSessionActive(ctx, other)
+	assert.NoError(t, err)
+	assert.True(t, active, "RekeyVault() revoked a session without a password change")
+
+	assert.NoError(t, testDs.RekeyVault(ctx, models.User{Password: []byte("New Password"), Kdf: kdf, WrappedKey: []byte("wrapped 2")}, nil, nil))
+	active, err = testDs.IsSessionActive(ctx, other)
+	assert.NoError(t, err)
+	assert.False(t, active, "RekeyVault() kept another session after a password change")
+	active, err = testDs.IsSessionActive(ctx, current)
+	assert.NoError(t, err)
+	assert.True(t, active, "RekeyVault() revoked the session that changed the password")
+}
+
+func TestDataStore_Sessions(t *testing.T) {
+	uidU4 := uuid.New()
+	ctx := addContext(context.Background(), uidU4)
+	_, err := testDs.AddUser(ctx, &models.User{ID: uidU4, Username: "Test User", Password: []byte("Test Password"), Email: "user4@test.com"})
+	assert.NoError(t, err)
+
+	sid1, sid2, sid3 := uuid.New(), uuid.New(), uuid.New()
+	expires := time.Now().Add(time.Hour)
+	assert.NoError(t, testDs.AddSession(ctx, models.Session{ID: sid1, UserID: uidU4, RefreshHash: []byte("hash 1"), Device: "laptop", ExpiresAt: expires}))
+	assert.NoError(t, testDs.AddSession(ctx, models.Session{ID: sid2, UserID: uidU4, RefreshHash: []byte("hash 2"), Device: "phone", ExpiresAt: expires}))
+	assert.NoError(t, testDs.AddSession(ctx, models.Session{ID: sid3, UserID: uidU4, RefreshHash: []byte("hash 3"), ExpiresAt: time.Now().Add(-time.Minute)}))
+
+	t.Run("refresh rotates hash", func(t *testing.T) {
+		user, err := testDs.RefreshSession(context.Background(), sid1, []byte("hash 1"), []byte("hash 1b"), expires)
+		assert.NoError(t, err)
+		assert.Equal(t, "user4@test.com", user.Email)
+
+		_, err = testDs.RefreshSession(context.Background(), sid1, []byte("hash 1b"), []byte("hash 1c"), expires)
+		assert.NoError(t, err)
+	})
+	t.Run("reused refresh token revokes session", func(t *testing.T) {
+		_, err := testDs.RefreshSession(context.Background(), sid1, []byte("hash 1"), []byte("hash 1d"), expires)
+		assert.ErrorIs(t, err, ErrSessionRevoked)
+
+		_, err = testDs.RefreshSession(context.Background(), sid1, []byte("hash 1c"), []byte("hash 1d"), expires)
+		assert.ErrorIs(t, err, ErrSessionRevoked)
+		active, err := testDs.IsSessionActive(ctx, sid1)
+		assert.NoError(t, err)
+		assert.False(t, active)
+	})
+	t.Run("expired session", func(t *testing.T) {
+		_, err := testDs.RefreshSession(context.Background(), sid3, []byte("hash 3"), []byte("hash 3b"), expires)
+		assert.ErrorIs(t, err, ErrSessionRevoked)
+	})
+	t.Run("unknown session", func(t *testing.T) {
+		_, err := testDs.RefreshSession(context.Background(), uuid.New(), []byte("hash"), []byte("hash b"), expires)
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+	})
+	t.Run("list active sessions", func(t *testing.T) {
+		sessions, err := testDs.ListSessions(ctx)
+		assert.NoError(t, err)
+		if assert.Len(t, *sessions, 1) {
+			assert.Equal(t, sid2, (*sessions)[0].ID)
+			assert.Equal(t, "phone", (*sessions)[0].Device)
+		}
+	})
+	t.Run("revoke session", func(t *testing.T) {
+		ok, err := testDs.RevokeSession(addContext(context.Background(), uidU1), sid2)
+		assert.NoError(t, err)
+		assert.False(t, ok, "another user must not revoke the session")
+
+		ok, err = testDs.RevokeSession(ctx, sid2)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		active, err := testDs.IsSessionActive(ctx, sid2)
+		assert.NoError(t, err)
+		assert.False(t, active)
+
+		ok, err = testDs.RevokeSession(ctx, sid2)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestDataStore_Totp(t *testing.T) {
+	uidU5 := uuid.New()
+	ctx := addContext(context.Background(), uidU5)
+	_, err := testDs.AddUser(ctx, &models.User{ID: uidU5, Username: "Test User", Password: []byte("Test Password"), Email: "user5@test.com"})
+	assert.NoError(t, err)
+
+	t.Run("enable", func(t *testing.T) {
+		err := testDs.SetTotp(ctx, models.User{ID: uidU5, TotpSecret: "SECRET", TotpEnabled: true, TotpLastStep: 10, RecoveryCodes: [][]byte{[]byte("code 1"), []byte("code 2")}})
+		assert.NoError(t, err)
+		user, err := testDs.GetUser(ctx, "user5@test.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "SECRET", user.TotpSecret)
+		assert.True(t, user.TotpEnabled)
+		assert.Equal(t, [][]byte{[]byte("code 1"), []byte("code 2")}, user.RecoveryCodes)
+	})
+	t.Run("steps only move forward", func(t *testing.T) {
+		for _, tt := range []struct {
+			step int64
+			want bool
+		}{{step: 10, want: false}, {step: 11, want: true}, {step: 11, want: false}, {step: 9, want: false}} {
+			got, err := testDs.UseTotpStep(ctx, uidU5, tt.step)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got, "step %d", tt.step)
+		}
+	})
+	t.Run("recovery code is used once", func(t *testing.T) {
+		used, err := testDs.UseRecoveryCode(ctx, uidU5, []byte("code 2"))
+		assert.NoError(t, err)
+		assert.True(t, used)
+		used, err = testDs.UseRecoveryCode(ctx, uidU5, []byte("code 2"))
+		assert.NoError(t, err)
+		assert.False(t, used)
+		user, err := testDs.GetUser(ctx, "user5@test.com")
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte("code 1")}, user.RecoveryCodes)
+	})
+	t.Run("disable", func(t *testing.T) {
+		assert.NoError(t, testDs.SetTotp(ctx, models.User{ID: uidU5}))
+		user, err := testDs.GetUser(ctx, "user5@test.com")
+		assert.NoError(t, err)
+		assert.False(t, user.TotpEnabled)
+		assert.Empty(t, user.TotpSecret)
+		assert.Empty(t, user.RecoveryCodes)
+	})
+	t.Run("unknown user", func(t *testing.T) {
+		assert.ErrorIs(t, testDs.SetTotp(ctx, models.User{ID: uuid.New()}), ErrUserNotFound)
+	})
+}
+
+func TestDataStore_SyncSecretData(t *testing.T) {
+	ctx := addContext(context.Background(), uuid.New())
+	idA, idB := uuid.New(), uuid.New()
+
+	changes, err := testDs.SyncSecretData(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &Changes{Secrets: []models.SecretData{}, Full: true}, changes)
+
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: idA, Type: "TEXT", Name: "A", Secret: []byte("a")})
+	assert.NoError(t, err)
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: idB, Type: "TEXT", Name: "B", Secret: []byte("b")})
+	assert.NoError(t, err)
+	changes, err = testDs.SyncSecretData(ctx, 0)
+	assert.NoError(t, err)
+	assert.True(t, changes.Full)
+	assert.Len(t, changes.Secrets, 2)
+	assert.Equal(t, int64(2), changes.Cursor)
+
+	_, err = testDs.UpdateSecretData(ctx, models.SecretData{ID: idA, Secret: []byte("a2"), Revision: 1})
+	assert.NoError(t, err)
+	_, err = testDs.DeleteSecretData(ctx, idB, 1)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		cursor     int64
+		wantFull   bool
+		wantIDs    []uuid.UUID
+		wantTombs  []uuid.UUID
+		wantCursor int64
+	}{
+		{name: "changes after cursor", cursor: 2, wantIDs: []uuid.UUID{idA}, wantTombs: []uuid.UUID{idB}, wantCursor: 4},
+		{name: "nothing changed", cursor: 4, wantCursor: 4},
+		{name: "unknown cursor", cursor: 99, wantFull: true, wantIDs: []uuid.UUID{idA}, wantCursor: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := testDs.SyncSecretData(ctx, tt.cursor)
+			assert.NoError(t, err)
+			var ids, tombs []uuid.UUID
+			for _, secret := range changes.Secrets {
+				if secret.DeletedAt.Valid {
+					tombs = append(tombs, secret.ID)
+					continue
+				}
+				ids = append(ids, secret.ID)
+			}
+			assert.Equal(t, tt.wantFull, changes.Full, "SyncSecretData() full")
+			assert.Equal(t, tt.wantIDs, ids, "SyncSecretData() changed")
+			assert.Equal(t, tt.wantTombs, tombs, "SyncSecretData() deleted")
+			assert.Equal(t, tt.wantCursor, changes.Cursor, "SyncSecretData() cursor")
+		})
+	}
+
+	_, err = testDs.SyncSecretData(context.Background(), 0)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestDataStore_WatchSecretData(t *testing.T) {
+	ctx := addContext(context.Background(), uuid.New())
+	watchCtx, cancel := context.WithCancel(ctx)
+	changes, err := testDs.WatchSecretData(watchCtx)
+	assert.NoError(t, err)
+	other, err := testDs.WatchSecretData(addContext(watchCtx, uuid.New()))
+	assert.NoError(t, err)
+
+	id := uuid.New()
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: id, Type: "TEXT", Name: "A", Secret: []byte("a")})
+	assert.NoError(t, err)
+	_, err = testDs.UpdateSecretData(ctx, models.SecretData{ID: id, Secret: []byte("a2"), Revision: 1})
+	assert.NoError(t, err)
+	_, err = testDs.UpdateSecretData(ctx, models.SecretData{ID: id, Secret: []byte("lost"), Revision: 1})
+	assert.Error(t, err)
+	_, err = testDs.DeleteSecretData(ctx, id, 2)
+	assert.NoError(t, err)
+
+	for _, want := range []struct {
+		kind     ChangeKind
+		revision int64
+		seq      int64
+	}{{SecretCreated, 1, 1}, {SecretUpdated, 2, 2}, {SecretDeleted, 3, 3}} {
+		change := <-changes
+		assert.Equal(t, want.kind, change.Kind, "WatchSecretData() kind")
+		assert.Equal(t, id, change.Secret.ID, "WatchSecretData() id")
+		assert.Equal(t, want.revision, change.Secret.Revision, "WatchSecretData() revision")
+		assert.Equal(t, want.seq, change.Secret.ChangeSeq, "WatchSecretData() seq")
+	}
+	assert.Empty(t, other, "WatchSecretData() of another user")
+
+	cancel()
+	_, open := <-changes
+	assert.False(t, open, "WatchSecretData() channel open after cancel")
+
+	_, err = testDs.WatchSecretData(context.Background())
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestDataStore_Blobs(t *testing.T) {
+	ctx := addContext(context.Background(), uuid.New())
+	id := uuid.New()
+
+	_, err := testDs.AddBlobChunk(ctx, id, 1, []byte("late"), false)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "AddBlobChunk() of a new blob past index 0")
+
+	blob, err := testDs.AddBlobChunk(ctx, id, 0, []byte("abc"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), blob.Chunks)
+	// a resent chunk is acknowledged without being stored twice
+	blob, err = testDs.AddBlobChunk(ctx, id, 0, []byte("abc"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), blob.Chunks)
+	_, err = testDs.AddBlobChunk(ctx, id, 2, []byte("gap"), false)
+	assert.ErrorIs(t, err, ErrChunkOutOfOrder)
+	blob, err = testDs.AddBlobChunk(ctx, id, 1, []byte("de"), true)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Blob{ID: id, Chunks: 2, Size: 5, Complete: true},
+		models.Blob{ID: blob.ID, Chunks: blob.Chunks, Size: blob.Size, Complete: blob.Complete})
+	_, err = testDs.AddBlobChunk(ctx, id, 2, []byte("more"), true)
+	assert.ErrorIs(t, err, ErrBlobComplete)
+
+	blob, err = testDs.GetBlob(ctx, id)
+	assert.NoError(t, err)
+	assert.True(t, blob.Complete)
+	data, err := testDs.GetBlobChunk(ctx, id, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("de"), data)
+
+	other := addContext(context.Background(), uuid.New())
+	_, err = testDs.GetBlob(other, id)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "GetBlob() of another user")
+	_, err = testDs.GetBlobChunk(other, id, 0)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "GetBlobChunk() of another user")
+	deleted, err := testDs.DeleteBlob(other, id)
+	assert.NoError(t, err)
+	assert.False(t, deleted, "DeleteBlob() of another user")
+
+	_, err = testBlobs.Get(ctx, objectKey([]byte("de")))
+	assert.NoError(t, err, "chunk in the blob store")
+
+	deleted, err = testDs.DeleteBlob(ctx, id)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	_, err = testDs.GetBlobChunk(ctx, id, 0)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "GetBlobChunk() after DeleteBlob()")
+	_, err = testBlobs.Get(ctx, objectKey([]byte("de")))
+	assert.ErrorIs(t, err, ErrObjectNotFound, "chunk left in the blob store")
+
+	_, err = testDs.AddBlobChunk(context.Background(), id, 0, nil, true)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestDataStore_DeleteBlobInUse(t *testing.T) {
+	ctx := addContext(context.Background(), uuid.New())
+	file := uuid.New()
+	_, err := testDs.AddBlobChunk(ctx, file, 0, []byte("in use"), true)
+	assert.NoError(t, err)
+	note := uuid.New()
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: note, Type: "BINARY", Name: "A", Secret: []byte("a"), BlobID: &file})
+	assert.NoError(t, err)
+
+	_, err = testDs.DeleteBlob(ctx, file)
+	assert.ErrorIs(t, err, ErrBlobInUse, "DeleteBlob() of an attached blob")
+	_, err = testDs.DeleteSecretData(ctx, note, 1)
+	assert.NoError(t, err)
+	_, err = testDs.DeleteBlob(ctx, file)
+	assert.ErrorIs(t, err, ErrBlobInUse, "DeleteBlob() of a blob in the trash")
+	_, err = testBlobs.Get(ctx, objectKey([]byte("in use")))
+	assert.NoError(t, err, "attached chunk removed from the blob store")
+}
+
+func TestDataStore_SecretBlobCheck(t *testing.T) {
+	ctx := addContext(context.Background(), uuid.New())
+	other := addContext(context.Background(), uuid.New())
+	complete, partial, foreign, missing := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	_, err := testDs.AddBlobChunk(ctx, complete, 0, []byte("complete"), true)
+	assert.NoError(t, err)
+	_, err = testDs.AddBlobChunk(ctx, partial, 0, []byte("partial"), false)
+	assert.NoError(t, err)
+	_, err = testDs.AddBlobChunk(other, foreign, 0, []byte("foreign"), true)
+	assert.NoError(t, err)
+
+	for name, blob := range map[string]uuid.UUID{"incomplete": partial, "of another user": foreign, "missing": missing} {
+		blob := blob
+		_, err = testDs.AddSecretData(ctx, models.SecretData{ID: uuid.New(), Type: "BINARY", Name: "A", Secret: []byte("a"), BlobID: &blob})
+		assert.ErrorIs(t, err, ErrBlobUnusable, "AddSecretData() with a blob %s", name)
+	}
+
+	note := uuid.New()
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: note, Type: "BINARY", Name: "A", Secret: []byte("a"), BlobID: &complete})
+	assert.NoError(t, err)
+	_, err = testDs.UpdateSecretData(ctx, models.SecretData{ID: note, Secret: []byte("a2"), Revision: 1, BlobID: &foreign})
+	assert.ErrorIs(t, err, ErrBlobUnusable, "UpdateSecretData() with a blob of another user")
+	stored, err := testDs.UpdateSecretData(ctx, models.SecretData{ID: note, Secret: []byte("a2"), Revision: 1, BlobID: &complete})
+	assert.NoError(t, err)
+	assert.Equal(t, &complete, stored.BlobID)
+}
+
+func TestDataStore_CollectBlobs(t *testing.T) {
+	// without history a replaced blob is released at once
+	defer KeepRevisions(historySize)
+	KeepRevisions(0)
+	ctx := addContext(context.Background(), uuid.New())
+	upload := func(data string) uuid.UUID {
+		id := uuid.New()
+		_, err := testDs.AddBlobChunk(ctx, id, 0, []byte(data), true)
+		assert.NoError(t, err)
+		return id
+	}
+	exists := func(id uuid.UUID) bool {
+		_, err := testDs.GetBlob(ctx, id)
+		return err == nil
+	}
+	first, second, shared, orphan := upload("first"), upload("second"), upload("shared"), upload("orphan")
+
+	note := uuid.New()
+	_, err := testDs.AddSecretData(ctx, models.SecretData{ID: note, Type: "BINARY", Name: "A", Secret: []byte("a"), BlobID: &first})
+	assert.NoError(t, err)
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: uuid.New(), Type: "BINARY", Name: "B", Secret: []byte("b"), BlobID: &shared})
+	assert.NoError(t, err)
+
+	// replacing the file collects the previous one
+	_, err = testDs.UpdateSecretData(ctx, models.SecretData{ID: note, Secret: []byte("a2"), Revision: 1, BlobID: &second})
+	assert.NoError(t, err)
+	assert.False(t, exists(first), "replaced blob kept")
+	_, err = testBlobs.Get(ctx, objectKey([]byte("first")))
+	assert.ErrorIs(t, err, ErrObjectNotFound, "replaced blob left in the store")
+
+	// a blob another note refers to stays
+	_, err = testDs.UpdateSecretData(ctx, models.SecretData{ID: note, Secret: []byte("a3"), Revision: 2, BlobID: &shared})
+	assert.NoError(t, err)
+	assert.False(t, exists(second), "replaced blob kept")
+	_, err = testDs.DeleteSecretData(ctx, note, 3)
+	assert.NoError(t, err)
+	assert.True(t, exists(shared), "blob of another note collected")
+
+	n, err := testDs.CollectBlobs(ctx, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n, "CollectBlobs() of recent blobs")
+	n, err = testDs.CollectBlobs(ctx, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, n, 1)
+	assert.False(t, exists(orphan), "orphan blob kept")
+	assert.True(t, exists(shared), "referenced blob collected")
+}
+
+func TestDataStore_SecretRevisions(t *testing.T) {
+	defer KeepRevisions(historySize)
+	KeepRevisions(2)
+	ctx := addContext(context.Background(), uuid.New())
+	exists := func(id uuid.UUID) bool {
+		_, err := testDs.GetBlob(ctx, id)
+		return err == nil
+	}
+	file := uuid.New()
+	_, err := testDs.AddBlobChunk(ctx, file, 0, []byte("attached"), true)
+	assert.NoError(t, err)
+
+	id := uuid.New()
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: id, Type: "BINARY", Name: "v1", Secret: []byte("v1"), BlobID: &file})
+	assert.NoError(t, err)
+	for revision := int64(1); revision < 4; revision++ {
+		name := fmt.Sprintf("v%d", revision+1)
+		_, err = testDs.UpdateSecretData(ctx, models.SecretData{ID: id, Name: name, Secret: []byte(name), Revision: revision})
+		assert.NoError(t, err)
+	}
+
+	revisions, err := testDs.ListSecretRevisions(ctx, id)
+	assert.NoError(t, err)
+	if assert.Len(t, *revisions, 2, "revisions beyond the history size kept") {
+		assert.Equal(t, int64(3), (*revisions)[0].Revision)
+		assert.Equal(t, []byte("v3"), (*revisions)[0].Secret)
+		assert.Equal(t, int64(2), (*revisions)[1].Revision)
+		assert.Equal(t, "BINARY", (*revisions)[1].Type)
+		assert.NotNil(t, (*revisions)[1].WrittenAt)
+	}
+	assert.False(t, exists(file), "blob of pruned revisions kept")
+
+	other, err := testDs.ListSecretRevisions(addContext(context.Background(), uuid.New()), id)
+	assert.NoError(t, err)
+	assert.Empty(t, *other, "ListSecretRevisions() of another user")
+
+	restored, err := testDs.RestoreSecretRevision(ctx, id, 2, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), restored.Revision)
+	assert.Equal(t, []byte("v2"), restored.Secret)
+	assert.Equal(t, "v2", restored.Name)
+	revisions, err = testDs.ListSecretRevisions(ctx, id)
+	assert.NoError(t, err)
+	if assert.Len(t, *revisions, 2) {
+		assert.Equal(t, []byte("v4"), (*revisions)[0].Secret, "replaced revision not kept")
+	}
+
+	var conflict *ConflictError
+	_, err = testDs.RestoreSecretRevision(ctx, id, 4, 4)
+	if assert.ErrorAs(t, err, &conflict, "RestoreSecretRevision() of an outdated revision") {
+		assert.Equal(t, int64(5), conflict.Current.Revision)
+	}
+	_, err = testDs.RestoreSecretRevision(ctx, id, 1, 5)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "RestoreSecretRevision() of a pruned revision")
+
+	// a file the history refers to stays until the revision is pruned
+	second := uuid.New()
+	_, err = testDs.AddBlobChunk(ctx, second, 0, []byte("second"), true)
+	assert.NoError(t, err)
+	_, err = testDs.UpdateSecretData(ctx, models.SecretData{ID: id, Secret: []byte("v6"), Revision: 5, BlobID: &second})
+	assert.NoError(t, err)
+	_, err = testDs.UpdateSecretData(ctx, models.SecretData{ID: id, Secret: []byte("v7"), Revision: 6})
+	assert.NoError(t, err)
+	assert.True(t, exists(second), "blob of a kept revision collected")
+	_, err = testDs.CollectBlobs(ctx, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.True(t, exists(second), "blob of a kept revision collected by CollectBlobs()")
+	restored, err = testDs.RestoreSecretRevision(ctx, id, 6, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, &second, restored.BlobID)
+}
+
+func TestDataStore_Trash(t *testing.T) {
+	ctx := addContext(context.Background(), uuid.New())
+	exists := func(id uuid.UUID) bool {
+		_, err := testDs.GetBlob(ctx, id)
+		return err == nil
+	}
+	file := uuid.New()
+	_, err := testDs.AddBlobChunk(ctx, file, 0, []byte("trashed file"), true)
+	assert.NoError(t, err)
+	idA, idB, idC := uuid.New(), uuid.New(), uuid.New()
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: idA, Type: "BINARY", Name: "A", Secret: []byte("a"), BlobID: &file})
+	assert.NoError(t, err)
+	_, err = testDs.UpdateSecretData(ctx, models.SecretData{ID: idA, Secret: []byte("a2"), Revision: 1, BlobID: &file})
+	assert.NoError(t, err)
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: idB, Type: "TEXT", Name: "B", Secret: []byte("b")})
+	assert.NoError(t, err)
+	_, err = testDs.AddSecretData(ctx, models.SecretData{ID: idC, Type: "TEXT", Name: "C", Secret: []byte("c")})
+	assert.NoError(t, err)
+	_, err = testDs.DeleteSecretData(ctx, idA, 2)
+	assert.NoError(t, err)
+	_, err = testDs.DeleteSecretData(ctx, idB, 1)
+	assert.NoError(t, err)
+
+	trash, err := testDs.ListTrash(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, *trash, 2) {
+		assert.Equal(t, idB, (*trash)[0].ID, "ListTrash() order")
+		assert.Equal(t, []byte("a2"), (*trash)[1].Secret)
+	}
+	other, err := testDs.ListTrash(addContext(context.Background(), uuid.New()))
+	assert.NoError(t, err)
+	assert.Empty(t, *other, "ListTrash() of another user")
+	_, err = testDs.CollectBlobs(ctx, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.True(t, exists(file), "blob of a trashed note collected")
+
+	restored, err := testDs.RestoreSecretData(ctx, idB)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), restored.Revision)
+	assert.False(t, restored.DeletedAt.Valid)
+	_, err = testDs.RestoreSecretData(ctx, idB)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "RestoreSecretData() of a live note")
+	_, err = testDs.RestoreSecretData(addContext(context.Background(), uuid.New()), idA)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "RestoreSecretData() of another user")
+
+	// purging the tombstone of A makes older cursors sync in full
+	n, err := testDs.PurgeTrash(ctx, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n, "PurgeTrash() of recent deletes")
+	synced, err := testDs.SyncSecretData(ctx, 4)
+	assert.NoError(t, err)
+	assert.False(t, synced.Full)
+	n, err = testDs.PurgeTrash(ctx, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, n, 1)
+	trash, err = testDs.ListTrash(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, *trash, "ListTrash() after purge")
+	revisions, err := testDs.ListSecretRevisions(ctx, idA)
+	assert.NoError(t, err)
+	assert.Empty(t, *revisions, "history of a purged note kept")
+	assert.False(t, exists(file), "blob of a purged note kept")
+	synced, err = testDs.SyncSecretData(ctx, 4)
+	assert.NoError(t, err)
+	assert.True(t, synced.Full, "SyncSecretData() before a purged tombstone")
+	synced, err = testDs.SyncSecretData(ctx, 5)
+	assert.NoError(t, err)
+	assert.False(t, synced.Full, "SyncSecretData() after a purged tombstone")
+
+	_, err = testDs.DeleteSecretData(ctx, idC, 1)
+	assert.NoError(t, err)
+	n, err = testDs.EmptyTrash(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	notes, err := testDs.GetSecretData(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, *notes, 1, "EmptyTrash() touched live notes") {
+		assert.Equal(t, idB, (*notes)[0].ID)
+	}
+}
+
+func TestOpenDB(t *testing.T) {
+	db, err := OpenDB(DriverSQLite, filepath.Join(t.TempDir(), "open.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &DataStore{db: db, broker: NewBroker(), blobs: testBlobs}
+	assert.NoError(t, store.Migrate())
+	user := models.User{ID: uuid.New(), Username: "A", Password: []byte("a"), Email: "open@test.com"}
+	assert.NoError(t, db.Create(&user).Error)
+	user.ID = uuid.New()
+	assert.ErrorIs(t, db.Create(&user).Error, gorm.ErrDuplicatedKey, "Create() of a taken e-mail")
+
+	_, err = OpenDB("mysql", "")
+	assert.Error(t, err)
+}
+
 func addContext(ctx context.Context, userId uuid.UUID) context.Context {
 	return context.WithValue(ctx, "UserCtx", &models.UserCtx{
 		Username: "Test",
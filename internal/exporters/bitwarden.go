@@ -0,0 +1,219 @@
+package exporters
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/importers"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+)
+
+// Bitwarden item types.
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+)
+
+type bitwardenFile struct {
+	Encrypted bool              `json:"encrypted"`
+	Folders   []bitwardenFolder `json:"folders"`
+	Items     []bitwardenItem   `json:"items"`
+}
+
+type bitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bitwardenItem struct {
+	ID         string              `json:"id"`
+	FolderID   *string             `json:"folderId"`
+	Type       int                 `json:"type"`
+	Reprompt   int                 `json:"reprompt"`
+	Name       string              `json:"name"`
+	Notes      *string             `json:"notes"`
+	Favorite   bool                `json:"favorite"`
+	Fields     []bitwardenField    `json:"fields,omitempty"`
+	Login      *bitwardenLoginData `json:"login,omitempty"`
+	SecureNote *struct {
+		Type int `json:"type"`
+	} `json:"secureNote,omitempty"`
+	Card         *bitwardenCardData `json:"card,omitempty"`
+	CreationDate string             `json:"creationDate"`
+	RevisionDate string             `json:"revisionDate"`
+
+	attachments []attachment
+}
+
+type bitwardenField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  int    `json:"type"`
+}
+
+type bitwardenLoginData struct {
+	URIs     []bitwardenURI `json:"uris,omitempty"`
+	Username string         `json:"username"`
+	Password string         `json:"password"`
+	Totp     *string        `json:"totp"`
+}
+
+type bitwardenURI struct {
+	Match *int   `json:"match"`
+	URI   string `json:"uri"`
+}
+
+type bitwardenCardData struct {
+	CardholderName string `json:"cardholderName"`
+	Brand          string `json:"brand"`
+	Number         string `json:"number"`
+	ExpMonth       string `json:"expMonth"`
+	ExpYear        string `json:"expYear"`
+	Code           string `json:"code"`
+}
+
+// writeBitwarden writes a ZIP with data.json, the JSON export, and the files
+// of each binary note in attachments/<note id>/. Credentials are logins, bank
+// cards are cards, and text and binary notes are secure notes. The bank of a
+// card, and an expiry not written as MM/YY, go in fields.
+func writeBitwarden(w io.Writer, notes []models.Noteable, files FileReader) error {
+	file := bitwardenFile{Folders: []bitwardenFolder{}, Items: make([]bitwardenItem, 0, len(notes))}
+	folders := make(map[string]string)
+	for _, note := range notes {
+		item, folder, err := bitwardenItemOf(note, files)
+		if err != nil {
+			return err
+		}
+		if folder != "" {
+			id, ok := folders[folder]
+			if !ok {
+				id = uuid.NewString()
+				folders[folder] = id
+				file.Folders = append(file.Folders, bitwardenFolder{ID: id, Name: folder})
+			}
+			item.FolderID = &id
+		}
+		file.Items = append(file.Items, item)
+	}
+
+	zw := zip.NewWriter(w)
+	data, err := zw.Create("data.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(data)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(file); err != nil {
+		return fmt.Errorf("writing Bitwarden JSON: %w", err)
+	}
+	for _, item := range file.Items {
+		for _, a := range item.attachments {
+			f, err := zw.Create("attachments/" + item.ID + "/" + a.name)
+			if err != nil {
+				return err
+			}
+			if _, err = f.Write(a.data); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+// bitwardenItemOf maps note to an item and returns the folder it belongs to.
+func bitwardenItemOf(note models.Noteable, files FileReader) (bitwardenItem, string, error) {
+	item := bitwardenItem{ID: note.GetID().String(), Name: note.GetName()}
+	var base models.BaseNote
+	var info *metaInfo
+	var notes string
+	switch n := note.(type) {
+	case *models.CredentialNote:
+		base, info = n.BaseNote, newMetaInfo(n.MetaInfo)
+		item.Type = bitwardenLogin
+		item.Login = &bitwardenLoginData{Username: n.Username, Password: n.Password}
+		for {
+			uri, ok := info.take("URL")
+			if !ok {
+				break
+			}
+			item.Login.URIs = append(item.Login.URIs, bitwardenURI{URI: uri})
+		}
+		if totp, ok := info.take("TOTP"); ok {
+			item.Login.Totp = &totp
+		}
+		notes, _ = info.take("Notes")
+	case *models.BankCardNote:
+		base, info = n.BaseNote, newMetaInfo(n.MetaInfo)
+		item.Type = bitwardenCard
+		item.Card = &bitwardenCardData{CardholderName: n.Cardholder, Number: n.Number, Code: n.SecurityCode}
+		item.Card.Brand, _ = info.take("Brand")
+		if month, year, ok := bitwardenExpiration(n.Expiration); ok {
+			item.Card.ExpMonth, item.Card.ExpYear = month, year
+		} else if n.Expiration != "" {
+			item.Fields = append(item.Fields, bitwardenField{Name: importers.CardExpiry, Value: n.Expiration})
+		}
+		if n.Bank != "" {
+			item.Fields = append(item.Fields, bitwardenField{Name: importers.BankName, Value: n.Bank})
+		}
+		notes, _ = info.take("Notes")
+	case *models.TextNote:
+		base, info = n.BaseNote, newMetaInfo(n.MetaInfo)
+		item.Type = bitwardenSecureNote
+		notes = n.Text
+	case *models.BinaryNote:
+		base, info = n.BaseNote, newMetaInfo(n.MetaInfo)
+		item.Type = bitwardenSecureNote
+		var err error
+		if item.attachments, err = attachments(n, info, files); err != nil {
+			return item, "", err
+		}
+	default:
+		return item, "", fmt.Errorf("unsupported note %T", note)
+	}
+	if item.Type == bitwardenSecureNote {
+		item.SecureNote = &struct {
+			Type int `json:"type"`
+		}{}
+	}
+	if notes != "" {
+		item.Notes = &notes
+	}
+	folder, _ := info.take("Folder")
+	if favorite, ok := info.take("Favorite"); ok {
+		if favorite == "yes" {
+			item.Favorite = true
+		} else {
+			item.Fields = append(item.Fields, bitwardenField{Name: "Favorite", Value: favorite})
+		}
+	}
+	info.rest(func(name, value string, ok bool) {
+		if ok && item.Card != nil && (name == importers.BankName || name == importers.CardExpiry) {
+			name, value, ok = "", name+": "+value, false
+		}
+		if !ok {
+			name = importers.AdditionalInfo
+		}
+		item.Fields = append(item.Fields, bitwardenField{Name: name, Value: value})
+	})
+	item.CreationDate = created(base)
+	item.RevisionDate = item.CreationDate
+	return item, folder, nil
+}
+
+// bitwardenExpiration splits an MM/YY expiry into the month and four digit
+// year Bitwarden stores.
+func bitwardenExpiration(expiration string) (month, year string, ok bool) {
+	month, year, ok = strings.Cut(strings.TrimSpace(expiration), "/")
+	m, errMonth := strconv.Atoi(month)
+	_, errYear := strconv.Atoi(year)
+	if !ok || errMonth != nil || errYear != nil || m < 1 || m > 12 || len(month) != 2 || len(year) != 2 {
+		return "", "", false
+	}
+	return strconv.Itoa(m), "20" + year, true
+}
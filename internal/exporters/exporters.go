@@ -0,0 +1,142 @@
+// Package exporters writes notes in the formats of other password managers:
+// KeePass 2.x XML and the Bitwarden ZIP export, its JSON export with the files
+// attached to items next to it. Both are unencrypted. The importers package
+// reads them back into the same notes; MetaInfo lines a format has no place
+// for are written as custom fields.
+package exporters
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/importers"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+)
+
+type Format string
+
+const (
+	KeePass   Format = "keepass"
+	Bitwarden Format = "bitwarden"
+)
+
+// Formats lists the formats Write writes.
+var Formats = []Format{KeePass, Bitwarden}
+
+func ParseFormat(s string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == s {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q, use keepass or bitwarden", s)
+}
+
+// FileReader returns the content of an uploaded file.
+type FileReader func(ref *models.BlobRef) ([]byte, error)
+
+// Write writes notes to w in format. The uploaded files of binary notes are
+// read with files.
+func Write(format Format, w io.Writer, notes []models.Noteable, files FileReader) error {
+	switch format {
+	case KeePass:
+		return writeKeePass(w, notes, files)
+	case Bitwarden:
+		return writeBitwarden(w, notes, files)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// metaInfo is the MetaInfo of a note, taken line by line as the note is
+// mapped to the fields of a format.
+type metaInfo struct {
+	lines []string
+	taken []bool
+}
+
+func newMetaInfo(lines []string) *metaInfo {
+	return &metaInfo{lines: lines, taken: make([]bool, len(lines))}
+}
+
+// take returns the value of the first line named name that is not taken yet.
+func (m *metaInfo) take(name string) (string, bool) {
+	for i, line := range m.lines {
+		if key, value, ok := field(line); ok && !m.taken[i] && key == name {
+			m.taken[i] = true
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// rest calls fn with the lines that are not taken, split as field does.
+func (m *metaInfo) rest(fn func(name, value string, ok bool)) {
+	for i, line := range m.lines {
+		if !m.taken[i] && strings.TrimSpace(line) != "" {
+			name, value, ok := field(line)
+			fn(name, value, ok)
+		}
+	}
+}
+
+// field splits a MetaInfo line written as "name: value". A line of another
+// shape is returned whole as the value, to be kept in an AdditionalInfo field.
+func field(line string) (name, value string, ok bool) {
+	name, value, ok = strings.Cut(line, ": ")
+	if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(value) == "" ||
+		strings.Contains(name, "\n") || strings.HasPrefix(name, importers.AdditionalInfo) {
+		return "", line, false
+	}
+	return name, strings.TrimSpace(value), true
+}
+
+type attachment struct {
+	name string
+	data []byte
+}
+
+// attachments returns the files of a binary note: its uploaded file and its
+// inline data, named after the note's "File" line.
+func attachments(note *models.BinaryNote, info *metaInfo, files FileReader) ([]attachment, error) {
+	inlineName, ok := info.take("File")
+	if !ok {
+		inlineName = note.NameRecord
+	}
+	var list []attachment
+	if note.Blob != nil {
+		if files == nil {
+			return nil, fmt.Errorf("%s: %w", note.NameRecord, ErrNoFiles)
+		}
+		data, err := files(note.Blob)
+		if err != nil {
+			return nil, fmt.Errorf("reading the file of %s: %w", note.NameRecord, err)
+		}
+		list = append(list, attachment{name: fileName(note.Blob.FileName), data: data})
+	}
+	if len(note.Binary) > 0 || note.Blob == nil {
+		name := fileName(inlineName)
+		if len(list) > 0 && list[0].name == name {
+			name += " (2)"
+		}
+		list = append(list, attachment{name: name, data: note.Binary})
+	}
+	return list, nil
+}
+
+// fileName makes name fit for a file name inside an archive.
+func fileName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+func created(note models.BaseNote) string {
+	return time.Unix(note.Created, 0).UTC().Format(time.RFC3339)
+}
+
+var ErrNoFiles = errors.New("the note has an uploaded file and there is no way to read it")
@@ -0,0 +1,98 @@
+package exporters
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/importers"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func base(typeNote models.TypeNote, name string, info ...string) models.BaseNote {
+	created := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC).Unix()
+	return models.BaseNote{Id: uuid.New(), NameRecord: name, Created: created, Type: typeNote, MetaInfo: info}
+}
+
+func TestWrite_roundTrip(t *testing.T) {
+	blob := &models.BlobRef{ID: uuid.New(), FileName: "clip.mp4", Size: 5}
+	notes := []models.Noteable{
+		&models.CredentialNote{Username: "me", Password: "pw", BaseNote: base(models.CREDENTIAL, "Mail",
+			"URL: https://mail.example.com", "URL: https://webmail.example.com", "Notes: work",
+			"TOTP: otpauth://totp/mail", "Group: Work/Mail", "Folder: Personal", "Favorite: yes",
+			"Recovery: blue", "Recovery: green", "a line of its own", "Additional information: kept")},
+		&models.BankCardNote{Bank: "Bank", Number: "4111111111111111", Expiration: "03/29", Cardholder: "Jane Doe",
+			SecurityCode: "123", BaseNote: base(models.CARD, "Visa", "Brand: Visa", "Bank name: another", "Notes: main")},
+		&models.BankCardNote{Number: "5500000000000004", Expiration: "never", BaseNote: base(models.CARD, "Old card")},
+		&models.TextNote{Text: "guest network", BaseNote: base(models.TEXT, "Wifi", "Notes: extra", "Group: Home")},
+		&models.BinaryNote{Binary: []byte("-----BEGIN KEY-----"), BaseNote: base(models.BINARY, "Deploy key",
+			"File: id_ed25519", "Owner: ops", "URL: https://git.example.com")},
+		&models.BinaryNote{Blob: blob, BaseNote: base(models.BINARY, "Video", "Group: Media")},
+	}
+	files := func(ref *models.BlobRef) ([]byte, error) {
+		assert.Equal(t, blob.ID, ref.ID)
+		return []byte("movie"), nil
+	}
+
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if !assert.NoError(t, Write(format, &buf, notes, files)) {
+				return
+			}
+			result, err := importers.Parse(importers.Format(format), &buf)
+			if !assert.NoError(t, err) || !assert.Len(t, result.Notes, len(notes)) {
+				return
+			}
+			assert.Empty(t, result.Skipped)
+			// KeePass lists notes by group.
+			byName := make(map[string]models.Noteable, len(result.Notes))
+			for _, note := range result.Notes {
+				byName[note.GetName()] = note
+			}
+			for _, want := range notes {
+				got := byName[want.GetName()]
+				if !assert.NotNil(t, got, want.GetName()) || !assert.Equal(t, want.GetType(), got.GetType(), want.GetName()) {
+					continue
+				}
+				switch w := want.(type) {
+				case *models.CredentialNote:
+					g := got.(*models.CredentialNote)
+					assert.Equal(t, []string{w.Username, w.Password}, []string{g.Username, g.Password})
+					assert.Equal(t, w.Created, g.Created)
+					assert.ElementsMatch(t, w.MetaInfo, g.MetaInfo)
+				case *models.BankCardNote:
+					g := got.(*models.BankCardNote)
+					assert.Equal(t, w.Bank, g.Bank)
+					assert.Equal(t, w.Number, g.Number)
+					assert.Equal(t, w.Expiration, g.Expiration)
+					assert.Equal(t, w.Cardholder, g.Cardholder)
+					assert.Equal(t, w.SecurityCode, g.SecurityCode)
+					assert.ElementsMatch(t, w.MetaInfo, g.MetaInfo)
+				case *models.TextNote:
+					g := got.(*models.TextNote)
+					assert.Equal(t, w.Text, g.Text)
+					assert.ElementsMatch(t, w.MetaInfo, g.MetaInfo)
+				case *models.BinaryNote:
+					g := got.(*models.BinaryNote)
+					data := w.Binary
+					if w.Blob != nil {
+						data = []byte("movie")
+						assert.Contains(t, g.MetaInfo, "File: clip.mp4")
+					}
+					assert.Equal(t, data, g.Binary)
+					assert.Subset(t, g.MetaInfo, w.MetaInfo)
+				}
+			}
+		})
+	}
+}
+
+func TestWrite_noFiles(t *testing.T) {
+	notes := []models.Noteable{&models.BinaryNote{Blob: &models.BlobRef{FileName: "a"}, BaseNote: base(models.BINARY, "File")}}
+	for _, format := range Formats {
+		assert.ErrorIs(t, Write(format, &bytes.Buffer{}, notes, nil), ErrNoFiles)
+	}
+}
@@ -0,0 +1,232 @@
+package exporters
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/importers"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+)
+
+type keePassFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    struct {
+		Generator         string          `xml:"Generator"`
+		DatabaseName      string          `xml:"DatabaseName"`
+		RecycleBinEnabled string          `xml:"RecycleBinEnabled"`
+		Binaries          []keePassBinary `xml:"Binaries>Binary"`
+	} `xml:"Meta"`
+	Root struct {
+		Group *keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassBinary struct {
+	ID   string `xml:"ID,attr"`
+	Data string `xml:",chardata"`
+}
+
+type keePassGroup struct {
+	UUID    string          `xml:"UUID"`
+	Name    string          `xml:"Name"`
+	Entries []keePassEntry  `xml:"Entry"`
+	Groups  []*keePassGroup `xml:"Group"`
+}
+
+type keePassEntry struct {
+	UUID  string `xml:"UUID"`
+	Times struct {
+		CreationTime         string `xml:"CreationTime"`
+		LastModificationTime string `xml:"LastModificationTime"`
+	} `xml:"Times"`
+	Strings  []keePassString `xml:"String"`
+	Binaries []keePassRef    `xml:"Binary"`
+}
+
+type keePassString struct {
+	Key   string `xml:"Key"`
+	Value struct {
+		Protected string `xml:"ProtectInMemory,attr,omitempty"`
+		Text      string `xml:",chardata"`
+	} `xml:"Value"`
+}
+
+type keePassRef struct {
+	Key   string `xml:"Key"`
+	Value struct {
+		Ref string `xml:"Ref,attr"`
+	} `xml:"Value"`
+}
+
+// keePassReserved are the fields KeePass shows for every entry and the card
+// fields the importers read.
+var keePassReserved = []string{"Title", "UserName", "Password", "URL", "Notes",
+	importers.BankName, importers.CardNumber, importers.CardExpiry, importers.Cardholder, importers.SecurityCode}
+
+// writeKeePass writes a KeePass XML export with one entry per note, in the
+// groups of the notes' "Group" lines. Bank cards are entries with the card
+// fields; the files of binary notes are attachments.
+func writeKeePass(w io.Writer, notes []models.Noteable, files FileReader) error {
+	var file keePassFile
+	file.Meta.Generator = "GophKeeper"
+	file.Meta.DatabaseName = "GophKeeper"
+	file.Meta.RecycleBinEnabled = "False"
+	file.Root.Group = &keePassGroup{UUID: keePassUUID(uuid.New()), Name: "GophKeeper"}
+	groups := map[string]*keePassGroup{"": file.Root.Group}
+
+	for _, note := range notes {
+		entry, path, attached, err := keePassEntryOf(note, files)
+		if err != nil {
+			return err
+		}
+		for _, a := range attached {
+			id := strconv.Itoa(len(file.Meta.Binaries))
+			file.Meta.Binaries = append(file.Meta.Binaries, keePassBinary{ID: id, Data: base64.StdEncoding.EncodeToString(a.data)})
+			ref := keePassRef{Key: a.name}
+			ref.Value.Ref = id
+			entry.Binaries = append(entry.Binaries, ref)
+		}
+		group := keePassGroupOf(groups, path)
+		group.Entries = append(group.Entries, entry)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(file); err != nil {
+		return fmt.Errorf("writing KeePass XML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// keePassEntryOf maps note to an entry and returns its group path and files.
+func keePassEntryOf(note models.Noteable, files FileReader) (keePassEntry, string, []attachment, error) {
+	var entry keePassEntry
+	var base models.BaseNote
+	var info *metaInfo
+	var attached []attachment
+	set := func(key, value string, protected bool) {
+		s := keePassString{Key: key}
+		s.Value.Text = value
+		if protected {
+			s.Value.Protected = "True"
+		}
+		entry.Strings = append(entry.Strings, s)
+	}
+	set("Title", note.GetName(), false)
+
+	// A binary note has no standard fields, so all its lines are custom ones
+	// and go back to its files.
+	standard := true
+	switch n := note.(type) {
+	case *models.CredentialNote:
+		base, info = n.BaseNote, newMetaInfo(n.MetaInfo)
+		set("UserName", n.Username, false)
+		set("Password", n.Password, true)
+	case *models.BankCardNote:
+		base, info = n.BaseNote, newMetaInfo(n.MetaInfo)
+		set(importers.BankName, n.Bank, false)
+		set(importers.CardNumber, n.Number, false)
+		set(importers.CardExpiry, n.Expiration, false)
+		set(importers.Cardholder, n.Cardholder, false)
+		set(importers.SecurityCode, n.SecurityCode, true)
+	case *models.TextNote:
+		base, info = n.BaseNote, newMetaInfo(n.MetaInfo)
+		set("Notes", n.Text, false)
+	case *models.BinaryNote:
+		base, info = n.BaseNote, newMetaInfo(n.MetaInfo)
+		standard = false
+		var err error
+		if attached, err = attachments(n, info, files); err != nil {
+			return entry, "", nil, err
+		}
+	default:
+		return entry, "", nil, fmt.Errorf("unsupported note %T", note)
+	}
+	if standard {
+		for _, key := range []string{"URL", "Notes"} {
+			if entry.has(key) {
+				continue
+			}
+			if value, ok := info.take(key); ok {
+				set(key, value, false)
+			}
+		}
+	}
+	path, _ := info.take("Group")
+
+	// Keys are unique in an entry and some have a meaning of their own; other
+	// lines are kept whole in AdditionalInfo fields.
+	additional := 0
+	info.rest(func(name, value string, ok bool) {
+		if !ok || entry.has(name) || isReserved(name) {
+			additional++
+			if ok {
+				value = name + ": " + value
+			}
+			name = importers.AdditionalInfo
+			if additional > 1 {
+				name += " " + strconv.Itoa(additional)
+			}
+		}
+		set(name, value, false)
+	})
+
+	entry.UUID = keePassUUID(base.Id)
+	entry.Times.CreationTime = created(base)
+	entry.Times.LastModificationTime = entry.Times.CreationTime
+	return entry, path, attached, nil
+}
+
+func (e *keePassEntry) has(key string) bool {
+	for _, s := range e.Strings {
+		if s.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isReserved(key string) bool {
+	for _, reserved := range keePassReserved {
+		if key == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// keePassGroupOf returns the group at path, a "/" separated list of names,
+// adding the groups that are missing.
+func keePassGroupOf(groups map[string]*keePassGroup, path string) *keePassGroup {
+	group, current := groups[""], ""
+	for _, name := range strings.Split(path, "/") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		if current != "" {
+			current += "/"
+		}
+		current += name
+		sub, ok := groups[current]
+		if !ok {
+			sub = &keePassGroup{UUID: keePassUUID(uuid.New()), Name: name}
+			group.Groups = append(group.Groups, sub)
+			groups[current] = sub
+		}
+		group = sub
+	}
+	return group
+}
+
+func keePassUUID(id uuid.UUID) string {
+	return base64.StdEncoding.EncodeToString(id[:])
+}
@@ -0,0 +1,255 @@
+package importers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+)
+
+// Bitwarden item types.
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+)
+
+// bitwardenData is the name of the JSON export in a Bitwarden ZIP export. The
+// files attached to an item are next to it, in attachments/<item id>/.
+const bitwardenData = "data.json"
+
+// bitwardenFile is an unencrypted Bitwarden JSON export.
+type bitwardenFile struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []bitwardenItem `json:"items"`
+}
+
+type bitwardenItem struct {
+	ID           string    `json:"id"`
+	Type         int       `json:"type"`
+	Name         string    `json:"name"`
+	Notes        string    `json:"notes"`
+	FolderID     string    `json:"folderId"`
+	Favorite     bool      `json:"favorite"`
+	CreationDate time.Time `json:"creationDate"`
+	DeletedDate  *string   `json:"deletedDate"`
+	Fields       []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"fields"`
+	Login *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Totp     string `json:"totp"`
+		URIs     []struct {
+			URI string `json:"uri"`
+		} `json:"uris"`
+	} `json:"login"`
+	Card *struct {
+		CardholderName string `json:"cardholderName"`
+		Brand          string `json:"brand"`
+		Number         string `json:"number"`
+		ExpMonth       string `json:"expMonth"`
+		ExpYear        string `json:"expYear"`
+		Code           string `json:"code"`
+	} `json:"card"`
+	// Identity is kept as the text of a note, in the order of the export.
+	Identity json.RawMessage `json:"identity"`
+}
+
+// parseBitwarden maps logins to credentials, cards to bank cards, and secure
+// notes and identities to text notes. It reads the JSON export or the ZIP one,
+// whose attachments become binary notes. Items in the trash are skipped.
+func parseBitwarden(r io.Reader) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading Bitwarden export: %w", err)
+	}
+	var attachments map[string][]bitwardenAttachment
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		if data, attachments, err = readBitwardenZip(data); err != nil {
+			return nil, err
+		}
+	}
+	var file bitwardenFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("reading Bitwarden JSON: %w", err)
+	}
+	if file.Encrypted {
+		return nil, ErrEncryptedExport
+	}
+	folders := make(map[string]string, len(file.Folders))
+	for _, folder := range file.Folders {
+		folders[folder.ID] = folder.Name
+	}
+
+	result := &Result{}
+	for _, item := range file.Items {
+		name := item.Name
+		if strings.TrimSpace(name) == "" {
+			name = "Untitled"
+		}
+		if item.DeletedDate != nil {
+			result.skip(name, "in the trash")
+			continue
+		}
+		files := attachments[item.ID]
+		var folder meta
+		folder.add("Folder", folders[item.FolderID])
+		info := append(meta{}, folder...)
+		if item.Favorite {
+			info.add("Favorite", "yes")
+		}
+		bank, expiry := "", ""
+		for _, field := range item.Fields {
+			switch {
+			case item.Type == bitwardenCard && field.Name == BankName:
+				bank = field.Value
+			case item.Type == bitwardenCard && field.Name == CardExpiry:
+				expiry = field.Value
+			default:
+				info.add(field.Name, field.Value)
+			}
+		}
+
+		hasNote := true
+		switch {
+		case item.Type == bitwardenLogin && item.Login != nil:
+			for _, uri := range item.Login.URIs {
+				info.add("URL", uri.URI)
+			}
+			info.add("TOTP", item.Login.Totp)
+			info.add("Notes", item.Notes)
+			result.Notes = append(result.Notes, &models.CredentialNote{
+				Username: item.Login.Username,
+				Password: item.Login.Password,
+				BaseNote: newBase(models.CREDENTIAL, name, item.CreationDate, info),
+			})
+		case item.Type == bitwardenCard && item.Card != nil:
+			info.add("Brand", item.Card.Brand)
+			info.add("Notes", item.Notes)
+			if e := expiration(item.Card.ExpMonth, item.Card.ExpYear); e != "" {
+				expiry = e
+			}
+			result.Notes = append(result.Notes, &models.BankCardNote{
+				Bank:         bank,
+				Number:       item.Card.Number,
+				Expiration:   expiry,
+				Cardholder:   item.Card.CardholderName,
+				SecurityCode: item.Card.Code,
+				BaseNote:     newBase(models.CARD, name, item.CreationDate, info),
+			})
+		case item.Type == bitwardenSecureNote && item.Notes == "" && len(files) > 0:
+			// A note that only holds files is made of them.
+			hasNote = false
+		case item.Type == bitwardenSecureNote:
+			result.Notes = append(result.Notes, &models.TextNote{
+				Text:     item.Notes,
+				BaseNote: newBase(models.TEXT, name, item.CreationDate, info),
+			})
+		case item.Type == bitwardenIdentity && len(item.Identity) > 0 && string(item.Identity) != "null":
+			text, err := identityText(item.Identity)
+			if err != nil {
+				result.skip(name, "unreadable identity")
+				continue
+			}
+			info.add("Notes", item.Notes)
+			result.Notes = append(result.Notes, &models.TextNote{
+				Text:     text,
+				BaseNote: newBase(models.TEXT, name, item.CreationDate, info),
+			})
+		default:
+			result.skip(name, fmt.Sprintf("unsupported item type %d", item.Type))
+			continue
+		}
+
+		only := !hasNote && len(files) == 1
+		if !hasNote {
+			folder = info
+		}
+		for _, f := range files {
+			result.Notes = append(result.Notes, attachmentNote(name, f.name, f.data, only, item.CreationDate, folder))
+		}
+	}
+	return result, nil
+}
+
+type bitwardenAttachment struct {
+	name string
+	data []byte
+}
+
+// readBitwardenZip returns the JSON export of a ZIP export and the files
+// attached to each item, by item id.
+func readBitwardenZip(data []byte) ([]byte, map[string][]bitwardenAttachment, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading Bitwarden ZIP: %w", err)
+	}
+	var export []byte
+	attachments := make(map[string][]bitwardenAttachment)
+	for _, f := range zr.File {
+		parts := strings.Split(f.Name, "/")
+		isData := f.Name == bitwardenData
+		isAttachment := len(parts) == 3 && parts[0] == "attachments" && parts[2] != ""
+		if !isData && !isAttachment {
+			continue
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %s from Bitwarden ZIP: %w", f.Name, err)
+		}
+		if isData {
+			export = content
+			continue
+		}
+		attachments[parts[1]] = append(attachments[parts[1]], bitwardenAttachment{name: parts[2], data: content})
+	}
+	if export == nil {
+		return nil, nil, fmt.Errorf("the Bitwarden ZIP has no %s", bitwardenData)
+	}
+	return export, attachments, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// identityText lists the filled fields of an identity, one "name: value" per
+// line in the order of the export.
+func identityText(raw json.RawMessage) (string, error) {
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	if start, err := decoder.Token(); err != nil || start != json.Delim('{') {
+		return "", fmt.Errorf("identity is not an object: %v", err)
+	}
+	var lines meta
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		var value interface{}
+		if err = decoder.Decode(&value); err != nil {
+			return "", err
+		}
+		if s, ok := value.(string); ok {
+			lines.add(fmt.Sprint(key), s)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
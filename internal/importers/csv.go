@@ -0,0 +1,135 @@
+package importers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+)
+
+// Column names of the password CSV of Chrome (name, url, username, password,
+// note) and Firefox (url, username, password, httpRealm, formActionOrigin,
+// guid, timeCreated, timeLastUsed, timePasswordChanged), lower-cased.
+var (
+	csvName     = []string{"name", "title"}
+	csvURL      = []string{"url", "origin"}
+	csvUsername = []string{"username", "login"}
+	csvPassword = []string{"password"}
+	csvNote     = []string{"note", "notes"}
+	csvCreated  = []string{"timecreated"}
+)
+
+// parseCSV makes a credential of every row with a username or password. Its
+// name is the name column, else the host of its URL. Other columns are kept
+// in MetaInfo; Firefox times, in milliseconds, are written as dates.
+func parseCSV(r io.Reader) (*Result, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		columns[strings.ToLower(header[i])] = i
+	}
+	if column(columns, csvPassword) < 0 || column(columns, csvUsername) < 0 {
+		return nil, ErrNotPasswordCSV
+	}
+	known := make(map[int]bool)
+	for _, names := range [][]string{csvName, csvURL, csvUsername, csvPassword, csvNote, csvCreated} {
+		known[column(columns, names)] = true
+	}
+
+	result := &Result{}
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV: %w", err)
+		}
+		get := func(names []string) string {
+			if i := column(columns, names); i >= 0 && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+
+		link := get(csvURL)
+		name := csvEntryName(get(csvName), link, get(csvUsername))
+		username, password := get(csvUsername), get(csvPassword)
+		if username == "" && password == "" {
+			if name == "" {
+				name = fmt.Sprintf("row %d", row)
+			}
+			result.skip(name, "no username or password")
+			continue
+		}
+		if name == "" {
+			name = "Untitled"
+		}
+
+		var info meta
+		info.add("URL", link)
+		info.add("Notes", get(csvNote))
+		for i, value := range record {
+			if i < len(header) && !known[i] {
+				info.add(header[i], csvValue(header[i], value))
+			}
+		}
+		result.Notes = append(result.Notes, &models.CredentialNote{
+			Username: username,
+			Password: password,
+			BaseNote: newBase(models.CREDENTIAL, name, csvTime(get(csvCreated)), info),
+		})
+	}
+	return result, nil
+}
+
+func column(columns map[string]int, names []string) int {
+	for _, name := range names {
+		if i, ok := columns[name]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+func csvEntryName(name, link, username string) string {
+	if strings.TrimSpace(name) != "" {
+		return name
+	}
+	if u, err := url.Parse(link); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return username
+}
+
+// csvTime reads a time in milliseconds since 1970.
+func csvTime(ms string) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(ms), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(n)
+}
+
+func csvValue(column, value string) string {
+	if strings.HasPrefix(strings.ToLower(column), "time") {
+		if t := csvTime(value); !t.IsZero() {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return value
+}
+
+var ErrNotPasswordCSV = errors.New("the CSV has no username and password columns")
@@ -0,0 +1,150 @@
+// Package importers reads the exports of other password managers into notes:
+// KeePass 2.x XML, unencrypted Bitwarden JSON and the password CSV of Chrome
+// and Firefox. Fields a note has no place for are kept in its MetaInfo as
+// "name: value" lines; a custom field named AdditionalInfo, as the exporters
+// package writes for lines of another shape, is kept as its bare value.
+package importers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+)
+
+type Format string
+
+const (
+	KeePass   Format = "keepass"
+	Bitwarden Format = "bitwarden"
+	CSV       Format = "csv"
+)
+
+// Formats lists the formats Parse reads.
+var Formats = []Format{KeePass, Bitwarden, CSV}
+
+func ParseFormat(s string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == s {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown import format %q, use keepass, bitwarden or csv", s)
+}
+
+// Result is what an export converts to: the notes to create, each with a new
+// id, and the entries that were left out.
+type Result struct {
+	Notes   []models.Noteable
+	Skipped []Skipped
+}
+
+// Skipped is an entry of the export that no note is created for.
+type Skipped struct {
+	Entry  string `json:"entry"`
+	Reason string `json:"reason"`
+}
+
+// Parse converts the export in r, written in format.
+func Parse(format Format, r io.Reader) (*Result, error) {
+	switch format {
+	case KeePass:
+		return parseKeePass(r)
+	case Bitwarden:
+		return parseBitwarden(r)
+	case CSV:
+		return parseCSV(r)
+	}
+	return nil, fmt.Errorf("unknown import format %q", format)
+}
+
+// Report writes one line for every note that would be created and every
+// entry that was skipped.
+func (r *Result) Report(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, note := range r.Notes {
+		fmt.Fprintf(tw, "create\t%s\t%s\n", note.GetType(), note.GetName())
+	}
+	for _, skipped := range r.Skipped {
+		fmt.Fprintf(tw, "skip\t%s\t%s\n", skipped.Entry, skipped.Reason)
+	}
+	fmt.Fprintf(tw, "%d to create, %d skipped\n", len(r.Notes), len(r.Skipped))
+	return tw.Flush()
+}
+
+func (r *Result) skip(entry, reason string) {
+	r.Skipped = append(r.Skipped, Skipped{Entry: entry, Reason: reason})
+}
+
+// meta collects the MetaInfo lines of a note, leaving out empty values.
+type meta []string
+
+func (m *meta) add(name, value string) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+	case strings.HasPrefix(name, AdditionalInfo):
+		*m = append(*m, value)
+	default:
+		*m = append(*m, name+": "+value)
+	}
+}
+
+// AdditionalInfo names custom fields that hold a MetaInfo line as it is.
+const AdditionalInfo = "Additional information"
+
+// Labels of the bank card fields, as the card form shows them. KeePass has no
+// cards; an entry with a CardNumber field is read as one.
+const (
+	BankName     = "Bank name"
+	CardNumber   = "Card number"
+	CardExpiry   = "Card expiration"
+	Cardholder   = "Cardholder name"
+	SecurityCode = "Security code"
+)
+
+func newBase(typeNote models.TypeNote, name string, created time.Time, info meta) models.BaseNote {
+	if created.IsZero() {
+		created = time.Now()
+	}
+	return models.BaseNote{Id: uuid.New(), NameRecord: name, Created: created.Unix(), Type: typeNote, MetaInfo: info}
+}
+
+// expiration formats a card expiry as the card form expects it, MM/YY.
+func expiration(month, year string) string {
+	month, year = strings.TrimSpace(month), strings.TrimSpace(year)
+	if month == "" && year == "" {
+		return ""
+	}
+	if len(month) == 1 {
+		month = "0" + month
+	}
+	if len(year) == 4 {
+		year = year[2:]
+	}
+	return month + "/" + year
+}
+
+// attachmentNote makes a binary note of a file attached to entry. An entry
+// whose only content is a single file becomes a note of the same name.
+func attachmentNote(entry, file string, data []byte, only bool, created time.Time, info meta) *models.BinaryNote {
+	name := entry + " - " + file
+	if only {
+		name = entry
+	}
+	fileInfo := meta{"File: " + file}
+	if !only {
+		fileInfo.add("Entry", entry)
+	}
+	return &models.BinaryNote{
+		Binary:   data,
+		BaseNote: newBase(models.BINARY, name, created, append(fileInfo, info...)),
+	}
+}
+
+var ErrEncryptedExport = errors.New("the export is encrypted, export it again without encryption")
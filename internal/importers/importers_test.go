@@ -0,0 +1,228 @@
+package importers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipBase64(t *testing.T, data string) string {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestParse_keePass(t *testing.T) {
+	export := `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta>
+		<RecycleBinEnabled>True</RecycleBinEnabled>
+		<RecycleBinUUID>cmVjeWNsZQ==</RecycleBinUUID>
+		<Binaries>
+			<Binary ID="0" Compressed="True">` + gzipBase64(t, "-----BEGIN KEY-----") + `</Binary>
+		</Binaries>
+	</Meta>
+	<Root>
+		<Group>
+			<UUID>cm9vdA==</UUID>
+			<Name>Database</Name>
+			<Entry>
+				<Times><CreationTime>2023-05-01T10:00:00Z</CreationTime></Times>
+				<String><Key>Title</Key><Value>Mail</Value></String>
+				<String><Key>UserName</Key><Value>me@example.com</Value></String>
+				<String><Key>Password</Key><Value ProtectInMemory="True">s3cret</Value></String>
+				<String><Key>URL</Key><Value>https://mail.example.com</Value></String>
+				<String><Key>Notes</Key><Value>work</Value></String>
+				<String><Key>Recovery</Key><Value>blue</Value></String>
+				<History>
+					<Entry><String><Key>Title</Key><Value>Old mail</Value></String></Entry>
+				</History>
+			</Entry>
+			<Group>
+				<UUID>c2VydmVycw==</UUID>
+				<Name>Servers</Name>
+				<Entry>
+					<Times><CreationTime>AESZ2w4AAAA=</CreationTime></Times>
+					<String><Key>Title</Key><Value>Deploy key</Value></String>
+					<Binary><Key>id_ed25519</Key><Value Ref="0"/></Binary>
+					<Binary><Key>broken</Key><Value Ref="7"/></Binary>
+				</Entry>
+				<Entry>
+					<String><Key>Title</Key><Value>Wifi</Value></String>
+					<String><Key>Notes</Key><Value>guest network</Value></String>
+				</Entry>
+				<Entry>
+					<String><Key>Title</Key><Value>Nothing</Value></String>
+				</Entry>
+			</Group>
+			<Group>
+				<UUID>cmVjeWNsZQ==</UUID>
+				<Name>Recycle Bin</Name>
+				<Entry><String><Key>Title</Key><Value>Deleted</Value></String></Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`
+
+	result, err := Parse(KeePass, strings.NewReader(export))
+	if !assert.NoError(t, err) || !assert.Len(t, result.Notes, 3) {
+		return
+	}
+	mail := result.Notes[0].(*models.CredentialNote)
+	assert.Equal(t, "Mail", mail.NameRecord)
+	assert.Equal(t, "me@example.com", mail.Username)
+	assert.Equal(t, "s3cret", mail.Password)
+	assert.Equal(t, []string{"Recovery: blue", "URL: https://mail.example.com", "Notes: work"}, mail.MetaInfo)
+	assert.Equal(t, time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC).Unix(), mail.Created)
+
+	key := result.Notes[1].(*models.BinaryNote)
+	assert.Equal(t, "Deploy key - id_ed25519", key.NameRecord)
+	assert.Equal(t, []byte("-----BEGIN KEY-----"), key.Binary)
+	assert.Equal(t, []string{"File: id_ed25519", "Entry: Deploy key", "Group: Servers"}, key.MetaInfo)
+	assert.Equal(t, int64(1678200064), key.Created, "KDBX 4 time")
+
+	wifi := result.Notes[2].(*models.TextNote)
+	assert.Equal(t, "guest network", wifi.Text)
+	assert.Equal(t, []string{"Group: Servers"}, wifi.MetaInfo)
+
+	assert.Equal(t, []Skipped{
+		{Entry: "Deploy key - broken", Reason: "attachment data missing"},
+		{Entry: "Nothing", Reason: "empty entry"},
+		{Entry: "Deleted", Reason: "in the recycle bin"},
+	}, result.Skipped)
+}
+
+func TestParse_bitwarden(t *testing.T) {
+	export := `{
+  "encrypted": false,
+  "folders": [{"id": "f1", "name": "Work"}],
+  "items": [
+    {"type": 1, "name": "GitHub", "folderId": "f1", "favorite": true, "notes": "2fa on",
+     "creationDate": "2022-01-02T03:04:05.000Z",
+     "fields": [{"name": "PIN", "value": "1234", "type": 1}],
+     "login": {"username": "octo", "password": "pw", "totp": "otpauth://totp/x",
+               "uris": [{"match": null, "uri": "https://github.com"}]}},
+    {"type": 2, "name": "Alarm", "notes": "code 0000", "secureNote": {"type": 0}},
+    {"type": 3, "name": "Visa", "card": {"cardholderName": "Jane Doe", "brand": "Visa",
+      "number": "4111111111111111", "expMonth": "3", "expYear": "2029", "code": "123"}},
+    {"type": 4, "name": "Passport", "identity": {"title": "Ms", "firstName": "Jane", "middleName": null,
+      "lastName": "Doe", "passportNumber": "X123"}},
+    {"type": 1, "name": "Old", "deletedDate": "2023-01-01T00:00:00.000Z", "login": {"username": "x"}},
+    {"type": 5, "name": "SSH key"}
+  ]
+}`
+
+	result, err := Parse(Bitwarden, strings.NewReader(export))
+	if !assert.NoError(t, err) || !assert.Len(t, result.Notes, 4) {
+		return
+	}
+	login := result.Notes[0].(*models.CredentialNote)
+	assert.Equal(t, "octo", login.Username)
+	assert.Equal(t, "pw", login.Password)
+	assert.Equal(t, []string{"Folder: Work", "Favorite: yes", "PIN: 1234", "URL: https://github.com", "TOTP: otpauth://totp/x", "Notes: 2fa on"}, login.MetaInfo)
+	assert.Equal(t, time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC).Unix(), login.Created)
+
+	assert.Equal(t, "code 0000", result.Notes[1].(*models.TextNote).Text)
+
+	card := result.Notes[2].(*models.BankCardNote)
+	assert.Equal(t, "03/29", card.Expiration)
+	assert.Equal(t, "Jane Doe", card.Cardholder)
+	assert.Equal(t, "123", card.SecurityCode)
+	assert.Equal(t, []string{"Brand: Visa"}, card.MetaInfo)
+
+	passport := result.Notes[3].(*models.TextNote)
+	assert.Equal(t, "title: Ms\nfirstName: Jane\nlastName: Doe\npassportNumber: X123", passport.Text)
+
+	assert.Equal(t, []Skipped{
+		{Entry: "Old", Reason: "in the trash"},
+		{Entry: "SSH key", Reason: "unsupported item type 5"},
+	}, result.Skipped)
+
+	_, err = Parse(Bitwarden, strings.NewReader(`{"encrypted": true, "items": []}`))
+	assert.ErrorIs(t, err, ErrEncryptedExport)
+}
+
+func TestParse_csv(t *testing.T) {
+	tests := []struct {
+		name        string
+		export      string
+		want        []models.CredentialNote
+		wantSkipped []Skipped
+		wantErr     error
+	}{
+		{
+			name: "Chrome",
+			export: "\ufeffname,url,username,password,note\n" +
+				"Mail,https://mail.example.com/login,me,pw,\"work, main\"\n" +
+				",https://shop.example.com,buyer,pw2,\n" +
+				"Empty,https://empty.example.com,,,\n",
+			want: []models.CredentialNote{
+				{Username: "me", Password: "pw", BaseNote: models.BaseNote{NameRecord: "Mail", MetaInfo: []string{"URL: https://mail.example.com/login", "Notes: work, main"}}},
+				{Username: "buyer", Password: "pw2", BaseNote: models.BaseNote{NameRecord: "shop.example.com", MetaInfo: []string{"URL: https://shop.example.com"}}},
+			},
+			wantSkipped: []Skipped{{Entry: "Empty", Reason: "no username or password"}},
+		},
+		{
+			name: "Firefox",
+			export: `"url","username","password","httpRealm","formActionOrigin","guid","timeCreated","timeLastUsed","timePasswordChanged"` + "\n" +
+				`"https://forum.example.org","fox","pw","","https://forum.example.org","{abc}","1600000000000","1600000000000","1600000000000"` + "\n",
+			want: []models.CredentialNote{
+				{Username: "fox", Password: "pw", BaseNote: models.BaseNote{NameRecord: "forum.example.org", Created: 1600000000, MetaInfo: []string{
+					"URL: https://forum.example.org",
+					"formActionOrigin: https://forum.example.org",
+					"guid: {abc}",
+					"timeLastUsed: 2020-09-13T12:26:40Z",
+					"timePasswordChanged: 2020-09-13T12:26:40Z",
+				}}},
+			},
+		},
+		{name: "Not passwords", export: "date,amount\n2024-01-01,3\n", wantErr: ErrNotPasswordCSV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Parse(CSV, strings.NewReader(tt.export))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			if !assert.NoError(t, err) || !assert.Len(t, result.Notes, len(tt.want)) {
+				return
+			}
+			for i, want := range tt.want {
+				got := result.Notes[i].(*models.CredentialNote)
+				assert.Equal(t, models.CREDENTIAL, got.Type)
+				if want.Created != 0 {
+					assert.Equal(t, want.Created, got.Created)
+				}
+				assert.Equal(t, want.NameRecord, got.NameRecord)
+				assert.Equal(t, want.Username, got.Username)
+				assert.Equal(t, want.Password, got.Password)
+				assert.Equal(t, want.MetaInfo, got.MetaInfo)
+			}
+			assert.Equal(t, tt.wantSkipped, result.Skipped)
+		})
+	}
+}
+
+func TestResult_Report(t *testing.T) {
+	result, err := Parse(CSV, strings.NewReader("name,url,username,password\nMail,,me,pw\nNone,,,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, result.Report(&buf))
+	assert.Equal(t, "create  credential  Mail\nskip    None        no username or password\n1 to create, 1 skipped\n", buf.String())
+	assert.NotEqual(t, result.Notes[0].GetID(), [16]byte{})
+}
@@ -0,0 +1,255 @@
+package importers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+)
+
+// keePassFile is the part of a KeePass 2.x XML export the importer reads.
+// Entry history is left out.
+type keePassFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    struct {
+		RecycleBinEnabled bool            `xml:"RecycleBinEnabled"`
+		RecycleBinUUID    string          `xml:"RecycleBinUUID"`
+		Binaries          []keePassBinary `xml:"Binaries>Binary"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassBinary struct {
+	ID         string `xml:"ID,attr"`
+	Compressed bool   `xml:"Compressed,attr"`
+	Data       string `xml:",chardata"`
+}
+
+type keePassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keePassEntry `xml:"Entry"`
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+type keePassEntry struct {
+	Times struct {
+		CreationTime string `xml:"CreationTime"`
+	} `xml:"Times"`
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"String"`
+	Binaries []struct {
+		Key   string `xml:"Key"`
+		Value struct {
+			Ref  string `xml:"Ref,attr"`
+			Data string `xml:",chardata"`
+		} `xml:"Value"`
+	} `xml:"Binary"`
+}
+
+// parseKeePass makes a credential of every entry with a username or password,
+// a bank card of an entry with a CardNumber field and a text note of every
+// other entry with notes. Each attachment becomes a binary note of its own.
+// Entries in the recycle bin are skipped.
+func parseKeePass(r io.Reader) (*Result, error) {
+	var file keePassFile
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("reading KeePass XML: %w", err)
+	}
+	binaries := make(map[string][]byte, len(file.Meta.Binaries))
+	for _, b := range file.Meta.Binaries {
+		data, err := keePassData(b.Data, b.Compressed)
+		if err != nil {
+			return nil, fmt.Errorf("reading KeePass attachment %s: %w", b.ID, err)
+		}
+		binaries[b.ID] = data
+	}
+
+	result := &Result{}
+	recycleBin := ""
+	if file.Meta.RecycleBinEnabled {
+		recycleBin = file.Meta.RecycleBinUUID
+	}
+	var walk func(group keePassGroup, path string, recycled bool)
+	walk = func(group keePassGroup, path string, recycled bool) {
+		recycled = recycled || (recycleBin != "" && group.UUID == recycleBin)
+		for _, entry := range group.Entries {
+			if recycled {
+				result.skip(keePassTitle(entry), "in the recycle bin")
+				continue
+			}
+			keePassNotes(result, entry, path, binaries)
+		}
+		for _, sub := range group.Groups {
+			subPath := sub.Name
+			if path != "" {
+				subPath = path + "/" + sub.Name
+			}
+			walk(sub, subPath, recycled)
+		}
+	}
+	// The top group is the database itself and is not part of the path.
+	for _, group := range file.Root.Groups {
+		walk(group, "", false)
+	}
+	return result, nil
+}
+
+func keePassNotes(result *Result, entry keePassEntry, path string, binaries map[string][]byte) {
+	title := keePassTitle(entry)
+	created := keePassTime(entry.Times.CreationTime)
+	isCard := keePassString(entry, CardNumber) != "" &&
+		keePassString(entry, "UserName") == "" && keePassString(entry, "Password") == ""
+	var username, password, url, notes string
+	var card models.BankCardNote
+	custom := 0
+	var info meta
+	info.add("Group", path)
+	for _, s := range entry.Strings {
+		switch s.Key {
+		case "Title":
+		case "UserName":
+			username = s.Value
+		case "Password":
+			password = s.Value
+		case "URL":
+			url = s.Value
+		case "Notes":
+			notes = s.Value
+		default:
+			if isCard && keePassCardField(&card, s.Key, s.Value) {
+				continue
+			}
+			if strings.TrimSpace(s.Value) != "" {
+				custom++
+			}
+			info.add(s.Key, s.Value)
+		}
+	}
+
+	hasNote := true
+	switch {
+	case username != "" || password != "":
+		info.add("URL", url)
+		info.add("Notes", notes)
+		result.Notes = append(result.Notes, &models.CredentialNote{
+			Username: username,
+			Password: password,
+			BaseNote: newBase(models.CREDENTIAL, title, created, info),
+		})
+	case isCard:
+		info.add("URL", url)
+		info.add("Notes", notes)
+		card.BaseNote = newBase(models.CARD, title, created, info)
+		result.Notes = append(result.Notes, &card)
+	case notes != "" || url != "" || (custom > 0 && len(entry.Binaries) == 0):
+		info.add("URL", url)
+		result.Notes = append(result.Notes, &models.TextNote{
+			Text:     notes,
+			BaseNote: newBase(models.TEXT, title, created, info),
+		})
+	case len(entry.Binaries) == 0:
+		result.skip(title, "empty entry")
+	default:
+		hasNote = false
+	}
+
+	// Custom fields of an entry that only holds files go with the files.
+	only := !hasNote && len(entry.Binaries) == 1
+	var fileInfo meta
+	fileInfo.add("Group", path)
+	if !hasNote {
+		fileInfo = info
+	}
+	for _, attachment := range entry.Binaries {
+		data, ok := binaries[attachment.Value.Ref]
+		if attachment.Value.Ref == "" {
+			var err error
+			data, err = keePassData(attachment.Value.Data, false)
+			ok = err == nil
+		}
+		if !ok {
+			result.skip(title+" - "+attachment.Key, "attachment data missing")
+			continue
+		}
+		result.Notes = append(result.Notes, attachmentNote(title, attachment.Key, data, only, created, fileInfo))
+	}
+}
+
+// keePassCardField sets the card field labelled key, reporting whether there
+// is one.
+func keePassCardField(card *models.BankCardNote, key, value string) bool {
+	switch key {
+	case BankName:
+		card.Bank = value
+	case CardNumber:
+		card.Number = value
+	case CardExpiry:
+		card.Expiration = value
+	case Cardholder:
+		card.Cardholder = value
+	case SecurityCode:
+		card.SecurityCode = value
+	default:
+		return false
+	}
+	return true
+}
+
+func keePassString(entry keePassEntry, key string) string {
+	for _, s := range entry.Strings {
+		if s.Key == key {
+			return strings.TrimSpace(s.Value)
+		}
+	}
+	return ""
+}
+
+func keePassTitle(entry keePassEntry) string {
+	for _, s := range entry.Strings {
+		if s.Key == "Title" && strings.TrimSpace(s.Value) != "" {
+			return s.Value
+		}
+	}
+	return "Untitled"
+}
+
+func keePassData(encoded string, compressed bool) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || !compressed {
+		return data, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// secondsToUnixEpoch is the number of seconds from year 1 to 1970.
+const secondsToUnixEpoch = 62135596800
+
+// keePassTime reads an RFC 3339 time, or the base64 seconds since year 1
+// that KDBX 4 databases write.
+func keePassTime(s string) time.Time {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(raw) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(int64(binary.LittleEndian.Uint64(raw))-secondsToUnixEpoch, 0)
+}
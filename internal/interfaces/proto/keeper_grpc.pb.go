@@ -20,10 +20,21 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	NoteServices_AddNote_FullMethodName    = "/proto.NoteServices/AddNote"
-	NoteServices_DeleteNote_FullMethodName = "/proto.NoteServices/DeleteNote"
-	NoteServices_UpdateNote_FullMethodName = "/proto.NoteServices/UpdateNote"
-	NoteServices_GetNotes_FullMethodName   = "/proto.NoteServices/GetNotes"
+	NoteServices_AddNote_FullMethodName             = "/proto.NoteServices/AddNote"
+	NoteServices_DeleteNote_FullMethodName          = "/proto.NoteServices/DeleteNote"
+	NoteServices_UpdateNote_FullMethodName          = "/proto.NoteServices/UpdateNote"
+	NoteServices_GetNotes_FullMethodName            = "/proto.NoteServices/GetNotes"
+	NoteServices_SyncNotes_FullMethodName           = "/proto.NoteServices/SyncNotes"
+	NoteServices_WatchNotes_FullMethodName          = "/proto.NoteServices/WatchNotes"
+	NoteServices_UploadBlob_FullMethodName          = "/proto.NoteServices/UploadBlob"
+	NoteServices_DownloadBlob_FullMethodName        = "/proto.NoteServices/DownloadBlob"
+	NoteServices_GetBlobStatus_FullMethodName       = "/proto.NoteServices/GetBlobStatus"
+	NoteServices_DeleteBlob_FullMethodName          = "/proto.NoteServices/DeleteBlob"
+	NoteServices_ListNoteRevisions_FullMethodName   = "/proto.NoteServices/ListNoteRevisions"
+	NoteServices_RestoreNoteRevision_FullMethodName = "/proto.NoteServices/RestoreNoteRevision"
+	NoteServices_ListTrash_FullMethodName           = "/proto.NoteServices/ListTrash"
+	NoteServices_RestoreNote_FullMethodName         = "/proto.NoteServices/RestoreNote"
+	NoteServices_EmptyTrash_FullMethodName          = "/proto.NoteServices/EmptyTrash"
 )
 
 // NoteServicesClient is the client API for NoteServices service.
@@ -34,6 +45,25 @@ type NoteServicesClient interface {
 	DeleteNote(ctx context.Context, in *NoteRequest, opts ...grpc.CallOption) (*empty.Empty, error)
 	UpdateNote(ctx context.Context, in *Note, opts ...grpc.CallOption) (*empty.Empty, error)
 	GetNotes(ctx context.Context, in *NoteRequest, opts ...grpc.CallOption) (*NoteList, error)
+	SyncNotes(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error)
+	// WatchNotes streams the changes made by the other sessions of the user
+	WatchNotes(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[NoteEvent], error)
+	// UploadBlob stores chunks in order; chunks the server already has are skipped
+	UploadBlob(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[BlobChunk, BlobStatus], error)
+	DownloadBlob(ctx context.Context, in *BlobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BlobChunk], error)
+	GetBlobStatus(ctx context.Context, in *BlobRequest, opts ...grpc.CallOption) (*BlobStatus, error)
+	DeleteBlob(ctx context.Context, in *BlobRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	// ListNoteRevisions returns the revisions an update replaced, as far as
+	// the server keeps them
+	ListNoteRevisions(ctx context.Context, in *NoteRequest, opts ...grpc.CallOption) (*NoteRevisionList, error)
+	// RestoreNoteRevision writes a kept revision back as the next revision
+	RestoreNoteRevision(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*Note, error)
+	// ListTrash returns the deleted notes the server has not purged yet
+	ListTrash(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*TrashList, error)
+	// RestoreNote takes a note out of the trash as its next revision
+	RestoreNote(ctx context.Context, in *NoteRequest, opts ...grpc.CallOption) (*Note, error)
+	// EmptyTrash deletes the notes in the trash for good
+	EmptyTrash(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*TrashCount, error)
 }
 
 type noteServicesClient struct {
@@ -84,6 +114,137 @@ func (c *noteServicesClient) GetNotes(ctx context.Context, in *NoteRequest, opts
 	return out, nil
 }
 
+func (c *noteServicesClient) SyncNotes(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SyncResponse)
+	err := c.cc.Invoke(ctx, NoteServices_SyncNotes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *noteServicesClient) WatchNotes(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[NoteEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &NoteServices_ServiceDesc.Streams[0], NoteServices_WatchNotes_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[empty.Empty, NoteEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NoteServices_WatchNotesClient = grpc.ServerStreamingClient[NoteEvent]
+
+func (c *noteServicesClient) UploadBlob(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[BlobChunk, BlobStatus], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &NoteServices_ServiceDesc.Streams[1], NoteServices_UploadBlob_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BlobChunk, BlobStatus]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NoteServices_UploadBlobClient = grpc.ClientStreamingClient[BlobChunk, BlobStatus]
+
+func (c *noteServicesClient) DownloadBlob(ctx context.Context, in *BlobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BlobChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &NoteServices_ServiceDesc.Streams[2], NoteServices_DownloadBlob_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BlobRequest, BlobChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NoteServices_DownloadBlobClient = grpc.ServerStreamingClient[BlobChunk]
+
+func (c *noteServicesClient) GetBlobStatus(ctx context.Context, in *BlobRequest, opts ...grpc.CallOption) (*BlobStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BlobStatus)
+	err := c.cc.Invoke(ctx, NoteServices_GetBlobStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *noteServicesClient) DeleteBlob(ctx context.Context, in *BlobRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, NoteServices_DeleteBlob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *noteServicesClient) ListNoteRevisions(ctx context.Context, in *NoteRequest, opts ...grpc.CallOption) (*NoteRevisionList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NoteRevisionList)
+	err := c.cc.Invoke(ctx, NoteServices_ListNoteRevisions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *noteServicesClient) RestoreNoteRevision(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*Note, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Note)
+	err := c.cc.Invoke(ctx, NoteServices_RestoreNoteRevision_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *noteServicesClient) ListTrash(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*TrashList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TrashList)
+	err := c.cc.Invoke(ctx, NoteServices_ListTrash_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *noteServicesClient) RestoreNote(ctx context.Context, in *NoteRequest, opts ...grpc.CallOption) (*Note, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Note)
+	err := c.cc.Invoke(ctx, NoteServices_RestoreNote_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *noteServicesClient) EmptyTrash(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*TrashCount, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TrashCount)
+	err := c.cc.Invoke(ctx, NoteServices_EmptyTrash_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NoteServicesServer is the server API for NoteServices service.
 // All implementations must embed UnimplementedNoteServicesServer
 // for forward compatibility.
@@ -92,6 +253,25 @@ type NoteServicesServer interface {
 	DeleteNote(context.Context, *NoteRequest) (*empty.Empty, error)
 	UpdateNote(context.Context, *Note) (*empty.Empty, error)
 	GetNotes(context.Context, *NoteRequest) (*NoteList, error)
+	SyncNotes(context.Context, *SyncRequest) (*SyncResponse, error)
+	// WatchNotes streams the changes made by the other sessions of the user
+	WatchNotes(*empty.Empty, grpc.ServerStreamingServer[NoteEvent]) error
+	// UploadBlob stores chunks in order; chunks the server already has are skipped
+	UploadBlob(grpc.ClientStreamingServer[BlobChunk, BlobStatus]) error
+	DownloadBlob(*BlobRequest, grpc.ServerStreamingServer[BlobChunk]) error
+	GetBlobStatus(context.Context, *BlobRequest) (*BlobStatus, error)
+	DeleteBlob(context.Context, *BlobRequest) (*empty.Empty, error)
+	// ListNoteRevisions returns the revisions an update replaced, as far as
+	// the server keeps them
+	ListNoteRevisions(context.Context, *NoteRequest) (*NoteRevisionList, error)
+	// RestoreNoteRevision writes a kept revision back as the next revision
+	RestoreNoteRevision(context.Context, *RestoreRequest) (*Note, error)
+	// ListTrash returns the deleted notes the server has not purged yet
+	ListTrash(context.Context, *empty.Empty) (*TrashList, error)
+	// RestoreNote takes a note out of the trash as its next revision
+	RestoreNote(context.Context, *NoteRequest) (*Note, error)
+	// EmptyTrash deletes the notes in the trash for good
+	EmptyTrash(context.Context, *empty.Empty) (*TrashCount, error)
 	mustEmbedUnimplementedNoteServicesServer()
 }
 
@@ -114,6 +294,39 @@ func (UnimplementedNoteServicesServer) UpdateNote(context.Context, *Note) (*empt
 func (UnimplementedNoteServicesServer) GetNotes(context.Context, *NoteRequest) (*NoteList, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetNotes not implemented")
 }
+func (UnimplementedNoteServicesServer) SyncNotes(context.Context, *SyncRequest) (*SyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SyncNotes not implemented")
+}
+func (UnimplementedNoteServicesServer) WatchNotes(*empty.Empty, grpc.ServerStreamingServer[NoteEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchNotes not implemented")
+}
+func (UnimplementedNoteServicesServer) UploadBlob(grpc.ClientStreamingServer[BlobChunk, BlobStatus]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadBlob not implemented")
+}
+func (UnimplementedNoteServicesServer) DownloadBlob(*BlobRequest, grpc.ServerStreamingServer[BlobChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadBlob not implemented")
+}
+func (UnimplementedNoteServicesServer) GetBlobStatus(context.Context, *BlobRequest) (*BlobStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlobStatus not implemented")
+}
+func (UnimplementedNoteServicesServer) DeleteBlob(context.Context, *BlobRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteBlob not implemented")
+}
+func (UnimplementedNoteServicesServer) ListNoteRevisions(context.Context, *NoteRequest) (*NoteRevisionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNoteRevisions not implemented")
+}
+func (UnimplementedNoteServicesServer) RestoreNoteRevision(context.Context, *RestoreRequest) (*Note, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreNoteRevision not implemented")
+}
+func (UnimplementedNoteServicesServer) ListTrash(context.Context, *empty.Empty) (*TrashList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrash not implemented")
+}
+func (UnimplementedNoteServicesServer) RestoreNote(context.Context, *NoteRequest) (*Note, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreNote not implemented")
+}
+func (UnimplementedNoteServicesServer) EmptyTrash(context.Context, *empty.Empty) (*TrashCount, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EmptyTrash not implemented")
+}
 func (UnimplementedNoteServicesServer) mustEmbedUnimplementedNoteServicesServer() {}
 func (UnimplementedNoteServicesServer) testEmbeddedByValue()                      {}
 
@@ -207,6 +420,179 @@ func _NoteServices_GetNotes_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NoteServices_SyncNotes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NoteServicesServer).SyncNotes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NoteServices_SyncNotes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NoteServicesServer).SyncNotes(ctx, req.(*SyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NoteServices_WatchNotes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(empty.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NoteServicesServer).WatchNotes(m, &grpc.GenericServerStream[empty.Empty, NoteEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NoteServices_WatchNotesServer = grpc.ServerStreamingServer[NoteEvent]
+
+func _NoteServices_UploadBlob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(NoteServicesServer).UploadBlob(&grpc.GenericServerStream[BlobChunk, BlobStatus]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NoteServices_UploadBlobServer = grpc.ClientStreamingServer[BlobChunk, BlobStatus]
+
+func _NoteServices_DownloadBlob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NoteServicesServer).DownloadBlob(m, &grpc.GenericServerStream[BlobRequest, BlobChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NoteServices_DownloadBlobServer = grpc.ServerStreamingServer[BlobChunk]
+
+func _NoteServices_GetBlobStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NoteServicesServer).GetBlobStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NoteServices_GetBlobStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NoteServicesServer).GetBlobStatus(ctx, req.(*BlobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NoteServices_DeleteBlob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NoteServicesServer).DeleteBlob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NoteServices_DeleteBlob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NoteServicesServer).DeleteBlob(ctx, req.(*BlobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NoteServices_ListNoteRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NoteServicesServer).ListNoteRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NoteServices_ListNoteRevisions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NoteServicesServer).ListNoteRevisions(ctx, req.(*NoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NoteServices_RestoreNoteRevision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NoteServicesServer).RestoreNoteRevision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NoteServices_RestoreNoteRevision_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NoteServicesServer).RestoreNoteRevision(ctx, req.(*RestoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NoteServices_ListTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NoteServicesServer).ListTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NoteServices_ListTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NoteServicesServer).ListTrash(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NoteServices_RestoreNote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NoteServicesServer).RestoreNote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NoteServices_RestoreNote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NoteServicesServer).RestoreNote(ctx, req.(*NoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NoteServices_EmptyTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NoteServicesServer).EmptyTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NoteServices_EmptyTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NoteServicesServer).EmptyTrash(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // NoteServices_ServiceDesc is the grpc.ServiceDesc for NoteServices service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -230,14 +616,76 @@ var NoteServices_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetNotes",
 			Handler:    _NoteServices_GetNotes_Handler,
 		},
+		{
+			MethodName: "SyncNotes",
+			Handler:    _NoteServices_SyncNotes_Handler,
+		},
+		{
+			MethodName: "GetBlobStatus",
+			Handler:    _NoteServices_GetBlobStatus_Handler,
+		},
+		{
+			MethodName: "DeleteBlob",
+			Handler:    _NoteServices_DeleteBlob_Handler,
+		},
+		{
+			MethodName: "ListNoteRevisions",
+			Handler:    _NoteServices_ListNoteRevisions_Handler,
+		},
+		{
+			MethodName: "RestoreNoteRevision",
+			Handler:    _NoteServices_RestoreNoteRevision_Handler,
+		},
+		{
+			MethodName: "ListTrash",
+			Handler:    _NoteServices_ListTrash_Handler,
+		},
+		{
+			MethodName: "RestoreNote",
+			Handler:    _NoteServices_RestoreNote_Handler,
+		},
+		{
+			MethodName: "EmptyTrash",
+			Handler:    _NoteServices_EmptyTrash_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchNotes",
+			Handler:       _NoteServices_WatchNotes_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "UploadBlob",
+			Handler:       _NoteServices_UploadBlob_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadBlob",
+			Handler:       _NoteServices_DownloadBlob_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/interfaces/proto/keeper.proto",
 }
 
 const (
-	UserServices_Register_FullMethodName = "/proto.UserServices/Register"
-	UserServices_Login_FullMethodName    = "/proto.UserServices/Login"
+	UserServices_Register_FullMethodName       = "/proto.UserServices/Register"
+	UserServices_Login_FullMethodName          = "/proto.UserServices/Login"
+	UserServices_RekeyVault_FullMethodName     = "/proto.UserServices/RekeyVault"
+	UserServices_ChangePassword_FullMethodName = "/proto.UserServices/ChangePassword"
+	UserServices_Refresh_FullMethodName        = "/proto.UserServices/Refresh"
+	UserServices_Logout_FullMethodName         = "/proto.UserServices/Logout"
+	UserServices_ListSessions_FullMethodName   = "/proto.UserServices/ListSessions"
+	UserServices_RevokeSession_FullMethodName  = "/proto.UserServices/RevokeSession"
+	UserServices_RegisterSrp_FullMethodName    = "/proto.UserServices/RegisterSrp"
+	UserServices_LoginStart_FullMethodName     = "/proto.UserServices/LoginStart"
+	UserServices_LoginFinish_FullMethodName    = "/proto.UserServices/LoginFinish"
+	UserServices_UpgradeToSrp_FullMethodName   = "/proto.UserServices/UpgradeToSrp"
+	UserServices_VerifyMfa_FullMethodName      = "/proto.UserServices/VerifyMfa"
+	UserServices_EnrollTotp_FullMethodName     = "/proto.UserServices/EnrollTotp"
+	UserServices_ConfirmTotp_FullMethodName    = "/proto.UserServices/ConfirmTotp"
+	UserServices_DisableTotp_FullMethodName    = "/proto.UserServices/DisableTotp"
 )
 
 // UserServicesClient is the client API for UserServices service.
@@ -246,6 +694,20 @@ const (
 type UserServicesClient interface {
 	Register(ctx context.Context, in *User, opts ...grpc.CallOption) (*JwtToken, error)
 	Login(ctx context.Context, in *User, opts ...grpc.CallOption) (*JwtToken, error)
+	RekeyVault(ctx context.Context, in *VaultRekey, opts ...grpc.CallOption) (*empty.Empty, error)
+	ChangePassword(ctx context.Context, in *PasswordChange, opts ...grpc.CallOption) (*JwtToken, error)
+	Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*JwtToken, error)
+	Logout(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
+	ListSessions(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*SessionList, error)
+	RevokeSession(ctx context.Context, in *SessionRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	RegisterSrp(ctx context.Context, in *SrpRegister, opts ...grpc.CallOption) (*JwtToken, error)
+	LoginStart(ctx context.Context, in *SrpStart, opts ...grpc.CallOption) (*SrpChallenge, error)
+	LoginFinish(ctx context.Context, in *SrpProof, opts ...grpc.CallOption) (*SrpSession, error)
+	UpgradeToSrp(ctx context.Context, in *SrpUpgrade, opts ...grpc.CallOption) (*empty.Empty, error)
+	VerifyMfa(ctx context.Context, in *MfaRequest, opts ...grpc.CallOption) (*JwtToken, error)
+	EnrollTotp(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*TotpEnrollment, error)
+	ConfirmTotp(ctx context.Context, in *TotpCode, opts ...grpc.CallOption) (*RecoveryCodes, error)
+	DisableTotp(ctx context.Context, in *TotpCode, opts ...grpc.CallOption) (*empty.Empty, error)
 }
 
 type userServicesClient struct {
@@ -276,20 +738,174 @@ func (c *userServicesClient) Login(ctx context.Context, in *User, opts ...grpc.C
 	return out, nil
 }
 
-// UserServicesServer is the server API for UserServices service.
-// All implementations must embed UnimplementedUserServicesServer
-// for forward compatibility.
-type UserServicesServer interface {
-	Register(context.Context, *User) (*JwtToken, error)
-	Login(context.Context, *User) (*JwtToken, error)
-	mustEmbedUnimplementedUserServicesServer()
+func (c *userServicesClient) RekeyVault(ctx context.Context, in *VaultRekey, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, UserServices_RekeyVault_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-// UnimplementedUserServicesServer must be embedded to have
-// forward compatible implementations.
-//
-// NOTE: this should be embedded by value instead of pointer to avoid a nil
-// pointer dereference when methods are called.
+func (c *userServicesClient) ChangePassword(ctx context.Context, in *PasswordChange, opts ...grpc.CallOption) (*JwtToken, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JwtToken)
+	err := c.cc.Invoke(ctx, UserServices_ChangePassword_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*JwtToken, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JwtToken)
+	err := c.cc.Invoke(ctx, UserServices_Refresh_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) Logout(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, UserServices_Logout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) ListSessions(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*SessionList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SessionList)
+	err := c.cc.Invoke(ctx, UserServices_ListSessions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) RevokeSession(ctx context.Context, in *SessionRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, UserServices_RevokeSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) RegisterSrp(ctx context.Context, in *SrpRegister, opts ...grpc.CallOption) (*JwtToken, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JwtToken)
+	err := c.cc.Invoke(ctx, UserServices_RegisterSrp_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) LoginStart(ctx context.Context, in *SrpStart, opts ...grpc.CallOption) (*SrpChallenge, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SrpChallenge)
+	err := c.cc.Invoke(ctx, UserServices_LoginStart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) LoginFinish(ctx context.Context, in *SrpProof, opts ...grpc.CallOption) (*SrpSession, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SrpSession)
+	err := c.cc.Invoke(ctx, UserServices_LoginFinish_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) UpgradeToSrp(ctx context.Context, in *SrpUpgrade, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, UserServices_UpgradeToSrp_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) VerifyMfa(ctx context.Context, in *MfaRequest, opts ...grpc.CallOption) (*JwtToken, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JwtToken)
+	err := c.cc.Invoke(ctx, UserServices_VerifyMfa_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) EnrollTotp(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*TotpEnrollment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TotpEnrollment)
+	err := c.cc.Invoke(ctx, UserServices_EnrollTotp_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) ConfirmTotp(ctx context.Context, in *TotpCode, opts ...grpc.CallOption) (*RecoveryCodes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecoveryCodes)
+	err := c.cc.Invoke(ctx, UserServices_ConfirmTotp_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServicesClient) DisableTotp(ctx context.Context, in *TotpCode, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, UserServices_DisableTotp_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserServicesServer is the server API for UserServices service.
+// All implementations must embed UnimplementedUserServicesServer
+// for forward compatibility.
+type UserServicesServer interface {
+	Register(context.Context, *User) (*JwtToken, error)
+	Login(context.Context, *User) (*JwtToken, error)
+	RekeyVault(context.Context, *VaultRekey) (*empty.Empty, error)
+	ChangePassword(context.Context, *PasswordChange) (*JwtToken, error)
+	Refresh(context.Context, *RefreshRequest) (*JwtToken, error)
+	Logout(context.Context, *empty.Empty) (*empty.Empty, error)
+	ListSessions(context.Context, *empty.Empty) (*SessionList, error)
+	RevokeSession(context.Context, *SessionRequest) (*empty.Empty, error)
+	RegisterSrp(context.Context, *SrpRegister) (*JwtToken, error)
+	LoginStart(context.Context, *SrpStart) (*SrpChallenge, error)
+	LoginFinish(context.Context, *SrpProof) (*SrpSession, error)
+	UpgradeToSrp(context.Context, *SrpUpgrade) (*empty.Empty, error)
+	VerifyMfa(context.Context, *MfaRequest) (*JwtToken, error)
+	EnrollTotp(context.Context, *empty.Empty) (*TotpEnrollment, error)
+	ConfirmTotp(context.Context, *TotpCode) (*RecoveryCodes, error)
+	DisableTotp(context.Context, *TotpCode) (*empty.Empty, error)
+	mustEmbedUnimplementedUserServicesServer()
+}
+
+// UnimplementedUserServicesServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
 type UnimplementedUserServicesServer struct{}
 
 func (UnimplementedUserServicesServer) Register(context.Context, *User) (*JwtToken, error) {
@@ -298,6 +914,48 @@ func (UnimplementedUserServicesServer) Register(context.Context, *User) (*JwtTok
 func (UnimplementedUserServicesServer) Login(context.Context, *User) (*JwtToken, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
 }
+func (UnimplementedUserServicesServer) RekeyVault(context.Context, *VaultRekey) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RekeyVault not implemented")
+}
+func (UnimplementedUserServicesServer) ChangePassword(context.Context, *PasswordChange) (*JwtToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangePassword not implemented")
+}
+func (UnimplementedUserServicesServer) Refresh(context.Context, *RefreshRequest) (*JwtToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refresh not implemented")
+}
+func (UnimplementedUserServicesServer) Logout(context.Context, *empty.Empty) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
+}
+func (UnimplementedUserServicesServer) ListSessions(context.Context, *empty.Empty) (*SessionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSessions not implemented")
+}
+func (UnimplementedUserServicesServer) RevokeSession(context.Context, *SessionRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeSession not implemented")
+}
+func (UnimplementedUserServicesServer) RegisterSrp(context.Context, *SrpRegister) (*JwtToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterSrp not implemented")
+}
+func (UnimplementedUserServicesServer) LoginStart(context.Context, *SrpStart) (*SrpChallenge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LoginStart not implemented")
+}
+func (UnimplementedUserServicesServer) LoginFinish(context.Context, *SrpProof) (*SrpSession, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LoginFinish not implemented")
+}
+func (UnimplementedUserServicesServer) UpgradeToSrp(context.Context, *SrpUpgrade) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpgradeToSrp not implemented")
+}
+func (UnimplementedUserServicesServer) VerifyMfa(context.Context, *MfaRequest) (*JwtToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyMfa not implemented")
+}
+func (UnimplementedUserServicesServer) EnrollTotp(context.Context, *empty.Empty) (*TotpEnrollment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnrollTotp not implemented")
+}
+func (UnimplementedUserServicesServer) ConfirmTotp(context.Context, *TotpCode) (*RecoveryCodes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmTotp not implemented")
+}
+func (UnimplementedUserServicesServer) DisableTotp(context.Context, *TotpCode) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableTotp not implemented")
+}
 func (UnimplementedUserServicesServer) mustEmbedUnimplementedUserServicesServer() {}
 func (UnimplementedUserServicesServer) testEmbeddedByValue()                      {}
 
@@ -355,6 +1013,258 @@ func _UserServices_Login_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserServices_RekeyVault_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VaultRekey)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).RekeyVault(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_RekeyVault_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).RekeyVault(ctx, req.(*VaultRekey))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_ChangePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PasswordChange)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).ChangePassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_ChangePassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).ChangePassword(ctx, req.(*PasswordChange))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_Refresh_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).Refresh(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_Refresh_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).Refresh(ctx, req.(*RefreshRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_Logout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).Logout(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_ListSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).ListSessions(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_RevokeSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).RevokeSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_RevokeSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).RevokeSession(ctx, req.(*SessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_RegisterSrp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SrpRegister)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).RegisterSrp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_RegisterSrp_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).RegisterSrp(ctx, req.(*SrpRegister))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_LoginStart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SrpStart)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).LoginStart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_LoginStart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).LoginStart(ctx, req.(*SrpStart))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_LoginFinish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SrpProof)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).LoginFinish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_LoginFinish_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).LoginFinish(ctx, req.(*SrpProof))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_UpgradeToSrp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SrpUpgrade)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).UpgradeToSrp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_UpgradeToSrp_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).UpgradeToSrp(ctx, req.(*SrpUpgrade))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_VerifyMfa_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MfaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).VerifyMfa(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_VerifyMfa_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).VerifyMfa(ctx, req.(*MfaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_EnrollTotp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).EnrollTotp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_EnrollTotp_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).EnrollTotp(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_ConfirmTotp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TotpCode)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).ConfirmTotp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_ConfirmTotp_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).ConfirmTotp(ctx, req.(*TotpCode))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserServices_DisableTotp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TotpCode)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServicesServer).DisableTotp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserServices_DisableTotp_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServicesServer).DisableTotp(ctx, req.(*TotpCode))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // UserServices_ServiceDesc is the grpc.ServiceDesc for UserServices service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -370,6 +1280,62 @@ var UserServices_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Login",
 			Handler:    _UserServices_Login_Handler,
 		},
+		{
+			MethodName: "RekeyVault",
+			Handler:    _UserServices_RekeyVault_Handler,
+		},
+		{
+			MethodName: "ChangePassword",
+			Handler:    _UserServices_ChangePassword_Handler,
+		},
+		{
+			MethodName: "Refresh",
+			Handler:    _UserServices_Refresh_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _UserServices_Logout_Handler,
+		},
+		{
+			MethodName: "ListSessions",
+			Handler:    _UserServices_ListSessions_Handler,
+		},
+		{
+			MethodName: "RevokeSession",
+			Handler:    _UserServices_RevokeSession_Handler,
+		},
+		{
+			MethodName: "RegisterSrp",
+			Handler:    _UserServices_RegisterSrp_Handler,
+		},
+		{
+			MethodName: "LoginStart",
+			Handler:    _UserServices_LoginStart_Handler,
+		},
+		{
+			MethodName: "LoginFinish",
+			Handler:    _UserServices_LoginFinish_Handler,
+		},
+		{
+			MethodName: "UpgradeToSrp",
+			Handler:    _UserServices_UpgradeToSrp_Handler,
+		},
+		{
+			MethodName: "VerifyMfa",
+			Handler:    _UserServices_VerifyMfa_Handler,
+		},
+		{
+			MethodName: "EnrollTotp",
+			Handler:    _UserServices_EnrollTotp_Handler,
+		},
+		{
+			MethodName: "ConfirmTotp",
+			Handler:    _UserServices_ConfirmTotp_Handler,
+		},
+		{
+			MethodName: "DisableTotp",
+			Handler:    _UserServices_DisableTotp_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/interfaces/proto/keeper.proto",
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/database"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// maxBlobChunk limits the size of one encrypted chunk.
+const maxBlobChunk = 1 << 20
+
+// UploadBlob stores the chunks of one blob. Every chunk is committed on its
+// own, so an interrupted upload keeps what arrived and continues from the
+// index GetBlobStatus reports.
+func (s *Controller) UploadBlob(stream pb.NoteServices_UploadBlobServer) error {
+	ctx := stream.Context()
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "UploadBlob",
+		"user":   userCtx.Email,
+	})
+
+	var blobID uuid.UUID
+	var blob *models.Blob
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		id, err := uuid.Parse(chunk.BlobId)
+		if err != nil {
+			log.Error(err.Error())
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if blobID != uuid.Nil && id != blobID {
+			return status.Error(codes.InvalidArgument, "an upload carries a single blob")
+		}
+		blobID = id
+		if len(chunk.Data) > maxBlobChunk {
+			return status.Error(codes.InvalidArgument, "blob chunk is too large")
+		}
+		blob, err = s.db.AddBlobChunk(ctx, id, chunk.Index, chunk.Data, chunk.Last)
+		if err != nil {
+			return blobError(log, err, chunk.BlobId)
+		}
+	}
+	if blob == nil {
+		return status.Error(codes.InvalidArgument, "no blob chunks")
+	}
+	log.Infof("blob %s has %d chunks, complete: %v", blob.ID, blob.Chunks, blob.Complete)
+	return stream.SendAndClose(blobStatus(blob))
+}
+
+// DownloadBlob streams a complete blob from req.FromIndex on.
+func (s *Controller) DownloadBlob(req *pb.BlobRequest, stream pb.NoteServices_DownloadBlobServer) error {
+	ctx := stream.Context()
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "DownloadBlob",
+		"user":   userCtx.Email,
+	})
+
+	id, err := uuid.Parse(req.BlobId)
+	if err != nil || req.FromIndex < 0 {
+		return status.Error(codes.InvalidArgument, "invalid blob request")
+	}
+	blob, err := s.db.GetBlob(ctx, id)
+	if err != nil {
+		return blobError(log, err, req.BlobId)
+	}
+	if !blob.Complete {
+		return status.Error(codes.FailedPrecondition, "blob upload is not finished")
+	}
+	for index := req.FromIndex; index < blob.Chunks; index++ {
+		data, err := s.db.GetBlobChunk(ctx, id, index)
+		if err != nil {
+			return blobError(log, err, req.BlobId)
+		}
+		chunk := &pb.BlobChunk{BlobId: req.BlobId, Index: index, Data: data, Last: index == blob.Chunks-1}
+		if err = stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Controller) GetBlobStatus(ctx context.Context, req *pb.BlobRequest) (*pb.BlobStatus, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "GetBlobStatus",
+		"user":   userCtx.Email,
+	})
+
+	id, err := uuid.Parse(req.BlobId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	blob, err := s.db.GetBlob(ctx, id)
+	if err != nil {
+		return nil, blobError(log, err, req.BlobId)
+	}
+	return blobStatus(blob), nil
+}
+
+func (s *Controller) DeleteBlob(ctx context.Context, req *pb.BlobRequest) (*empty.Empty, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "DeleteBlob",
+		"user":   userCtx.Email,
+	})
+
+	id, err := uuid.Parse(req.BlobId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ok, err = s.db.DeleteBlob(ctx, id)
+	if err != nil {
+		return nil, blobError(log, err, req.BlobId)
+	}
+	if !ok {
+		return nil, status.Error(codes.NotFound, req.BlobId)
+	}
+	log.Infof("blob %s deleted", id)
+	return &empty.Empty{}, nil
+}
+
+func blobStatus(blob *models.Blob) *pb.BlobStatus {
+	return &pb.BlobStatus{BlobId: blob.ID.String(), NextIndex: blob.Chunks, Complete: blob.Complete, Size: blob.Size}
+}
+
+// blobError converts a failed blob operation. Out-of-order and late chunks
+// become FailedPrecondition: the client asks GetBlobStatus where to resume.
+// So does deleting a blob a note still refers to.
+func blobError(log *logrus.Entry, err error, id string) error {
+	switch {
+	case errors.Is(err, database.ErrChunkOutOfOrder), errors.Is(err, database.ErrBlobComplete), errors.Is(err, database.ErrBlobInUse):
+		log.Warn(err.Error())
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, id)
+	case errors.Is(err, database.ErrUserNotFound):
+		log.Warn("Context not found")
+		return status.Error(codes.Unauthenticated, "User not authenticated")
+	}
+	log.Error(err.Error())
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/database"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Controller) ListNoteRevisions(ctx context.Context, req *pb.NoteRequest) (*pb.NoteRevisionList, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "ListNoteRevisions",
+		"user":   userCtx.Email,
+	})
+
+	id, err := uuid.Parse(req.IdNote)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	revisions, err := s.db.ListSecretRevisions(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrUserNotFound) {
+			log.Warn("Context not found")
+			return nil, status.Error(codes.Unauthenticated, "User not authenticated")
+		}
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	list := &pb.NoteRevisionList{Revisions: make([]*pb.NoteRevision, 0, len(*revisions))}
+	for _, revision := range *revisions {
+		list.Revisions = append(list.Revisions, interfaces.RevisionToDto(revision))
+	}
+	return list, nil
+}
+
+// RestoreNoteRevision writes a kept revision back. A note that changed since
+// req.Current is a conflict like any other write; a revision the server no
+// longer keeps is NotFound.
+func (s *Controller) RestoreNoteRevision(ctx context.Context, req *pb.RestoreRequest) (*pb.Note, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "RestoreNoteRevision",
+		"user":   userCtx.Email,
+	})
+
+	id, err := uuid.Parse(req.IdNote)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	restored, err := s.db.RestoreSecretRevision(ctx, id, req.Revision, req.Current)
+	if err != nil {
+		return nil, noteWriteError(log, err, req.IdNote)
+	}
+	return interfaces.EntityToDto(*restored), nil
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/auth"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// maxMfaAttempts is how many wrong codes one login may send before it has to
+// start over with the password.
+const maxMfaAttempts = 5
+
+var mfaChallenges = newPendingStore[*mfaChallenge](5 * time.Minute)
+
+type mfaChallenge struct {
+	user     *models.User
+	attempts atomic.Int32
+}
+
+func (s *Controller) VerifyMfa(ctx context.Context, req *pb.MfaRequest) (*pb.JwtToken, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "VerifyMfa",
+	})
+
+	challenge, ok := mfaChallenges.get(req.MfaToken)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "login expired")
+	}
+	log = log.WithField("user", challenge.user.Email)
+	if err := checkCertIdentity(ctx, challenge.user.Email); err != nil {
+		return nil, err
+	}
+	if challenge.attempts.Add(1) > maxMfaAttempts {
+		mfaChallenges.take(req.MfaToken)
+		log.Warn("too many wrong codes")
+		return nil, status.Error(codes.Unauthenticated, "login expired")
+	}
+	if err := s.checkSecondFactor(ctx, challenge.user, req.Code); err != nil {
+		return nil, err
+	}
+	if _, ok = mfaChallenges.take(req.MfaToken); !ok {
+		return nil, status.Error(codes.Unauthenticated, "login expired")
+	}
+
+	token, err := s.openSession(ctx, challenge.user)
+	if err != nil {
+		log.WithError(err).Error("could not open session")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return token, nil
+}
+
+func (s *Controller) EnrollTotp(ctx context.Context, _ *empty.Empty) (*pb.TotpEnrollment, error) {
+	getUser, log, err := s.mfaUser(ctx, "EnrollTotp")
+	if err != nil {
+		return nil, err
+	}
+	if getUser.TotpEnabled {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+
+	secret, err := auth.NewTotpSecret()
+	if err != nil {
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if err = s.db.SetTotp(ctx, models.User{ID: getUser.ID, TotpSecret: secret}); err != nil {
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.TotpEnrollment{Secret: secret, Uri: auth.TotpURI(getUser.Email, secret)}, nil
+}
+
+func (s *Controller) ConfirmTotp(ctx context.Context, req *pb.TotpCode) (*pb.RecoveryCodes, error) {
+	getUser, log, err := s.mfaUser(ctx, "ConfirmTotp")
+	if err != nil {
+		return nil, err
+	}
+	if getUser.TotpEnabled {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+	if getUser.TotpSecret == "" {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor enrollment is not started")
+	}
+	step, ok := auth.ValidateTotp(getUser.TotpSecret, req.Code, s.now())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "invalid code")
+	}
+
+	recovery, hashes, err := auth.NewRecoveryCodes()
+	if err != nil {
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	err = s.db.SetTotp(ctx, models.User{
+		ID:            getUser.ID,
+		TotpSecret:    getUser.TotpSecret,
+		TotpEnabled:   true,
+		TotpLastStep:  step,
+		RecoveryCodes: hashes,
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	log.Info("two-factor authentication enabled")
+	return &pb.RecoveryCodes{Codes: recovery}, nil
+}
+
+func (s *Controller) DisableTotp(ctx context.Context, req *pb.TotpCode) (*empty.Empty, error) {
+	getUser, log, err := s.mfaUser(ctx, "DisableTotp")
+	if err != nil {
+		return nil, err
+	}
+	if !getUser.TotpEnabled {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is not enabled")
+	}
+	if err = s.checkSecondFactor(ctx, getUser, req.Code); err != nil {
+		return nil, err
+	}
+	if err = s.db.SetTotp(ctx, models.User{ID: getUser.ID}); err != nil {
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	log.Info("two-factor authentication disabled")
+	return &empty.Empty{}, nil
+}
+
+// startSession opens a session for an authenticated user, or returns an MFA
+// token when the account requires a second factor first.
+func (s *Controller) startSession(ctx context.Context, user *models.User) (*pb.JwtToken, error) {
+	if !user.TotpEnabled {
+		token, err := s.openSession(ctx, user)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return token, nil
+	}
+	id, err := mfaChallenges.add(&mfaChallenge{user: user})
+	if err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return &pb.JwtToken{MfaToken: id}, nil
+}
+
+// checkSecondFactor accepts a TOTP code that was not used before or an unused
+// recovery code.
+func (s *Controller) checkSecondFactor(ctx context.Context, user *models.User, code string) error {
+	if step, ok := auth.ValidateTotp(user.TotpSecret, code, s.now()); ok {
+		fresh, err := s.db.UseTotpStep(ctx, user.ID, step)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if !fresh {
+			return status.Error(codes.Unauthenticated, "code already used")
+		}
+		return nil
+	}
+	if hash, ok := auth.MatchRecoveryCode(user.RecoveryCodes, code); ok {
+		used, err := s.db.UseRecoveryCode(ctx, user.ID, hash)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if used {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid code")
+}
+
+func (s *Controller) mfaUser(ctx context.Context, method string) (*models.User, *logrus.Entry, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": method,
+		"user":   userCtx.Email,
+	})
+
+	getUser, err := s.db.GetUser(ctx, userCtx.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, status.Error(codes.NotFound, "User not found")
+		}
+		log.WithError(err).Error("Could not get user")
+		return nil, nil, status.Error(codes.Internal, err.Error())
+	}
+	return getUser, log, nil
+}
+
+func (s *Controller) now() time.Time {
+	if s.clock != nil {
+		return s.clock()
+	}
+	return time.Now()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/google/uuid"
@@ -18,6 +19,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 var (
@@ -31,6 +33,8 @@ type Controller struct {
 	pb.UnimplementedNoteServicesServer
 	pb.UnimplementedUserServicesServer
 	db database.DataStorable
+	// clock checks TOTP codes; time.Now when nil
+	clock func() time.Time
 }
 
 func NewController(logger *logger.Logger, db database.DataStorable, authService auth.ServiceAuth) *Controller {
@@ -65,6 +69,10 @@ func (s *Controller) AddNote(ctx context.Context, note *pb.Note) (*empty.Empty,
 			log.Warn("Context not found")
 			return nil, status.Error(codes.Unauthenticated, "User not authenticated")
 		}
+		if errors.Is(err, database.ErrBlobUnusable) {
+			log.Warn(err.Error())
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		log.Error(err.Error())
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -87,14 +95,9 @@ func (s *Controller) DeleteNote(ctx context.Context, req *pb.NoteRequest) (*empt
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	ok, err = s.db.DeleteSecretData(ctx, parse)
+	ok, err = s.db.DeleteSecretData(ctx, parse, req.Revision)
 	if err != nil {
-		if errors.Is(err, database.ErrUserNotFound) {
-			log.Warn("Context not found")
-			return nil, status.Error(codes.Unauthenticated, "User not authenticated")
-		}
-		log.Error(err.Error())
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, noteWriteError(log, err, req.IdNote)
 	}
 	if !ok {
 		log.Warn("User not found")
@@ -121,12 +124,7 @@ func (s *Controller) UpdateNote(ctx context.Context, note *pb.Note) (*empty.Empt
 
 	_, err = s.db.UpdateSecretData(ctx, sd)
 	if err != nil {
-		if errors.Is(err, database.ErrUserNotFound) {
-			log.Warn("Context not found")
-			return nil, status.Error(codes.Unauthenticated, "User not authenticated")
-		}
-		log.Error(err.Error())
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, noteWriteError(log, err, note.Id)
 	}
 	return &empty.Empty{}, nil
 }
@@ -153,21 +151,189 @@ func (s *Controller) GetNotes(ctx context.Context, _ *pb.NoteRequest) (*pb.NoteL
 	notes := make([]*pb.Note, 0)
 	log.Info("Create list of notes")
 	for _, data := range *sd {
-		notes = append(notes, &pb.Note{
-			Id:         data.ID.String(),
-			Name:       data.Name,
-			Type:       data.Type,
-			SecretData: data.Secret},
-		)
+		notes = append(notes, interfaces.EntityToDto(data))
 	}
 	return &pb.NoteList{Notes: notes}, nil
 }
 
+func (s *Controller) SyncNotes(ctx context.Context, req *pb.SyncRequest) (*pb.SyncResponse, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "SyncNotes",
+		"user":   userCtx.Email,
+	})
+
+	changes, err := s.db.SyncSecretData(ctx, req.Cursor)
+	if err != nil {
+		if errors.Is(err, database.ErrUserNotFound) {
+			log.Warn("Context not found")
+			return nil, status.Error(codes.Unauthenticated, "User not authenticated")
+		}
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &pb.SyncResponse{Notes: make([]*pb.Note, 0), Cursor: changes.Cursor, Full: changes.Full}
+	for _, data := range changes.Secrets {
+		if data.DeletedAt.Valid {
+			resp.Deleted = append(resp.Deleted, data.ID.String())
+			continue
+		}
+		resp.Notes = append(resp.Notes, interfaces.EntityToDto(data))
+	}
+	log.Infof("sync from %d to %d: %d changed, %d deleted", req.Cursor, resp.Cursor, len(resp.Notes), len(resp.Deleted))
+	return resp, nil
+}
+
+// WatchNotes streams the note writes of the user's other sessions. The
+// session is checked again every watchRecheck, so a revoked session stops
+// receiving. A watcher that falls behind is disconnected with Aborted and is
+// expected to sync before it watches again.
+func (s *Controller) WatchNotes(_ *empty.Empty, stream pb.NoteServices_WatchNotesServer) error {
+	ctx := stream.Context()
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "WatchNotes",
+		"user":   userCtx.Email,
+	})
+
+	changes, err := s.db.WatchSecretData(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return status.Error(codes.Internal, err.Error())
+	}
+	// tells the client it is subscribed and may sync without missing a write
+	if err = stream.SendHeader(metadata.Pairs("watching", "true")); err != nil {
+		return err
+	}
+	recheck := time.NewTicker(watchRecheck)
+	defer recheck.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-recheck.C:
+			if err = checkSession(ctx, userCtx.SessionID); err != nil {
+				log.Info("session ended, watch closed")
+				return err
+			}
+		case change, ok := <-changes:
+			if !ok {
+				log.Warn("watcher fell behind")
+				return status.Error(codes.Aborted, "too many changes, sync and watch again")
+			}
+			if change.Session == userCtx.SessionID {
+				continue
+			}
+			if err = stream.Send(changeToEvent(change)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchRecheck is how often WatchNotes checks that its session is active.
+var watchRecheck = time.Minute
+
+func changeToEvent(change database.Change) *pb.NoteEvent {
+	event := &pb.NoteEvent{Id: change.Secret.ID.String(), Cursor: change.Secret.ChangeSeq}
+	switch change.Kind {
+	case database.SecretCreated:
+		event.Kind = pb.NoteEvent_CREATED
+	case database.SecretUpdated:
+		event.Kind = pb.NoteEvent_UPDATED
+	case database.SecretDeleted:
+		event.Kind = pb.NoteEvent_DELETED
+		return event
+	}
+	event.Note = interfaces.EntityToDto(change.Secret)
+	return event
+}
+
+// noteWriteError converts a failed update or delete. A revision conflict
+// becomes Aborted with the stored note attached, so the client can merge
+// without another round trip.
+func noteWriteError(log *logrus.Entry, err error, id string) error {
+	var conflict *database.ConflictError
+	switch {
+	case errors.As(err, &conflict):
+		st, detailErr := status.New(codes.Aborted, "note was changed on another device").WithDetails(interfaces.EntityToDto(conflict.Current))
+		if detailErr != nil {
+			log.Error(detailErr.Error())
+			return status.Error(codes.Aborted, "note was changed on another device")
+		}
+		return st.Err()
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, id)
+	case errors.Is(err, database.ErrBlobUnusable):
+		log.Warn(err.Error())
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, database.ErrUserNotFound):
+		log.Warn("Context not found")
+		return status.Error(codes.Unauthenticated, "User not authenticated")
+	}
+	log.Error(err.Error())
+	return status.Error(codes.Internal, err.Error())
+}
+
 func TokenInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	if info.FullMethod == pb.UserServices_Register_FullMethodName ||
-		info.FullMethod == pb.UserServices_Login_FullMethodName {
+	switch info.FullMethod {
+	case pb.UserServices_Register_FullMethodName,
+		pb.UserServices_Login_FullMethodName,
+		pb.UserServices_RegisterSrp_FullMethodName,
+		pb.UserServices_LoginStart_FullMethodName:
+		user, _ := req.(interface{ GetEmail() string })
+		email := ""
+		if user != nil {
+			email = user.GetEmail()
+		}
+		if err := checkCertIdentity(ctx, email); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	case pb.UserServices_Refresh_FullMethodName,
+		pb.UserServices_LoginFinish_FullMethodName,
+		pb.UserServices_VerifyMfa_FullMethodName:
+		// Refresh checks the certificate against the account of the
+		// session, LoginFinish and VerifyMfa against the one of the pending
+		// login
 		return handler(ctx, req)
 	}
+	ctx, err := authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// TokenStreamInterceptor authenticates streaming calls the way
+// TokenInterceptor does unary ones.
+func TokenStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := authorize(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &userStream{ServerStream: ss, ctx: ctx})
+}
+
+// userStream carries the UserCtx added by TokenStreamInterceptor.
+type userStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *userStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize checks the access token, the client certificate and the session
+// of a call and adds the UserCtx to ctx.
+func authorize(ctx context.Context) (context.Context, error) {
 	var token string
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		values := md.Get("token")
@@ -182,5 +348,24 @@ func TokenInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServ
 	if err != nil {
 		return nil, status.Error(codes.Unauthenticated, "invalid token")
 	}
-	return handler(context.WithValue(ctx, "UserCtx", userCtx), req)
+	if err = checkCertIdentity(ctx, userCtx.Email); err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, "UserCtx", userCtx)
+	if err = checkSession(ctx, userCtx.SessionID); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+func checkSession(ctx context.Context, id uuid.UUID) error {
+	active, err := cs.db.IsSessionActive(ctx, id)
+	if err != nil {
+		log.WithError(err).Error("could not check session")
+		return status.Error(codes.Internal, "could not check session")
+	}
+	if !active {
+		return status.Error(codes.Unauthenticated, "session revoked")
+	}
+	return nil
 }
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/database"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/auth"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Controller) Refresh(ctx context.Context, req *pb.RefreshRequest) (*pb.JwtToken, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "Refresh",
+	})
+
+	sessionID, hash, err := auth.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	refreshToken, newHash, err := auth.NewRefreshToken(sessionID)
+	if err != nil {
+		log.WithError(err).Error("could not create refresh token")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	user, err := s.db.RefreshSession(ctx, sessionID, hash, newHash, time.Now().Add(auth.RefreshTokenTTL))
+	if err != nil {
+		if errors.Is(err, database.ErrSessionNotFound) || errors.Is(err, database.ErrSessionRevoked) {
+			log.Warn(err.Error())
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	// the interceptor does not know the account of a refresh token, so the
+	// certificate is checked once the session names it
+	if err = checkCertIdentity(ctx, user.Email); err != nil {
+		log.WithField("user", user.Email).Warn(err.Error())
+		return nil, err
+	}
+
+	jwt, err := as.CreateJwt(user, sessionID)
+	if err != nil {
+		log.WithError(err).Error("could not create jwt")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.JwtToken{
+		Token:        jwt,
+		Kdf:          interfaces.KdfToDto(user.Kdf),
+		WrappedKey:   user.WrappedKey,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(auth.AccessTokenTTL).Unix(),
+	}, nil
+}
+
+func (s *Controller) Logout(ctx context.Context, _ *empty.Empty) (*empty.Empty, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	return s.revokeSession(ctx, userCtx, userCtx.SessionID, "Logout")
+}
+
+func (s *Controller) RevokeSession(ctx context.Context, req *pb.SessionRequest) (*empty.Empty, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return s.revokeSession(ctx, userCtx, id, "RevokeSession")
+}
+
+func (s *Controller) revokeSession(ctx context.Context, userCtx *models.UserCtx, id uuid.UUID, method string) (*empty.Empty, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": method,
+		"user":   userCtx.Email,
+	})
+
+	ok, err := s.db.RevokeSession(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrUserNotFound) {
+			log.Warn("Context not found")
+			return nil, status.Error(codes.Unauthenticated, "User not authenticated")
+		}
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !ok {
+		return nil, status.Error(codes.NotFound, id.String())
+	}
+	log.WithField("session", id).Info("session revoked")
+	return &empty.Empty{}, nil
+}
+
+func (s *Controller) ListSessions(ctx context.Context, _ *empty.Empty) (*pb.SessionList, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "ListSessions",
+		"user":   userCtx.Email,
+	})
+
+	sessions, err := s.db.ListSessions(ctx)
+	if err != nil {
+		if errors.Is(err, database.ErrUserNotFound) {
+			log.Warn("Context not found")
+			return nil, status.Error(codes.Unauthenticated, "User not authenticated")
+		}
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	list := make([]*pb.Session, 0, len(*sessions))
+	for _, session := range *sessions {
+		list = append(list, interfaces.SessionToDto(session, userCtx.SessionID))
+	}
+	return &pb.SessionList{Sessions: list}, nil
+}
+
+// openSession stores a new session for user and issues its access and
+// refresh tokens.
+func (s *Controller) openSession(ctx context.Context, user *models.User) (*pb.JwtToken, error) {
+	sessionID := uuid.New()
+	refreshToken, hash, err := auth.NewRefreshToken(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.AddSession(ctx, models.Session{
+		ID:          sessionID,
+		UserID:      user.ID,
+		RefreshHash: hash,
+		Device:      deviceName(ctx),
+		ExpiresAt:   time.Now().Add(auth.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	jwt, err := as.CreateJwt(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.JwtToken{
+		Token:        jwt,
+		Kdf:          interfaces.KdfToDto(user.Kdf),
+		WrappedKey:   user.WrappedKey,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(auth.AccessTokenTTL).Unix(),
+	}, nil
+}
+
+// deviceName prefers the "device" metadata set by the client and falls back
+// to its user agent.
+func deviceName(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{"device", "user-agent"} {
+		if values := md.Get(key); len(values) > 0 && values[0] != "" {
+			if len(values[0]) > 255 {
+				return values[0][:255]
+			}
+			return values[0]
+		}
+	}
+	return ""
+}
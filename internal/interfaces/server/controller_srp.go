@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+var (
+	passwordAuth bool
+	handshakes   = newPendingStore[*srpHandshake](time.Minute)
+)
+
+// AllowPasswordAuth enables the legacy Register and Login calls, which send
+// the password to the server. Keep it on only while accounts move to SRP.
+func AllowPasswordAuth(enabled bool) {
+	passwordAuth = enabled
+}
+
+func (s *Controller) RegisterSrp(ctx context.Context, req *pb.SrpRegister) (*pb.JwtToken, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "RegisterSrp",
+		"user":   req.Email,
+	})
+
+	kdf := interfaces.DtoToKdf(req.Kdf)
+	if len(kdf.Salt) == 0 || len(req.Verifier) == 0 || len(req.WrappedKey) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "kdf parameters, verifier and wrapped key are required")
+	}
+	userCtx := util.AddContextUserCtx(ctx, "not register", req.Email, uuid.Nil)
+	newUser, err := s.db.AddUser(userCtx, &models.User{
+		Username:   req.Username,
+		Email:      req.Email,
+		Password:   []byte{},
+		Kdf:        kdf,
+		WrappedKey: req.WrappedKey,
+		Verifier:   req.Verifier,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, status.Error(codes.AlreadyExists, "user already exists")
+		}
+		log.WithError(err).Error("could not add user")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	token, err := s.openSession(ctx, newUser)
+	if err != nil {
+		log.WithError(err).Error("could not open session")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return token, nil
+}
+
+func (s *Controller) LoginStart(ctx context.Context, req *pb.SrpStart) (*pb.SrpChallenge, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "LoginStart",
+		"user":   req.Email,
+	})
+
+	userCtx := util.AddContextUserCtx(ctx, "not sig in", req.Email, uuid.Nil)
+	getUser, err := s.db.GetUser(userCtx, req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "User not found")
+		}
+		log.WithError(err).Error("Could not get user")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if len(getUser.Verifier) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "account has no SRP verifier")
+	}
+
+	srp, err := util.NewSrpServer(getUser.Email, getUser.Kdf.Salt, getUser.Verifier, req.A)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	id, err := handshakes.add(&srpHandshake{srp: srp, user: getUser})
+	if err != nil {
+		log.Warn(err.Error())
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return &pb.SrpChallenge{
+		HandshakeId: id,
+		Kdf:         interfaces.KdfToDto(getUser.Kdf),
+		B:           srp.B(),
+	}, nil
+}
+
+func (s *Controller) LoginFinish(ctx context.Context, req *pb.SrpProof) (*pb.SrpSession, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "LoginFinish",
+	})
+
+	h, err := verifyHandshake(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.startSession(ctx, h.user)
+	if err != nil {
+		log.WithError(err).Error("could not open session")
+		return nil, err
+	}
+	return &pb.SrpSession{M2: h.m2, Token: token}, nil
+}
+
+func (s *Controller) UpgradeToSrp(ctx context.Context, req *pb.SrpUpgrade) (*empty.Empty, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "UpgradeToSrp",
+		"user":   userCtx.Email,
+	})
+
+	if len(req.Verifier) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "verifier is required")
+	}
+	getUser, err := s.db.GetUser(ctx, userCtx.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "User not found")
+		}
+		log.WithError(err).Error("Could not get user")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if len(getUser.Kdf.Salt) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "vault has no kdf parameters")
+	}
+	if err = checkPassword(getUser, req.Password); err != nil {
+		return nil, err
+	}
+
+	if _, err = s.db.UpdateUser(ctx, models.User{ID: getUser.ID, Password: []byte{}, Verifier: req.Verifier}); err != nil {
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	log.Info("account moved to SRP")
+	return &empty.Empty{}, nil
+}
+
+// verifyHandshake finishes a pending handshake. It can be used once, by a
+// client whose certificate belongs to the account.
+func verifyHandshake(ctx context.Context, proof *pb.SrpProof) (*srpHandshake, error) {
+	h, ok := handshakes.take(proof.GetHandshakeId())
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "handshake expired")
+	}
+	if err := checkCertIdentity(ctx, h.user.Email); err != nil {
+		return nil, err
+	}
+	m2, err := h.srp.Verify(proof.M1)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Passwords is incorrect")
+	}
+	h.m2 = m2
+	return h, nil
+}
+
+// checkPassword compares password with the bcrypt hash of an account that
+// still signs in with a password.
+func checkPassword(user *models.User, password string) error {
+	if len(user.Password) == 0 {
+		return status.Error(codes.FailedPrecondition, "account signs in with SRP")
+	}
+	if err := bcrypt.CompareHashAndPassword(user.Password, []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return status.Error(codes.Unauthenticated, "Passwords is incorrect")
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+type srpHandshake struct {
+	srp  *util.SrpServer
+	user *models.User
+	m2   []byte
+}
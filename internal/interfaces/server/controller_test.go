@@ -2,7 +2,10 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"io"
 	"os"
 	"reflect"
 	"testing"
@@ -12,14 +15,22 @@ import (
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/google/uuid"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/database"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces"
 	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/logger"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/mocks"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/auth"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
 )
 
@@ -51,6 +62,7 @@ var (
 
 func TestMain(m *testing.M) {
 	log = logger.NewLogger(logrus.New())
+	AllowPasswordAuth(true)
 	code := m.Run()
 	os.Exit(code)
 }
@@ -161,9 +173,9 @@ func TestController_DeleteNote(t *testing.T) {
 	defer ctrl.Finish()
 	m := mocks.NewMockDataStorable(ctrl)
 
-	m.EXPECT().DeleteSecretData(userCtx1, uidS1).Return(true, nil)
-	m.EXPECT().DeleteSecretData(userCtx2, uidS2).Return(false, database.ErrUserNotFound)
-	m.EXPECT().DeleteSecretData(userCtx1, uidS2).Return(false, nil)
+	m.EXPECT().DeleteSecretData(userCtx1, uidS1, int64(1)).Return(true, nil)
+	m.EXPECT().DeleteSecretData(userCtx2, uidS2, int64(1)).Return(false, database.ErrUserNotFound)
+	m.EXPECT().DeleteSecretData(userCtx1, uidS2, int64(1)).Return(false, gorm.ErrRecordNotFound)
 
 	type fields struct {
 		UnimplementedNoteServicesServer pb.UnimplementedNoteServicesServer
@@ -190,7 +202,8 @@ func TestController_DeleteNote(t *testing.T) {
 			args: args{
 				ctx: userCtx1,
 				req: &pb.NoteRequest{
-					IdNote: uidS1.String(),
+					IdNote:   uidS1.String(),
+					Revision: 1,
 				},
 			},
 			want:    &empty.Empty{},
@@ -205,7 +218,8 @@ func TestController_DeleteNote(t *testing.T) {
 			args: args{
 				ctx: userCtx2,
 				req: &pb.NoteRequest{
-					IdNote: uidS2.String(),
+					IdNote:   uidS2.String(),
+					Revision: 1,
 				},
 			},
 			want:    nil,
@@ -235,7 +249,8 @@ func TestController_DeleteNote(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				req: &pb.NoteRequest{
-					IdNote: uidS1.String(),
+					IdNote:   uidS1.String(),
+					Revision: 1,
 				},
 			},
 			want:    nil,
@@ -250,7 +265,8 @@ func TestController_DeleteNote(t *testing.T) {
 			args: args{
 				ctx: userCtx1,
 				req: &pb.NoteRequest{
-					IdNote: uidS2.String(),
+					IdNote:   uidS2.String(),
+					Revision: 1,
 				},
 			},
 			want:    nil,
@@ -276,6 +292,499 @@ func TestController_DeleteNote(t *testing.T) {
 	}
 }
 
+func TestController_SyncNotes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockDataStorable(ctrl)
+
+	deleted := secretData2
+	deleted.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	m.EXPECT().SyncSecretData(userCtx1, int64(3)).Return(&database.Changes{Secrets: []models.SecretData{secretData1, deleted}, Cursor: 5}, nil)
+	m.EXPECT().SyncSecretData(userCtx2, int64(0)).Return(nil, database.ErrUserNotFound)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		req     *pb.SyncRequest
+		want    *pb.SyncResponse
+		wantErr bool
+	}{
+		{
+			name: "Success",
+			ctx:  userCtx1,
+			req:  &pb.SyncRequest{Cursor: 3},
+			want: &pb.SyncResponse{Notes: []*pb.Note{&note1}, Deleted: []string{uidS2.String()}, Cursor: 5},
+		},
+		{
+			name:    "User not found",
+			ctx:     userCtx2,
+			req:     &pb.SyncRequest{},
+			wantErr: true,
+		},
+		{
+			name:    "Wrong Ctx",
+			ctx:     context.Background(),
+			req:     &pb.SyncRequest{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Controller{db: m}
+			got, err := s.SyncNotes(tt.ctx, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SyncNotes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SyncNotes() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeWatchStream records what WatchNotes sends.
+type fakeWatchStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+	events []*pb.NoteEvent
+}
+
+func (f *fakeWatchStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeWatchStream) SendHeader(md metadata.MD) error {
+	f.header = md
+	return nil
+}
+
+func (f *fakeWatchStream) Send(event *pb.NoteEvent) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+func TestController_WatchNotes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockDataStorable(ctrl)
+	prev, prevRecheck := cs, watchRecheck
+	cs = &Controller{db: m}
+	watchRecheck = 10 * time.Millisecond
+	defer func() { cs, watchRecheck = prev, prevRecheck }()
+
+	own := userCtx1.Value("UserCtx").(*models.UserCtx).SessionID
+	lagging := make(chan database.Change, 3)
+	lagging <- database.Change{Kind: database.SecretUpdated, Secret: secretData2, Session: own}
+	lagging <- database.Change{Kind: database.SecretCreated, Secret: secretData1, Session: uuid.New()}
+	lagging <- database.Change{Kind: database.SecretDeleted, Secret: secretData2, Session: uuid.New()}
+	close(lagging)
+	idle := make(chan database.Change)
+	m.EXPECT().WatchSecretData(userCtx1).Return(lagging, nil)
+	m.EXPECT().WatchSecretData(userCtx2).Return(idle, nil)
+	m.EXPECT().IsSessionActive(gomock.Any(), own).Return(true, nil).AnyTimes()
+	m.EXPECT().IsSessionActive(gomock.Any(), userCtx2.Value("UserCtx").(*models.UserCtx).SessionID).Return(false, nil).AnyTimes()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantEvents []*pb.NoteEvent
+		wantCode   codes.Code
+	}{
+		{
+			name:       "Other sessions only",
+			ctx:        userCtx1,
+			wantEvents: []*pb.NoteEvent{{Kind: pb.NoteEvent_CREATED, Id: uidS1.String(), Note: &note1}, {Kind: pb.NoteEvent_DELETED, Id: uidS2.String()}},
+			wantCode:   codes.Aborted,
+		},
+		{
+			name:     "Session revoked",
+			ctx:      userCtx2,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "Wrong Ctx",
+			ctx:      context.Background(),
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Controller{db: m}
+			stream := &fakeWatchStream{ctx: tt.ctx}
+			err := s.WatchNotes(&empty.Empty{}, stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("WatchNotes() error = %v, want %v", err, tt.wantCode)
+			}
+			if !reflect.DeepEqual(stream.events, tt.wantEvents) {
+				t.Errorf("WatchNotes() events = %v, want %v", stream.events, tt.wantEvents)
+			}
+		})
+	}
+}
+
+// fakeUploadStream feeds chunks to UploadBlob and records its reply.
+type fakeUploadStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	chunks []*pb.BlobChunk
+	reply  *pb.BlobStatus
+}
+
+func (f *fakeUploadStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeUploadStream) Recv() (*pb.BlobChunk, error) {
+	if len(f.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := f.chunks[0]
+	f.chunks = f.chunks[1:]
+	return chunk, nil
+}
+
+func (f *fakeUploadStream) SendAndClose(st *pb.BlobStatus) error {
+	f.reply = st
+	return nil
+}
+
+func TestController_UploadBlob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockDataStorable(ctrl)
+
+	blobID, other := uuid.New(), uuid.New()
+	m.EXPECT().AddBlobChunk(userCtx1, other, int64(0), []byte(nil), false).Return(&models.Blob{ID: other, Chunks: 1}, nil)
+	m.EXPECT().AddBlobChunk(userCtx1, blobID, int64(0), []byte("a"), false).Return(&models.Blob{ID: blobID, Chunks: 1, Size: 1}, nil)
+	m.EXPECT().AddBlobChunk(userCtx1, blobID, int64(1), []byte("b"), true).Return(&models.Blob{ID: blobID, Chunks: 2, Size: 2, Complete: true}, nil)
+	m.EXPECT().AddBlobChunk(userCtx2, blobID, int64(3), []byte("c"), false).Return(nil, database.ErrChunkOutOfOrder)
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		chunks    []*pb.BlobChunk
+		wantReply *pb.BlobStatus
+		wantCode  codes.Code
+	}{
+		{
+			name: "Complete upload",
+			ctx:  userCtx1,
+			chunks: []*pb.BlobChunk{
+				{BlobId: blobID.String(), Index: 0, Data: []byte("a")},
+				{BlobId: blobID.String(), Index: 1, Data: []byte("b"), Last: true},
+			},
+			wantReply: &pb.BlobStatus{BlobId: blobID.String(), NextIndex: 2, Complete: true, Size: 2},
+		},
+		{
+			name:     "Out of order",
+			ctx:      userCtx2,
+			chunks:   []*pb.BlobChunk{{BlobId: blobID.String(), Index: 3, Data: []byte("c")}},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name: "Two blobs",
+			ctx:  userCtx1,
+			chunks: []*pb.BlobChunk{
+				{BlobId: other.String(), Index: 0},
+				{BlobId: blobID.String(), Index: 0},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Chunk too large",
+			ctx:      userCtx1,
+			chunks:   []*pb.BlobChunk{{BlobId: blobID.String(), Data: make([]byte, maxBlobChunk+1)}},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "No chunks",
+			ctx:      userCtx1,
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Wrong Ctx",
+			ctx:      context.Background(),
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Controller{db: m}
+			stream := &fakeUploadStream{ctx: tt.ctx, chunks: tt.chunks}
+			err := s.UploadBlob(stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("UploadBlob() error = %v, want %v", err, tt.wantCode)
+			}
+			if !reflect.DeepEqual(stream.reply, tt.wantReply) {
+				t.Errorf("UploadBlob() reply = %v, want %v", stream.reply, tt.wantReply)
+			}
+		})
+	}
+}
+
+func TestController_DeleteBlob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockDataStorable(ctrl)
+
+	free, attached, missing := uuid.New(), uuid.New(), uuid.New()
+	m.EXPECT().DeleteBlob(userCtx1, free).Return(true, nil)
+	m.EXPECT().DeleteBlob(userCtx1, attached).Return(false, database.ErrBlobInUse)
+	m.EXPECT().DeleteBlob(userCtx1, missing).Return(false, nil)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		id       string
+		wantCode codes.Code
+	}{
+		{name: "Unattached blob", ctx: userCtx1, id: free.String()},
+		{name: "Attached blob", ctx: userCtx1, id: attached.String(), wantCode: codes.FailedPrecondition},
+		{name: "Missing blob", ctx: userCtx1, id: missing.String(), wantCode: codes.NotFound},
+		{name: "Invalid id", ctx: userCtx1, id: "blob", wantCode: codes.InvalidArgument},
+		{name: "Wrong Ctx", ctx: context.Background(), id: free.String(), wantCode: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Controller{db: m}
+			if _, err := s.DeleteBlob(tt.ctx, &pb.BlobRequest{BlobId: tt.id}); status.Code(err) != tt.wantCode {
+				t.Errorf("DeleteBlob() error = %v, want %v", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+// fakeDownloadStream records what DownloadBlob sends.
+type fakeDownloadStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	chunks []*pb.BlobChunk
+}
+
+func (f *fakeDownloadStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeDownloadStream) Send(chunk *pb.BlobChunk) error {
+	f.chunks = append(f.chunks, chunk)
+	return nil
+}
+
+func TestController_DownloadBlob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockDataStorable(ctrl)
+
+	complete, partial, missing := uuid.New(), uuid.New(), uuid.New()
+	m.EXPECT().GetBlob(userCtx1, complete).Return(&models.Blob{ID: complete, Chunks: 3, Complete: true}, nil).Times(2)
+	m.EXPECT().GetBlobChunk(userCtx1, complete, int64(0)).Return([]byte("a"), nil)
+	m.EXPECT().GetBlobChunk(userCtx1, complete, int64(1)).Return([]byte("b"), nil).Times(2)
+	m.EXPECT().GetBlobChunk(userCtx1, complete, int64(2)).Return([]byte("c"), nil).Times(2)
+	m.EXPECT().GetBlob(userCtx1, partial).Return(&models.Blob{ID: partial, Chunks: 1}, nil)
+	m.EXPECT().GetBlob(userCtx1, missing).Return(nil, gorm.ErrRecordNotFound)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		req        *pb.BlobRequest
+		wantChunks []*pb.BlobChunk
+		wantCode   codes.Code
+	}{
+		{
+			name: "Whole blob",
+			ctx:  userCtx1,
+			req:  &pb.BlobRequest{BlobId: complete.String()},
+			wantChunks: []*pb.BlobChunk{
+				{BlobId: complete.String(), Index: 0, Data: []byte("a")},
+				{BlobId: complete.String(), Index: 1, Data: []byte("b")},
+				{BlobId: complete.String(), Index: 2, Data: []byte("c"), Last: true},
+			},
+		},
+		{
+			name: "From index",
+			ctx:  userCtx1,
+			req:  &pb.BlobRequest{BlobId: complete.String(), FromIndex: 1},
+			wantChunks: []*pb.BlobChunk{
+				{BlobId: complete.String(), Index: 1, Data: []byte("b")},
+				{BlobId: complete.String(), Index: 2, Data: []byte("c"), Last: true},
+			},
+		},
+		{
+			name:     "Upload not finished",
+			ctx:      userCtx1,
+			req:      &pb.BlobRequest{BlobId: partial.String()},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "Not found",
+			ctx:      userCtx1,
+			req:      &pb.BlobRequest{BlobId: missing.String()},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "Invalid id",
+			ctx:      userCtx1,
+			req:      &pb.BlobRequest{BlobId: "blob"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Wrong Ctx",
+			ctx:      context.Background(),
+			req:      &pb.BlobRequest{BlobId: complete.String()},
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Controller{db: m}
+			stream := &fakeDownloadStream{ctx: tt.ctx}
+			err := s.DownloadBlob(tt.req, stream)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("DownloadBlob() error = %v, want %v", err, tt.wantCode)
+			}
+			if !reflect.DeepEqual(stream.chunks, tt.wantChunks) {
+				t.Errorf("DownloadBlob() chunks = %v, want %v", stream.chunks, tt.wantChunks)
+			}
+		})
+	}
+}
+
+func TestController_NoteBlob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockDataStorable(ctrl)
+
+	blob := uuid.New()
+	data := models.SecretData{ID: uidS3, Type: "BINARY", Name: "Scan", Secret: []byte("scan"), Revision: 1, BlobID: &blob}
+	owned := data
+	owned.UserID = uidU1
+	m.EXPECT().AddSecretData(userCtx1, owned).Return(nil, database.ErrBlobUnusable)
+	m.EXPECT().UpdateSecretData(userCtx1, data).Return(nil, database.ErrBlobUnusable)
+
+	s := &Controller{db: m}
+	_, err := s.AddNote(userCtx1, interfaces.EntityToDto(data))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "AddNote() with a blob of another user")
+	_, err = s.UpdateNote(userCtx1, interfaces.EntityToDto(data))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "UpdateNote() with a blob of another user")
+}
+
+func TestController_UpdateNoteConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockDataStorable(ctrl)
+
+	current := secretData3
+	current.Revision = 3
+	stale := models.SecretData{ID: uidS3, Type: "CARD", Name: "Test Secret", Secret: []byte("Mine"), Revision: 2}
+	m.EXPECT().UpdateSecretData(userCtx1, stale).Return(nil, &database.ConflictError{Current: current})
+	m.EXPECT().DeleteSecretData(userCtx1, uidS3, int64(2)).Return(false, &database.ConflictError{Current: current})
+
+	s := &Controller{db: m}
+	_, updateErr := s.UpdateNote(userCtx1, interfaces.EntityToDto(stale))
+	_, deleteErr := s.DeleteNote(userCtx1, &pb.NoteRequest{IdNote: uidS3.String(), Revision: 2})
+	for _, err := range []error{updateErr, deleteErr} {
+		st := status.Convert(err)
+		assert.Equal(t, codes.Aborted, st.Code())
+		if assert.Len(t, st.Details(), 1) {
+			note, ok := st.Details()[0].(*pb.Note)
+			assert.True(t, ok, "detail is the stored note")
+			assert.Equal(t, int64(3), note.GetRevision())
+			assert.Equal(t, current.Secret, note.GetSecretData())
+		}
+	}
+}
+
+func TestController_NoteRevisions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockDataStorable(ctrl)
+
+	written := time.Unix(1700000000, 0)
+	kept := []models.SecretRevision{
+		{SecretID: uidS1, Revision: 2, UserID: uidU1, Type: "CARD", Name: "Old", Secret: []byte("Old"), WrittenAt: &written},
+		{SecretID: uidS1, Revision: 1, UserID: uidU1, Secret: []byte("Sealed"), Sealed: true},
+	}
+	restored := secretData1
+	restored.Revision = 4
+	current := secretData1
+	current.Revision = 5
+	m.EXPECT().ListSecretRevisions(userCtx1, uidS1).Return(&kept, nil)
+	m.EXPECT().ListSecretRevisions(userCtx2, uidS1).Return(nil, database.ErrUserNotFound)
+	m.EXPECT().RestoreSecretRevision(userCtx1, uidS1, int64(2), int64(3)).Return(&restored, nil)
+	m.EXPECT().RestoreSecretRevision(userCtx1, uidS1, int64(2), int64(4)).Return(nil, &database.ConflictError{Current: current})
+	m.EXPECT().RestoreSecretRevision(userCtx1, uidS1, int64(1), int64(5)).Return(nil, gorm.ErrRecordNotFound)
+
+	s := &Controller{db: m}
+	list, err := s.ListNoteRevisions(userCtx1, &pb.NoteRequest{IdNote: uidS1.String()})
+	assert.NoError(t, err)
+	if assert.Len(t, list.GetRevisions(), 2) {
+		assert.Equal(t, int64(2), list.Revisions[0].GetNote().GetRevision())
+		assert.Equal(t, "Old", list.Revisions[0].GetNote().GetName())
+		assert.Equal(t, written.Unix(), list.Revisions[0].GetWrittenAt())
+		assert.True(t, list.Revisions[1].GetNote().GetSealed())
+		assert.Zero(t, list.Revisions[1].GetWrittenAt())
+	}
+	_, err = s.ListNoteRevisions(userCtx2, &pb.NoteRequest{IdNote: uidS1.String()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = s.ListNoteRevisions(userCtx1, &pb.NoteRequest{IdNote: "UUID"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	note, err := s.RestoreNoteRevision(userCtx1, &pb.RestoreRequest{IdNote: uidS1.String(), Revision: 2, Current: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), note.GetRevision())
+	_, err = s.RestoreNoteRevision(userCtx1, &pb.RestoreRequest{IdNote: uidS1.String(), Revision: 2, Current: 4})
+	st := status.Convert(err)
+	assert.Equal(t, codes.Aborted, st.Code())
+	assert.Len(t, st.Details(), 1, "conflict carries the stored note")
+	_, err = s.RestoreNoteRevision(userCtx1, &pb.RestoreRequest{IdNote: uidS1.String(), Revision: 1, Current: 5})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = s.RestoreNoteRevision(userCtx1, &pb.RestoreRequest{IdNote: "UUID"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestController_Trash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	m := mocks.NewMockDataStorable(ctrl)
+
+	deleted := secretData1
+	deleted.DeletedAt = gorm.DeletedAt{Time: time.Unix(1700000000, 0), Valid: true}
+	restored := secretData1
+	restored.Revision = 3
+	m.EXPECT().ListTrash(userCtx1).Return(&[]models.SecretData{deleted}, nil)
+	m.EXPECT().ListTrash(userCtx2).Return(nil, database.ErrUserNotFound)
+	m.EXPECT().RestoreSecretData(userCtx1, uidS1).Return(&restored, nil)
+	m.EXPECT().RestoreSecretData(userCtx1, uidS2).Return(nil, gorm.ErrRecordNotFound)
+	m.EXPECT().EmptyTrash(userCtx1).Return(2, nil)
+	m.EXPECT().EmptyTrash(userCtx2).Return(0, errors.New("database is locked"))
+
+	s := &Controller{db: m}
+	list, err := s.ListTrash(userCtx1, &empty.Empty{})
+	assert.NoError(t, err)
+	if assert.Len(t, list.GetNotes(), 1) {
+		assert.Equal(t, uidS1.String(), list.Notes[0].GetNote().GetId())
+		assert.Equal(t, int64(1700000000), list.Notes[0].GetDeletedAt())
+	}
+	_, err = s.ListTrash(userCtx2, &empty.Empty{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	note, err := s.RestoreNote(userCtx1, &pb.NoteRequest{IdNote: uidS1.String()})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), note.GetRevision())
+	_, err = s.RestoreNote(userCtx1, &pb.NoteRequest{IdNote: uidS2.String()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = s.RestoreNote(userCtx1, &pb.NoteRequest{IdNote: "UUID"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	count, err := s.EmptyTrash(userCtx1, &empty.Empty{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count.GetPurged())
+	_, err = s.EmptyTrash(userCtx2, &empty.Empty{})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
 func TestController_GetNotes(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -473,12 +982,13 @@ func TestController_Login(t *testing.T) {
 	testUserCrpt2.Password, _ = bcrypt.GenerateFromPassword(testUser2.Password, bcrypt.DefaultCost)
 
 	md.EXPECT().GetUser(gomock.Any(), testUser1.Email).Return(&testUserCrpt1, nil)
-	ms.EXPECT().CreateJwt(&testUserCrpt1).Return("test token", nil)
+	md.EXPECT().AddSession(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	ms.EXPECT().CreateJwt(&testUserCrpt1, gomock.Any()).Return("test token", nil)
 
 	md.EXPECT().GetUser(gomock.Any(), "userNotFound@test.com").Return(nil, gorm.ErrRecordNotFound)
 
 	md.EXPECT().GetUser(gomock.Any(), testUser2.Email).Return(&testUserCrpt2, nil)
-	ms.EXPECT().CreateJwt(&testUserCrpt2).Return("", errors.New("test error"))
+	ms.EXPECT().CreateJwt(&testUserCrpt2, gomock.Any()).Return("", errors.New("test error"))
 
 	md.EXPECT().GetUser(gomock.Any(), "password@test.com").Return(&testUserCrpt2, nil)
 
@@ -579,6 +1089,11 @@ func TestController_Login(t *testing.T) {
 				t.Errorf("Login() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if got != nil {
+				assert.NotEmpty(t, got.RefreshToken)
+				assert.NotZero(t, got.ExpiresAt)
+				got.RefreshToken, got.ExpiresAt = "", 0
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Login() got = %v, want %v", got, tt.want)
 			}
@@ -596,7 +1111,14 @@ func TestController_Register(t *testing.T) {
 	newUser := pb.User{Username: testUser1.Username, Password: string(testUser1.Password), Email: testUser1.Email}
 
 	md.EXPECT().AddUser(gomock.Any(), gomock.Any()).Return(&testUser1, nil)
-	ms.EXPECT().CreateJwt(&testUser1).Return("test token", nil)
+	md.EXPECT().AddSession(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, session models.Session) error {
+			if session.UserID != testUser1.ID || len(session.RefreshHash) == 0 {
+				t.Errorf("Register() session = %v", session)
+			}
+			return nil
+		})
+	ms.EXPECT().CreateJwt(&testUser1, gomock.Any()).Return("test token", nil)
 
 	type fields struct {
 		UnimplementedNoteServicesServer pb.UnimplementedNoteServicesServer
@@ -640,6 +1162,11 @@ func TestController_Register(t *testing.T) {
 				t.Errorf("Register() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if got != nil {
+				assert.NotEmpty(t, got.RefreshToken)
+				assert.NotZero(t, got.ExpiresAt)
+				got.RefreshToken, got.ExpiresAt = "", 0
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Register() got = %v, want %v", got, tt.want)
 			}
@@ -647,41 +1174,903 @@ func TestController_Register(t *testing.T) {
 	}
 }
 
-func TestNewController(t *testing.T) {
+func TestController_RekeyVault(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	md := mocks.NewMockDataStorable(ctrl)
-	ms := mocks.NewMockServiceAuth(ctrl)
-	t.Run("New controller", func(t *testing.T) {
-		got := NewController(log, md, ms)
-		assert.NotNil(t, got)
-	})
-}
 
-func TestTokenInterceptor(t *testing.T) {
+	kdf := &pb.KdfParams{Algorithm: "argon2id", Salt: []byte("salt"), Time: 3, Memory: 65536, Threads: 4}
+	user := models.User{Kdf: models.KdfParams{Algorithm: "argon2id", Salt: []byte("salt"), Time: 3, Memory: 65536, Threads: 4}}
+	secretData1rekey := models.SecretData{ID: uidS1, Type: "CARD", Name: "Test Secret", Secret: []byte("Test Secret")}
+	revision1rekey := models.SecretRevision{SecretID: uidS1, Revision: 1, Type: "CARD", Name: "Test Secret", Secret: []byte("Old Secret")}
+	revision1 := &pb.NoteRevision{Note: &pb.Note{Id: uidS1.String(), Name: "Test Secret", Type: "CARD", SecretData: []byte("Old Secret"), Revision: 1}}
+
+	legacyUser := testUser1
+	envelopedUser := testUser1
+	envelopedUser.Password, _ = bcrypt.GenerateFromPassword(testUser1.Password, bcrypt.DefaultCost)
+	envelopedUser.WrappedKey = []byte("wrapped")
+	envelopedCtx := addContext(context.Background(), uuid.New())
+	srpUser := models.User{ID: uidU1, Email: "test@test.com", WrappedKey: []byte("wrapped"), Verifier: util.SrpVerifier(util.SrpX([]byte("key")))}
+	srpCtx := addContext(context.Background(), uuid.New())
+
+	md.EXPECT().GetUser(userCtx1, "test@test.com").Return(&legacyUser, nil).Times(2)
+	md.EXPECT().GetUser(userCtx2, "test@test.com").Return(&legacyUser, nil)
+	md.EXPECT().GetUser(envelopedCtx, "test@test.com").Return(&envelopedUser, nil).Times(3)
+	md.EXPECT().GetUser(srpCtx, "test@test.com").Return(&srpUser, nil)
+	md.EXPECT().RekeyVault(userCtx1, user, []models.SecretData{secretData1rekey}, []models.SecretRevision{revision1rekey}).Return(nil)
+	md.EXPECT().RekeyVault(userCtx2, user, []models.SecretData{}, []models.SecretRevision{}).Return(database.ErrVaultMismatch)
+	md.EXPECT().RekeyVault(envelopedCtx, models.User{Kdf: user.Kdf, WrappedKey: []byte("rewrapped")}, gomock.Nil(), gomock.Nil()).Return(nil)
+	md.EXPECT().RekeyVault(userCtx1, models.User{Kdf: user.Kdf, WrappedKey: []byte("rewrapped")}, gomock.Nil(), gomock.Nil()).Return(nil)
+
 	type args struct {
-		ctx     context.Context
-		req     interface{}
-		info    *grpc.UnaryServerInfo
-		handler grpc.UnaryHandler
+		ctx context.Context
+		req *pb.VaultRekey
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    interface{}
+		want    *empty.Empty
 		wantErr bool
 	}{
-		// TODO: Add test cases.
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := TokenInterceptor(tt.args.ctx, tt.args.req, tt.args.info, tt.args.handler)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("TokenInterceptor() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("TokenInterceptor() got = %v, want %v", got, tt.want)
+		{
+			name: "Legacy vault without proof",
+			args: args{
+				ctx: userCtx1,
+				req: &pb.VaultRekey{Kdf: kdf, WrappedKey: []byte("rewrapped")},
+			},
+			want:    &empty.Empty{},
+			wantErr: false,
+		},
+		{
+			name: "Wrapped key with the password",
+			args: args{
+				ctx: envelopedCtx,
+				req: &pb.VaultRekey{Kdf: kdf, WrappedKey: []byte("rewrapped"), Password: "Test Password"},
+			},
+			want:    &empty.Empty{},
+			wantErr: false,
+		},
+		{
+			name: "Wrapped key without proof",
+			args: args{
+				ctx: envelopedCtx,
+				req: &pb.VaultRekey{Kdf: kdf, WrappedKey: []byte("rewrapped")},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "SRP account without a handshake",
+			args: args{
+				ctx: srpCtx,
+				req: &pb.VaultRekey{Kdf: kdf, WrappedKey: []byte("rewrapped"), Password: "Test Password"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Wrapped key with a wrong password",
+			args: args{
+				ctx: envelopedCtx,
+				req: &pb.VaultRekey{Kdf: kdf, WrappedKey: []byte("rewrapped"), Password: "password"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Success",
+			args: args{
+				ctx: userCtx1,
+				req: &pb.VaultRekey{Kdf: kdf, Notes: []*pb.Note{&note1}, Revisions: []*pb.NoteRevision{revision1}},
+			},
+			want:    &empty.Empty{},
+			wantErr: false,
+		},
+		{
+			name: "Vault mismatch",
+			args: args{
+				ctx: userCtx2,
+				req: &pb.VaultRekey{Kdf: kdf, Notes: []*pb.Note{}},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Without kdf",
+			args: args{
+				ctx: userCtx1,
+				req: &pb.VaultRekey{Notes: []*pb.Note{&note1}},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Wrong Ctx",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.VaultRekey{Kdf: kdf, Notes: []*pb.Note{&note1}},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Controller{db: md}
+			got, err := s.RekeyVault(tt.args.ctx, tt.args.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RekeyVault() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RekeyVault() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestController_ChangePassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	md := mocks.NewMockDataStorable(ctrl)
+	ms := mocks.NewMockServiceAuth(ctrl)
+	as = ms
+
+	kdf := &pb.KdfParams{Algorithm: "argon2id", Salt: []byte("salt"), Time: 3, Memory: 65536, Threads: 4}
+	testUserCrpt1 := testUser1
+	testUserCrpt1.Password, _ = bcrypt.GenerateFromPassword(testUser1.Password, bcrypt.DefaultCost)
+
+	md.EXPECT().GetUser(userCtx1, "test@test.com").Return(&testUserCrpt1, nil).Times(4)
+	md.EXPECT().RekeyVault(userCtx1, gomock.Any(), gomock.Len(1), gomock.Len(0)).DoAndReturn(
+		func(_ context.Context, user models.User, _ []models.SecretData, _ []models.SecretRevision) error {
+			if err := bcrypt.CompareHashAndPassword(user.Password, []byte("New Password")); err != nil {
+				t.Errorf("ChangePassword() stored hash does not match new password: %v", err)
+			}
+			return nil
+		})
+	md.EXPECT().RekeyVault(userCtx1, gomock.Any(), []models.SecretData{}, []models.SecretRevision{}).Return(database.ErrVaultMismatch)
+	md.EXPECT().RekeyVault(userCtx1, gomock.Any(), gomock.Nil(), gomock.Nil()).Return(nil)
+	ms.EXPECT().CreateJwt(gomock.Any(), userCtx1.Value("UserCtx").(*models.UserCtx).SessionID).Return("test token", nil).Times(2)
+
+	type args struct {
+		ctx context.Context
+		req *pb.PasswordChange
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *pb.JwtToken
+		wantErr bool
+	}{
+		{
+			name: "Success",
+			args: args{
+				ctx: userCtx1,
+				req: &pb.PasswordChange{OldPassword: "Test Password", NewPassword: "New Password", Kdf: kdf, Notes: []*pb.Note{&note1}},
+			},
+			want:    &pb.JwtToken{Token: "test token", Kdf: kdf},
+			wantErr: false,
+		},
+		{
+			name: "Rewrap data key only",
+			args: args{
+				ctx: userCtx1,
+				req: &pb.PasswordChange{OldPassword: "Test Password", NewPassword: "New Password", Kdf: kdf, WrappedKey: []byte("wrapped")},
+			},
+			want:    &pb.JwtToken{Token: "test token", Kdf: kdf, WrappedKey: []byte("wrapped")},
+			wantErr: false,
+		},
+		{
+			name: "Wrong old password",
+			args: args{
+				ctx: userCtx1,
+				req: &pb.PasswordChange{OldPassword: "password", NewPassword: "New Password", Kdf: kdf, Notes: []*pb.Note{&note1}},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Vault mismatch",
+			args: args{
+				ctx: userCtx1,
+				req: &pb.PasswordChange{OldPassword: "Test Password", NewPassword: "New Password", Kdf: kdf, Notes: []*pb.Note{}},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Without kdf",
+			args: args{
+				ctx: userCtx1,
+				req: &pb.PasswordChange{OldPassword: "Test Password", NewPassword: "New Password"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Wrong Ctx",
+			args: args{
+				ctx: context.Background(),
+				req: &pb.PasswordChange{OldPassword: "Test Password", NewPassword: "New Password", Kdf: kdf},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Controller{db: md}
+			got, err := s.ChangePassword(tt.args.ctx, tt.args.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ChangePassword() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != nil {
+				assert.NotZero(t, got.ExpiresAt)
+				got.ExpiresAt = 0
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChangePassword() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestController_LoginSrp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	md := mocks.NewMockDataStorable(ctrl)
+	ms := mocks.NewMockServiceAuth(ctrl)
+	as = ms
+
+	salt := []byte("0123456789abcdef")
+	srpUser := models.User{ID: uidU1, Email: "srp@test.com", Kdf: models.KdfParams{Salt: salt}, Verifier: util.SrpVerifier(util.SrpX([]byte("key")))}
+
+	md.EXPECT().GetUser(gomock.Any(), srpUser.Email).Return(&srpUser, nil).Times(3)
+	md.EXPECT().GetUser(gomock.Any(), testUser2.Email).Return(&testUser2, nil)
+	md.EXPECT().GetUser(gomock.Any(), "userNotFound@test.com").Return(nil, gorm.ErrRecordNotFound)
+	md.EXPECT().AddSession(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	ms.EXPECT().CreateJwt(&srpUser, gomock.Any()).Return("test token", nil).Times(2)
+
+	tests := []struct {
+		name     string
+		email    string
+		key      string
+		replay   bool
+		wantCode codes.Code
+	}{
+		{name: "Success", email: srpUser.Email, key: "key", wantCode: codes.OK},
+		{name: "Replayed proof", email: srpUser.Email, key: "key", replay: true, wantCode: codes.Unauthenticated},
+		{name: "Wrong password", email: srpUser.Email, key: "another key", wantCode: codes.Unauthenticated},
+		{name: "Without verifier", email: testUser2.Email, key: "key", wantCode: codes.FailedPrecondition},
+		{name: "User not found", email: "userNotFound@test.com", key: "key", wantCode: codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Controller{db: md}
+			client, err := util.NewSrpClient(tt.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			challenge, err := s.LoginStart(context.Background(), &pb.SrpStart{Email: tt.email, A: client.A()})
+			if err != nil {
+				assert.Equal(t, tt.wantCode, status.Code(err))
+				return
+			}
+			m1, err := client.Proof(salt, util.SrpX([]byte(tt.key)), challenge.B)
+			if err != nil {
+				t.Fatal(err)
+			}
+			proof := &pb.SrpProof{HandshakeId: challenge.HandshakeId, M1: m1}
+			session, err := s.LoginFinish(context.Background(), proof)
+			if tt.replay {
+				_, err = s.LoginFinish(context.Background(), proof)
+			}
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if err == nil {
+				assert.NoError(t, client.VerifyServer(session.M2))
+				assert.Equal(t, "test token", session.Token.Token)
+			}
+		})
+	}
+}
+
+func TestController_UpgradeToSrp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	md := mocks.NewMockDataStorable(ctrl)
+
+	verifier := util.SrpVerifier(util.SrpX([]byte("key")))
+	testUserCrpt1 := testUser1
+	testUserCrpt1.Password, _ = bcrypt.GenerateFromPassword(testUser1.Password, bcrypt.DefaultCost)
+	testUserCrpt1.Kdf = models.KdfParams{Salt: []byte("salt")}
+	srpUser := testUser2
+	srpUser.Password = []byte{}
+	srpUser.Kdf = models.KdfParams{Salt: []byte("salt")}
+	srpUser.Verifier = verifier
+
+	md.EXPECT().GetUser(userCtx1, "test@test.com").Return(&testUserCrpt1, nil).Times(2)
+	md.EXPECT().GetUser(userCtx2, "test@test.com").Return(&srpUser, nil)
+	md.EXPECT().UpdateUser(userCtx1, models.User{ID: uidU1, Password: []byte{}, Verifier: verifier}).Return(&testUserCrpt1, nil)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		req      *pb.SrpUpgrade
+		wantCode codes.Code
+	}{
+		{name: "Success", ctx: userCtx1, req: &pb.SrpUpgrade{Password: "Test Password", Verifier: verifier}, wantCode: codes.OK},
+		{name: "Wrong password", ctx: userCtx1, req: &pb.SrpUpgrade{Password: "password", Verifier: verifier}, wantCode: codes.Unauthenticated},
+		{name: "Already SRP", ctx: userCtx2, req: &pb.SrpUpgrade{Password: "Test Password", Verifier: verifier}, wantCode: codes.FailedPrecondition},
+		{name: "Without verifier", ctx: userCtx1, req: &pb.SrpUpgrade{Password: "Test Password"}, wantCode: codes.InvalidArgument},
+		{name: "Wrong Ctx", ctx: context.Background(), req: &pb.SrpUpgrade{Password: "Test Password", Verifier: verifier}, wantCode: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Controller{db: md}
+			_, err := s.UpgradeToSrp(tt.ctx, tt.req)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestController_ChangePasswordSrp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	md := mocks.NewMockDataStorable(ctrl)
+	ms := mocks.NewMockServiceAuth(ctrl)
+	as = ms
+
+	kdf := &pb.KdfParams{Algorithm: "argon2id", Salt: []byte("new salt"), Time: 3, Memory: 65536, Threads: 4}
+	salt := []byte("0123456789abcdef")
+	srpUser := models.User{ID: uidU1, Email: "test@test.com", Kdf: models.KdfParams{Salt: salt}, Verifier: util.SrpVerifier(util.SrpX([]byte("key")))}
+	otherUser := srpUser
+	otherUser.ID, otherUser.Email = uidU2, "other@test.com"
+	newVerifier := util.SrpVerifier(util.SrpX([]byte("new key")))
+
+	md.EXPECT().GetUser(gomock.Any(), srpUser.Email).Return(&srpUser, nil).AnyTimes()
+	md.EXPECT().GetUser(gomock.Any(), "other@test.com").Return(&otherUser, nil).AnyTimes()
+	md.EXPECT().RekeyVault(userCtx1, models.User{Kdf: interfaces.DtoToKdf(kdf), WrappedKey: []byte("wrapped"), Verifier: newVerifier}, gomock.Nil(), gomock.Nil()).Return(nil)
+	ms.EXPECT().CreateJwt(gomock.Any(), gomock.Any()).Return("test token", nil)
+
+	tests := []struct {
+		name     string
+		email    string
+		key      string
+		verifier []byte
+		wantCode codes.Code
+	}{
+		{name: "Success", email: srpUser.Email, key: "key", verifier: newVerifier, wantCode: codes.OK},
+		{name: "Wrong old password", email: srpUser.Email, key: "another key", verifier: newVerifier, wantCode: codes.Unauthenticated},
+		{name: "Handshake of another user", email: "other@test.com", key: "key", verifier: newVerifier, wantCode: codes.PermissionDenied},
+		{name: "Without verifier", email: srpUser.Email, key: "key", wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Controller{db: md}
+			client, err := util.NewSrpClient(tt.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			challenge, err := s.LoginStart(context.Background(), &pb.SrpStart{Email: tt.email, A: client.A()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			m1, err := client.Proof(salt, util.SrpX([]byte(tt.key)), challenge.B)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = s.ChangePassword(userCtx1, &pb.PasswordChange{
+				Kdf:        kdf,
+				WrappedKey: []byte("wrapped"),
+				Proof:      &pb.SrpProof{HandshakeId: challenge.HandshakeId, M1: m1},
+				Verifier:   tt.verifier,
+			})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestController_passwordAuthDisabled(t *testing.T) {
+	AllowPasswordAuth(false)
+	defer AllowPasswordAuth(true)
+
+	s := &Controller{}
+	_, err := s.Login(context.Background(), &pb.User{Email: "user1@test.com", Password: "Test Password"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = s.Register(context.Background(), &pb.User{Email: "user1@test.com", Password: "Test Password"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestController_VerifyMfa(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	md := mocks.NewMockDataStorable(ctrl)
+	ms := mocks.NewMockServiceAuth(ctrl)
+	as = ms
+
+	now := time.Unix(1700000000, 0)
+	secret, err := auth.NewTotpSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := auth.TotpCode(secret, now)
+	step, _ := auth.ValidateTotp(secret, code, now)
+	recovery, hashes, err := auth.NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfaUser := testUser1
+	mfaUser.TotpSecret, mfaUser.TotpEnabled, mfaUser.RecoveryCodes = secret, true, hashes
+
+	md.EXPECT().UseTotpStep(gomock.Any(), uidU1, step).Return(true, nil)
+	md.EXPECT().UseTotpStep(gomock.Any(), uidU1, step).Return(false, nil)
+	md.EXPECT().UseRecoveryCode(gomock.Any(), uidU1, hashes[0]).Return(true, nil)
+	md.EXPECT().UseRecoveryCode(gomock.Any(), uidU1, hashes[1]).Return(false, nil)
+	md.EXPECT().AddSession(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	ms.EXPECT().CreateJwt(&mfaUser, gomock.Any()).Return("test token", nil).Times(2)
+
+	tests := []struct {
+		name     string
+		codes    []string
+		token    string
+		wantCode codes.Code
+	}{
+		{name: "TOTP code", codes: []string{code}, wantCode: codes.OK},
+		{name: "Replayed TOTP code", codes: []string{code}, wantCode: codes.Unauthenticated},
+		{name: "Recovery code", codes: []string{recovery[0]}, wantCode: codes.OK},
+		{name: "Used recovery code", codes: []string{recovery[1]}, wantCode: codes.Unauthenticated},
+		{name: "Wrong code", codes: []string{"000000"}, wantCode: codes.Unauthenticated},
+		{name: "Too many attempts", codes: []string{"1", "2", "3", "4", "5", code}, wantCode: codes.Unauthenticated},
+		{name: "Unknown token", codes: []string{code}, token: "token", wantCode: codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Controller{db: md, clock: func() time.Time { return now }}
+			challenge, err := s.startSession(context.Background(), &mfaUser)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, &pb.JwtToken{MfaToken: challenge.MfaToken}, challenge, "no tokens before the second factor")
+			if tt.token == "" {
+				tt.token = challenge.MfaToken
+			}
+
+			var got *pb.JwtToken
+			for _, c := range tt.codes {
+				got, err = s.VerifyMfa(context.Background(), &pb.MfaRequest{MfaToken: tt.token, Code: c})
+			}
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if err == nil {
+				assert.Equal(t, "test token", got.Token)
+				_, err = s.VerifyMfa(context.Background(), &pb.MfaRequest{MfaToken: tt.token, Code: tt.codes[0]})
+				assert.Equal(t, codes.Unauthenticated, status.Code(err), "mfa token must be single use")
+			}
+		})
+	}
+}
+
+func TestController_Totp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	md := mocks.NewMockDataStorable(ctrl)
+
+	now := time.Unix(1700000000, 0)
+	s := &Controller{db: md, clock: func() time.Time { return now }}
+	user := testUser1
+
+	t.Run("enroll", func(t *testing.T) {
+		md.EXPECT().GetUser(userCtx1, "test@test.com").Return(&user, nil)
+		md.EXPECT().SetTotp(userCtx1, gomock.Any()).DoAndReturn(func(_ context.Context, u models.User) error {
+			assert.False(t, u.TotpEnabled, "enrollment must be confirmed first")
+			user.TotpSecret = u.TotpSecret
+			return nil
+		})
+		got, err := s.EnrollTotp(userCtx1, &empty.Empty{})
+		assert.NoError(t, err)
+		assert.Equal(t, user.TotpSecret, got.Secret)
+		assert.Equal(t, auth.TotpURI("user1@test.com", got.Secret), got.Uri)
+	})
+	t.Run("confirm with a wrong code", func(t *testing.T) {
+		md.EXPECT().GetUser(userCtx1, "test@test.com").Return(&user, nil)
+		_, err := s.ConfirmTotp(userCtx1, &pb.TotpCode{Code: "000000"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("confirm", func(t *testing.T) {
+		code, _ := auth.TotpCode(user.TotpSecret, now)
+		md.EXPECT().GetUser(userCtx1, "test@test.com").Return(&user, nil)
+		md.EXPECT().SetTotp(userCtx1, gomock.Any()).DoAndReturn(func(_ context.Context, u models.User) error {
+			assert.True(t, u.TotpEnabled)
+			assert.Len(t, u.RecoveryCodes, auth.RecoveryCodeCount)
+			user.TotpEnabled, user.RecoveryCodes = true, u.RecoveryCodes
+			return nil
+		})
+		got, err := s.ConfirmTotp(userCtx1, &pb.TotpCode{Code: code})
+		assert.NoError(t, err)
+		assert.Len(t, got.Codes, auth.RecoveryCodeCount)
+	})
+	t.Run("enroll again", func(t *testing.T) {
+		md.EXPECT().GetUser(userCtx1, "test@test.com").Return(&user, nil)
+		_, err := s.EnrollTotp(userCtx1, &empty.Empty{})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+	t.Run("disable", func(t *testing.T) {
+		code, _ := auth.TotpCode(user.TotpSecret, now.Add(30*time.Second))
+		md.EXPECT().GetUser(userCtx1, "test@test.com").Return(&user, nil)
+		md.EXPECT().UseTotpStep(userCtx1, uidU1, gomock.Any()).Return(true, nil)
+		md.EXPECT().SetTotp(userCtx1, models.User{ID: uidU1}).Return(nil)
+		_, err := s.DisableTotp(userCtx1, &pb.TotpCode{Code: code})
+		assert.NoError(t, err)
+	})
+	t.Run("wrong ctx", func(t *testing.T) {
+		_, err := s.EnrollTotp(context.Background(), &empty.Empty{})
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+}
+
+func TestController_Refresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	md := mocks.NewMockDataStorable(ctrl)
+	ms := mocks.NewMockServiceAuth(ctrl)
+	as = ms
+
+	sessionID := uuid.New()
+	refreshToken, hash, err := auth.NewRefreshToken(sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedID := uuid.New()
+	revokedToken, _, err := auth.NewRefreshToken(revokedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stolenID := uuid.New()
+	stolenToken, _, err := auth.NewRefreshToken(stolenID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RequireCertIdentity(false)
+	withCert := func(email string) context.Context {
+		cert := &x509.Certificate{EmailAddresses: []string{email}}
+		return peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+		})
+	}
+
+	md.EXPECT().RefreshSession(gomock.Any(), sessionID, hash, gomock.Not(hash), gomock.Any()).Return(&testUser1, nil).Times(2)
+	md.EXPECT().RefreshSession(gomock.Any(), revokedID, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, database.ErrSessionRevoked)
+	md.EXPECT().RefreshSession(gomock.Any(), stolenID, gomock.Any(), gomock.Any(), gomock.Any()).Return(&testUser1, nil)
+	ms.EXPECT().CreateJwt(&testUser1, sessionID).Return("test token", nil).Times(2)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		certAuth bool
+		req      *pb.RefreshRequest
+		want     *pb.JwtToken
+		wantCode codes.Code
+	}{
+		{
+			name: "Success",
+			req:  &pb.RefreshRequest{RefreshToken: refreshToken},
+			want: &pb.JwtToken{Token: "test token"},
+		},
+		{
+			name:     "Certificate of the user",
+			ctx:      withCert(testUser1.Email),
+			certAuth: true,
+			req:      &pb.RefreshRequest{RefreshToken: refreshToken},
+			want:     &pb.JwtToken{Token: "test token"},
+		},
+		{
+			name:     "Certificate of another user",
+			ctx:      withCert("user2@test.com"),
+			certAuth: true,
+			req:      &pb.RefreshRequest{RefreshToken: stolenToken},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "Revoked session",
+			req:      &pb.RefreshRequest{RefreshToken: revokedToken},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "Malformed token",
+			req:      &pb.RefreshRequest{RefreshToken: "token"},
+			wantCode: codes.Unauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RequireCertIdentity(tt.certAuth)
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			s := &Controller{db: md}
+			got, err := s.Refresh(ctx, tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("Refresh() error = %v, wantCode %v", err, tt.wantCode)
+				return
+			}
+			if got != nil {
+				assert.NotEqual(t, refreshToken, got.RefreshToken)
+				assert.NotZero(t, got.ExpiresAt)
+				got.RefreshToken, got.ExpiresAt = "", 0
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Refresh() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestController_RevokeSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	md := mocks.NewMockDataStorable(ctrl)
+
+	current := userCtx1.Value("UserCtx").(*models.UserCtx).SessionID
+	other := uuid.New()
+	md.EXPECT().RevokeSession(userCtx1, current).Return(true, nil)
+	md.EXPECT().RevokeSession(userCtx1, other).Return(true, nil)
+	md.EXPECT().RevokeSession(userCtx1, uidS1).Return(false, nil)
+
+	s := &Controller{db: md}
+	t.Run("Logout", func(t *testing.T) {
+		_, err := s.Logout(userCtx1, &empty.Empty{})
+		assert.NoError(t, err)
+	})
+	t.Run("Revoke other session", func(t *testing.T) {
+		_, err := s.RevokeSession(userCtx1, &pb.SessionRequest{Id: other.String()})
+		assert.NoError(t, err)
+	})
+	t.Run("Unknown session", func(t *testing.T) {
+		_, err := s.RevokeSession(userCtx1, &pb.SessionRequest{Id: uidS1.String()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("Wrong id", func(t *testing.T) {
+		_, err := s.RevokeSession(userCtx1, &pb.SessionRequest{Id: "session"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("Wrong Ctx", func(t *testing.T) {
+		_, err := s.Logout(context.Background(), &empty.Empty{})
+		assert.Error(t, err)
+	})
+}
+
+func TestController_ListSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	md := mocks.NewMockDataStorable(ctrl)
+
+	current := userCtx1.Value("UserCtx").(*models.UserCtx).SessionID
+	other := uuid.New()
+	expires := time.Unix(1700000000, 0)
+	md.EXPECT().ListSessions(userCtx1).Return(&[]models.Session{
+		{ID: current, UserID: uidU1, Device: "laptop", CreatedAt: &expires, ExpiresAt: expires},
+		{ID: other, UserID: uidU1, Device: "phone", ExpiresAt: expires},
+	}, nil)
+
+	s := &Controller{db: md}
+	got, err := s.ListSessions(userCtx1, &empty.Empty{})
+	assert.NoError(t, err)
+	want := &pb.SessionList{Sessions: []*pb.Session{
+		{Id: current.String(), Device: "laptop", CreatedAt: expires.Unix(), ExpiresAt: expires.Unix(), Current: true},
+		{Id: other.String(), Device: "phone", ExpiresAt: expires.Unix()},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListSessions() got = %v, want %v", got, want)
+	}
+}
+
+func TestNewController(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	md := mocks.NewMockDataStorable(ctrl)
+	ms := mocks.NewMockServiceAuth(ctrl)
+	t.Run("New controller", func(t *testing.T) {
+		got := NewController(log, md, ms)
+		assert.NotNil(t, got)
+	})
+}
+
+func TestTokenInterceptor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ms := mocks.NewMockServiceAuth(ctrl)
+	md := mocks.NewMockDataStorable(ctrl)
+	as = ms
+	prev := cs
+	cs = &Controller{db: md}
+	defer func() { cs = prev }()
+	defer RequireCertIdentity(false)
+
+	user1 := &models.UserCtx{Id: uidU1, Username: "Test User", Email: "user1@test.com", SessionID: uuid.New()}
+	revoked := &models.UserCtx{Id: uidU1, Username: "Test User", Email: "user1@test.com", SessionID: uuid.New()}
+	ms.EXPECT().CreateUserCtx("valid").Return(user1, nil).AnyTimes()
+	ms.EXPECT().CreateUserCtx("revoked").Return(revoked, nil).AnyTimes()
+	ms.EXPECT().CreateUserCtx("invalid").Return(nil, errors.New("invalid token")).AnyTimes()
+	md.EXPECT().IsSessionActive(gomock.Any(), user1.SessionID).Return(true, nil).AnyTimes()
+	md.EXPECT().IsSessionActive(gomock.Any(), revoked.SessionID).Return(false, nil).AnyTimes()
+
+	withToken := func(ctx context.Context, token string) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs("token", token))
+	}
+	withCert := func(email string) context.Context {
+		cert := &x509.Certificate{EmailAddresses: []string{email}}
+		return peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+		})
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	notesInfo := &grpc.UnaryServerInfo{FullMethod: pb.NoteServices_GetNotes_FullMethodName}
+	loginInfo := &grpc.UnaryServerInfo{FullMethod: pb.UserServices_Login_FullMethodName}
+	refreshInfo := &grpc.UnaryServerInfo{FullMethod: pb.UserServices_Refresh_FullMethodName}
+	srpStartInfo := &grpc.UnaryServerInfo{FullMethod: pb.UserServices_LoginStart_FullMethodName}
+	srpFinishInfo := &grpc.UnaryServerInfo{FullMethod: pb.UserServices_LoginFinish_FullMethodName}
+
+	type args struct {
+		ctx     context.Context
+		req     interface{}
+		info    *grpc.UnaryServerInfo
+		handler grpc.UnaryHandler
+	}
+	tests := []struct {
+		name     string
+		args     args
+		certAuth bool
+		want     interface{}
+		wantErr  bool
+	}{
+		{
+			name:    "valid token",
+			args:    args{ctx: withToken(context.Background(), "valid"), info: notesInfo, handler: handler},
+			want:    "ok",
+			wantErr: false,
+		},
+		{
+			name:    "missing token",
+			args:    args{ctx: context.Background(), info: notesInfo, handler: handler},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "invalid token",
+			args:    args{ctx: withToken(context.Background(), "invalid"), info: notesInfo, handler: handler},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "revoked session",
+			args:    args{ctx: withToken(context.Background(), "revoked"), info: notesInfo, handler: handler},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "refresh without token",
+			args:    args{ctx: context.Background(), req: &pb.RefreshRequest{}, info: refreshInfo, handler: handler},
+			want:    "ok",
+			wantErr: false,
+		},
+		{
+			name:    "login without token",
+			args:    args{ctx: context.Background(), req: &pb.User{Email: "user1@test.com"}, info: loginInfo, handler: handler},
+			want:    "ok",
+			wantErr: false,
+		},
+		{
+			name:     "certificate matches token user",
+			args:     args{ctx: withToken(withCert("USER1@test.com"), "valid"), info: notesInfo, handler: handler},
+			certAuth: true,
+			want:     "ok",
+			wantErr:  false,
+		},
+		{
+			name:     "certificate of another user",
+			args:     args{ctx: withToken(withCert("user2@test.com"), "valid"), info: notesInfo, handler: handler},
+			certAuth: true,
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "missing certificate",
+			args:     args{ctx: withToken(context.Background(), "valid"), info: notesInfo, handler: handler},
+			certAuth: true,
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "login with own certificate",
+			args:     args{ctx: withCert("user1@test.com"), req: &pb.User{Email: "user1@test.com"}, info: loginInfo, handler: handler},
+			certAuth: true,
+			want:     "ok",
+			wantErr:  false,
+		},
+		{
+			name:     "login with another certificate",
+			args:     args{ctx: withCert("user2@test.com"), req: &pb.User{Email: "user1@test.com"}, info: loginInfo, handler: handler},
+			certAuth: true,
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:     "srp login with another certificate",
+			args:     args{ctx: withCert("user2@test.com"), req: &pb.SrpStart{Email: "user1@test.com"}, info: srpStartInfo, handler: handler},
+			certAuth: true,
+			want:     nil,
+			wantErr:  true,
+		},
+		{
+			name:    "srp proof without token",
+			args:    args{ctx: context.Background(), req: &pb.SrpProof{}, info: srpFinishInfo, handler: handler},
+			want:    "ok",
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RequireCertIdentity(tt.certAuth)
+			got, err := TokenInterceptor(tt.args.ctx, tt.args.req, tt.args.info, tt.args.handler)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TokenInterceptor() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TokenInterceptor() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenStreamInterceptor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ms := mocks.NewMockServiceAuth(ctrl)
+	md := mocks.NewMockDataStorable(ctrl)
+	as = ms
+	prev := cs
+	cs = &Controller{db: md}
+	defer func() { cs = prev }()
+
+	user1 := &models.UserCtx{Id: uidU1, Username: "Test User", Email: "user1@test.com", SessionID: uuid.New()}
+	revoked := &models.UserCtx{Id: uidU1, Username: "Test User", Email: "user1@test.com", SessionID: uuid.New()}
+	ms.EXPECT().CreateUserCtx("valid").Return(user1, nil).AnyTimes()
+	ms.EXPECT().CreateUserCtx("revoked").Return(revoked, nil).AnyTimes()
+	md.EXPECT().IsSessionActive(gomock.Any(), user1.SessionID).Return(true, nil).AnyTimes()
+	md.EXPECT().IsSessionActive(gomock.Any(), revoked.SessionID).Return(false, nil).AnyTimes()
+	info := &grpc.StreamServerInfo{FullMethod: pb.NoteServices_WatchNotes_FullMethodName, IsServerStream: true}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantUser *models.UserCtx
+		wantErr  bool
+	}{
+		{name: "valid token", token: "valid", wantUser: user1},
+		{name: "missing token", wantErr: true},
+		{name: "revoked session", token: "revoked", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("token", tt.token))
+			}
+			var got *models.UserCtx
+			handler := func(srv interface{}, stream grpc.ServerStream) error {
+				got, _ = stream.Context().Value("UserCtx").(*models.UserCtx)
+				return nil
+			}
+			err := TokenStreamInterceptor(nil, &fakeWatchStream{ctx: ctx}, info, handler)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TokenStreamInterceptor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wantUser {
+				t.Errorf("TokenStreamInterceptor() user = %v, want %v", got, tt.wantUser)
 			}
 		})
 	}
@@ -689,8 +2078,9 @@ func TestTokenInterceptor(t *testing.T) {
 
 func addContext(ctx context.Context, userId uuid.UUID) context.Context {
 	return context.WithValue(ctx, "UserCtx", &models.UserCtx{
-		Username: "Test",
-		Email:    "test@test.com",
-		Id:       userId,
+		Username:  "Test",
+		Email:     "test@test.com",
+		Id:        userId,
+		SessionID: uuid.NewSHA1(uuid.Nil, userId[:]),
 	})
 }
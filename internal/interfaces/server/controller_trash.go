@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/database"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Controller) ListTrash(ctx context.Context, _ *empty.Empty) (*pb.TrashList, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "ListTrash",
+		"user":   userCtx.Email,
+	})
+
+	trash, err := s.db.ListTrash(ctx)
+	if err != nil {
+		if errors.Is(err, database.ErrUserNotFound) {
+			log.Warn("Context not found")
+			return nil, status.Error(codes.Unauthenticated, "User not authenticated")
+		}
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	list := &pb.TrashList{Notes: make([]*pb.TrashedNote, 0, len(*trash))}
+	for _, data := range *trash {
+		list.Notes = append(list.Notes, interfaces.TrashedToDto(data))
+	}
+	return list, nil
+}
+
+// RestoreNote takes a note out of the trash. A note that is not in the trash,
+// or was purged, is NotFound.
+func (s *Controller) RestoreNote(ctx context.Context, req *pb.NoteRequest) (*pb.Note, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "RestoreNote",
+		"user":   userCtx.Email,
+	})
+
+	id, err := uuid.Parse(req.IdNote)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	restored, err := s.db.RestoreSecretData(ctx, id)
+	if err != nil {
+		return nil, noteWriteError(log, err, req.IdNote)
+	}
+	return interfaces.EntityToDto(*restored), nil
+}
+
+func (s *Controller) EmptyTrash(ctx context.Context, _ *empty.Empty) (*pb.TrashCount, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "EmptyTrash",
+		"user":   userCtx.Email,
+	})
+
+	purged, err := s.db.EmptyTrash(ctx)
+	if err != nil {
+		if errors.Is(err, database.ErrUserNotFound) {
+			log.Warn("Context not found")
+			return nil, status.Error(codes.Unauthenticated, "User not authenticated")
+		}
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.TrashCount{Purged: int64(purged)}, nil
+}
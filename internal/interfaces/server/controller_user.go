@@ -3,10 +3,15 @@ package server
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/database"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces"
 	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/auth"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
@@ -20,6 +25,9 @@ func (s *Controller) Register(ctx context.Context, user *pb.User) (*pb.JwtToken,
 		"method": "Register",
 		"user":   user.Email,
 	})
+	if !passwordAuth {
+		return nil, status.Error(codes.Unimplemented, "password login is disabled")
+	}
 
 	uc := &models.UserCtx{
 		Username: "not register",
@@ -33,9 +41,11 @@ func (s *Controller) Register(ctx context.Context, user *pb.User) (*pb.JwtToken,
 	}
 	userCtx := util.AddContextUserCtx(ctx, "not register", user.Email, uuid.Nil)
 	newUser, err := s.db.AddUser(userCtx, &models.User{
-		Username: user.Username,
-		Email:    user.Email,
-		Password: password,
+		Username:   user.Username,
+		Email:      user.Email,
+		Password:   password,
+		Kdf:        interfaces.DtoToKdf(user.Kdf),
+		WrappedKey: user.WrappedKey,
 	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrDuplicatedKey) {
@@ -44,18 +54,21 @@ func (s *Controller) Register(ctx context.Context, user *pb.User) (*pb.JwtToken,
 		log.WithError(err).Error("could not add user")
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-	jwt, err := as.CreateJwt(newUser)
+	token, err := s.openSession(ctx, newUser)
 	if err != nil {
-		log.WithError(err).Error("could not create jwt")
+		log.WithError(err).Error("could not open session")
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-	return &pb.JwtToken{Token: jwt}, nil
+	return token, nil
 }
 func (s *Controller) Login(ctx context.Context, user *pb.User) (*pb.JwtToken, error) {
 	log := log.WithFields(logrus.Fields{
 		"method": "Login",
 		"user":   user.Email,
 	})
+	if !passwordAuth {
+		return nil, status.Error(codes.Unimplemented, "password login is disabled")
+	}
 
 	userCtx := util.AddContextUserCtx(ctx, "not sig in", user.Email, uuid.Nil)
 	getUser, err := s.db.GetUser(userCtx, user.Email)
@@ -67,17 +80,195 @@ func (s *Controller) Login(ctx context.Context, user *pb.User) (*pb.JwtToken, er
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	if err = bcrypt.CompareHashAndPassword(getUser.Password, []byte(user.Password)); err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return nil, status.Error(codes.Unauthenticated, "Passwords is incorrect")
+	if err = checkPassword(getUser, user.Password); err != nil {
+		return nil, err
+	}
+
+	token, err := s.startSession(ctx, getUser)
+	if err != nil {
+		log.WithError(err).Error("could not open session")
+		return nil, err
+	}
+	return token, nil
+}
+
+func (s *Controller) RekeyVault(ctx context.Context, req *pb.VaultRekey) (*empty.Empty, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "RekeyVault",
+		"user":   userCtx.Email,
+	})
+
+	kdf := interfaces.DtoToKdf(req.Kdf)
+	if len(kdf.Salt) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "kdf parameters are required")
+	}
+	data, revisions, err := rekeyedSecrets(req.Notes, req.Revisions, req.WrappedKey)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	getUser, err := s.db.GetUser(ctx, userCtx.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "User not found")
+		}
+		log.WithError(err).Error("Could not get user")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	// Only a vault still on a password-derived key is moved forward with the
+	// session alone; a token must not be enough to replace a data key.
+	if len(getUser.WrappedKey) > 0 || len(getUser.Verifier) > 0 {
+		if err = checkOwner(ctx, getUser, req.Password, req.Proof); err != nil {
+			log.Warn("re-key without proof of the password")
+			return nil, err
+		}
+	}
+
+	if err = s.db.RekeyVault(ctx, models.User{Kdf: kdf, WrappedKey: req.WrappedKey}, data, revisions); err != nil {
+		if errors.Is(err, database.ErrVaultMismatch) {
+			log.Warn(err.Error())
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		if errors.Is(err, database.ErrUserNotFound) {
+			log.Warn("Context not found")
+			return nil, status.Error(codes.Unauthenticated, "User not authenticated")
 		}
+		log.Error(err.Error())
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	log.Info("vault re-keyed")
+	return &empty.Empty{}, nil
+}
+
+func (s *Controller) ChangePassword(ctx context.Context, req *pb.PasswordChange) (*pb.JwtToken, error) {
+	userCtx, ok := ctx.Value("UserCtx").(*models.UserCtx)
+	if !ok {
+		return nil, status.Error(codes.Internal, "User not found")
+	}
+	log := log.WithFields(logrus.Fields{
+		"method": "ChangePassword",
+		"user":   userCtx.Email,
+	})
+
+	kdf := interfaces.DtoToKdf(req.Kdf)
+	if len(kdf.Salt) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "kdf parameters are required")
+	}
+	data, revisions, err := rekeyedSecrets(req.Notes, req.Revisions, req.WrappedKey)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 
-	jwt, err := as.CreateJwt(getUser)
+	getUser, err := s.db.GetUser(ctx, userCtx.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "User not found")
+		}
+		log.WithError(err).Error("Could not get user")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	password, err := checkOldPassword(ctx, getUser, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.db.RekeyVault(ctx, models.User{Password: password, Kdf: kdf, WrappedKey: req.WrappedKey, Verifier: req.Verifier}, data, revisions); err != nil {
+		if errors.Is(err, database.ErrVaultMismatch) {
+			log.Warn(err.Error())
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		log.Error(err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	updated := *getUser
+	updated.Password = password
+	updated.Kdf = kdf
+	updated.WrappedKey = req.WrappedKey
+	updated.Verifier = req.Verifier
+	jwt, err := as.CreateJwt(&updated, userCtx.SessionID)
 	if err != nil {
 		log.WithError(err).Error("could not create jwt")
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-	return &pb.JwtToken{Token: jwt}, nil
+	log.Info("password changed")
+	return &pb.JwtToken{
+		Token:      jwt,
+		Kdf:        interfaces.KdfToDto(kdf),
+		WrappedKey: req.WrappedKey,
+		ExpiresAt:  time.Now().Add(auth.AccessTokenTTL).Unix(),
+	}, nil
+}
+
+// checkOldPassword authenticates a password change and returns the password
+// hash to store. SRP accounts prove the old password with a finished
+// handshake and keep an empty hash. A password account that sends a verifier
+// moves to SRP.
+func checkOldPassword(ctx context.Context, user *models.User, req *pb.PasswordChange) ([]byte, error) {
+	if len(user.Verifier) > 0 && len(req.Verifier) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "verifier is required")
+	}
+	if err := checkOwner(ctx, user, req.OldPassword, req.GetProof()); err != nil {
+		return nil, err
+	}
+	if len(user.Verifier) > 0 {
+		return nil, nil
+	}
+	if len(req.Verifier) > 0 {
+		return []byte{}, nil
+	}
+	password, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.WithError(err).Error("error generating password")
+		return nil, status.Errorf(codes.Internal, "error hash password")
+	}
+	return password, nil
+}
+
+// checkOwner authenticates a change of the key material: SRP accounts with a
+// finished handshake of their own, password accounts with the password.
+func checkOwner(ctx context.Context, user *models.User, password string, proof *pb.SrpProof) error {
+	if len(user.Verifier) == 0 {
+		return checkPassword(user, password)
+	}
+	h, err := verifyHandshake(ctx, proof)
+	if err != nil {
+		return err
+	}
+	if h.user.ID != user.ID {
+		return status.Error(codes.PermissionDenied, "handshake belongs to another user")
+	}
+	return nil
+}
+
+// rekeyedSecrets converts re-encrypted notes and revisions into entities. A
+// request that carries only a wrapped data key leaves the notes untouched and
+// yields nil.
+func rekeyedSecrets(notes []*pb.Note, revisions []*pb.NoteRevision, wrappedKey []byte) ([]models.SecretData, []models.SecretRevision, error) {
+	if len(notes) == 0 && len(revisions) == 0 && len(wrappedKey) > 0 {
+		return nil, nil, nil
+	}
+	data := make([]models.SecretData, 0, len(notes))
+	for _, note := range notes {
+		sd, err := interfaces.DtoToEntity(note)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = append(data, sd)
+	}
+	history := make([]models.SecretRevision, 0, len(revisions))
+	for _, revision := range revisions {
+		sr, err := interfaces.DtoToRevision(revision)
+		if err != nil {
+			return nil, nil, err
+		}
+		history = append(history, sr)
+	}
+	return data, history, nil
 }
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var certIdentity bool
+
+// RequireCertIdentity makes TokenInterceptor map the verified client
+// certificate of every call to a user: its e-mail address, or common name,
+// must match the account the call acts for. The server has to run with
+// mutual TLS.
+func RequireCertIdentity(enabled bool) {
+	certIdentity = enabled
+}
+
+func checkCertIdentity(ctx context.Context, email string) error {
+	if !certIdentity {
+		return nil
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "missing client certificate")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return status.Error(codes.Unauthenticated, "missing client certificate")
+	}
+	if !strings.EqualFold(util.CertIdentity(info.State.VerifiedChains[0][0]), email) {
+		return status.Error(codes.PermissionDenied, "client certificate does not belong to the user")
+	}
+	return nil
+}
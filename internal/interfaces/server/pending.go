@@ -0,0 +1,79 @@
+package server
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxPending bounds every store, so unfinished logins cannot exhaust memory.
+const maxPending = 10000
+
+// pendingStore keeps the state of multi-step logins under a random id until
+// the next step arrives or ttl passes. The state lives in the memory of this
+// instance, so the steps of one login must reach the same server.
+type pendingStore[T any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	pending map[string]pendingItem[T]
+}
+
+type pendingItem[T any] struct {
+	value   T
+	expires time.Time
+}
+
+func newPendingStore[T any](ttl time.Duration) *pendingStore[T] {
+	return &pendingStore[T]{ttl: ttl, pending: make(map[string]pendingItem[T])}
+}
+
+func (ps *pendingStore[T]) add(value T) (string, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	now := time.Now()
+	if len(ps.pending) >= maxPending {
+		for id, item := range ps.pending {
+			if now.After(item.expires) {
+				delete(ps.pending, id)
+			}
+		}
+		if len(ps.pending) >= maxPending {
+			return "", ErrTooManyPending
+		}
+	}
+	id := uuid.NewString()
+	ps.pending[id] = pendingItem[T]{value: value, expires: now.Add(ps.ttl)}
+	return id, nil
+}
+
+// get returns the value of id and keeps it in the store.
+func (ps *pendingStore[T]) get(id string) (T, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	item, ok := ps.pending[id]
+	if ok && time.Now().After(item.expires) {
+		delete(ps.pending, id)
+		ok = false
+	}
+	return item.value, ok
+}
+
+// take removes id from the store. Only one caller gets its value.
+func (ps *pendingStore[T]) take(id string) (T, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	item, ok := ps.pending[id]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	delete(ps.pending, id)
+	return item.value, time.Now().Before(item.expires)
+}
+
+var ErrTooManyPending = errors.New("too many pending logins")
@@ -11,10 +11,120 @@ func DtoToEntity(note *pb.Note) (models.SecretData, error) {
 	if err != nil {
 		return models.SecretData{}, err
 	}
+	var blobID *uuid.UUID
+	if note.BlobId != "" {
+		id, err := uuid.Parse(note.BlobId)
+		if err != nil {
+			return models.SecretData{}, err
+		}
+		blobID = &id
+	}
+	if note.Sealed {
+		return models.SecretData{ID: uid, Secret: note.SecretData, Sealed: true, Revision: note.Revision, BlobID: blobID}, nil
+	}
 	return models.SecretData{
-		ID:     uid,
-		Type:   note.Type,
-		Name:   note.Name,
-		Secret: note.SecretData,
+		ID:       uid,
+		Type:     note.Type,
+		Name:     note.Name,
+		Secret:   note.SecretData,
+		Revision: note.Revision,
+		BlobID:   blobID,
 	}, nil
 }
+
+func EntityToDto(data models.SecretData) *pb.Note {
+	note := &pb.Note{
+		Id:         data.ID.String(),
+		Name:       data.Name,
+		Type:       data.Type,
+		SecretData: data.Secret,
+		Sealed:     data.Sealed,
+		Revision:   data.Revision,
+	}
+	if data.BlobID != nil {
+		note.BlobId = data.BlobID.String()
+	}
+	return note
+}
+
+// RevisionToDto returns a kept revision as the note it was.
+func RevisionToDto(revision models.SecretRevision) *pb.NoteRevision {
+	note := EntityToDto(models.SecretData{
+		ID:       revision.SecretID,
+		Type:     revision.Type,
+		Name:     revision.Name,
+		Secret:   revision.Secret,
+		Sealed:   revision.Sealed,
+		Revision: revision.Revision,
+		BlobID:   revision.BlobID,
+	})
+	dto := &pb.NoteRevision{Note: note}
+	if revision.WrittenAt != nil {
+		dto.WrittenAt = revision.WrittenAt.Unix()
+	}
+	return dto
+}
+
+// DtoToRevision returns the kept revision a NoteRevision describes.
+func DtoToRevision(revision *pb.NoteRevision) (models.SecretRevision, error) {
+	data, err := DtoToEntity(revision.GetNote())
+	if err != nil {
+		return models.SecretRevision{}, err
+	}
+	return models.SecretRevision{
+		SecretID: data.ID,
+		Revision: data.Revision,
+		Type:     data.Type,
+		Name:     data.Name,
+		Secret:   data.Secret,
+		Sealed:   data.Sealed,
+		BlobID:   data.BlobID,
+	}, nil
+}
+
+// TrashedToDto returns a deleted note with the time it was deleted.
+func TrashedToDto(data models.SecretData) *pb.TrashedNote {
+	return &pb.TrashedNote{Note: EntityToDto(data), DeletedAt: data.DeletedAt.Time.Unix()}
+}
+
+func KdfToDto(params models.KdfParams) *pb.KdfParams {
+	if len(params.Salt) == 0 {
+		return nil
+	}
+	return &pb.KdfParams{
+		Algorithm: params.Algorithm,
+		Salt:      params.Salt,
+		Time:      params.Time,
+		Memory:    params.Memory,
+		Threads:   uint32(params.Threads),
+	}
+}
+
+func DtoToKdf(params *pb.KdfParams) models.KdfParams {
+	if params == nil {
+		return models.KdfParams{}
+	}
+	return models.KdfParams{
+		Algorithm: params.Algorithm,
+		Salt:      params.Salt,
+		Time:      params.Time,
+		Memory:    params.Memory,
+		Threads:   uint8(params.Threads),
+	}
+}
+
+func SessionToDto(session models.Session, current uuid.UUID) *pb.Session {
+	dto := &pb.Session{
+		Id:        session.ID.String(),
+		Device:    session.Device,
+		ExpiresAt: session.ExpiresAt.Unix(),
+		Current:   session.ID == current,
+	}
+	if session.CreatedAt != nil {
+		dto.CreatedAt = session.CreatedAt.Unix()
+	}
+	if session.UpdatedAt != nil {
+		dto.LastUsedAt = session.UpdatedAt.Unix()
+	}
+	return dto
+}
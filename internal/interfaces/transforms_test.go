@@ -10,6 +10,7 @@ import (
 )
 
 func TestDtoToEntity(t *testing.T) {
+	blobID := uuid.New()
 	type args struct {
 		note *pb.Note
 	}
@@ -27,16 +28,65 @@ func TestDtoToEntity(t *testing.T) {
 					Name:       "Test Note",
 					Type:       models.CARD.String(),
 					SecretData: []byte{1, 2, 3},
+					Revision:   4,
+				},
+			},
+			want: models.SecretData{
+				ID:       uuid.Nil,
+				Name:     "Test Note",
+				Type:     models.CARD.String(),
+				Secret:   []byte{1, 2, 3},
+				Revision: 4,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Sealed drops metadata",
+			args: args{
+				note: &pb.Note{
+					Id:         uuid.Nil.String(),
+					Name:       "Test Note",
+					Type:       models.CARD.String(),
+					SecretData: []byte{1, 2, 3},
+					Sealed:     true,
+				},
+			},
+			want: models.SecretData{
+				ID:     uuid.Nil,
+				Secret: []byte{1, 2, 3},
+				Sealed: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Attached blob",
+			args: args{
+				note: &pb.Note{
+					Id:         uuid.Nil.String(),
+					SecretData: []byte{1, 2, 3},
+					Sealed:     true,
+					BlobId:     blobID.String(),
 				},
 			},
 			want: models.SecretData{
 				ID:     uuid.Nil,
-				Name:   "Test Note",
-				Type:   models.CARD.String(),
 				Secret: []byte{1, 2, 3},
+				Sealed: true,
+				BlobID: &blobID,
 			},
 			wantErr: false,
 		},
+		{
+			name: "Wrong blob id",
+			args: args{
+				note: &pb.Note{
+					Id:     uuid.Nil.String(),
+					BlobId: "blob",
+				},
+			},
+			want:    models.SecretData{},
+			wantErr: true,
+		},
 		{
 			name: "Wrong Id",
 			args: args{
@@ -64,3 +114,34 @@ func TestDtoToEntity(t *testing.T) {
 		})
 	}
 }
+
+func TestKdfToDto(t *testing.T) {
+	params := models.KdfParams{Algorithm: "argon2id", Salt: []byte{1, 2, 3}, Time: 3, Memory: 65536, Threads: 4}
+	tests := []struct {
+		name   string
+		params models.KdfParams
+		want   *pb.KdfParams
+	}{
+		{
+			name:   "Success",
+			params: params,
+			want:   &pb.KdfParams{Algorithm: "argon2id", Salt: []byte{1, 2, 3}, Time: 3, Memory: 65536, Threads: 4},
+		},
+		{
+			name:   "Legacy user without salt",
+			params: models.KdfParams{},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := KdfToDto(tt.params)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("KdfToDto() got = %v, want %v", got, tt.want)
+			}
+			if got != nil && !reflect.DeepEqual(DtoToKdf(got), tt.params) {
+				t.Errorf("DtoToKdf() got = %v, want %v", DtoToKdf(got), tt.params)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
 	models "github.com/katvixlab/go-diplom-gophkeeper/internal/models"
 )
 
@@ -35,18 +36,18 @@ func (m *MockServiceAuth) EXPECT() *MockServiceAuthMockRecorder {
 }
 
 // CreateJwt mocks base method.
-func (m *MockServiceAuth) CreateJwt(arg0 *models.User) (string, error) {
+func (m *MockServiceAuth) CreateJwt(arg0 *models.User, arg1 uuid.UUID) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateJwt", arg0)
+	ret := m.ctrl.Call(m, "CreateJwt", arg0, arg1)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateJwt indicates an expected call of CreateJwt.
-func (mr *MockServiceAuthMockRecorder) CreateJwt(arg0 interface{}) *gomock.Call {
+func (mr *MockServiceAuthMockRecorder) CreateJwt(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJwt", reflect.TypeOf((*MockServiceAuth)(nil).CreateJwt), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJwt", reflect.TypeOf((*MockServiceAuth)(nil).CreateJwt), arg0, arg1)
 }
 
 // CreateUserCtx mocks base method.
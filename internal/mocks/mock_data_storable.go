@@ -7,9 +7,11 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
+	database "github.com/katvixlab/go-diplom-gophkeeper/internal/database"
 	models "github.com/katvixlab/go-diplom-gophkeeper/internal/models"
 )
 
@@ -36,6 +38,21 @@ func (m *MockDataStorable) EXPECT() *MockDataStorableMockRecorder {
 	return m.recorder
 }
 
+// AddBlobChunk mocks base method.
+func (m *MockDataStorable) AddBlobChunk(arg0 context.Context, arg1 uuid.UUID, arg2 int64, arg3 []byte, arg4 bool) (*models.Blob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddBlobChunk", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*models.Blob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddBlobChunk indicates an expected call of AddBlobChunk.
+func (mr *MockDataStorableMockRecorder) AddBlobChunk(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBlobChunk", reflect.TypeOf((*MockDataStorable)(nil).AddBlobChunk), arg0, arg1, arg2, arg3, arg4)
+}
+
 // AddSecretData mocks base method.
 func (m *MockDataStorable) AddSecretData(arg0 context.Context, arg1 models.SecretData) (*models.SecretData, error) {
 	m.ctrl.T.Helper()
@@ -51,6 +68,20 @@ func (mr *MockDataStorableMockRecorder) AddSecretData(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSecretData", reflect.TypeOf((*MockDataStorable)(nil).AddSecretData), arg0, arg1)
 }
 
+// AddSession mocks base method.
+func (m *MockDataStorable) AddSession(arg0 context.Context, arg1 models.Session) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddSession", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddSession indicates an expected call of AddSession.
+func (mr *MockDataStorableMockRecorder) AddSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSession", reflect.TypeOf((*MockDataStorable)(nil).AddSession), arg0, arg1)
+}
+
 // AddUser mocks base method.
 func (m *MockDataStorable) AddUser(arg0 context.Context, arg1 *models.User) (*models.User, error) {
 	m.ctrl.T.Helper()
@@ -66,19 +97,64 @@ func (mr *MockDataStorableMockRecorder) AddUser(arg0, arg1 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUser", reflect.TypeOf((*MockDataStorable)(nil).AddUser), arg0, arg1)
 }
 
+// Backup mocks base method.
+func (m *MockDataStorable) Backup(arg0 context.Context, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Backup", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Backup indicates an expected call of Backup.
+func (mr *MockDataStorableMockRecorder) Backup(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Backup", reflect.TypeOf((*MockDataStorable)(nil).Backup), arg0, arg1)
+}
+
+// CollectBlobs mocks base method.
+func (m *MockDataStorable) CollectBlobs(arg0 context.Context, arg1 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CollectBlobs", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CollectBlobs indicates an expected call of CollectBlobs.
+func (mr *MockDataStorableMockRecorder) CollectBlobs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectBlobs", reflect.TypeOf((*MockDataStorable)(nil).CollectBlobs), arg0, arg1)
+}
+
+// DeleteBlob mocks base method.
+func (m *MockDataStorable) DeleteBlob(arg0 context.Context, arg1 uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBlob", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteBlob indicates an expected call of DeleteBlob.
+func (mr *MockDataStorableMockRecorder) DeleteBlob(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBlob", reflect.TypeOf((*MockDataStorable)(nil).DeleteBlob), arg0, arg1)
+}
+
 // DeleteSecretData mocks base method.
-func (m *MockDataStorable) DeleteSecretData(arg0 context.Context, arg1 uuid.UUID) (bool, error) {
+func (m *MockDataStorable) DeleteSecretData(arg0 context.Context, arg1 uuid.UUID, arg2 int64) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteSecretData", arg0, arg1)
+	ret := m.ctrl.Call(m, "DeleteSecretData", arg0, arg1, arg2)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // DeleteSecretData indicates an expected call of DeleteSecretData.
-func (mr *MockDataStorableMockRecorder) DeleteSecretData(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockDataStorableMockRecorder) DeleteSecretData(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSecretData", reflect.TypeOf((*MockDataStorable)(nil).DeleteSecretData), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSecretData", reflect.TypeOf((*MockDataStorable)(nil).DeleteSecretData), arg0, arg1, arg2)
 }
 
 // DeleteUser mocks base method.
@@ -96,6 +172,51 @@ func (mr *MockDataStorableMockRecorder) DeleteUser(arg0, arg1 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockDataStorable)(nil).DeleteUser), arg0, arg1)
 }
 
+// EmptyTrash mocks base method.
+func (m *MockDataStorable) EmptyTrash(arg0 context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EmptyTrash", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EmptyTrash indicates an expected call of EmptyTrash.
+func (mr *MockDataStorableMockRecorder) EmptyTrash(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EmptyTrash", reflect.TypeOf((*MockDataStorable)(nil).EmptyTrash), arg0)
+}
+
+// GetBlob mocks base method.
+func (m *MockDataStorable) GetBlob(arg0 context.Context, arg1 uuid.UUID) (*models.Blob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlob", arg0, arg1)
+	ret0, _ := ret[0].(*models.Blob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlob indicates an expected call of GetBlob.
+func (mr *MockDataStorableMockRecorder) GetBlob(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlob", reflect.TypeOf((*MockDataStorable)(nil).GetBlob), arg0, arg1)
+}
+
+// GetBlobChunk mocks base method.
+func (m *MockDataStorable) GetBlobChunk(arg0 context.Context, arg1 uuid.UUID, arg2 int64) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlobChunk", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlobChunk indicates an expected call of GetBlobChunk.
+func (mr *MockDataStorableMockRecorder) GetBlobChunk(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlobChunk", reflect.TypeOf((*MockDataStorable)(nil).GetBlobChunk), arg0, arg1, arg2)
+}
+
 // GetSecretData mocks base method.
 func (m *MockDataStorable) GetSecretData(arg0 context.Context) (*[]models.SecretData, error) {
 	m.ctrl.T.Helper()
@@ -126,6 +247,66 @@ func (mr *MockDataStorableMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockDataStorable)(nil).GetUser), arg0, arg1)
 }
 
+// IsSessionActive mocks base method.
+func (m *MockDataStorable) IsSessionActive(arg0 context.Context, arg1 uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSessionActive", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsSessionActive indicates an expected call of IsSessionActive.
+func (mr *MockDataStorableMockRecorder) IsSessionActive(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSessionActive", reflect.TypeOf((*MockDataStorable)(nil).IsSessionActive), arg0, arg1)
+}
+
+// ListSecretRevisions mocks base method.
+func (m *MockDataStorable) ListSecretRevisions(arg0 context.Context, arg1 uuid.UUID) (*[]models.SecretRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSecretRevisions", arg0, arg1)
+	ret0, _ := ret[0].(*[]models.SecretRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSecretRevisions indicates an expected call of ListSecretRevisions.
+func (mr *MockDataStorableMockRecorder) ListSecretRevisions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSecretRevisions", reflect.TypeOf((*MockDataStorable)(nil).ListSecretRevisions), arg0, arg1)
+}
+
+// ListSessions mocks base method.
+func (m *MockDataStorable) ListSessions(arg0 context.Context) (*[]models.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions", arg0)
+	ret0, _ := ret[0].(*[]models.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockDataStorableMockRecorder) ListSessions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockDataStorable)(nil).ListSessions), arg0)
+}
+
+// ListTrash mocks base method.
+func (m *MockDataStorable) ListTrash(arg0 context.Context) (*[]models.SecretData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTrash", arg0)
+	ret0, _ := ret[0].(*[]models.SecretData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTrash indicates an expected call of ListTrash.
+func (mr *MockDataStorableMockRecorder) ListTrash(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTrash", reflect.TypeOf((*MockDataStorable)(nil).ListTrash), arg0)
+}
+
 // Migrate mocks base method.
 func (m *MockDataStorable) Migrate() error {
 	m.ctrl.T.Helper()
@@ -140,6 +321,153 @@ func (mr *MockDataStorableMockRecorder) Migrate() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Migrate", reflect.TypeOf((*MockDataStorable)(nil).Migrate))
 }
 
+// MigrateDown mocks base method.
+func (m *MockDataStorable) MigrateDown(arg0 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateDown", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateDown indicates an expected call of MigrateDown.
+func (mr *MockDataStorableMockRecorder) MigrateDown(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateDown", reflect.TypeOf((*MockDataStorable)(nil).MigrateDown), arg0)
+}
+
+// MigrationStatus mocks base method.
+func (m *MockDataStorable) MigrationStatus() ([]database.MigrationState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrationStatus")
+	ret0, _ := ret[0].([]database.MigrationState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MigrationStatus indicates an expected call of MigrationStatus.
+func (mr *MockDataStorableMockRecorder) MigrationStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrationStatus", reflect.TypeOf((*MockDataStorable)(nil).MigrationStatus))
+}
+
+// PurgeTrash mocks base method.
+func (m *MockDataStorable) PurgeTrash(arg0 context.Context, arg1 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeTrash", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeTrash indicates an expected call of PurgeTrash.
+func (mr *MockDataStorableMockRecorder) PurgeTrash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeTrash", reflect.TypeOf((*MockDataStorable)(nil).PurgeTrash), arg0, arg1)
+}
+
+// RefreshSession mocks base method.
+func (m *MockDataStorable) RefreshSession(arg0 context.Context, arg1 uuid.UUID, arg2, arg3 []byte, arg4 time.Time) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshSession", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshSession indicates an expected call of RefreshSession.
+func (mr *MockDataStorableMockRecorder) RefreshSession(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshSession", reflect.TypeOf((*MockDataStorable)(nil).RefreshSession), arg0, arg1, arg2, arg3, arg4)
+}
+
+// RekeyVault mocks base method.
+func (m *MockDataStorable) RekeyVault(arg0 context.Context, arg1 models.User, arg2 []models.SecretData, arg3 []models.SecretRevision) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RekeyVault", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RekeyVault indicates an expected call of RekeyVault.
+func (mr *MockDataStorableMockRecorder) RekeyVault(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RekeyVault", reflect.TypeOf((*MockDataStorable)(nil).RekeyVault), arg0, arg1, arg2, arg3)
+}
+
+// RestoreSecretData mocks base method.
+func (m *MockDataStorable) RestoreSecretData(arg0 context.Context, arg1 uuid.UUID) (*models.SecretData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreSecretData", arg0, arg1)
+	ret0, _ := ret[0].(*models.SecretData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreSecretData indicates an expected call of RestoreSecretData.
+func (mr *MockDataStorableMockRecorder) RestoreSecretData(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreSecretData", reflect.TypeOf((*MockDataStorable)(nil).RestoreSecretData), arg0, arg1)
+}
+
+// RestoreSecretRevision mocks base method.
+func (m *MockDataStorable) RestoreSecretRevision(arg0 context.Context, arg1 uuid.UUID, arg2, arg3 int64) (*models.SecretData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreSecretRevision", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*models.SecretData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreSecretRevision indicates an expected call of RestoreSecretRevision.
+func (mr *MockDataStorableMockRecorder) RestoreSecretRevision(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreSecretRevision", reflect.TypeOf((*MockDataStorable)(nil).RestoreSecretRevision), arg0, arg1, arg2, arg3)
+}
+
+// RevokeSession mocks base method.
+func (m *MockDataStorable) RevokeSession(arg0 context.Context, arg1 uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockDataStorableMockRecorder) RevokeSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockDataStorable)(nil).RevokeSession), arg0, arg1)
+}
+
+// SetTotp mocks base method.
+func (m *MockDataStorable) SetTotp(arg0 context.Context, arg1 models.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTotp", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTotp indicates an expected call of SetTotp.
+func (mr *MockDataStorableMockRecorder) SetTotp(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTotp", reflect.TypeOf((*MockDataStorable)(nil).SetTotp), arg0, arg1)
+}
+
+// SyncSecretData mocks base method.
+func (m *MockDataStorable) SyncSecretData(arg0 context.Context, arg1 int64) (*database.Changes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncSecretData", arg0, arg1)
+	ret0, _ := ret[0].(*database.Changes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SyncSecretData indicates an expected call of SyncSecretData.
+func (mr *MockDataStorableMockRecorder) SyncSecretData(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncSecretData", reflect.TypeOf((*MockDataStorable)(nil).SyncSecretData), arg0, arg1)
+}
+
 // UpdateSecretData mocks base method.
 func (m *MockDataStorable) UpdateSecretData(arg0 context.Context, arg1 models.SecretData) (*models.SecretData, error) {
 	m.ctrl.T.Helper()
@@ -169,3 +497,48 @@ func (mr *MockDataStorableMockRecorder) UpdateUser(arg0, arg1 interface{}) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockDataStorable)(nil).UpdateUser), arg0, arg1)
 }
+
+// UseRecoveryCode mocks base method.
+func (m *MockDataStorable) UseRecoveryCode(arg0 context.Context, arg1 uuid.UUID, arg2 []byte) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockDataStorableMockRecorder) UseRecoveryCode(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockDataStorable)(nil).UseRecoveryCode), arg0, arg1, arg2)
+}
+
+// UseTotpStep mocks base method.
+func (m *MockDataStorable) UseTotpStep(arg0 context.Context, arg1 uuid.UUID, arg2 int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTotpStep", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTotpStep indicates an expected call of UseTotpStep.
+func (mr *MockDataStorableMockRecorder) UseTotpStep(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTotpStep", reflect.TypeOf((*MockDataStorable)(nil).UseTotpStep), arg0, arg1, arg2)
+}
+
+// WatchSecretData mocks base method.
+func (m *MockDataStorable) WatchSecretData(arg0 context.Context) (<-chan database.Change, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchSecretData", arg0)
+	ret0, _ := ret[0].(<-chan database.Change)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchSecretData indicates an expected call of WatchSecretData.
+func (mr *MockDataStorableMockRecorder) WatchSecretData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchSecretData", reflect.TypeOf((*MockDataStorable)(nil).WatchSecretData), arg0)
+}
@@ -4,22 +4,108 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// User is an account. SRP accounts have a Verifier and an empty Password;
+// accounts that still sign in with a password have only the bcrypt Password.
+// TotpSecret is set on enrollment, but the second factor is only required
+// once TotpEnabled confirms that the authenticator works. RecoveryCodes holds
+// bcrypt hashes of the unused recovery codes.
 type User struct {
-	ID         uuid.UUID     `gorm:"primary_key;type:uuid" json:"id"`
-	Username   string        `gorm:"size:255;not null" json:"username"`
-	Password   []byte        `gorm:"size:255;not null" json:"password"`
-	Email      string        `gorm:"size:255;not null;unique;index:idx_email" json:"email"`
-	CreatedAt  *time.Time    `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt  *time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
-	SecretData *[]SecretData `gorm:"foreignKey:UserID" json:"secret_data,omitempty"`
+	ID            uuid.UUID     `gorm:"primary_key;type:uuid" json:"id"`
+	Username      string        `gorm:"size:255;not null" json:"username"`
+	Password      []byte        `gorm:"size:255;not null" json:"password"`
+	Email         string        `gorm:"size:255;not null;unique;index:idx_email" json:"email"`
+	Kdf           KdfParams     `gorm:"embedded;embeddedPrefix:kdf_" json:"kdf"`
+	WrappedKey    []byte        `gorm:"size:128" json:"wrapped_key"`
+	Verifier      []byte        `gorm:"size:256" json:"verifier"`
+	TotpSecret    string        `gorm:"size:64" json:"-"`
+	TotpEnabled   bool          `gorm:"not null;default:false" json:"totp_enabled"`
+	TotpLastStep  int64         `gorm:"not null;default:0" json:"-"`
+	RecoveryCodes [][]byte      `gorm:"serializer:json" json:"-"`
+	CreatedAt     *time.Time    `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     *time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
+	SecretData    *[]SecretData `gorm:"foreignKey:UserID" json:"secret_data,omitempty"`
 }
 
+// SecretData is a stored note. A sealed note keeps its name and type inside
+// Secret, so Name and Type stay empty on the server. Revision starts at 1 and
+// grows with every write; a write names the revision it is based on.
+// ChangeSeq is the value of the owner's ChangeCounter at the last write,
+// including the delete, which only moves the secret to the trash by setting
+// DeletedAt, so that syncing clients learn about it. BlobID names the file
+// attached to the note, if any.
 type SecretData struct {
-	ID     uuid.UUID `gorm:"primary_key;type:uuid" json:"id"`
-	UserID uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
-	Type   string    `gorm:"size:255;not null" json:"type"`
-	Name   string    `gorm:"size:255;not null" json:"name"`
-	Secret []byte    `gorm:"type:bytes;size:20480;not null" json:"secret"`
+	ID        uuid.UUID      `gorm:"primary_key;type:uuid" json:"id"`
+	UserID    uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
+	Type      string         `gorm:"size:255;not null" json:"type"`
+	Name      string         `gorm:"size:255;not null" json:"name"`
+	Secret    []byte         `gorm:"type:bytes;size:20480;not null" json:"secret"`
+	Sealed    bool           `gorm:"not null;default:false" json:"sealed"`
+	Revision  int64          `gorm:"not null;default:1" json:"revision"`
+	ChangeSeq int64          `gorm:"not null;default:0;index" json:"change_seq"`
+	BlobID    *uuid.UUID     `gorm:"type:uuid;index" json:"blob_id,omitempty"`
+	UpdatedAt *time.Time     `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// SecretRevision is an earlier version of a secret, kept when a write
+// replaces it. Revision is the revision it had and WrittenAt the time it was
+// written. Only the newest revisions of each secret are kept.
+type SecretRevision struct {
+	SecretID  uuid.UUID  `gorm:"primary_key;type:uuid" json:"secret_id"`
+	Revision  int64      `gorm:"primary_key;autoIncrement:false" json:"revision"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Type      string     `gorm:"size:255;not null" json:"type"`
+	Name      string     `gorm:"size:255;not null" json:"name"`
+	Secret    []byte     `gorm:"type:bytes;size:20480;not null" json:"secret"`
+	Sealed    bool       `gorm:"not null;default:false" json:"sealed"`
+	BlobID    *uuid.UUID `gorm:"type:uuid;index" json:"blob_id,omitempty"`
+	WrittenAt *time.Time `json:"written_at"`
+}
+
+// ChangeCounter counts the writes to a user's secrets and orders them for
+// sync. PurgedSeq is the newest change of a tombstone purged from the trash;
+// a client that has not synced up to it may have missed a delete.
+type ChangeCounter struct {
+	UserID    uuid.UUID `gorm:"primary_key;type:uuid" json:"user_id"`
+	Seq       int64     `gorm:"not null;default:0" json:"seq"`
+	PurgedSeq int64     `gorm:"not null;default:0" json:"purged_seq"`
+}
+
+// Blob is a large encrypted file kept outside the notes table. The note that
+// refers to it holds the blob key, so the server sees only the chunk count
+// and the total ciphertext size. Chunks grows with every chunk stored in
+// order; Complete is set once the final chunk has arrived.
+type Blob struct {
+	ID        uuid.UUID  `gorm:"primary_key;type:uuid" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Chunks    int64      `gorm:"not null;default:0" json:"chunks"`
+	Size      int64      `gorm:"not null;default:0" json:"size"`
+	Complete  bool       `gorm:"not null;default:false" json:"complete"`
+	CreatedAt *time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt *time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// BlobChunk is one encrypted chunk of a blob; Number counts from 0. The
+// bytes live in a BlobStore under Key, the SHA-256 of the chunk.
+type BlobChunk struct {
+	BlobID uuid.UUID `gorm:"primary_key;type:uuid" json:"blob_id"`
+	Number int64     `gorm:"primary_key;autoIncrement:false" json:"number"`
+	Key    string    `gorm:"size:64;not null;default:'';index" json:"key"`
+	Size   int64     `gorm:"not null;default:0" json:"size"`
+}
+
+// Session is a signed-in device. Access tokens carry its ID; the refresh token
+// is only stored as a hash and is replaced on every refresh.
+type Session struct {
+	ID          uuid.UUID  `gorm:"primary_key;type:uuid" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	RefreshHash []byte     `gorm:"size:64;not null" json:"-"`
+	Device      string     `gorm:"size:255" json:"device"`
+	CreatedAt   *time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   *time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
 }
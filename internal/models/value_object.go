@@ -3,7 +3,16 @@ package models
 import "github.com/google/uuid"
 
 type UserCtx struct {
-	Username string    `json:"username"`
-	Email    string    `json:"email"`
-	Id       uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Id        uuid.UUID `json:"id"`
+	SessionID uuid.UUID `json:"session_id"`
+}
+
+type KdfParams struct {
+	Algorithm string `gorm:"size:32" json:"algorithm"`
+	Salt      []byte `gorm:"size:64" json:"salt"`
+	Time      uint32 `json:"time"`
+	Memory    uint32 `json:"memory"`
+	Threads   uint8  `json:"threads"`
 }
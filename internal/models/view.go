@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -89,15 +90,30 @@ func (tn TextNote) GetID() uuid.UUID {
 	return tn.Id
 }
 
+// BinaryNote keeps small data inline in Binary. A file is uploaded as a blob
+// instead and referenced by Blob.
 type BinaryNote struct {
-	Binary   []byte `json:"binary"`
+	Binary   []byte   `json:"binary"`
+	Blob     *BlobRef `json:"blob,omitempty"`
 	BaseNote `json:"data"`
 }
 
+// BlobRef points to an uploaded file. Key encrypts its chunks; it is only
+// stored inside the encrypted note.
+type BlobRef struct {
+	ID       uuid.UUID `json:"id"`
+	FileName string    `json:"file_name"`
+	Size     int64     `json:"size"`
+	Key      []byte    `json:"key"`
+}
+
 func (bn BinaryNote) Print() string {
 	var str string
 	str += "Note: " + bn.NameRecord + "\n"
 	str += "Binary: " + string(bn.Binary) + "\n"
+	if bn.Blob != nil {
+		str += fmt.Sprintf("File: %s (%d bytes)\n", bn.Blob.FileName, bn.Blob.Size)
+	}
 	str += "Additional information: " + strings.Join(bn.MetaInfo, "; ") + "\n"
 	str += "Created: " + time.Unix(bn.Created, 0).Format(time.RFC822) + "\n"
 	return str
@@ -148,3 +164,74 @@ func (bnc BankCardNote) GetType() TypeNote {
 func (bnc BankCardNote) GetID() uuid.UUID {
 	return bnc.Id
 }
+
+// NoteField is a field of a note as the forms show it.
+type NoteField struct {
+	Name  string
+	Value string
+}
+
+// Fields lists the fields of note in form order.
+func Fields(note Noteable) []NoteField {
+	var base BaseNote
+	var fields []NoteField
+	switch n := note.(type) {
+	case *CredentialNote:
+		base = n.BaseNote
+		fields = []NoteField{{"Username", n.Username}, {"Password", n.Password}}
+	case *TextNote:
+		base = n.BaseNote
+		fields = []NoteField{{"Text", n.Text}}
+	case *BinaryNote:
+		base = n.BaseNote
+		fields = []NoteField{{"Binary", string(n.Binary)}, {"File", ""}}
+		if n.Blob != nil {
+			fields[1].Value = fmt.Sprintf("%s (%d bytes)", n.Blob.FileName, n.Blob.Size)
+		}
+	case *BankCardNote:
+		base = n.BaseNote
+		fields = []NoteField{
+			{"Bank name", n.Bank},
+			{"Card number", n.Number},
+			{"Card expiration", n.Expiration},
+			{"Cardholder name", n.Cardholder},
+			{"Security code", n.SecurityCode},
+		}
+	default:
+		return nil
+	}
+	fields = append([]NoteField{{"Note", base.NameRecord}}, fields...)
+	return append(fields, NoteField{"Additional information", strings.Join(base.MetaInfo, "; ")})
+}
+
+// FieldChange is a field whose value differs between two versions of a note.
+type FieldChange struct {
+	Name string
+	Old  string
+	New  string
+}
+
+// DiffNotes compares two versions of a note field by field, in form order. A
+// field only one of them has compares with an empty value.
+func DiffNotes(older, newer Noteable) []FieldChange {
+	values := make(map[string]string)
+	var names []string
+	for _, field := range Fields(older) {
+		values[field.Name] = field.Value
+		names = append(names, field.Name)
+	}
+	var changes []FieldChange
+	seen := make(map[string]bool)
+	for _, field := range Fields(newer) {
+		seen[field.Name] = true
+		if values[field.Name] != field.Value {
+			changes = append(changes, FieldChange{Name: field.Name, Old: values[field.Name], New: field.Value})
+		}
+	}
+	for _, name := range names {
+		if !seen[name] && values[name] != "" {
+			changes = append(changes, FieldChange{Name: name, Old: values[name]})
+		}
+	}
+	return changes
+}
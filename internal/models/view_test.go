@@ -241,6 +241,7 @@ func TestBinaryNote_GetType(t *testing.T) {
 func TestBinaryNote_Print(t *testing.T) {
 	type fields struct {
 		Binary   []byte
+		Blob     *BlobRef
 		BaseNote BaseNote
 	}
 	tests := []struct {
@@ -259,11 +260,24 @@ func TestBinaryNote_Print(t *testing.T) {
 				"Additional information: test; test\n" +
 				"Created: 14 Aug 24 19:25 MSK\n",
 		},
+		{
+			name: "file",
+			fields: fields{
+				Blob:     &BlobRef{FileName: "scan.pdf", Size: 1048576},
+				BaseNote: baseNote,
+			},
+			want: "Note: Test Note\n" +
+				"Binary: \n" +
+				"File: scan.pdf (1048576 bytes)\n" +
+				"Additional information: test; test\n" +
+				"Created: 14 Aug 24 19:25 MSK\n",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			bn := BinaryNote{
 				Binary:   tt.fields.Binary,
+				Blob:     tt.fields.Blob,
 				BaseNote: tt.fields.BaseNote,
 			}
 			if got := bn.Print(); got != tt.want {
@@ -583,3 +597,50 @@ func TestTypeNote_String(t *testing.T) {
 		})
 	}
 }
+
+func TestDiffNotes(t *testing.T) {
+	base := BaseNote{Id: uuid.New(), NameRecord: "Mail", MetaInfo: []string{"work"}}
+	tests := []struct {
+		name  string
+		older Noteable
+		newer Noteable
+		want  []FieldChange
+	}{
+		{
+			name:  "same",
+			older: &CredentialNote{Username: "gopher", Password: "secret", BaseNote: base},
+			newer: &CredentialNote{Username: "gopher", Password: "secret", BaseNote: base},
+		},
+		{
+			name:  "password and name",
+			older: &CredentialNote{Username: "gopher", Password: "old", BaseNote: base},
+			newer: &CredentialNote{Username: "gopher", Password: "new", BaseNote: BaseNote{Id: base.Id, NameRecord: "Mail 2", MetaInfo: []string{"work"}}},
+			want: []FieldChange{
+				{Name: "Note", Old: "Mail", New: "Mail 2"},
+				{Name: "Password", Old: "old", New: "new"},
+			},
+		},
+		{
+			name:  "file attached",
+			older: &BinaryNote{Binary: []byte("x"), BaseNote: base},
+			newer: &BinaryNote{Binary: []byte("x"), Blob: &BlobRef{FileName: "a.pdf", Size: 3}, BaseNote: base},
+			want:  []FieldChange{{Name: "File", New: "a.pdf (3 bytes)"}},
+		},
+		{
+			name:  "other type",
+			older: &TextNote{Text: "hello", BaseNote: base},
+			newer: &CredentialNote{Username: "gopher", BaseNote: base},
+			want: []FieldChange{
+				{Name: "Username", New: "gopher"},
+				{Name: "Text", Old: "hello"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffNotes(tt.older, tt.newer); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffNotes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package mvc
 
 import (
+	"errors"
 	"fmt"
 
 	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/ui"
 	"github.com/rivo/tview"
 )
 
@@ -21,11 +23,18 @@ func createFormAuthorization(cu *UIController) {
 
 	formAuthorization.AddButton("Sign in", func() {
 		err := cu.sn.Login(user)
+		if errors.Is(err, ui.ErrMfaRequired) {
+			formMfa.Clear(true)
+			createFormMfa(cu, user.Email)
+			pagesMenu.SwitchToPage(PageMfa)
+			return
+		}
 		if err != nil {
 			createModalError(err, PageSignIn)
 			return
 		}
 		cu.AddItemInfoList(fmt.Sprintf("Welcome back %s!", user.Username))
+		cu.watchNotes()
 		pagesMenu.SwitchToPage(PageMenu)
 
 	})
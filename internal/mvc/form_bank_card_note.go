@@ -16,6 +16,7 @@ var (
 
 func createFormBankCardNote(cu *UIController, note models.BankCardNote) {
 	formCardBankNote.Clear(true)
+	stored := note
 	var metaInfo string
 	var cardNumber string
 	formCardBankNote.AddInputField("Bank name", note.Bank, 40,
@@ -81,9 +82,18 @@ func createFormBankCardNote(cu *UIController, note models.BankCardNote) {
 			createModalError(err, PageFormBankCardNote)
 			return
 		}
-		cu.AddItemInfoList(fmt.Sprintf("The note has been deleted:  %s", note.NameRecord))
+		cu.AddItemInfoList(fmt.Sprintf("The note has been moved to the trash:  %s", note.NameRecord))
 		pagesMenu.SwitchToPage(PageMenu)
 	})
+	if note.Id != uuid.Nil {
+		formCardBankNote.AddButton("History", func() {
+			if err := createHistoryView(cu, &stored, PageFormBankCardNote); err != nil {
+				createModalError(err, PageFormBankCardNote)
+				return
+			}
+			pagesMenu.SwitchToPage(PageHistory)
+		})
+	}
 	formCardBankNote.SetBorder(true).SetTitle("New bank card note").SetTitleAlign(tview.AlignLeft)
 }
 
@@ -1,6 +1,7 @@
 package mvc
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -16,8 +17,10 @@ var (
 
 func createFormBinaryNote(cu *UIController, note models.BinaryNote) {
 	formBinaryNote.Clear(true)
+	stored := note
 	var metaInfo string
 	var textArea string
+	var attach, saveTo string
 	formBinaryNote.AddTextArea("Binary data", string(note.Binary), 40, 0, 0,
 		func(text string) { textArea = text })
 	formBinaryNote.AddTextArea("Additional information", strings.Join(note.MetaInfo, "\n"), 40, 0, 0,
@@ -25,6 +28,15 @@ func createFormBinaryNote(cu *UIController, note models.BinaryNote) {
 	formBinaryNote.AddInputField("Save as", note.NameRecord, 40,
 		nil,
 		func(text string) { note.NameRecord = text })
+	formBinaryNote.AddInputField("Attach file", "", 40,
+		nil,
+		func(text string) { attach = strings.TrimSpace(text) })
+	if note.Blob != nil {
+		formBinaryNote.AddInputField("Save file to", note.Blob.FileName, 40,
+			nil,
+			func(text string) { saveTo = strings.TrimSpace(text) })
+		saveTo = note.Blob.FileName
+	}
 
 	formBinaryNote.AddButton("Save", func() {
 		if note.Id == uuid.Nil {
@@ -44,20 +56,48 @@ func createFormBinaryNote(cu *UIController, note models.BinaryNote) {
 			note.Binary = []byte(textArea)
 		}
 		note.Type = models.BINARY
+		old := note.Blob
+		if attach != "" {
+			ref, err := cu.sn.UploadFile(attach)
+			if err != nil {
+				createModalError(err, PageFormBinaryNote)
+				return
+			}
+			note.Blob = ref
+		}
 		err := cu.AddNote(&note)
 		if err != nil {
+			if note.Blob != old {
+				_ = cu.sn.DeleteBlob(note.Blob.ID)
+				note.Blob = old
+			}
 			createModalError(err, PageFormBinaryNote)
 			return
 		}
+		attach = ""
 		cu.AddItemInfoList(fmt.Sprintf("The note has been saved with the binary data: %s", note.NameRecord))
 		pagesMenu.SwitchToPage(PageMenu)
 	})
 
+	if note.Blob != nil {
+		formBinaryNote.AddButton("Download", func() {
+			if saveTo == "" {
+				createModalError(errors.New("Enter where to save the file"), PageFormBinaryNote)
+				return
+			}
+			if err := cu.sn.DownloadFile(*note.Blob, saveTo); err != nil {
+				createModalError(err, PageFormBinaryNote)
+				return
+			}
+			cu.AddItemInfoList(fmt.Sprintf("The file has been saved to %s", saveTo))
+		})
+	}
+
 	formBinaryNote.AddButton("Back", func() {
 		pagesMenu.SwitchToPage(PageMenu)
 	})
 
-	formTextNote.AddButton("Delete", func() {
+	formBinaryNote.AddButton("Delete", func() {
 		if note.Id == uuid.Nil {
 			pagesMenu.SwitchToPage(PageMenu)
 			return
@@ -67,8 +107,17 @@ func createFormBinaryNote(cu *UIController, note models.BinaryNote) {
 			createModalError(err, PageFormBinaryNote)
 			return
 		}
-		cu.AddItemInfoList(fmt.Sprintf("The note has been deleted:  %s", note.NameRecord))
+		cu.AddItemInfoList(fmt.Sprintf("The note has been moved to the trash:  %s", note.NameRecord))
 		pagesMenu.SwitchToPage(PageMenu)
 	})
+	if note.Id != uuid.Nil {
+		formBinaryNote.AddButton("History", func() {
+			if err := createHistoryView(cu, &stored, PageFormBinaryNote); err != nil {
+				createModalError(err, PageFormBinaryNote)
+				return
+			}
+			pagesMenu.SwitchToPage(PageHistory)
+		})
+	}
 	formBinaryNote.SetBorder(true).SetTitle("New binary note").SetTitleAlign(tview.AlignLeft)
 }
@@ -0,0 +1,50 @@
+package mvc
+
+import (
+	"errors"
+
+	"github.com/rivo/tview"
+)
+
+var (
+	formChangePassword = tview.NewForm()
+)
+
+func createFormChangePassword(cu *UIController) {
+	var oldPassword, newPassword, confirmPassword string
+	formChangePassword.AddPasswordField("Current password", "", 40, rune(42),
+		func(text string) { oldPassword = text })
+	formChangePassword.AddPasswordField("New password", "", 40, rune(42),
+		func(text string) { newPassword = text })
+	formChangePassword.AddPasswordField("Confirm password", "", 40, rune(42),
+		func(text string) { confirmPassword = text })
+
+	formChangePassword.AddButton("Save", func() {
+		if err := validatePasswordChange(newPassword, confirmPassword); err != nil {
+			createModalError(err, PageChangePassword)
+			return
+		}
+		err := cu.sn.ChangePassword(oldPassword, newPassword)
+		if err != nil {
+			createModalError(err, PageChangePassword)
+			return
+		}
+		cu.AddItemInfoList("The password has been changed, the vault is re-encrypted")
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+
+	formChangePassword.AddButton("Back", func() {
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+	formChangePassword.SetBorder(true).SetTitle("Change password").SetTitleAlign(tview.AlignLeft)
+}
+
+func validatePasswordChange(password, confirm string) error {
+	if password != confirm {
+		return errors.New("The passwords not equal")
+	}
+	if len(password) < 8 {
+		return errors.New("Password must be at least 8 characters")
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ var (
 
 func createFormCredentialNote(cu *UIController, note models.CredentialNote) {
 	formCredentialNote.Clear(true)
+	stored := note
 	var metaInfo string
 	formCredentialNote.AddInputField("Username", note.Username, 40,
 		nil,
@@ -68,8 +69,17 @@ func createFormCredentialNote(cu *UIController, note models.CredentialNote) {
 			createModalError(err, PageFormCredential)
 			return
 		}
-		cu.AddItemInfoList(fmt.Sprintf("The note has been deleted:  %s", note.NameRecord))
+		cu.AddItemInfoList(fmt.Sprintf("The note has been moved to the trash:  %s", note.NameRecord))
 		pagesMenu.SwitchToPage(PageMenu)
 	})
+	if note.Id != uuid.Nil {
+		formCredentialNote.AddButton("History", func() {
+			if err := createHistoryView(cu, &stored, PageFormCredential); err != nil {
+				createModalError(err, PageFormCredential)
+				return
+			}
+			pagesMenu.SwitchToPage(PageHistory)
+		})
+	}
 	formCredentialNote.SetBorder(true).SetTitle("New credential note").SetTitleAlign(tview.AlignLeft)
 }
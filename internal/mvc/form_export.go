@@ -0,0 +1,97 @@
+package mvc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/ui"
+	"github.com/rivo/tview"
+)
+
+var (
+	formExport = tview.NewForm()
+	formImport = tview.NewForm()
+)
+
+const defaultExportFile = "vault-export.json"
+
+func createFormExport(cu *UIController) {
+	path := defaultExportFile
+	var passphrase, confirm string
+	formExport.AddInputField("File", path, 40, nil,
+		func(text string) { path = text })
+	formExport.AddPasswordField("Passphrase", "", 40, rune(42),
+		func(text string) { passphrase = text })
+	formExport.AddPasswordField("Confirm passphrase", "", 40, rune(42),
+		func(text string) { confirm = text })
+
+	formExport.AddButton("Export", func() {
+		if err := validatePassphrase(passphrase, confirm); err != nil {
+			createModalError(err, PageExport)
+			return
+		}
+		count, err := cu.sn.ExportVaultFile(path, passphrase)
+		if err != nil {
+			createModalError(err, PageExport)
+			return
+		}
+		cu.AddItemInfoList(fmt.Sprintf("%d notes are exported to %s", count, path))
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+
+	formExport.AddButton("Back", func() {
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+	formExport.SetBorder(true).SetTitle("Export vault").SetTitleAlign(tview.AlignLeft)
+}
+
+func createFormImport(cu *UIController) {
+	path := defaultExportFile
+	var passphrase string
+	policy := ui.ImportSkip
+	options := make([]string, 0, len(ui.ImportPolicies))
+	for _, p := range ui.ImportPolicies {
+		options = append(options, string(p))
+	}
+	formImport.AddInputField("File", path, 40, nil,
+		func(text string) { path = text })
+	formImport.AddPasswordField("Passphrase", "", 40, rune(42),
+		func(text string) { passphrase = text })
+	formImport.AddDropDown("Existing notes", options, 0,
+		func(_ string, i int) {
+			if i >= 0 {
+				policy = ui.ImportPolicies[i]
+			}
+		})
+
+	formImport.AddButton("Import", func() {
+		result, err := cu.sn.ImportVaultFile(path, passphrase, policy)
+		if err != nil {
+			createModalError(err, PageImport)
+			return
+		}
+		notes, err := cu.sn.LoadNote()
+		if err != nil {
+			createModalError(err, PageMenu)
+			return
+		}
+		createNotesList(*notes)
+		cu.AddItemInfoList(fmt.Sprintf("Imported %s: %s", path, result))
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+
+	formImport.AddButton("Back", func() {
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+	formImport.SetBorder(true).SetTitle("Import vault").SetTitleAlign(tview.AlignLeft)
+}
+
+func validatePassphrase(passphrase, confirm string) error {
+	if passphrase != confirm {
+		return errors.New("The passphrases not equal")
+	}
+	if len(passphrase) < 8 {
+		return errors.New("Passphrase must be at least 8 characters")
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package mvc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/ui"
+	"github.com/rivo/tview"
+)
+
+var (
+	historyList = tview.NewList()
+	historyDiff = tview.NewTextView().SetDynamicColors(true)
+	flexHistory = tview.NewFlex().
+			AddItem(historyList, 0, 1, true).
+			AddItem(historyDiff, 0, 2, false)
+)
+
+// createHistoryView lists the kept revisions of note and shows what changed
+// between the highlighted one and the current version. Selecting a revision
+// asks to restore it; Back returns to backPage.
+func createHistoryView(cu *UIController, note models.Noteable, backPage string) error {
+	revisions, err := cu.sn.ListRevisions(note.GetID())
+	if err != nil {
+		return err
+	}
+
+	historyList.Clear()
+	historyDiff.Clear()
+	historyList.SetChangedFunc(func(i int, _ string, _ string, _ rune) {
+		if i < len(revisions) {
+			historyDiff.SetText(revisionDiff(revisions[i], note))
+			historyDiff.ScrollToBeginning()
+		}
+	})
+	for _, revision := range revisions {
+		historyList.AddItem(fmt.Sprintf("Revision %d", revision.Revision), revision.WrittenAt.Format("2006-01-02 15:04"), 0, func() {
+			createModalRestore(cu, revision)
+		})
+	}
+	historyList.AddItem("Back", "", 'b', func() {
+		pagesMenu.SwitchToPage(backPage)
+	})
+	if len(revisions) == 0 {
+		historyDiff.SetText("The server keeps no earlier versions of this note.")
+	} else {
+		historyDiff.SetText(revisionDiff(revisions[0], note))
+	}
+	historyList.SetCurrentItem(0)
+
+	historyList.SetBorder(true).SetTitle(fmt.Sprintf("History of %s (enter to restore)", note.GetName())).SetTitleAlign(tview.AlignLeft)
+	historyDiff.SetBorder(true).SetTitle("Changes since then").SetTitleAlign(tview.AlignLeft)
+	return nil
+}
+
+// revisionDiff describes the fields of current that differ from revision.
+func revisionDiff(revision ui.NoteRevision, current models.Noteable) string {
+	changes := models.DiffNotes(revision.Note, current)
+	if len(changes) == 0 {
+		return "Same as the current version."
+	}
+	var b strings.Builder
+	for _, change := range changes {
+		fmt.Fprintf(&b, "%s\n[red]- %s[-]\n[green]+ %s[-]\n\n", change.Name,
+			tview.Escape(change.Old), tview.Escape(change.New))
+	}
+	return b.String()
+}
+
+func createModalRestore(cu *UIController, revision ui.NoteRevision) {
+	modalError.
+		SetText(fmt.Sprintf("Restore revision %d of %s? The current version stays in the history.",
+			revision.Revision, revision.Note.GetName())).
+		ClearButtons().
+		AddButtons([]string{"Restore", "Cancel"}).
+		SetDoneFunc(func(_ int, buttonLabel string) {
+			if buttonLabel != "Restore" {
+				pagesMenu.SwitchToPage(PageHistory)
+				return
+			}
+			storage, err := cu.sn.RestoreRevision(revision)
+			if err != nil {
+				createModalError(err, PageHistory)
+				return
+			}
+			createNotesList(*storage)
+			cu.AddItemInfoList(fmt.Sprintf("Revision %d of %s is restored", revision.Revision, revision.Note.GetName()))
+			pagesMenu.SwitchToPage(PageMenu)
+		}).SetTitle("Restore")
+	pagesMenu.SwitchToPage(PageError)
+}
@@ -0,0 +1,32 @@
+package mvc
+
+import (
+	"fmt"
+
+	"github.com/rivo/tview"
+)
+
+var (
+	formMfa = tview.NewForm()
+)
+
+func createFormMfa(cu *UIController, email string) {
+	var code string
+	formMfa.AddInputField("Code", "", 20, nil,
+		func(text string) { code = text })
+
+	formMfa.AddButton("Verify", func() {
+		if err := cu.sn.VerifyMfa(code); err != nil {
+			createModalError(err, PageMfa)
+			return
+		}
+		cu.AddItemInfoList(fmt.Sprintf("Welcome back %s!", email))
+		cu.watchNotes()
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+
+	formMfa.AddButton("Cancel", func() {
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+	formMfa.SetBorder(true).SetTitle("Authenticator or recovery code").SetTitleAlign(tview.AlignLeft)
+}
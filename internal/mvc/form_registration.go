@@ -36,6 +36,7 @@ func createFormRegistrationUser(cu *UIController) {
 				return
 			}
 			cu.AddItemInfoList(fmt.Sprintf("The user: %s registered successful", user.Username))
+			cu.watchNotes()
 			pagesMenu.SwitchToPage(PageMenu)
 		}
 	})
@@ -0,0 +1,52 @@
+package mvc
+
+import (
+	"fmt"
+	"time"
+
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/rivo/tview"
+)
+
+var (
+	sessionsList = tview.NewList()
+)
+
+func createSessionsList(cu *UIController, sessions []*pb.Session) {
+	sessionsList.Clear()
+	for _, session := range sessions {
+		sessionsList.AddItem(sessionLabel(session), sessionDetails(session), 0, func() {
+			if session.Current {
+				createModalError(fmt.Errorf("Use (o) to sign out of this session"), PageSessions)
+				return
+			}
+			if err := cu.sn.RevokeSession(session.Id); err != nil {
+				createModalError(err, PageSessions)
+				return
+			}
+			cu.AddItemInfoList(fmt.Sprintf("Session %s is revoked", sessionLabel(session)))
+			pagesMenu.SwitchToPage(PageMenu)
+		})
+	}
+	sessionsList.AddItem("Back", "", 'b', func() {
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+	sessionsList.SetBorder(true).SetTitle("Sessions (enter to revoke)").SetTitleAlign(tview.AlignLeft)
+}
+
+func sessionLabel(session *pb.Session) string {
+	device := session.Device
+	if device == "" {
+		device = "unknown device"
+	}
+	if session.Current {
+		return device + " (this session)"
+	}
+	return device
+}
+
+func sessionDetails(session *pb.Session) string {
+	const layout = "2006-01-02 15:04"
+	return fmt.Sprintf("signed in %s, last used %s",
+		time.Unix(session.CreatedAt, 0).Format(layout), time.Unix(session.LastUsedAt, 0).Format(layout))
+}
@@ -2,10 +2,13 @@ package mvc
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/logger"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/ui"
@@ -190,6 +193,13 @@ func Test_createFormBinaryNote(t *testing.T) {
 				cu: &UIController{},
 			},
 		},
+		{
+			name: "TestCreateFormBinaryNoteWithFile",
+			args: args{
+				cu:   &UIController{},
+				note: models.BinaryNote{Blob: &models.BlobRef{ID: uuid.New(), FileName: "photo.jpg", Size: 1024}},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -198,6 +208,189 @@ func Test_createFormBinaryNote(t *testing.T) {
 	}
 }
 
+func Test_createFormChangePassword(t *testing.T) {
+	type args struct {
+		cu *UIController
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{
+			name: "TestCreateFormChangePassword",
+			args: args{
+				cu: &UIController{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createFormChangePassword(tt.args.cu)
+		})
+	}
+}
+
+func Test_createFormMfa(t *testing.T) {
+	createFormMfa(&UIController{}, "user1@test.com")
+	assert.Equal(t, 1, formMfa.GetFormItemCount())
+	assert.Equal(t, 2, formMfa.GetButtonCount())
+}
+
+func Test_createFormTwoFactor(t *testing.T) {
+	createFormTwoFactor(&UIController{})
+	assert.Equal(t, 1, formTwoFactor.GetFormItemCount())
+	assert.Equal(t, 4, formTwoFactor.GetButtonCount())
+}
+
+func Test_totpQRCode(t *testing.T) {
+	got, err := totpQRCode("otpauth://totp/GophKeeper:user1%40test.com?secret=JBSWY3DPEHPK3PXP&issuer=GophKeeper")
+	assert.NoError(t, err)
+	assert.Contains(t, got, "█")
+}
+
+func Test_createSessionsList(t *testing.T) {
+	sessions := []*pb.Session{
+		{Id: uuid.NewString(), Device: "laptop", Current: true},
+		{Id: uuid.NewString()},
+	}
+	createSessionsList(&UIController{}, sessions)
+	assert.Equal(t, 3, sessionsList.GetItemCount())
+}
+
+func Test_createTrashList(t *testing.T) {
+	trash := []ui.TrashedNote{
+		{DeletedAt: time.Now(), Note: &models.TextNote{BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "A", Type: models.TEXT}}},
+		{DeletedAt: time.Now(), Note: &models.CredentialNote{BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "B", Type: models.CREDENTIAL}}},
+	}
+	createTrashList(&UIController{}, trash)
+	assert.Equal(t, 4, trashList.GetItemCount())
+	createTrashList(&UIController{}, nil)
+	assert.Equal(t, 1, trashList.GetItemCount(), "empty trash offers only Back")
+}
+
+func Test_sessionLabel(t *testing.T) {
+	tests := []struct {
+		name    string
+		session *pb.Session
+		want    string
+	}{
+		{
+			name:    "current session",
+			session: &pb.Session{Device: "laptop", Current: true},
+			want:    "laptop (this session)",
+		},
+		{
+			name:    "other session",
+			session: &pb.Session{Device: "phone"},
+			want:    "phone",
+		},
+		{
+			name:    "without device",
+			session: &pb.Session{},
+			want:    "unknown device",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionLabel(tt.session); got != tt.want {
+				t.Errorf("sessionLabel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_createHistoryView(t *testing.T) {
+	conn, _ := grpc.NewClient(":3200", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	service := ui.NewUIService(logger.NewLogger(logrus.New()), conn)
+	note := &models.TextNote{BaseNote: models.BaseNote{Id: uuid.New()}}
+	assert.Error(t, createHistoryView(&UIController{sn: service}, note, PageFormTextNote), "history without signing in")
+}
+
+func Test_revisionDiff(t *testing.T) {
+	id := uuid.New()
+	older := &models.CredentialNote{BaseNote: models.BaseNote{Id: id, NameRecord: "mail"}, Username: "alice", Password: "old"}
+	newer := &models.CredentialNote{BaseNote: models.BaseNote{Id: id, NameRecord: "mail"}, Username: "alice", Password: "new[1]"}
+	tests := []struct {
+		name     string
+		revision ui.NoteRevision
+		current  models.Noteable
+		want     string
+	}{
+		{
+			name:     "unchanged",
+			revision: ui.NoteRevision{Revision: 1, Note: older},
+			current:  older,
+			want:     "Same as the current version.",
+		},
+		{
+			name:     "changed password",
+			revision: ui.NoteRevision{Revision: 1, Note: older},
+			current:  newer,
+			want:     "Password\n[red]- old[-]\n[green]+ new[1[][-]\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, revisionDiff(tt.revision, tt.current))
+		})
+	}
+}
+
+func Test_validatePasswordChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		confirm  string
+		wantErr  bool
+	}{
+		{
+			name:     "valid",
+			password: "NewPass123!",
+			confirm:  "NewPass123!",
+			wantErr:  false,
+		},
+		{
+			name:     "not equal",
+			password: "NewPass123!",
+			confirm:  "NewPass123?",
+			wantErr:  true,
+		},
+		{
+			name:     "too short",
+			password: "short",
+			confirm:  "short",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePasswordChange(tt.password, tt.confirm); (err != nil) != tt.wantErr {
+				t.Errorf("validatePasswordChange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_validatePassphrase(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		confirm    string
+		wantErr    bool
+	}{
+		{name: "valid", passphrase: "correct horse", confirm: "correct horse"},
+		{name: "not equal", passphrase: "correct horse", confirm: "correct house", wantErr: true},
+		{name: "too short", passphrase: "horse", confirm: "horse", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePassphrase(tt.passphrase, tt.confirm); (err != nil) != tt.wantErr {
+				t.Errorf("validatePassphrase() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func Test_createFormCredentialNote(t *testing.T) {
 	type args struct {
 		cu   *UIController
@@ -298,6 +491,13 @@ func Test_createModalError(t *testing.T) {
 				switchToPage: PageMenu,
 			},
 		},
+		{
+			name: "TestCreateModalConflict",
+			args: args{
+				err:          fmt.Errorf("save: %w", &ui.ConflictError{}),
+				switchToPage: PageFormTextNote,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -16,6 +16,7 @@ var (
 
 func createFormTextNote(cu *UIController, note models.TextNote) {
 	formTextNote.Clear(true)
+	stored := note
 	var metaInfo string
 	var textArea string
 	formTextNote.AddTextArea("Text data", note.Text, 40, 0, 0,
@@ -68,8 +69,17 @@ func createFormTextNote(cu *UIController, note models.TextNote) {
 			createModalError(err, PageFormTextNote)
 			return
 		}
-		cu.AddItemInfoList(fmt.Sprintf("The note has been deleted:  %s", note.NameRecord))
+		cu.AddItemInfoList(fmt.Sprintf("The note has been moved to the trash:  %s", note.NameRecord))
 		pagesMenu.SwitchToPage(PageMenu)
 	})
+	if note.Id != uuid.Nil {
+		formTextNote.AddButton("History", func() {
+			if err := createHistoryView(cu, &stored, PageFormTextNote); err != nil {
+				createModalError(err, PageFormTextNote)
+				return
+			}
+			pagesMenu.SwitchToPage(PageHistory)
+		})
+	}
 	formTextNote.SetBorder(true).SetTitle("New text note").SetTitleAlign(tview.AlignLeft)
 }
@@ -0,0 +1,60 @@
+package mvc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/services/ui"
+	"github.com/rivo/tview"
+)
+
+var (
+	trashList = tview.NewList()
+)
+
+func createTrashList(cu *UIController, trash []ui.TrashedNote) {
+	trashList.Clear()
+	for _, trashed := range trash {
+		item := fmt.Sprintf("[\r%s] %s", strings.ToUpper(trashed.Note.GetType().String()), trashed.Note.GetName())
+		trashList.AddItem(item, "deleted "+trashed.DeletedAt.Format("2006-01-02 15:04"), 0, func() {
+			notes, err := cu.sn.RestoreNote(trashed.Note.GetID())
+			if err != nil {
+				createModalError(err, PageTrash)
+				return
+			}
+			createNotesList(*notes)
+			cu.AddItemInfoList(fmt.Sprintf("The note has been restored:  %s", trashed.Note.GetName()))
+			pagesMenu.SwitchToPage(PageMenu)
+		})
+	}
+	if len(trash) > 0 {
+		trashList.AddItem("Empty trash", "delete these notes for good", 'x', func() {
+			createModalEmptyTrash(cu, len(trash))
+		})
+	}
+	trashList.AddItem("Back", "", 'b', func() {
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+	trashList.SetBorder(true).SetTitle("Trash (enter to restore)").SetTitleAlign(tview.AlignLeft)
+}
+
+func createModalEmptyTrash(cu *UIController, count int) {
+	modalError.
+		SetText(fmt.Sprintf("Delete %d notes in the trash for good? They cannot be restored afterwards.", count)).
+		ClearButtons().
+		AddButtons([]string{"Empty trash", "Cancel"}).
+		SetDoneFunc(func(_ int, buttonLabel string) {
+			if buttonLabel != "Empty trash" {
+				pagesMenu.SwitchToPage(PageTrash)
+				return
+			}
+			purged, err := cu.sn.EmptyTrash()
+			if err != nil {
+				createModalError(err, PageTrash)
+				return
+			}
+			cu.AddItemInfoList(fmt.Sprintf("The trash is empty, %d notes deleted", purged))
+			pagesMenu.SwitchToPage(PageMenu)
+		}).SetTitle("Empty trash")
+	pagesMenu.SwitchToPage(PageError)
+}
@@ -0,0 +1,75 @@
+package mvc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+	"github.com/skip2/go-qrcode"
+)
+
+var (
+	formTwoFactor = tview.NewForm()
+	textTwoFactor = tview.NewTextView()
+	flexTwoFactor = tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(textTwoFactor, 0, 1, false).
+			AddItem(formTwoFactor, 7, 1, true)
+)
+
+func createFormTwoFactor(cu *UIController) {
+	var code string
+	textTwoFactor.SetText("Enroll to get a secret for your authenticator app.\n" +
+		"Enter a code from the app to disable two-factor login.")
+	formTwoFactor.AddInputField("Code", "", 20, nil,
+		func(text string) { code = text })
+
+	formTwoFactor.AddButton("Enroll", func() {
+		enrollment, err := cu.sn.EnrollTotp()
+		if err != nil {
+			createModalError(err, PageTwoFactor)
+			return
+		}
+		text, err := totpQRCode(enrollment.Uri)
+		if err != nil {
+			createModalError(err, PageTwoFactor)
+			return
+		}
+		textTwoFactor.SetText(fmt.Sprintf("%s\nSecret: %s\nScan the code and confirm with the first code from the app.", text, enrollment.Secret))
+		textTwoFactor.ScrollToBeginning()
+	})
+
+	formTwoFactor.AddButton("Confirm", func() {
+		recovery, err := cu.sn.ConfirmTotp(code)
+		if err != nil {
+			createModalError(err, PageTwoFactor)
+			return
+		}
+		textTwoFactor.SetText("Two-factor login is enabled. Keep these recovery codes, each works once:\n\n" +
+			strings.Join(recovery, "\n"))
+		cu.AddItemInfoList("Two-factor login is enabled")
+	})
+
+	formTwoFactor.AddButton("Disable", func() {
+		if err := cu.sn.DisableTotp(code); err != nil {
+			createModalError(err, PageTwoFactor)
+			return
+		}
+		cu.AddItemInfoList("Two-factor login is disabled")
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+
+	formTwoFactor.AddButton("Back", func() {
+		pagesMenu.SwitchToPage(PageMenu)
+	})
+	flexTwoFactor.SetBorder(true).SetTitle("Two-factor login").SetTitleAlign(tview.AlignLeft)
+}
+
+// totpQRCode renders an otpauth URI as a QR code made of half-block
+// characters, so it fits a terminal.
+func totpQRCode(uri string) (string, error) {
+	qr, err := qrcode.New(uri, qrcode.Low)
+	if err != nil {
+		return "", err
+	}
+	return qr.ToSmallString(false), nil
+}
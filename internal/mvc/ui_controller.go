@@ -1,6 +1,8 @@
 package mvc
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -29,6 +31,8 @@ var (
 type UIController struct {
 	infoList []string
 	sn       *ui.Service
+	// stopWatch ends the live updates of the signed-in session.
+	stopWatch context.CancelFunc
 }
 
 const (
@@ -40,6 +44,14 @@ const (
 	PageRegistrationUser = "Registration User"
 	PageError            = "Error"
 	PageSignIn           = "Sign in"
+	PageChangePassword   = "Change password"
+	PageSessions         = "Sessions"
+	PageMfa              = "Second factor"
+	PageTwoFactor        = "Two-factor login"
+	PageHistory          = "Note history"
+	PageTrash            = "Trash"
+	PageExport           = "Export vault"
+	PageImport           = "Import vault"
 )
 
 func NewUIController(logger *logger.Logger, serviceNote *ui.Service) *UIController {
@@ -63,6 +75,42 @@ func (cu *UIController) Run() error {
 	return app.SetRoot(pagesMenu, true).EnableMouse(true).Run()
 }
 
+// watchNotes reloads the notes list whenever another device changes a note,
+// reconnecting with backoff until the user signs out.
+func (cu *UIController) watchNotes() {
+	cu.stopWatching()
+	if cu.sn.Offline() {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cu.stopWatch = cancel
+	go cu.sn.Watch(ctx, func() {
+		app.QueueUpdateDraw(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			notes, err := cu.sn.LoadNote()
+			var conflict *ui.ConflictError
+			if errors.As(err, &conflict) {
+				createModalError(err, PageMenu)
+				return
+			}
+			if err != nil {
+				log.WithError(err).Warning("notes are not refreshed")
+				return
+			}
+			createNotesList(*notes)
+		})
+	})
+}
+
+func (cu *UIController) stopWatching() {
+	if cu.stopWatch != nil {
+		cu.stopWatch()
+		cu.stopWatch = nil
+	}
+}
+
 func (cu *UIController) AddItemInfoList(msg string) {
 	cu.infoList = append(cu.infoList, msg)
 	textInfo.Clear()
@@ -101,6 +149,10 @@ func setInput(cu *UIController) *tview.Box {
 				return event
 			}
 			createNotesList(*note)
+			if cu.sn.Offline() {
+				cu.AddItemInfoList("Server unreachable: cached notes shown, edits are queued")
+				return event
+			}
 			cu.AddItemInfoList("Notes load is successful")
 		case 98:
 			formCardBankNote.Clear(true)
@@ -126,6 +178,45 @@ func setInput(cu *UIController) *tview.Box {
 			formAuthorization.Clear(true)
 			createFormAuthorization(cu)
 			pagesMenu.SwitchToPage(PageSignIn)
+		case 112:
+			formChangePassword.Clear(true)
+			createFormChangePassword(cu)
+			pagesMenu.SwitchToPage(PageChangePassword)
+		case 101:
+			sessions, err := cu.sn.ListSessions()
+			if err != nil {
+				createModalError(err, PageMenu)
+				return event
+			}
+			createSessionsList(cu, sessions)
+			pagesMenu.SwitchToPage(PageSessions)
+		case 100:
+			trash, err := cu.sn.ListTrash()
+			if err != nil {
+				createModalError(err, PageMenu)
+				return event
+			}
+			createTrashList(cu, trash)
+			pagesMenu.SwitchToPage(PageTrash)
+		case 120:
+			formExport.Clear(true)
+			createFormExport(cu)
+			pagesMenu.SwitchToPage(PageExport)
+		case 109:
+			formImport.Clear(true)
+			createFormImport(cu)
+			pagesMenu.SwitchToPage(PageImport)
+		case 102:
+			formTwoFactor.Clear(true)
+			createFormTwoFactor(cu)
+			pagesMenu.SwitchToPage(PageTwoFactor)
+		case 111:
+			cu.stopWatching()
+			if err := cu.sn.Logout(); err != nil {
+				createModalError(err, PageMenu)
+			}
+			notesList.Clear()
+			cu.AddItemInfoList("Signed out")
 		}
 		return event
 	})
@@ -136,17 +227,25 @@ func createMainMenu() {
 	pagesMenu.AddPage(PageFormBankCardNote, createModalForm(formCardBankNote, 70, 23), true, false)
 	pagesMenu.AddPage(PageFormCredential, createModalForm(formCredentialNote, 70, 17), true, false)
 	pagesMenu.AddPage(PageFormTextNote, createModalForm(formTextNote, 70, 19), true, false)
-	pagesMenu.AddPage(PageFormBinaryNote, createModalForm(formBinaryNote, 70, 19), true, false)
+	pagesMenu.AddPage(PageFormBinaryNote, createModalForm(formBinaryNote, 70, 25), true, false)
 	pagesMenu.AddPage(PageRegistrationUser, createModalForm(formRegistrationUser, 70, 13), true, false)
 	pagesMenu.AddPage(PageError, modalError, true, false)
 	pagesMenu.AddPage(PageSignIn, createModalForm(formAuthorization, 55, 10), true, false)
+	pagesMenu.AddPage(PageChangePassword, createModalForm(formChangePassword, 70, 11), true, false)
+	pagesMenu.AddPage(PageSessions, createModalForm(sessionsList, 70, 15), true, false)
+	pagesMenu.AddPage(PageMfa, createModalForm(formMfa, 55, 7), true, false)
+	pagesMenu.AddPage(PageTwoFactor, createModalForm(flexTwoFactor, 70, 42), true, false)
+	pagesMenu.AddPage(PageHistory, createModalForm(flexHistory, 100, 20), true, false)
+	pagesMenu.AddPage(PageTrash, createModalForm(trashList, 70, 20), true, false)
+	pagesMenu.AddPage(PageExport, createModalForm(formExport, 70, 11), true, false)
+	pagesMenu.AddPage(PageImport, createModalForm(formImport, 70, 11), true, false)
 }
 
 func creteMainFlex() *tview.Flex {
-	textMenu1 := tview.NewTextView().SetTextColor(tcell.ColorGreen).SetText("(q) quit \n(l) load notes")
-	textMenu2 := tview.NewTextView().SetTextColor(tcell.ColorGreen).SetText("(b) add bank card \n(c) add credential")
-	textMenu3 := tview.NewTextView().SetTextColor(tcell.ColorGreen).SetText("(t) add text \n(i) add binary")
-	textMenu4 := tview.NewTextView().SetTextColor(tcell.ColorGreen).SetText("(r) register an account \n(s) sign in")
+	textMenu1 := tview.NewTextView().SetTextColor(tcell.ColorGreen).SetText("(q) quit \n(l) load notes \n(o) sign out \n(d) trash")
+	textMenu2 := tview.NewTextView().SetTextColor(tcell.ColorGreen).SetText("(b) add bank card \n(c) add credential \n(e) sessions \n(x) export vault")
+	textMenu3 := tview.NewTextView().SetTextColor(tcell.ColorGreen).SetText("(t) add text \n(i) add binary \n(f) two-factor login \n(m) import vault")
+	textMenu4 := tview.NewTextView().SetTextColor(tcell.ColorGreen).SetText("(r) register an account \n(s) sign in \n(p) change password")
 
 	flexMain.SetBorder(true).SetTitle("Welcome to gopher keeper").SetTitleAlign(tview.AlignLeft)
 	return flexMain.
@@ -157,7 +256,7 @@ func creteMainFlex() *tview.Flex {
 				AddItem(textMenu1, 0, 1, false).
 				AddItem(textMenu2, 0, 1, false).
 				AddItem(textMenu3, 0, 1, false).
-				AddItem(textMenu4, 0, 1, false), 2, 1, false), 0, 2, false).
+				AddItem(textMenu4, 0, 1, false), 4, 1, false), 0, 2, false).
 		AddItem(textInfo, 0, 1, false)
 }
 
@@ -205,6 +304,11 @@ func createModalForm(p tview.Primitive, width, height int) tview.Primitive {
 }
 
 func createModalError(err error, switchToPage string) {
+	var conflict *ui.ConflictError
+	if errors.As(err, &conflict) {
+		createModalConflict(conflict, switchToPage)
+		return
+	}
 	modalError.
 		SetText(fmt.Sprintf("Error: %s", err.Error())).
 		ClearButtons().
@@ -216,3 +320,27 @@ func createModalError(err error, switchToPage string) {
 		}).SetTitle("Error")
 	pagesMenu.SwitchToPage(PageError)
 }
+
+// createModalConflict asks whether a note edited on another device keeps the
+// local change or takes the server copy.
+func createModalConflict(conflict *ui.ConflictError, switchToPage string) {
+	modalError.
+		SetText(fmt.Sprintf("%s. Keep your version or use the one from the server?", conflict.Error())).
+		ClearButtons().
+		AddButtons([]string{"Keep mine", "Use theirs", "Cancel"}).
+		SetDoneFunc(func(_ int, buttonLabel string) {
+			if buttonLabel != "Keep mine" && buttonLabel != "Use theirs" {
+				pagesMenu.SwitchToPage(switchToPage)
+				return
+			}
+			storage, err := cu.sn.ResolveConflict(conflict, buttonLabel == "Keep mine")
+			if err != nil {
+				createModalError(err, switchToPage)
+				return
+			}
+			createNotesList(*storage)
+			cu.AddItemInfoList(fmt.Sprintf("Conflict resolved: %s", strings.ToLower(buttonLabel)))
+			pagesMenu.SwitchToPage(PageMenu)
+		}).SetTitle("Conflict")
+	pagesMenu.SwitchToPage(PageError)
+}
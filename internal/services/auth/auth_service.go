@@ -1,9 +1,6 @@
 package auth
 
 import (
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -24,35 +21,46 @@ var (
 	as   *Service
 )
 
+// AccessTokenTTL is the lifetime of an access token. Clients renew it with
+// the refresh token of their session.
+const AccessTokenTTL = 15 * time.Minute
+
 type ServiceAuth interface {
-	CreateJwt(user *models.User) (string, error)
+	CreateJwt(user *models.User, sessionID uuid.UUID) (string, error)
 	CreateUserCtx(token string) (*models.UserCtx, error)
 }
 
 type Service struct {
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
+	keys *KeyRing
 }
 
-func NewAuthService(logger *logger.Logger, pathPrivateKey string) (*Service, error) {
-	cert, err := getFile(pathPrivateKey)
-	if err != nil {
-		return nil, err
-	}
-	blocks, _ := pem.Decode(cert)
-	key, err := x509.ParsePKCS1PrivateKey(blocks.Bytes)
+// NewAuthService loads the signing keys from pathKeys: a single PEM key or a
+// key ring directory (see ActiveKeyFile).
+func NewAuthService(logger *logger.Logger, pathKeys string) (*Service, error) {
+	keys, err := LoadKeyRing(pathKeys)
 	if err != nil {
 		return nil, err
 	}
 
 	once.Do(func() {
 		log = logger
-		as = &Service{privateKey: key, publicKey: &key.PublicKey}
+		as = &Service{keys: keys}
 	})
 	return as, nil
 }
 
-func (as Service) CreateJwt(user *models.User) (string, error) {
+// Reload re-reads the signing keys. Tokens signed by keys that are still in
+// the ring stay valid.
+func (as Service) Reload() error {
+	if err := as.keys.Reload(); err != nil {
+		return err
+	}
+	kid, _ := as.keys.Signing()
+	log.WithField("kid", kid).Infof("signing keys reloaded, %d keys accepted", len(as.keys.KeyIDs()))
+	return nil
+}
+
+func (as Service) CreateJwt(user *models.User, sessionID uuid.UUID) (string, error) {
 	log := log.WithFields(logrus.Fields{
 		"method": "CreateJwt",
 		"user":   user.Email,
@@ -62,10 +70,13 @@ func (as Service) CreateJwt(user *models.User) (string, error) {
 		"Id":       user.ID,
 		"Username": user.Username,
 		"Email":    user.Email,
-		"exp":      time.Now().Add(time.Hour * 72).Unix(),
+		"Sid":      sessionID,
+		"exp":      time.Now().Add(AccessTokenTTL).Unix(),
 	})
 
-	signedToken, err := token.SignedString(as.privateKey)
+	kid, key := as.keys.Signing()
+	token.Header["kid"] = kid
+	signedToken, err := token.SignedString(key)
 	if err != nil {
 		log.WithError(err).Error("error signing token")
 		return "", err
@@ -84,7 +95,8 @@ func (as Service) CreateUserCtx(token string) (*models.UserCtx, error) {
 		if _, ok := jwtToken.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("unexpected method: %s", jwtToken.Header["alg"])
 		}
-		return as.publicKey, nil
+		kid, _ := jwtToken.Header["kid"].(string)
+		return as.keys.Verifying(kid)
 	})
 	if err != nil {
 		log.WithError(err).Error("error parsing token")
@@ -111,10 +123,21 @@ func (as Service) CreateUserCtx(token string) (*models.UserCtx, error) {
 		log.WithError(err).Error("error parsing id")
 		return nil, err
 	}
+	sid, ok := claims["Sid"].(string)
+	if !ok {
+		log.Error("token without session")
+		return nil, ErrNoSession
+	}
+	sessionID, err := uuid.Parse(sid)
+	if err != nil {
+		log.WithError(err).Error("error parsing session id")
+		return nil, err
+	}
 	return &models.UserCtx{
-		Id:       id,
-		Username: claims["Username"].(string),
-		Email:    claims["Email"].(string),
+		Id:        id,
+		Username:  claims["Username"].(string),
+		Email:     claims["Email"].(string),
+		SessionID: sessionID,
 	}, nil
 }
 
@@ -139,4 +162,7 @@ func getFile(path string) ([]byte, error) {
 	return fileBytes, nil
 }
 
-var ErrTokenExpired = errors.New("token expired")
+var (
+	ErrTokenExpired = errors.New("token expired")
+	ErrNoSession    = errors.New("token is not bound to a session")
+)
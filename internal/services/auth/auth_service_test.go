@@ -8,6 +8,8 @@ import (
 	"encoding/pem"
 	"math/big"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,10 +35,7 @@ func TestMain(m *testing.M) {
 	blocks, _ := pem.Decode(pemBytes)
 	key, err := x509.ParsePKCS1PrivateKey(blocks.Bytes)
 
-	as = &Service{
-		privateKey: key,
-		publicKey:  &privateKey.PublicKey,
-	}
+	as = &Service{keys: newStaticKeyRing(key)}
 
 	log = logger.NewLogger(logrus.New())
 	code := m.Run()
@@ -44,14 +43,15 @@ func TestMain(m *testing.M) {
 }
 
 func TestCreateJwt(t *testing.T) {
-	gotToken, err := as.CreateJwt(&testUser1)
+	sessionID := uuid.New()
+	gotToken, err := as.CreateJwt(&testUser1, sessionID)
 	if err != nil {
 		t.Errorf("CreateJwt() error = %v", err)
 	}
 
 	gotClaims := jwt.MapClaims{}
 	token, err := jwt.ParseWithClaims(gotToken, gotClaims, func(token *jwt.Token) (interface{}, error) {
-		return as.publicKey, nil
+		return as.keys.Verifying(token.Header["kid"].(string))
 	})
 	if err != nil {
 		t.Errorf("CreateJwt() error = %v", err)
@@ -61,15 +61,17 @@ func TestCreateJwt(t *testing.T) {
 		"Id":       testUser1.ID.String(),
 		"Username": testUser1.Username,
 		"Email":    testUser1.Email,
+		"Sid":      sessionID.String(),
 		"exp":      token.Claims.(jwt.MapClaims)["exp"].(float64),
 	}
 	assert.Equal(t, token.Claims, wantClaims,
 		"CreateJwt() gotToken = %v, want %v", token.Claims, wantClaims)
 
 	wantUserCtx := &models.UserCtx{
-		Id:       testUser1.ID,
-		Username: testUser1.Username,
-		Email:    testUser1.Email,
+		Id:        testUser1.ID,
+		Username:  testUser1.Username,
+		Email:     testUser1.Email,
+		SessionID: sessionID,
 	}
 	gotUserCtx, err := as.CreateUserCtx(gotToken)
 	if err != nil {
@@ -79,6 +81,81 @@ func TestCreateJwt(t *testing.T) {
 		"CreateUserCtx() gotUserCtx = %v, want %v", gotUserCtx, wantUserCtx)
 }
 
+func TestCreateUserCtx_withoutSession(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"Id":       testUser1.ID,
+		"Username": testUser1.Username,
+		"Email":    testUser1.Email,
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}).SignedString(as.keys.signing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = as.CreateUserCtx(token)
+	assert.ErrorIs(t, err, ErrNoSession)
+}
+
+func TestParseRefreshToken(t *testing.T) {
+	sessionID := uuid.New()
+	token, hash, err := NewRefreshToken(sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := NewRefreshToken(sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantHash bool
+		wantErr  bool
+	}{
+		{
+			name:     "success",
+			token:    token,
+			wantHash: true,
+			wantErr:  false,
+		},
+		{
+			name:     "another token of the session",
+			token:    other,
+			wantHash: false,
+			wantErr:  false,
+		},
+		{
+			name:    "without secret",
+			token:   sessionID.String(),
+			wantErr: true,
+		},
+		{
+			name:    "wrong session id",
+			token:   "session." + strings.SplitN(token, ".", 2)[1],
+			wantErr: true,
+		},
+		{
+			name:    "short secret",
+			token:   sessionID.String() + ".c2VjcmV0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotID, gotHash, err := ParseRefreshToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRefreshToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, sessionID, gotID)
+			assert.Equal(t, tt.wantHash, reflect.DeepEqual(gotHash, hash))
+		})
+	}
+}
+
 func Test_getFile(t *testing.T) {
 	testFile := "test.txt"
 	wantBytes := []byte("test")
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ActiveKeyFile names the file of a key ring directory that holds the kid of
+// the signing key. Every other "<kid>.pem" file in the directory is used to
+// verify tokens only.
+const ActiveKeyFile = "active"
+
+// KeyRing holds the signing key and the verification keys selected by the
+// "kid" token header. It is safe for concurrent use and can be reloaded.
+type KeyRing struct {
+	mu       sync.RWMutex
+	path     string
+	activeID string
+	signing  *rsa.PrivateKey
+	verify   map[string]*rsa.PublicKey
+}
+
+// LoadKeyRing reads a single PEM key or a key ring directory.
+func LoadKeyRing(path string) (*KeyRing, error) {
+	kr := &KeyRing{path: path}
+	if err := kr.Reload(); err != nil {
+		return nil, err
+	}
+	return kr, nil
+}
+
+func newStaticKeyRing(key *rsa.PrivateKey) *KeyRing {
+	kid := KeyID(&key.PublicKey)
+	return &KeyRing{activeID: kid, signing: key, verify: map[string]*rsa.PublicKey{kid: &key.PublicKey}}
+}
+
+// Reload re-reads the keys from disk. The ring keeps its previous keys when
+// the new set cannot be loaded.
+func (kr *KeyRing) Reload() error {
+	info, err := os.Stat(kr.path)
+	if err != nil {
+		return err
+	}
+
+	var (
+		activeID string
+		signing  *rsa.PrivateKey
+		verify   map[string]*rsa.PublicKey
+	)
+	if info.IsDir() {
+		activeID, signing, verify, err = readKeyDir(kr.path)
+	} else {
+		signing, err = readPrivateKey(kr.path)
+		if err == nil {
+			activeID = KeyID(&signing.PublicKey)
+			verify = map[string]*rsa.PublicKey{activeID: &signing.PublicKey}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.activeID, kr.signing, kr.verify = activeID, signing, verify
+	return nil
+}
+
+// Signing returns the active key and its kid.
+func (kr *KeyRing) Signing() (string, *rsa.PrivateKey) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.activeID, kr.signing
+}
+
+// Verifying returns the public key for kid. Tokens without a kid were issued
+// before key rotation and are checked with the active key.
+func (kr *KeyRing) Verifying(kid string) (*rsa.PublicKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	if kid == "" {
+		return &kr.signing.PublicKey, nil
+	}
+	key, ok := kr.verify[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	return key, nil
+}
+
+// KeyIDs returns the kids the ring accepts.
+func (kr *KeyRing) KeyIDs() []string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	ids := make([]string, 0, len(kr.verify))
+	for id := range kr.verify {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func readKeyDir(dir string) (string, *rsa.PrivateKey, map[string]*rsa.PublicKey, error) {
+	active, err := os.ReadFile(filepath.Join(dir, ActiveKeyFile))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	activeID := strings.TrimSpace(string(active))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	var signing *rsa.PrivateKey
+	verify := make(map[string]*rsa.PublicKey, len(files))
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		if kid == activeID {
+			if signing, err = readPrivateKey(file); err != nil {
+				return "", nil, nil, fmt.Errorf("%s: %w", file, err)
+			}
+			verify[kid] = &signing.PublicKey
+			continue
+		}
+		if verify[kid], err = readPublicKey(file); err != nil {
+			return "", nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	if signing == nil {
+		return "", nil, nil, fmt.Errorf("%w: %q", ErrNoActiveKey, activeID)
+	}
+	return activeID, signing, verify, nil
+}
+
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+			return rsaKey, nil
+		}
+	}
+	return nil, ErrUnsupportedKey
+}
+
+// readPublicKey accepts a private key as well, so retired signing keys can
+// stay in the ring unchanged.
+func readPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != "PUBLIC KEY" {
+		key, err := readPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if rsaKey, ok := key.(*rsa.PublicKey); ok {
+		return rsaKey, nil
+	}
+	return nil, ErrUnsupportedKey
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	buf, err := getFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return nil, ErrUnsupportedKey
+	}
+	return block, nil
+}
+
+// KeyID is the kid of a key loaded from a single file: a short fingerprint
+// of its public part.
+func KeyID(key *rsa.PublicKey) string {
+	sum := sha256.Sum256(x509.MarshalPKCS1PublicKey(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+var (
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrNoActiveKey    = errors.New("active signing key not found")
+	ErrUnsupportedKey = errors.New("unsupported key format")
+)
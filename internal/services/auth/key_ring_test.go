@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyRing_Reload(t *testing.T) {
+	dir := t.TempDir()
+	writeKey := func(kid string) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+		if err = os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setActive := func(kid string) {
+		if err := os.WriteFile(filepath.Join(dir, ActiveKeyFile), []byte(kid+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeKey("k1")
+	setActive("k1")
+	kr, err := LoadKeyRing(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := Service{keys: kr}
+	oldToken, err := svc.CreateJwt(&testUser1, uidU1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("rotated key keeps old tokens valid", func(t *testing.T) {
+		writeKey("k2")
+		setActive("k2")
+		assert.NoError(t, svc.Reload())
+		kid, _ := kr.Signing()
+		assert.Equal(t, "k2", kid)
+
+		_, err := svc.CreateUserCtx(oldToken)
+		assert.NoError(t, err)
+		newToken, err := svc.CreateJwt(&testUser1, uidU1)
+		assert.NoError(t, err)
+		_, err = svc.CreateUserCtx(newToken)
+		assert.NoError(t, err)
+	})
+	t.Run("removed key invalidates its tokens", func(t *testing.T) {
+		assert.NoError(t, os.Remove(filepath.Join(dir, "k1.pem")))
+		assert.NoError(t, svc.Reload())
+		_, err := svc.CreateUserCtx(oldToken)
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	})
+	t.Run("broken ring keeps previous keys", func(t *testing.T) {
+		setActive("k3")
+		assert.ErrorIs(t, svc.Reload(), ErrNoActiveKey)
+		kid, _ := kr.Signing()
+		assert.Equal(t, "k2", kid)
+	})
+}
+
+func TestLoadKeyRing_singleFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "private.pem")
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	kr, err := LoadKeyRing(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, signing := kr.Signing()
+	assert.Equal(t, KeyID(&key.PublicKey), kid)
+	assert.True(t, key.Equal(signing))
+
+	legacy, err := kr.Verifying("")
+	assert.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(legacy))
+}
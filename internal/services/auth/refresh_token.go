@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshTokenTTL is how long a session survives without a refresh. Every
+// refresh moves the expiry forward.
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+// NewRefreshToken returns an opaque token for the session and the hash that is
+// stored on the server. The token is "<session id>.<random secret>".
+func NewRefreshToken(sessionID uuid.UUID) (string, []byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	token := sessionID.String() + "." + base64.RawURLEncoding.EncodeToString(secret)
+	return token, hashRefreshSecret(secret), nil
+}
+
+// ParseRefreshToken returns the session ID and the hash of a refresh token.
+func ParseRefreshToken(token string) (uuid.UUID, []byte, error) {
+	sid, encoded, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, nil, ErrInvalidRefreshToken
+	}
+	sessionID, err := uuid.Parse(sid)
+	if err != nil {
+		return uuid.Nil, nil, ErrInvalidRefreshToken
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(secret) != 32 {
+		return uuid.Nil, nil, ErrInvalidRefreshToken
+	}
+	return sessionID, hashRefreshSecret(secret), nil
+}
+
+func hashRefreshSecret(secret []byte) []byte {
+	sum := sha256.Sum256(secret)
+	return sum[:]
+}
+
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TOTP (RFC 6238) with the parameters every authenticator app supports:
+// HMAC-SHA1, 6 digits, 30 second steps. A code of the previous or next step
+// is accepted as well to absorb clock drift.
+const (
+	TotpIssuer = "GophKeeper"
+
+	totpPeriod     = 30
+	totpDigits     = 6
+	totpSkew       = 1
+	totpSecretSize = 20
+
+	RecoveryCodeCount = 10
+	recoveryCodeSize  = 5
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTotpSecret returns a random base32 secret.
+func NewTotpSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TotpURI returns the otpauth:// URI that authenticator apps import from a QR
+// code.
+func TotpURI(account, secret string) string {
+	label := url.PathEscape(TotpIssuer + ":" + account)
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {TotpIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(totpDigits)},
+		"period":    {fmt.Sprint(totpPeriod)},
+	}
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// TotpCode returns the code for the step that contains t.
+func TotpCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", ErrInvalidTotpSecret
+	}
+	return totpCode(key, totpStep(t)), nil
+}
+
+// ValidateTotp checks code against the steps around t and returns the matched
+// step. Callers store the step and reject codes of earlier or equal steps, so
+// a code cannot be replayed.
+func ValidateTotp(secret, code string, t time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	now := totpStep(t)
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// NewRecoveryCodes returns one-time codes for a lost authenticator and their
+// bcrypt hashes. Only the hashes are stored.
+func NewRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	hashes := make([][]byte, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		raw := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(raw))
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hash)
+	}
+	return codes, hashes, nil
+}
+
+// MatchRecoveryCode returns the stored hash that code matches. Dashes, spaces
+// and case are ignored.
+func MatchRecoveryCode(hashes [][]byte, code string) ([]byte, bool) {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword(hash, []byte(code)) == nil {
+			return hash, true
+		}
+	}
+	return nil, false
+}
+
+var ErrInvalidTotpSecret = errors.New("invalid totp secret")
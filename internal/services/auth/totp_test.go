@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTotpCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+	for _, tt := range tests {
+		got, err := TotpCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("TotpCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTotp(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := TotpCode(rfcSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		clock    time.Time
+		wantStep int64
+		wantOk   bool
+	}{
+		{name: "current step", secret: rfcSecret, code: code, clock: now, wantStep: 1111111111 / 30, wantOk: true},
+		{name: "clock behind", secret: rfcSecret, code: code, clock: now.Add(-30 * time.Second), wantStep: 1111111111 / 30, wantOk: true},
+		{name: "clock ahead", secret: rfcSecret, code: code, clock: now.Add(30 * time.Second), wantStep: 1111111111 / 30, wantOk: true},
+		{name: "expired code", secret: rfcSecret, code: code, clock: now.Add(2 * time.Minute)},
+		{name: "wrong code", secret: rfcSecret, code: "000000", clock: now},
+		{name: "short code", secret: rfcSecret, code: code[:5], clock: now},
+		{name: "broken secret", secret: "not base32!", code: code, clock: now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTotp(tt.secret, tt.code, tt.clock)
+			if ok != tt.wantOk || step != tt.wantStep {
+				t.Errorf("ValidateTotp() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestTotpURI(t *testing.T) {
+	uri, err := url.Parse(TotpURI("user1@test.com", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/GophKeeper:user1@test.com" {
+		t.Errorf("TotpURI() = %s", uri)
+	}
+	if uri.Query().Get("secret") != rfcSecret || uri.Query().Get("issuer") != TotpIssuer {
+		t.Errorf("TotpURI() query = %v", uri.Query())
+	}
+}
+
+func TestMatchRecoveryCode(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != RecoveryCodeCount || len(hashes) != RecoveryCodeCount {
+		t.Fatalf("NewRecoveryCodes() returned %d codes, %d hashes", len(codes), len(hashes))
+	}
+	hash, ok := MatchRecoveryCode(hashes, codes[3])
+	if !ok || string(hash) != string(hashes[3]) {
+		t.Errorf("MatchRecoveryCode() did not match the issued code")
+	}
+	if _, ok = MatchRecoveryCode(hashes, " "+codes[3][:4]+codes[3][5:]+" "); !ok {
+		t.Errorf("MatchRecoveryCode() must ignore dashes and spaces")
+	}
+	if _, ok = MatchRecoveryCode(hashes, "aaaa-aaaa"); ok {
+		t.Errorf("MatchRecoveryCode() matched an unknown code")
+	}
+}
@@ -0,0 +1,237 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blobChunkSize is the plaintext size of every blob chunk but the last.
+const blobChunkSize = 64 << 10
+
+// An interrupted upload is retried uploadAttempts times, uploadRetryDelay
+// apart, each time from the chunk the server has acknowledged.
+var (
+	uploadAttempts   = 3
+	uploadRetryDelay = time.Second
+)
+
+// UploadFile encrypts the file at path under a new blob key and uploads it.
+// The returned reference is kept in a BinaryNote; without it the file cannot
+// be decrypted.
+func (cn *Service) UploadFile(path string) (*models.BlobRef, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	key, err := util.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	ref := &models.BlobRef{ID: uuid.New(), FileName: filepath.Base(path), Size: info.Size(), Key: key}
+	if err = cn.UploadBlob(ref, file); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// UploadBlob uploads ref.Size bytes of r as the blob ref. It continues after
+// the chunks the server already has, so calling it again after a failure
+// does not send the whole file again.
+func (cn *Service) UploadBlob(ref *models.BlobRef, r io.ReadSeeker) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "UploadBlob",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("UploadBlob: jwt not found")
+		return fmt.Errorf("You need sigin to app")
+	}
+	var err error
+	for attempt := 1; attempt <= uploadAttempts; attempt++ {
+		if err = cn.uploadBlob(ref, r); err == nil {
+			log.Infof("uploaded %s, %d bytes", ref.FileName, ref.Size)
+			return nil
+		}
+		if !unreachable(err) && status.Code(err) != codes.FailedPrecondition {
+			break
+		}
+		log.WithError(err).Warningf("upload interrupted, attempt %d of %d", attempt, uploadAttempts)
+		if attempt < uploadAttempts {
+			time.Sleep(uploadRetryDelay)
+		}
+	}
+	log.WithError(err).Error("Error uploading file")
+	return err
+}
+
+func (cn *Service) uploadBlob(ref *models.BlobRef, r io.ReadSeeker) error {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	ctx = cn.addToken(ctx)
+
+	id := ref.ID.String()
+	var next int64
+	st, err := cn.nc.GetBlobStatus(ctx, &pb.BlobRequest{BlobId: id})
+	switch {
+	case err == nil && st.Complete:
+		return nil
+	case err == nil:
+		next = st.NextIndex
+	case status.Code(err) != codes.NotFound:
+		return err
+	}
+	if _, err = r.Seek(next*blobChunkSize, io.SeekStart); err != nil {
+		return err
+	}
+
+	stream, err := cn.nc.UploadBlob(ctx)
+	if err != nil {
+		return err
+	}
+	chunks := blobChunks(ref.Size)
+	buf := make([]byte, blobChunkSize)
+	for index := next; index < chunks; index++ {
+		plain := buf[:min(blobChunkSize, ref.Size-index*blobChunkSize)]
+		if _, err = io.ReadFull(r, plain); err != nil {
+			return err
+		}
+		last := index == chunks-1
+		sealed, err := util.Seal(ctx, ref.Key, util.KdfVersionNone, util.BlobChunkAAD(id, index, last), plain)
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(&pb.BlobChunk{BlobId: id, Index: index, Data: sealed, Last: last}); err != nil {
+			// the server ended the stream; its status is returned below
+			break
+		}
+	}
+	st, err = stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	if !st.Complete {
+		return status.Error(codes.FailedPrecondition, "upload is not complete")
+	}
+	return nil
+}
+
+// DownloadFile downloads the blob ref into path. The file appears only once
+// every chunk has been verified.
+func (cn *Service) DownloadFile(ref models.BlobRef, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err = cn.DownloadBlob(ref, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// DownloadBlob writes the decrypted blob ref to w. A chunk that is missing,
+// out of place or altered fails the download with ErrBlobCorrupt.
+func (cn *Service) DownloadBlob(ref models.BlobRef, w io.Writer) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "DownloadBlob",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("DownloadBlob: jwt not found")
+		return fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	ctx = cn.addToken(ctx)
+
+	id := ref.ID.String()
+	stream, err := cn.nc.DownloadBlob(ctx, &pb.BlobRequest{BlobId: id})
+	if err != nil {
+		log.WithError(err).Error("Error downloading file")
+		return err
+	}
+	chunks := blobChunks(ref.Size)
+	var written int64
+	for index := int64(0); index < chunks; index++ {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return ErrBlobCorrupt
+		}
+		if err != nil {
+			log.WithError(err).Error("Error downloading file")
+			return err
+		}
+		if chunk.Index != index {
+			return ErrBlobCorrupt
+		}
+		plain, _, err := util.Open(ctx, ref.Key, chunk.Data, util.BlobChunkAAD(id, index, index == chunks-1))
+		if err != nil {
+			log.WithError(err).Error("Error decrypting file chunk")
+			return ErrBlobCorrupt
+		}
+		if _, err = w.Write(plain); err != nil {
+			return err
+		}
+		written += int64(len(plain))
+	}
+	if written != ref.Size {
+		return ErrBlobCorrupt
+	}
+	log.Infof("downloaded %s, %d bytes", ref.FileName, written)
+	return nil
+}
+
+// DeleteBlob deletes an uploaded file. A file that is already gone counts as
+// deleted. Files of saved notes need no explicit delete: the server deletes a
+// file once no note refers to it.
+func (cn *Service) DeleteBlob(id uuid.UUID) error {
+	if cn.jwt == "" {
+		return fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
+	defer cancelFunc()
+	_, err := cn.nc.DeleteBlob(cn.addToken(ctx), &pb.BlobRequest{BlobId: id.String()})
+	if err != nil && status.Code(err) != codes.NotFound {
+		log.WithField("method", "DeleteBlob").WithError(err).Error("Error deleting file")
+		return err
+	}
+	return nil
+}
+
+// blobOf returns the file attached to note, if any.
+func blobOf(note models.Noteable) *models.BlobRef {
+	if binary, ok := note.(*models.BinaryNote); ok {
+		return binary.Blob
+	}
+	return nil
+}
+
+// blobChunks is the number of chunks of a blob of size bytes; an empty blob
+// still has one, empty, chunk.
+func blobChunks(size int64) int64 {
+	return max(1, (size+blobChunkSize-1)/blobChunkSize)
+}
+
+var ErrBlobCorrupt = errors.New("The file is damaged or was tampered with")
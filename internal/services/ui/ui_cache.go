@@ -0,0 +1,225 @@
+package ui
+
+import (
+	"context"
+	"os"
+	"time"
+
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// vaultCache keeps the notes of the last sync together with the sync cursor,
+// the edits made while the server was unreachable and what is needed to
+// unlock the vault offline. Every note is stored sealed with the vault data
+// key, names and types included, and is opened only when it is loaded.
+type vaultCache struct {
+	db *gorm.DB
+}
+
+type cachedNote struct {
+	Account string `gorm:"primaryKey;size:255"`
+	ID      string `gorm:"primaryKey;size:36"`
+	Data    []byte
+}
+
+type cachedCursor struct {
+	Account string `gorm:"primaryKey;size:255"`
+	Cursor  int64
+}
+
+// cachedAccount holds the KDF parameters and the wrapped data key of an
+// account, so the password unlocks the vault without the server.
+type cachedAccount struct {
+	Account    string           `gorm:"primaryKey;size:255"`
+	Kdf        models.KdfParams `gorm:"embedded;embeddedPrefix:kdf_"`
+	WrappedKey []byte
+}
+
+// queuedEdit is a write that has not reached the server yet. There is at
+// most one per note: later edits replace the queued one and keep the
+// revision it was based on.
+type queuedEdit struct {
+	Account string `gorm:"primaryKey;size:255"`
+	ID      string `gorm:"primaryKey;size:36"`
+	Seq     int64  `gorm:"index"`
+	// Revision is the server revision the edit is based on. Added marks a
+	// note the server has never seen.
+	Revision int64
+	Added    bool
+	Deleted  bool
+	Data     []byte
+
+	Note *pb.Note `gorm:"-"`
+}
+
+func openVaultCache(path string) (*vaultCache, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		return nil, err
+	}
+	if err = db.AutoMigrate(&cachedNote{}, &cachedCursor{}, &cachedAccount{}, &queuedEdit{}); err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(path, 0o600); err != nil {
+		return nil, err
+	}
+	return &vaultCache{db: db}, nil
+}
+
+// load returns the cached notes of account and the cursor they are at. When
+// a note cannot be opened with key the cursor is 0, so the next sync
+// downloads the whole vault again.
+func (c *vaultCache) load(ctx context.Context, key []byte, account string) ([]*pb.Note, int64, error) {
+	var cursor cachedCursor
+	if err := c.db.Where("account = ?", account).Limit(1).Find(&cursor).Error; err != nil {
+		return nil, 0, err
+	}
+	var rows []cachedNote
+	if err := c.db.Where("account = ?", account).Find(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	notes := make([]*pb.Note, 0, len(rows))
+	for _, row := range rows {
+		note, err := openCached(ctx, key, account, row.ID, row.Data)
+		if err != nil {
+			cursor.Cursor = 0
+			continue
+		}
+		notes = append(notes, note)
+	}
+	return notes, cursor.Cursor, nil
+}
+
+// save applies a sync response to the cached notes of account in one
+// transaction, so the cursor never gets ahead of the notes.
+func (c *vaultCache) save(ctx context.Context, key []byte, account string, resp *pb.SyncResponse) error {
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		if resp.Full {
+			if err := tx.Where("account = ?", account).Delete(&cachedNote{}).Error; err != nil {
+				return err
+			}
+		}
+		for _, note := range resp.Notes {
+			data, err := sealCached(ctx, key, account, note)
+			if err != nil {
+				return err
+			}
+			row := cachedNote{Account: account, ID: note.Id, Data: data}
+			if err = tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error; err != nil {
+				return err
+			}
+		}
+		if len(resp.Deleted) > 0 {
+			if err := tx.Where("account = ?", account).Where("id IN ?", resp.Deleted).Delete(&cachedNote{}).Error; err != nil {
+				return err
+			}
+		}
+		cursor := cachedCursor{Account: account, Cursor: resp.Cursor}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&cursor).Error
+	})
+}
+
+// remember stores what an offline login of account needs.
+func (c *vaultCache) remember(account string, params models.KdfParams, wrapped []byte) error {
+	row := cachedAccount{Account: account, Kdf: params, WrappedKey: wrapped}
+	return c.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&row).Error
+}
+
+// account returns the stored KDF parameters and wrapped data key of account.
+func (c *vaultCache) account(account string) (*cachedAccount, error) {
+	var row cachedAccount
+	if err := c.db.Where("account = ?", account).First(&row).Error; err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// queue adds edit to the outbox of account, merging it with an edit of the
+// same note that is already waiting. A delete of a note the server has never
+// seen drops the queued edit altogether.
+func (c *vaultCache) queue(ctx context.Context, key []byte, edit queuedEdit) error {
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		var queued []queuedEdit
+		if err := tx.Where("account = ? AND id = ?", edit.Account, edit.ID).Limit(1).Find(&queued).Error; err != nil {
+			return err
+		}
+		edit.Seq = time.Now().UnixNano()
+		if len(queued) > 0 {
+			edit.Seq = queued[0].Seq
+			edit.Revision = queued[0].Revision
+			edit.Added = queued[0].Added
+		}
+		if edit.Deleted && edit.Added {
+			return tx.Delete(&queuedEdit{Account: edit.Account, ID: edit.ID}).Error
+		}
+		edit.Data = nil
+		if !edit.Deleted {
+			data, err := sealCached(ctx, key, edit.Account, edit.Note)
+			if err != nil {
+				return err
+			}
+			edit.Data = data
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&edit).Error
+	})
+}
+
+// pending returns the queued edits of account in the order they were made.
+func (c *vaultCache) pending(ctx context.Context, key []byte, account string) ([]queuedEdit, error) {
+	var edits []queuedEdit
+	if err := c.db.Where("account = ?", account).Order("seq").Find(&edits).Error; err != nil {
+		return nil, err
+	}
+	for i := range edits {
+		if edits[i].Deleted {
+			continue
+		}
+		note, err := openCached(ctx, key, account, edits[i].ID, edits[i].Data)
+		if err != nil {
+			return nil, err
+		}
+		edits[i].Note = note
+	}
+	return edits, nil
+}
+
+// done removes the queued edit of a note once the server has it.
+func (c *vaultCache) done(account, id string) error {
+	return c.db.Delete(&queuedEdit{Account: account, ID: id}).Error
+}
+
+func sealCached(ctx context.Context, key []byte, account string, note *pb.Note) ([]byte, error) {
+	data, err := proto.Marshal(note)
+	if err != nil {
+		return nil, err
+	}
+	return util.Seal(ctx, key, util.KdfVersionNone, cacheAAD(account, note.Id), data)
+}
+
+func openCached(ctx context.Context, key []byte, account, id string, data []byte) (*pb.Note, error) {
+	plain, _, err := util.Open(ctx, key, data, cacheAAD(account, id))
+	if err != nil {
+		return nil, err
+	}
+	note := &pb.Note{}
+	if err = proto.Unmarshal(plain, note); err != nil {
+		return nil, err
+	}
+	if note.Id != id {
+		return nil, ErrNoteMismatch
+	}
+	return note, nil
+}
+
+// cacheAAD binds a cached note to its account and id, so rows cannot be
+// swapped inside the file.
+func cacheAAD(account, id string) []byte {
+	return []byte("vault cache\x00" + account + "\x00" + id)
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConflictError is returned when a note was changed or deleted on another
+// device since it was loaded. Local is the edit that was rejected, nil for a
+// delete; Remote is the server copy, nil when the note is gone there.
+type ConflictError struct {
+	ID     uuid.UUID
+	Local  models.Noteable
+	Remote models.Noteable
+
+	revision int64
+	sealed   bool
+}
+
+func (e *ConflictError) Error() string {
+	if e.Remote == nil {
+		return "The note was deleted on another device"
+	}
+	return "The note was changed on another device"
+}
+
+// ResolveConflict settles a conflict. With keepLocal the rejected edit or
+// delete is applied on top of the server copy, otherwise the server copy
+// replaces the local one.
+func (cn *Service) ResolveConflict(conflict *ConflictError, keepLocal bool) (*[]models.Noteable, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "ResolveConflict",
+	})
+
+	switch {
+	case keepLocal && conflict.Local == nil:
+		cn.revisions[conflict.ID] = conflict.revision
+		return cn.DeleteNote(conflict.ID)
+	case keepLocal:
+		if conflict.Remote == nil {
+			delete(cn.revisions, conflict.ID)
+		} else {
+			cn.revisions[conflict.ID] = conflict.revision
+			cn.sealed[conflict.ID] = conflict.sealed
+		}
+		return cn.AddNote(conflict.Local)
+	case conflict.Remote == nil:
+		cn.dequeue(conflict.ID)
+		cn.forget(conflict.ID)
+	default:
+		cn.dequeue(conflict.ID)
+		cn.storage[conflict.ID] = &conflict.Remote
+		cn.revisions[conflict.ID] = conflict.revision
+		cn.sealed[conflict.ID] = conflict.sealed
+	}
+	log.WithField("note", conflict.ID).Info("server copy kept")
+	return toNotableList(cn.storage), nil
+}
+
+// conflict turns a rejected write into a *ConflictError. Aborted carries the
+// server copy; NotFound means the note was deleted. Other errors are returned
+// as they are.
+func (cn *Service) conflict(ctx context.Context, id uuid.UUID, local models.Noteable, err error) error {
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.NotFound:
+		return &ConflictError{ID: id, Local: local}
+	case codes.Aborted:
+		for _, detail := range st.Details() {
+			noteDto, ok := detail.(*pb.Note)
+			if !ok {
+				continue
+			}
+			remote, openErr := unmarshalNote(ctx, cn.dataKey, noteDto)
+			if openErr != nil {
+				return openErr
+			}
+			return &ConflictError{ID: id, Local: local, Remote: remote, revision: noteDto.Revision, sealed: noteDto.Sealed}
+		}
+	}
+	return err
+}
+
+func (cn *Service) forget(id uuid.UUID) {
+	delete(cn.storage, id)
+	delete(cn.sealed, id)
+	delete(cn.revisions, id)
+	delete(cn.legacy, id)
+}
@@ -0,0 +1,374 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
+	"github.com/sirupsen/logrus"
+)
+
+// A vault export is a JSON object: the header fields name the format and
+// carry the Argon2id parameters of the passphrase key, and payload is an
+// envelope sealed with that key and authenticated with the header. The
+// envelope holds exportPayload as JSON.
+const (
+	exportFormat  = "gophkeeper-export"
+	exportVersion = 1
+)
+
+type exportHeader struct {
+	Format    string           `json:"format"`
+	Version   int              `json:"version"`
+	Kdf       models.KdfParams `json:"kdf"`
+	CreatedAt int64            `json:"created_at"`
+}
+
+type exportFile struct {
+	exportHeader
+	Payload []byte `json:"payload"`
+}
+
+type exportPayload struct {
+	Manifest exportManifest `json:"manifest"`
+	Notes    []exportedNote `json:"notes"`
+	Files    []exportedFile `json:"files,omitempty"`
+}
+
+// exportManifest maps the id of every note and attached file in the export
+// to the SHA-256 of its JSON or data, so a note or file that was dropped,
+// added or changed is noticed before anything is imported.
+type exportManifest struct {
+	Notes map[string]string `json:"notes"`
+	Files map[string]string `json:"files,omitempty"`
+}
+
+type exportedNote struct {
+	Type string          `json:"type"`
+	Note json.RawMessage `json:"note"`
+}
+
+type exportedFile struct {
+	ID   uuid.UUID `json:"id"`
+	Data []byte    `json:"data"`
+}
+
+// ImportPolicy decides what ImportVault does with a note whose id is already
+// in the vault.
+type ImportPolicy string
+
+const (
+	ImportSkip      ImportPolicy = "skip"
+	ImportOverwrite ImportPolicy = "overwrite"
+	ImportDuplicate ImportPolicy = "duplicate"
+)
+
+// ImportPolicies lists the policies in the order they are offered.
+var ImportPolicies = []ImportPolicy{ImportSkip, ImportOverwrite, ImportDuplicate}
+
+func ParseImportPolicy(s string) (ImportPolicy, error) {
+	for _, policy := range ImportPolicies {
+		if string(policy) == s {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown import policy %q, use skip, overwrite or duplicate", s)
+}
+
+// ImportResult counts what ImportVault did with the notes of an export.
+type ImportResult struct {
+	Added       int `json:"added"`
+	Overwritten int `json:"overwritten"`
+	Duplicated  int `json:"duplicated"`
+	Skipped     int `json:"skipped"`
+}
+
+func (r ImportResult) String() string {
+	return fmt.Sprintf("%d added, %d overwritten, %d duplicated, %d skipped", r.Added, r.Overwritten, r.Duplicated, r.Skipped)
+}
+
+// ExportVault writes every note of the vault to w, together with the files
+// attached to them, encrypted with a key derived from passphrase. It returns
+// the number of notes written. The export is built in memory.
+func (cn *Service) ExportVault(w io.Writer, passphrase string) (int, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "ExportVault",
+	})
+
+	if passphrase == "" {
+		return 0, ErrEmptyPassphrase
+	}
+	loaded, err := cn.LoadNote()
+	if err != nil {
+		return 0, err
+	}
+	notes := *loaded
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].GetID().String() < notes[j].GetID().String()
+	})
+
+	payload := exportPayload{Manifest: exportManifest{Notes: make(map[string]string), Files: make(map[string]string)}}
+	for _, note := range notes {
+		data, err := json.Marshal(note)
+		if err != nil {
+			return 0, err
+		}
+		payload.Notes = append(payload.Notes, exportedNote{Type: note.GetType().String(), Note: data})
+		payload.Manifest.Notes[note.GetID().String()] = digest(data)
+
+		ref := blobOf(note)
+		if ref == nil {
+			continue
+		}
+		if _, ok := payload.Manifest.Files[ref.ID.String()]; ok {
+			continue
+		}
+		var file bytes.Buffer
+		if err = cn.DownloadBlob(*ref, &file); err != nil {
+			log.WithError(err).Errorf("Error downloading the file of %s", note.GetName())
+			return 0, err
+		}
+		payload.Files = append(payload.Files, exportedFile{ID: ref.ID, Data: file.Bytes()})
+		payload.Manifest.Files[ref.ID.String()] = digest(file.Bytes())
+	}
+	if err = writeExport(w, payload, passphrase, cn.kdfCost); err != nil {
+		log.WithError(err).Error("Error writing export")
+		return 0, err
+	}
+	log.Infof("exported %d notes and %d files", len(payload.Notes), len(payload.Files))
+	return len(payload.Notes), nil
+}
+
+// ExportVaultFile exports the vault into path, readable only by the user.
+// The file appears only once the export is complete.
+func (cn *Service) ExportVaultFile(path, passphrase string) (int, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".export-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	count, err := cn.ExportVault(tmp, passphrase)
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err = tmp.Close(); err != nil {
+		return 0, err
+	}
+	return count, os.Rename(tmp.Name(), path)
+}
+
+// writeExport seals payload with a key derived from passphrase at the cost
+// of cost.
+func writeExport(w io.Writer, payload exportPayload, passphrase string, cost models.KdfParams) error {
+	plain, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	params, err := util.NewKdfParams(cost.Time, cost.Memory, cost.Threads)
+	if err != nil {
+		return err
+	}
+	key, err := util.DeriveKey(passphrase, params)
+	if err != nil {
+		return err
+	}
+	file := exportFile{exportHeader: exportHeader{
+		Format:    exportFormat,
+		Version:   exportVersion,
+		Kdf:       params,
+		CreatedAt: time.Now().Unix(),
+	}}
+	aad, err := exportAAD(file.exportHeader)
+	if err != nil {
+		return err
+	}
+	if file.Payload, err = util.Seal(context.Background(), key, util.KdfVersionArgon2id, aad, plain); err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(file)
+}
+
+// ImportVault adds the notes of an export written by ExportVault to the
+// vault, uploading their files again. The whole export is decrypted and
+// checked against its manifest first, so a damaged one imports nothing. A
+// note whose id the vault already has is handled as policy says; duplicates
+// get a new id. On an error the notes imported so far stay.
+func (cn *Service) ImportVault(r io.Reader, passphrase string, policy ImportPolicy) (*ImportResult, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "ImportVault",
+	})
+
+	if _, err := ParseImportPolicy(string(policy)); err != nil {
+		return nil, err
+	}
+	notes, files, err := readExport(r, passphrase)
+	if err != nil {
+		log.WithError(err).Warning("Error reading export")
+		return nil, err
+	}
+	if _, err = cn.LoadNote(); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	for _, note := range notes {
+		counter := &result.Added
+		if _, ok := cn.storage[note.GetID()]; ok {
+			switch policy {
+			case ImportSkip:
+				result.Skipped++
+				continue
+			case ImportOverwrite:
+				counter = &result.Overwritten
+			case ImportDuplicate:
+				setNoteID(note, uuid.New())
+				counter = &result.Duplicated
+			}
+		}
+		if err = cn.importFile(note, files); err != nil {
+			return result, fmt.Errorf("importing the file of %q: %w", note.GetName(), err)
+		}
+		if _, err = cn.AddNote(note); err != nil {
+			return result, fmt.Errorf("importing %q: %w", note.GetName(), err)
+		}
+		*counter++
+	}
+	log.Infof("imported %+v", *result)
+	return result, nil
+}
+
+// ImportVaultFile imports the export in path.
+func (cn *Service) ImportVaultFile(path, passphrase string, policy ImportPolicy) (*ImportResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return cn.ImportVault(file, passphrase, policy)
+}
+
+// importFile uploads the file attached to note under a new id and key and
+// points the note at it.
+func (cn *Service) importFile(note models.Noteable, files map[uuid.UUID][]byte) error {
+	binary, ok := note.(*models.BinaryNote)
+	if !ok || binary.Blob == nil {
+		return nil
+	}
+	key, err := util.NewDataKey()
+	if err != nil {
+		return err
+	}
+	data := files[binary.Blob.ID]
+	ref := &models.BlobRef{ID: uuid.New(), FileName: binary.Blob.FileName, Size: int64(len(data)), Key: key}
+	if err = cn.UploadBlob(ref, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	binary.Blob = ref
+	return nil
+}
+
+// readExport decrypts an export and checks it against its manifest.
+func readExport(r io.Reader, passphrase string) ([]models.Noteable, map[uuid.UUID][]byte, error) {
+	var file exportFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil || file.Format != exportFormat {
+		return nil, nil, ErrExportFormat
+	}
+	if file.Version < 1 || file.Version > exportVersion {
+		return nil, nil, ErrExportVersion
+	}
+	// the header is not authenticated yet: a cost above the maximum would
+	// exhaust memory before the passphrase is checked
+	if util.CheckKdfLimits(file.Kdf) != nil {
+		return nil, nil, ErrExportFormat
+	}
+	key, err := util.DeriveKey(passphrase, file.Kdf)
+	if err != nil {
+		return nil, nil, err
+	}
+	aad, err := exportAAD(file.exportHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+	plain, _, err := util.Open(context.Background(), key, file.Payload, aad)
+	if err != nil {
+		return nil, nil, ErrExportPassphrase
+	}
+	var payload exportPayload
+	if err = json.Unmarshal(plain, &payload); err != nil {
+		return nil, nil, ErrExportManifest
+	}
+
+	manifest := payload.Manifest
+	if len(payload.Notes) != len(manifest.Notes) || len(payload.Files) != len(manifest.Files) {
+		return nil, nil, ErrExportManifest
+	}
+	files := make(map[uuid.UUID][]byte, len(payload.Files))
+	for _, file := range payload.Files {
+		if manifest.Files[file.ID.String()] != digest(file.Data) {
+			return nil, nil, ErrExportManifest
+		}
+		files[file.ID] = file.Data
+	}
+	notes := make([]models.Noteable, 0, len(payload.Notes))
+	for _, exported := range payload.Notes {
+		note, err := decodeNote(exported.Type, exported.Note)
+		if err != nil || manifest.Notes[note.GetID().String()] != digest(exported.Note) {
+			return nil, nil, ErrExportManifest
+		}
+		if ref := blobOf(note); ref != nil {
+			if data, ok := files[ref.ID]; !ok || int64(len(data)) != ref.Size {
+				return nil, nil, ErrExportManifest
+			}
+		}
+		notes = append(notes, note)
+	}
+	return notes, files, nil
+}
+
+// exportAAD binds the payload of an export to its header.
+func exportAAD(header exportHeader) ([]byte, error) {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("vault export\x00"), data...), nil
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func setNoteID(note models.Noteable, id uuid.UUID) {
+	switch n := note.(type) {
+	case *models.CredentialNote:
+		n.Id = id
+	case *models.TextNote:
+		n.Id = id
+	case *models.BankCardNote:
+		n.Id = id
+	case *models.BinaryNote:
+		n.Id = id
+	}
+}
+
+var (
+	ErrEmptyPassphrase  = errors.New("The export needs a passphrase")
+	ErrExportFormat     = errors.New("The file is not a vault export")
+	ErrExportVersion    = errors.New("The vault export was written by a newer version")
+	ErrExportPassphrase = errors.New("Wrong passphrase, or the export is damaged")
+	ErrExportManifest   = errors.New("The vault export does not match its manifest")
+)
@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NoteRevision is an earlier version of a note kept by the server, decrypted.
+type NoteRevision struct {
+	Revision  int64
+	WrittenAt time.Time
+	Note      models.Noteable
+}
+
+// ListRevisions fetches the kept revisions of a note, newest first, and
+// decrypts them. The history is only kept on the server, so it needs a
+// connection.
+func (cn *Service) ListRevisions(id uuid.UUID) ([]NoteRevision, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "ListRevisions",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("ListRevisions: jwt not found")
+		return nil, fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
+	defer cancelFunc()
+
+	resp, err := cn.nc.ListNoteRevisions(cn.addToken(ctx), &pb.NoteRequest{IdNote: id.String()})
+	if err != nil {
+		log.WithError(err).Error("Error listing revisions")
+		return nil, err
+	}
+	revisions := make([]NoteRevision, 0, len(resp.Revisions))
+	for _, revision := range resp.Revisions {
+		if revision.GetNote().GetId() != id.String() {
+			return nil, ErrNoteMismatch
+		}
+		note, err := unmarshalNote(ctx, cn.dataKey, revision.Note)
+		if err != nil {
+			log.WithError(err).Errorf("Error decrypting revision %d", revision.Note.Revision)
+			return nil, err
+		}
+		revisions = append(revisions, NoteRevision{
+			Revision:  revision.Note.Revision,
+			WrittenAt: time.Unix(revision.WrittenAt, 0),
+			Note:      note,
+		})
+	}
+	return revisions, nil
+}
+
+// RestoreRevision makes an earlier revision the current version of its note.
+// A note changed on another device since it was loaded returns a
+// *ConflictError whose Local is the revision.
+func (cn *Service) RestoreRevision(revision NoteRevision) (*[]models.Noteable, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "RestoreRevision",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("RestoreRevision: jwt not found")
+		return nil, fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
+	defer cancelFunc()
+
+	id := revision.Note.GetID()
+	noteDto, err := cn.nc.RestoreNoteRevision(cn.addToken(ctx), &pb.RestoreRequest{
+		IdNote:   id.String(),
+		Revision: revision.Revision,
+		Current:  cn.revisions[id],
+	})
+	if err != nil {
+		log.WithError(err).Error("Error restoring revision")
+		if status.Code(err) == codes.Aborted {
+			return nil, cn.conflict(ctx, id, revision.Note, err)
+		}
+		return nil, err
+	}
+	note, err := unmarshalNote(ctx, cn.dataKey, noteDto)
+	if err != nil {
+		log.WithError(err).Error("Error decrypting restored note")
+		return nil, err
+	}
+	cn.dequeue(id)
+	cn.storage[id] = &note
+	cn.sealed[id] = noteDto.Sealed
+	cn.revisions[id] = noteDto.Revision
+	log.WithField("note", note.GetName()).Infof("Restored revision %d", revision.Revision)
+	return toNotableList(cn.storage), nil
+}
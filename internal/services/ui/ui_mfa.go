@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/sirupsen/logrus"
+)
+
+// pendingMfa keeps what Login learned until the second factor is verified,
+// so the password is not asked for again.
+type pendingMfa struct {
+	token  string
+	user   *pb.User
+	kek    []byte
+	legacy bool
+}
+
+// VerifyMfa completes a login that returned ErrMfaRequired with a code from
+// the authenticator app or a recovery code.
+func (cn *Service) VerifyMfa(code string) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "VerifyMfa",
+	})
+
+	if cn.mfa == nil {
+		log.Warning("VerifyMfa: no login waits for a second factor")
+		return fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+	pending := cn.mfa
+	token, err := cn.uc.VerifyMfa(withDevice(ctx), &pb.MfaRequest{MfaToken: pending.token, Code: code})
+	if err != nil {
+		log.WithError(err).Error("Error verifying second factor")
+		return err
+	}
+	cn.mfa = nil
+	return cn.finishLogin(ctx, pending.user, token, pending.kek, pending.legacy)
+}
+
+// EnrollTotp starts two-factor enrollment. The secret is active only after
+// ConfirmTotp accepts a code generated from it.
+func (cn *Service) EnrollTotp() (*pb.TotpEnrollment, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "EnrollTotp",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("EnrollTotp: jwt not found")
+		return nil, fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
+	defer cancelFunc()
+	enrollment, err := cn.uc.EnrollTotp(cn.addToken(ctx), &empty.Empty{})
+	if err != nil {
+		log.WithError(err).Error("Error enrolling second factor")
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// ConfirmTotp enables two-factor login and returns the recovery codes. They
+// are shown once and cannot be requested again.
+func (cn *Service) ConfirmTotp(code string) ([]string, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "ConfirmTotp",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("ConfirmTotp: jwt not found")
+		return nil, fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+	recovery, err := cn.uc.ConfirmTotp(cn.addToken(ctx), &pb.TotpCode{Code: code})
+	if err != nil {
+		log.WithError(err).Error("Error confirming second factor")
+		return nil, err
+	}
+	log.Info("two-factor authentication enabled")
+	return recovery.Codes, nil
+}
+
+func (cn *Service) DisableTotp(code string) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "DisableTotp",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("DisableTotp: jwt not found")
+		return fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFunc()
+	if _, err := cn.uc.DisableTotp(cn.addToken(ctx), &pb.TotpCode{Code: code}); err != nil {
+		log.WithError(err).Error("Error disabling second factor")
+		return err
+	}
+	log.Info("two-factor authentication disabled")
+	return nil
+}
+
+var ErrMfaRequired = errors.New("a second factor is required")
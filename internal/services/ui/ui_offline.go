@@ -0,0 +1,199 @@
+package ui
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Offline reports whether the notes shown come from the local cache because
+// the server could not be reached. Edits made meanwhile are queued and sent
+// by the next LoadNote that reaches the server.
+func (cn *Service) Offline() bool {
+	return cn.offline
+}
+
+// unreachable reports whether err means that the server could not be
+// reached, as opposed to the server rejecting the call.
+func unreachable(err error) bool {
+	code := status.Code(err)
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
+
+// goOffline switches to the cache when err says the server is unreachable.
+// Without a cache there is nothing to fall back to.
+func (cn *Service) goOffline(err error) bool {
+	if cn.cache == nil || !unreachable(err) {
+		return false
+	}
+	if !cn.offline {
+		log.WithField("method", "goOffline").WithError(err).Warning("server unreachable, working offline")
+	}
+	cn.offline = true
+	return true
+}
+
+// loginOffline unlocks the vault of user with the key material remembered by
+// the last online login. There is no session then: notes are read from the
+// cache and edits are queued until the user signs in online again.
+func (cn *Service) loginOffline(ctx context.Context, user *pb.User) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "loginOffline",
+	})
+
+	account, err := cn.cache.account(user.Email)
+	if err != nil {
+		return ErrNoOfflineLogin
+	}
+	if err = util.CheckKdfLimits(account.Kdf); err != nil {
+		return err
+	}
+	kek, err := util.DeriveKey(user.Password, account.Kdf)
+	if err != nil {
+		return err
+	}
+	dataKey, err := util.UnwrapKey(ctx, kek, account.WrappedKey)
+	if err != nil {
+		return err
+	}
+	cn.resetVault()
+	cn.clearTokens()
+	cn.email = user.Email
+	cn.dataKey = dataKey
+	cn.enveloped = true
+	cn.offline = true
+	log.Infof("user unlocked the vault offline: %s", user.Email)
+	return nil
+}
+
+// rememberAccount keeps what loginOffline needs in the cache.
+func (cn *Service) rememberAccount(params models.KdfParams, wrapped []byte) {
+	cn.keys = &cachedAccount{Account: cn.email, Kdf: params, WrappedKey: wrapped}
+	if cn.cache == nil {
+		return
+	}
+	if err := cn.cache.remember(cn.email, params, wrapped); err != nil {
+		log.WithField("method", "rememberAccount").WithError(err).Warning("offline login is not available")
+	}
+}
+
+// queueEdit stores a write the server could not take. noteDto is nil for a
+// delete; revision is the server revision the write is based on.
+func (cn *Service) queueEdit(ctx context.Context, id uuid.UUID, noteDto *pb.Note, revision int64, added bool) error {
+	return cn.cache.queue(ctx, cn.dataKey, queuedEdit{
+		Account:  cn.email,
+		ID:       id.String(),
+		Revision: revision,
+		Added:    added,
+		Deleted:  noteDto == nil,
+		Note:     noteDto,
+	})
+}
+
+// dequeue drops a queued write that a later online write has replaced.
+func (cn *Service) dequeue(id uuid.UUID) {
+	if cn.cache == nil {
+		return
+	}
+	if err := cn.cache.done(cn.email, id.String()); err != nil {
+		log.WithField("method", "dequeue").WithError(err).Warning("queued edit is not removed")
+	}
+}
+
+// applyQueued shows the queued writes on top of the cached notes.
+func (cn *Service) applyQueued(ctx context.Context) {
+	log := log.WithFields(logrus.Fields{
+		"method": "applyQueued",
+	})
+
+	edits, err := cn.cache.pending(ctx, cn.dataKey, cn.email)
+	if err != nil {
+		log.WithError(err).Warning("could not read queued edits")
+		return
+	}
+	for _, edit := range edits {
+		id, err := uuid.Parse(edit.ID)
+		if err != nil {
+			continue
+		}
+		if edit.Deleted {
+			cn.forget(id)
+			continue
+		}
+		note, err := unmarshalNote(ctx, cn.dataKey, edit.Note)
+		if err != nil {
+			log.WithError(err).Error("Error unmarshalling queued note")
+			continue
+		}
+		cn.storage[id] = &note
+		cn.sealed[id] = edit.Note.Sealed
+		delete(cn.revisions, id)
+		if !edit.Added {
+			cn.revisions[id] = edit.Revision
+		}
+	}
+}
+
+// replay sends the queued writes in the order they were made. It stops at
+// the first one the server rejects; a write based on an outdated revision
+// comes back as a *ConflictError and stays queued until it is resolved.
+func (cn *Service) replay(ctx context.Context) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "replay",
+	})
+
+	if cn.cache == nil {
+		return nil
+	}
+	edits, err := cn.cache.pending(ctx, cn.dataKey, cn.email)
+	if err != nil {
+		return err
+	}
+	for _, edit := range edits {
+		id, err := uuid.Parse(edit.ID)
+		if err != nil {
+			continue
+		}
+		if edit.Deleted {
+			_, err = cn.nc.DeleteNote(ctx, &pb.NoteRequest{IdNote: edit.ID, Revision: edit.Revision})
+			if err != nil && status.Code(err) != codes.NotFound {
+				return cn.conflict(ctx, id, nil, err)
+			}
+		} else {
+			edit.Note.Revision = edit.Revision
+			if err = cn.putNote(ctx, edit.Note, !edit.Added); err != nil {
+				local, openErr := unmarshalNote(ctx, cn.dataKey, edit.Note)
+				if openErr != nil {
+					return openErr
+				}
+				return cn.conflict(ctx, id, local, err)
+			}
+		}
+		if err = cn.cache.done(cn.email, edit.ID); err != nil {
+			return err
+		}
+		log.WithField("note", edit.ID).Info("queued edit sent")
+	}
+	return nil
+}
+
+// putNote updates a note the server has, at the revision set in noteDto, or
+// adds a new one.
+func (cn *Service) putNote(ctx context.Context, noteDto *pb.Note, stored bool) error {
+	var err error
+	if stored {
+		_, err = cn.nc.UpdateNote(ctx, noteDto)
+	} else {
+		_, err = cn.nc.AddNote(ctx, noteDto)
+	}
+	return err
+}
+
+var ErrNoOfflineLogin = errors.New("The server is unreachable and this account has not signed in on this device before")
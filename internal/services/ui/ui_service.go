@@ -2,21 +2,24 @@ package ui
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces"
 	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/logger"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
 	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -30,117 +33,334 @@ type Service struct {
 	uc      pb.UserServicesClient
 	nc      pb.NoteServicesClient
 	jwt     string
-	hash    []byte
+	// tokenMu guards writes of the tokens against Watch, which reads jwt
+	// from its own goroutine.
+	tokenMu sync.RWMutex
+	// email is the signed-in account; srp is set once it signs in with SRP
+	// instead of sending its password.
+	email string
+	srp   bool
+	// passwordLogin allows sending the password to a server that has no SRP
+	// verifier for the account.
+	passwordLogin bool
+	// refreshToken renews jwt shortly before jwtExpires.
+	refreshToken string
+	jwtExpires   time.Time
+	kdfCost      models.KdfParams
+	// dataKey encrypts the notes. It is wrapped by the password-derived key
+	// unless enveloped is false, in which case it is that key itself.
+	dataKey   []byte
+	enveloped bool
+	// zeroKnowledge seals name and type of new and loaded notes into the
+	// payload. sealed tracks which notes are already stored that way.
+	zeroKnowledge bool
+	sealed        map[uuid.UUID]bool
+	// revisions holds the server revision each loaded note is based on.
+	revisions map[uuid.UUID]int64
+	// legacy marks loaded notes written before envelopes; they are sealed
+	// again on the next online load. v1 is set once a note of the vault opens
+	// as a version 1 envelope; envelopes that fail to open are no longer
+	// retried as legacy data then.
+	legacy map[uuid.UUID]bool
+	v1     bool
+	// cursor is where the next sync continues; cache keeps the synced notes,
+	// the cursor and the queued offline edits between runs when set.
+	cursor int64
+	cache  *vaultCache
+	// offline is set while the server cannot be reached. Without a jwt the
+	// vault was unlocked from the cache and nothing is sent to the server.
+	offline bool
+	// mfa is a login that waits for a second factor.
+	mfa *pendingMfa
+	// keys is the key material of the signed-in account, kept for
+	// SaveSession.
+	keys *cachedAccount
 }
 
 func NewUIService(logger *logger.Logger, conn *grpc.ClientConn) *Service {
 	once.Do(func() {
 		log = logger
-		sn = &Service{storage: make(map[uuid.UUID]*models.Noteable), uc: pb.NewUserServicesClient(conn), nc: pb.NewNoteServicesClient(conn)}
+		sn = &Service{storage: make(map[uuid.UUID]*models.Noteable), sealed: make(map[uuid.UUID]bool), revisions: make(map[uuid.UUID]int64), uc: pb.NewUserServicesClient(conn), nc: pb.NewNoteServicesClient(conn)}
 	})
 	return sn
 }
 
+// SetKdfCost sets the Argon2id cost used for new accounts and migrated vaults.
+// The server's parameters are refused when they cost less.
+func (cn *Service) SetKdfCost(time, memory uint32, threads uint8) {
+	cn.kdfCost = models.KdfParams{Time: time, Memory: memory, Threads: threads}
+}
+
+// SetCache keeps synced notes in a local file, so a restart only downloads
+// the notes changed since the last run and the vault stays usable while the
+// server is unreachable.
+func (cn *Service) SetCache(path string) error {
+	cache, err := openVaultCache(path)
+	if err != nil {
+		return err
+	}
+	cn.cache = cache
+	return nil
+}
+
+// SetZeroKnowledge enables sealing of note names and types. Notes that are
+// already sealed stay sealed when the mode is turned off.
+func (cn *Service) SetZeroKnowledge(enabled bool) {
+	cn.zeroKnowledge = enabled
+}
+
+// SetPasswordLogin allows Login to send the password when the server has no
+// SRP verifier for the account. Leave it off unless an account created before
+// SRP has to sign in once: a server that claims not to know the verifier would
+// learn the password otherwise.
+func (cn *Service) SetPasswordLogin(enabled bool) {
+	cn.passwordLogin = enabled
+}
+
+// AddNote stores note on the server. While the server is unreachable the
+// note is queued in the cache instead and shown right away.
 func (cn *Service) AddNote(note models.Noteable) (*[]models.Noteable, error) {
 	log := log.WithFields(logrus.Fields{
 		"method": "AddNote",
 	})
 
-	if cn.jwt == "" {
+	if cn.jwt == "" && !cn.offline {
 		log.Warning("AddNote: jwt not found")
 		return nil, fmt.Errorf("You need sigin to app")
 	}
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
 	defer cancelFunc()
 
-	marshal, err := json.Marshal(note)
-	if err != nil {
-		log.WithError(err).Error("Error marshalling note")
-		return nil, err
-	}
-
-	encrypt, err := util.Encrypt(ctx, cn.hash, marshal)
+	sealed := cn.zeroKnowledge || cn.sealed[note.GetID()]
+	noteDto, err := marshalNote(ctx, cn.dataKey, note, sealed)
 	if err != nil {
 		log.WithError(err).Error("Error encrypting note")
 		return nil, err
 	}
 
-	noteDto := &pb.Note{
-		Id:         note.GetID().String(),
-		Name:       note.GetName(),
-		Type:       note.GetType().String(),
-		SecretData: encrypt,
-	}
-
-	ctx = cn.addToken(ctx)
-	switch _, ok := cn.storage[note.GetID()]; ok {
-	case true:
-		_, err = cn.nc.UpdateNote(ctx, noteDto)
-		if err != nil {
-			log.WithError(err).Error("Error updating note")
-			return nil, err
-		}
-	case false:
-		_, err = cn.nc.AddNote(ctx, noteDto)
-		if err != nil {
-			log.WithError(err).Error("Error adding note")
-			return nil, err
+	revision, stored := cn.revisions[note.GetID()]
+	noteDto.Revision = revision
+	if cn.jwt != "" {
+		err = cn.putNote(cn.addToken(ctx), noteDto, stored)
+		switch {
+		case err == nil:
+			cn.dequeue(note.GetID())
+			cn.storage[note.GetID()] = &note
+			cn.sealed[note.GetID()] = sealed
+			cn.revisions[note.GetID()] = revision + 1
+			log.WithField("note", note.GetName()).Info("Added note")
+			return toNotableList(cn.storage), nil
+		case !cn.goOffline(err):
+			log.WithError(err).Error("Error saving note")
+			return nil, cn.conflict(ctx, note.GetID(), note, err)
 		}
 	}
 
+	if err = cn.queueEdit(ctx, note.GetID(), noteDto, revision, !stored); err != nil {
+		log.WithError(err).Error("Error queueing note")
+		return nil, err
+	}
 	cn.storage[note.GetID()] = &note
-	log.WithField("note", note.GetName()).Info("Added note")
+	cn.sealed[note.GetID()] = sealed
+	log.WithField("note", note.GetName()).Info("Queued note")
 	return toNotableList(cn.storage), nil
 }
 
+// LoadNote sends the queued offline edits and fetches the notes changed
+// since the last sync. The first load of a run starts from the cache, if
+// there is one; while the server is unreachable the cached notes are
+// returned and Offline reports true.
 func (cn *Service) LoadNote() (*[]models.Noteable, error) {
 	log := log.WithFields(logrus.Fields{
-		"method": "AddNote",
+		"method": "LoadNote",
 	})
 
-	if cn.jwt == "" {
-		log.Warning("AddNote: jwt not found")
+	if cn.jwt == "" && !cn.offline {
+		log.Warning("LoadNote: jwt not found")
 		return nil, fmt.Errorf("You need sigin to app")
 	}
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
 	defer cancelFunc()
+	if cn.cursor == 0 && cn.cache != nil {
+		cn.restoreCache(ctx)
+	}
+	if cn.jwt == "" {
+		return toNotableList(cn.storage), nil
+	}
 	ctx = cn.addToken(ctx)
-	notes, err := cn.nc.GetNotes(ctx, &pb.NoteRequest{})
+	if err := cn.replay(ctx); err != nil {
+		if cn.goOffline(err) {
+			return toNotableList(cn.storage), nil
+		}
+		log.WithError(err).Error("Error sending queued edits")
+		return nil, err
+	}
+	resp, err := cn.nc.SyncNotes(ctx, &pb.SyncRequest{Cursor: cn.cursor})
 	if err != nil {
-		log.WithError(err).Error("Error getting notes")
+		if cn.goOffline(err) {
+			return toNotableList(cn.storage), nil
+		}
+		log.WithError(err).Error("Error syncing notes")
 		return nil, err
 	}
-	for _, noteDto := range notes.Notes {
-		note, err := unmarshalNote(ctx, cn.hash, noteDto)
-		if err != nil {
-			log.WithError(err).Error("Error unmarshalling note")
-			continue
+	cn.offline = false
+	cn.applySync(ctx, resp)
+	if cn.cache != nil {
+		if err = cn.cache.save(ctx, cn.dataKey, cn.email, resp); err != nil {
+			log.WithError(err).Warning("could not update the vault cache")
 		}
-		cn.storage[note.GetID()] = &note
+	}
+	if cn.zeroKnowledge || len(cn.legacy) > 0 {
+		cn.sealNotes(ctx)
 	}
 
 	return toNotableList(cn.storage), nil
 }
 
+// restoreCache loads the notes and the cursor of the last run and the edits
+// still queued on top of them.
+func (cn *Service) restoreCache(ctx context.Context) {
+	notes, cursor, err := cn.cache.load(ctx, cn.dataKey, cn.email)
+	if err != nil {
+		log.WithField("method", "restoreCache").WithError(err).Warning("could not read the vault cache")
+		return
+	}
+	cn.applySync(ctx, &pb.SyncResponse{Notes: notes, Cursor: cursor, Full: true})
+	cn.applyQueued(ctx)
+}
+
+// applySync merges a sync response into the loaded notes.
+func (cn *Service) applySync(ctx context.Context, resp *pb.SyncResponse) {
+	log := log.WithFields(logrus.Fields{
+		"method": "applySync",
+	})
+
+	if resp.Full {
+		cn.resetVault()
+	}
+	var retry []*pb.Note
+	for _, noteDto := range resp.Notes {
+		if err := cn.loadNote(ctx, noteDto, false); err != nil {
+			if !cn.v1 {
+				retry = append(retry, noteDto)
+				continue
+			}
+			log.WithError(err).Error("Error unmarshalling note")
+		}
+	}
+	// a vault without version 1 notes may hold legacy data that looks like one
+	for _, noteDto := range retry {
+		if err := cn.loadNote(ctx, noteDto, !cn.v1); err != nil {
+			log.WithError(err).Error("Error unmarshalling note")
+		}
+	}
+	for _, deleted := range resp.Deleted {
+		if id, err := uuid.Parse(deleted); err == nil {
+			cn.forget(id)
+		}
+	}
+	cn.cursor = resp.Cursor
+	log.Infof("synced to %d: %d changed, %d deleted", resp.Cursor, len(resp.Notes), len(resp.Deleted))
+}
+
+// loadNote opens noteDto and keeps it as a loaded note. legacy lets an
+// envelope that does not open be read as legacy data.
+func (cn *Service) loadNote(ctx context.Context, noteDto *pb.Note, legacy bool) error {
+	decrypt, typeNote, version, err := openNote(ctx, cn.dataKey, noteDto, legacy)
+	if err != nil {
+		return err
+	}
+	note, err := decodeNote(typeNote, decrypt)
+	if err != nil {
+		return err
+	}
+	id := note.GetID()
+	cn.storage[id] = &note
+	cn.sealed[id] = noteDto.Sealed
+	cn.revisions[id] = noteDto.Revision
+	if version == util.EnvelopeLegacy {
+		if cn.legacy == nil {
+			cn.legacy = make(map[uuid.UUID]bool)
+		}
+		cn.legacy[id] = true
+		return nil
+	}
+	delete(cn.legacy, id)
+	cn.v1 = true
+	return nil
+}
+
+// resetVault forgets the loaded notes and the sync position.
+func (cn *Service) resetVault() {
+	cn.storage = make(map[uuid.UUID]*models.Noteable)
+	cn.sealed = make(map[uuid.UUID]bool)
+	cn.revisions = make(map[uuid.UUID]int64)
+	cn.legacy = make(map[uuid.UUID]bool)
+	cn.v1 = false
+	cn.cursor = 0
+}
+
+// sealNotes re-uploads every loaded note written before envelopes and, in
+// zero-knowledge mode, every note that still has plaintext metadata on the
+// server. Failures are logged and retried on the next load.
+func (cn *Service) sealNotes(ctx context.Context) {
+	log := log.WithFields(logrus.Fields{
+		"method": "sealNotes",
+	})
+	for id, note := range cn.storage {
+		sealed := cn.sealed[id] || cn.zeroKnowledge
+		if sealed == cn.sealed[id] && !cn.legacy[id] {
+			continue
+		}
+		noteDto, err := marshalNote(ctx, cn.dataKey, *note, sealed)
+		if err != nil {
+			log.WithError(err).Error("Error encrypting note")
+			continue
+		}
+		noteDto.Revision = cn.revisions[id]
+		if _, err = cn.nc.UpdateNote(ctx, noteDto); err != nil {
+			log.WithError(err).Warning("note was not sealed again")
+			continue
+		}
+		cn.sealed[id] = sealed
+		delete(cn.legacy, id)
+		cn.revisions[id]++
+	}
+}
+
+// DeleteNote deletes a note on the server, or queues the delete while the
+// server is unreachable.
 func (cn *Service) DeleteNote(id uuid.UUID) (*[]models.Noteable, error) {
 	log := log.WithFields(logrus.Fields{
 		"method": "DeleteNote",
 	})
 
-	if cn.jwt == "" {
+	if cn.jwt == "" && !cn.offline {
 		log.Warning("DeleteNote: jwt not found")
 		return nil, fmt.Errorf("You need sigin to app")
 	}
 
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
 	defer cancelFunc()
-	ctx = cn.addToken(ctx)
-	_, err := cn.nc.DeleteNote(ctx, &pb.NoteRequest{IdNote: id.String()})
-	if err != nil {
-		log.WithError(err).Error("Error deleting note")
+	revision, stored := cn.revisions[id]
+	if cn.jwt != "" {
+		_, err := cn.nc.DeleteNote(cn.addToken(ctx), &pb.NoteRequest{IdNote: id.String(), Revision: revision})
+		switch {
+		case err == nil || status.Code(err) == codes.NotFound:
+			cn.dequeue(id)
+			cn.forget(id)
+			return toNotableList(cn.storage), nil
+		case !cn.goOffline(err):
+			log.WithError(err).Error("Error deleting note")
+			return nil, cn.conflict(ctx, id, nil, err)
+		}
+	}
+
+	if err := cn.queueEdit(ctx, id, nil, revision, !stored); err != nil {
+		log.WithError(err).Error("Error queueing delete")
 		return nil, err
 	}
-	delete(cn.storage, id)
+	cn.forget(id)
 	return toNotableList(cn.storage), nil
 }
 
@@ -148,40 +368,362 @@ func (cn *Service) Register(user *pb.User) error {
 	log := log.WithFields(logrus.Fields{
 		"method": "Register",
 	})
+	params, err := util.NewKdfParams(cn.kdfCost.Time, cn.kdfCost.Memory, cn.kdfCost.Threads)
+	if err != nil {
+		return err
+	}
+	dataKey, err := util.NewDataKey()
+	if err != nil {
+		return err
+	}
+	kek, err := util.DeriveKey(user.Password, params)
+	if err != nil {
+		return err
+	}
+	wrapped, err := util.WrapKey(context.Background(), kek, dataKey)
+	if err != nil {
+		return err
+	}
+
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
 	defer cancelFunc()
-	token, err := cn.uc.Register(ctx, user)
+	token, err := cn.uc.RegisterSrp(withDevice(ctx), &pb.SrpRegister{
+		Username:   user.Username,
+		Email:      user.Email,
+		Kdf:        interfaces.KdfToDto(params),
+		WrappedKey: wrapped,
+		Verifier:   util.SrpVerifier(util.SrpX(kek)),
+	})
 	if err != nil {
 		return err
 	}
-	cn.jwt = token.Token
-	cn.hash = getHash(user)
+	cn.setTokens(token)
+	cn.email = user.Email
+	cn.srp = true
+	cn.dataKey = dataKey
+	cn.enveloped = true
 	log.Infof("registered new user: %s, %s", user.Username, user.Email)
 	return nil
 }
 
+// Login signs in with SRP. Accounts that still have a server-side password
+// sign in with it once, if SetPasswordLogin allows that, and are then moved
+// to SRP; ErrPasswordLogin is returned otherwise.
+// ErrMfaRequired is returned when the account asks for a second factor;
+// VerifyMfa completes the login then. While the server is unreachable the
+// vault is unlocked from the cache, if the account signed in here before.
 func (cn *Service) Login(user *pb.User) error {
 	log := log.WithFields(logrus.Fields{
 		"method": "Login",
 	})
-	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancelFunc()
-	token, err := cn.uc.Login(ctx, user)
+	cn.mfa = nil
+	token, kek, err := cn.loginSrp(ctx, user.Email, user.Password)
+	if unreachable(err) && cn.cache != nil {
+		log.WithError(err).Warning("server unreachable, unlocking the vault offline")
+		return cn.loginOffline(ctx, user)
+	}
+	legacy := status.Code(err) == codes.FailedPrecondition
+	if legacy && !cn.passwordLogin {
+		log.Warning("server asks for the password, refusing to send it")
+		return ErrPasswordLogin
+	}
+	if legacy {
+		log.Info("account has no SRP verifier, signing in with the password")
+		token, err = cn.uc.Login(withDevice(ctx), user)
+	}
 	if err != nil {
 		return err
 	}
+	if token.MfaToken != "" {
+		cn.mfa = &pendingMfa{token: token.MfaToken, user: user, kek: kek, legacy: legacy}
+		log.Infof("second factor required: %s", user.Email)
+		return ErrMfaRequired
+	}
+	return cn.finishLogin(ctx, user, token, kek, legacy)
+}
+
+// ErrPasswordLogin is returned by Login when the server has no SRP verifier
+// for the account and sending the password is not allowed.
+var ErrPasswordLogin = errors.New("the server asks for the password; start with -legacy-login to sign in with it once")
+
+// finishLogin restores the vault key from an issued token and moves old
+// vaults and password accounts forward.
+func (cn *Service) finishLogin(ctx context.Context, user *pb.User, token *pb.JwtToken, kek []byte, legacy bool) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "finishLogin",
+	})
+
+	var err error
+	cn.resetVault()
+	cn.setTokens(token)
+	cn.email = user.Email
+	cn.srp = !legacy
+	cn.enveloped = false
+	cn.offline = false
+
+	var params *models.KdfParams
+	switch {
+	case token.Kdf == nil:
+		cn.dataKey = util.LegacyKey(user.Email, user.Password)
+		if params, err = cn.migrateVault(user.Password, nil); err != nil {
+			log.WithError(err).Warning("vault stays on the legacy key")
+		}
+	case len(token.WrappedKey) == 0:
+		var current models.KdfParams
+		if current, err = cn.serverKdf(token.Kdf); err != nil {
+			cn.clearTokens()
+			return err
+		}
+		cn.dataKey, err = util.DeriveKey(user.Password, current)
+		if err != nil {
+			cn.clearTokens()
+			return err
+		}
+		if params, err = cn.migrateVault(user.Password, &current); err != nil {
+			log.WithError(err).Warning("vault stays without a data key")
+		}
+	default:
+		var current models.KdfParams
+		if current, err = cn.serverKdf(token.Kdf); err != nil {
+			cn.clearTokens()
+			return err
+		}
+		if kek == nil {
+			if kek, err = util.DeriveKey(user.Password, current); err != nil {
+				cn.clearTokens()
+				return err
+			}
+		}
+		cn.dataKey, err = util.UnwrapKey(ctx, kek, token.WrappedKey)
+		if err != nil {
+			cn.clearTokens()
+			return err
+		}
+		cn.enveloped = true
+		params = &current
+		cn.rememberAccount(current, token.WrappedKey)
+	}
+
+	if legacy && params != nil {
+		if err = cn.upgradeToSrp(ctx, user.Password, *params); err != nil {
+			log.WithError(err).Warning("account stays on password login")
+		}
+	}
+	log.Infof("user sign in: %s", user.Email)
+	return nil
+}
+
+// migrateVault moves an old vault, whose notes are encrypted directly with a
+// password-derived key, to a random data key wrapped by an Argon2id key. New
+// KDF parameters are generated when params is nil; the parameters in use are
+// returned. Every note is re-encrypted and submitted in one request, so the
+// server either switches the whole vault or keeps the old one.
+func (cn *Service) migrateVault(password string, params *models.KdfParams) (*models.KdfParams, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "migrateVault",
+	})
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+	ctx = cn.addToken(ctx)
+
+	if params == nil {
+		fresh, err := util.NewKdfParams(cn.kdfCost.Time, cn.kdfCost.Memory, cn.kdfCost.Threads)
+		if err != nil {
+			return nil, err
+		}
+		params = &fresh
+	}
+	dataKey, err := util.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := cn.wrapDataKey(password, *params, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	rekeyed, history, err := cn.reencryptVault(ctx, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &pb.VaultRekey{Kdf: interfaces.KdfToDto(*params), Notes: rekeyed, Revisions: history, WrappedKey: wrapped}
+	if _, err = cn.uc.RekeyVault(ctx, req); err != nil {
+		return nil, err
+	}
+	cn.dataKey = dataKey
+	cn.enveloped = true
+	cn.rekeyed(rekeyed)
+	cn.rememberAccount(*params, wrapped)
+	log.Infof("vault migrated to a wrapped data key, %d notes re-encrypted", len(rekeyed))
+	return params, nil
+}
+
+// ChangePassword wraps the vault data key with a key derived from the new
+// password and replaces the SRP verifier. Only a vault without a data key has
+// its notes re-encrypted. An SRP account proves the old password with a fresh
+// handshake; neither password is sent to the server then. The server stores
+// the new key material in a single transaction.
+func (cn *Service) ChangePassword(oldPassword, newPassword string) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "ChangePassword",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("ChangePassword: jwt not found")
+		return fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+	ctx = cn.addToken(ctx)
+
+	params, err := util.NewKdfParams(cn.kdfCost.Time, cn.kdfCost.Memory, cn.kdfCost.Threads)
+	if err != nil {
+		return err
+	}
+	dataKey := cn.dataKey
+	var rekeyed []*pb.Note
+	var history []*pb.NoteRevision
+	if !cn.enveloped {
+		if dataKey, err = util.NewDataKey(); err != nil {
+			return err
+		}
+		if rekeyed, history, err = cn.reencryptVault(ctx, dataKey); err != nil {
+			log.WithError(err).Error("Error re-encrypting vault")
+			return err
+		}
+	}
+	kek, err := util.DeriveKey(newPassword, params)
+	if err != nil {
+		return err
+	}
+	wrapped, err := util.WrapKey(ctx, kek, dataKey)
+	if err != nil {
+		return err
+	}
+
+	req := &pb.PasswordChange{
+		Kdf:        interfaces.KdfToDto(params),
+		Notes:      rekeyed,
+		Revisions:  history,
+		WrappedKey: wrapped,
+		Verifier:   util.SrpVerifier(util.SrpX(kek)),
+	}
+	if cn.srp {
+		if _, req.Proof, _, err = cn.srpProof(ctx, cn.email, oldPassword); err != nil {
+			log.WithError(err).Error("Error proving the old password")
+			return err
+		}
+	} else {
+		req.OldPassword = oldPassword
+	}
+	token, err := cn.uc.ChangePassword(ctx, req)
+	if err != nil {
+		log.WithError(err).Error("Error changing password")
+		return err
+	}
+	cn.tokenMu.Lock()
 	cn.jwt = token.Token
-	cn.hash = getHash(user)
-	log.Infof("user sign in: %s", user.Username)
+	cn.jwtExpires = time.Unix(token.ExpiresAt, 0)
+	cn.tokenMu.Unlock()
+	cn.srp = true
+	cn.dataKey = dataKey
+	cn.enveloped = true
+	cn.rekeyed(rekeyed)
+	cn.rememberAccount(params, wrapped)
+	log.Infof("password changed, %d notes re-encrypted", len(rekeyed))
 	return nil
 }
 
-func getHash(user *pb.User) []byte {
-	bytes := sha256.Sum256([]byte(user.Email + user.Password))
-	return bytes[:]
+func (cn *Service) wrapDataKey(password string, params models.KdfParams, dataKey []byte) ([]byte, error) {
+	kek, err := util.DeriveKey(password, params)
+	if err != nil {
+		return nil, err
+	}
+	return util.WrapKey(context.Background(), kek, dataKey)
+}
+
+// reencryptVault downloads every note, the trashed ones included, and the
+// kept revisions of each, and encrypts them under key. Nothing is sent back
+// to the server. The notes keep the revision they were read at, so the server
+// rejects the batch if one of them changed meanwhile.
+func (cn *Service) reencryptVault(ctx context.Context, key []byte) ([]*pb.Note, []*pb.NoteRevision, error) {
+	notes, err := cn.nc.GetNotes(ctx, &pb.NoteRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+	trash, err := cn.nc.ListTrash(ctx, &empty.Empty{})
+	if err != nil {
+		return nil, nil, err
+	}
+	all := notes.Notes
+	for _, trashed := range trash.Notes {
+		all = append(all, trashed.Note)
+	}
+
+	rekeyed := make([]*pb.Note, 0, len(all))
+	var history []*pb.NoteRevision
+	for _, noteDto := range all {
+		note, err := cn.reencryptNote(ctx, key, noteDto)
+		if err != nil {
+			return nil, nil, err
+		}
+		rekeyed = append(rekeyed, note)
+
+		revisions, err := cn.nc.ListNoteRevisions(ctx, &pb.NoteRequest{IdNote: noteDto.Id})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, revision := range revisions.Revisions {
+			note, err := cn.reencryptNote(ctx, key, revision.Note)
+			if err != nil {
+				return nil, nil, err
+			}
+			history = append(history, &pb.NoteRevision{Note: note, WrittenAt: revision.WrittenAt})
+		}
+	}
+	return rekeyed, history, nil
+}
+
+func (cn *Service) reencryptNote(ctx context.Context, key []byte, noteDto *pb.Note) (*pb.Note, error) {
+	plain, _, _, err := openNote(ctx, cn.dataKey, noteDto, !cn.v1)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := sealPayload(ctx, key, noteDto.Id, noteDto.Type, noteDto.Sealed, plain)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Note{
+		Id:         noteDto.Id,
+		Name:       noteDto.Name,
+		Type:       noteDto.Type,
+		SecretData: secret,
+		Sealed:     noteDto.Sealed,
+		Revision:   noteDto.Revision,
+	}, nil
+}
+
+// rekeyed moves loaded notes to the revision the server gave them when it
+// stored the re-encrypted vault.
+func (cn *Service) rekeyed(notes []*pb.Note) {
+	for _, note := range notes {
+		id, err := uuid.Parse(note.Id)
+		if err != nil {
+			continue
+		}
+		if _, ok := cn.revisions[id]; ok {
+			cn.revisions[id] = note.Revision + 1
+		}
+	}
 }
 
+// addToken attaches the access token to ctx, renewing it first when it is
+// about to expire.
 func (cn *Service) addToken(ctx context.Context) context.Context {
+	cn.refreshIfExpiring(ctx)
 	md := metadata.New(map[string]string{"token": cn.jwt})
 	return metadata.NewOutgoingContext(ctx, md)
 
@@ -195,48 +737,108 @@ func toNotableList(notes map[uuid.UUID]*models.Noteable) *[]models.Noteable {
 	return &l
 }
 
-func unmarshalNote(ctx context.Context, key []byte, noteDto *pb.Note) (models.Noteable, error) {
+// marshalNote encrypts note. A sealed note is sent without name and type and
+// its payload is padded to util.PadBlock. The id of an attached file is sent
+// in the clear so that the server can delete the file with the note.
+func marshalNote(ctx context.Context, key []byte, note models.Noteable, sealed bool) (*pb.Note, error) {
+	marshal, err := json.Marshal(note)
+	if err != nil {
+		return nil, err
+	}
 
-	decrypt, err := util.Decrypt(ctx, key, noteDto.SecretData)
+	encrypt, err := sealPayload(ctx, key, note.GetID().String(), note.GetType().String(), sealed, marshal)
 	if err != nil {
 		return nil, err
 	}
 
-	switch noteDto.Type {
-	case models.CARD.String():
-		note := &models.BankCardNote{}
-		err = json.Unmarshal(decrypt, note)
-		if err != nil {
-			return nil, err
-		}
-		return note, nil
+	var blobID string
+	if ref := blobOf(note); ref != nil {
+		blobID = ref.ID.String()
+	}
+	if sealed {
+		return &pb.Note{Id: note.GetID().String(), SecretData: encrypt, Sealed: true, BlobId: blobID}, nil
+	}
+	return &pb.Note{
+		Id:         note.GetID().String(),
+		Name:       note.GetName(),
+		Type:       note.GetType().String(),
+		SecretData: encrypt,
+		BlobId:     blobID,
+	}, nil
+}
 
-	case models.CREDENTIAL.String():
-		note := &models.CredentialNote{}
-		err = json.Unmarshal(decrypt, note)
-		if err != nil {
-			return nil, err
-		}
-		return note, nil
+func sealPayload(ctx context.Context, key []byte, id, typeNote string, sealed bool, plain []byte) ([]byte, error) {
+	if sealed {
+		return util.Seal(ctx, key, util.KdfVersionNone, util.SealedNoteAAD(id), util.Pad(plain, util.PadBlock))
+	}
+	return util.Seal(ctx, key, util.KdfVersionNone, util.NoteAAD(id, typeNote), plain)
+}
 
-	case models.BINARY.String():
-		note := &models.BinaryNote{}
-		err = json.Unmarshal(decrypt, note)
-		if err != nil {
-			return nil, err
+// openNote decrypts the payload of noteDto and checks that it describes the
+// same note the server claims it is. Envelope AAD already covers id and type;
+// the payload check also protects notes in the legacy format. The returned
+// type comes from the payload, which is the only place a sealed note keeps it,
+// and is followed by the envelope version. legacy lets an envelope that does
+// not open be read as legacy data.
+func openNote(ctx context.Context, key []byte, noteDto *pb.Note, legacy bool) ([]byte, string, byte, error) {
+	aad := util.NoteAAD(noteDto.Id, noteDto.Type)
+	if noteDto.Sealed {
+		aad = util.SealedNoteAAD(noteDto.Id)
+	}
+	open := util.Open
+	if legacy {
+		open = util.OpenLegacy
+	}
+	decrypt, header, err := open(ctx, key, noteDto.SecretData, aad)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if noteDto.Sealed {
+		if decrypt, err = util.Unpad(decrypt); err != nil {
+			return nil, "", 0, err
 		}
-		return note, nil
+	}
 
-	case models.TEXT.String():
-		note := &models.TextNote{}
-		err = json.Unmarshal(decrypt, note)
-		if err != nil {
-			return nil, err
-		}
-		return note, nil
+	var probe struct {
+		Data models.BaseNote `json:"data"`
+	}
+	if err = json.Unmarshal(decrypt, &probe); err != nil {
+		return nil, "", 0, err
+	}
+	if probe.Data.Id.String() != noteDto.Id || (!noteDto.Sealed && probe.Data.Type.String() != noteDto.Type) {
+		return nil, "", 0, ErrNoteMismatch
+	}
+	return decrypt, probe.Data.Type.String(), header.Version, nil
+}
+
+func unmarshalNote(ctx context.Context, key []byte, noteDto *pb.Note) (models.Noteable, error) {
+
+	decrypt, typeNote, _, err := openNote(ctx, key, noteDto, false)
+	if err != nil {
+		return nil, err
+	}
+	return decodeNote(typeNote, decrypt)
+}
 
+// decodeNote unmarshals the JSON of a note of type typeNote.
+func decodeNote(typeNote string, data []byte) (models.Noteable, error) {
+	var note models.Noteable
+	switch typeNote {
+	case models.CARD.String():
+		note = &models.BankCardNote{}
+	case models.CREDENTIAL.String():
+		note = &models.CredentialNote{}
+	case models.BINARY.String():
+		note = &models.BinaryNote{}
+	case models.TEXT.String():
+		note = &models.TextNote{}
 	default:
 		return nil, errors.New("unknown note type")
 	}
-
+	if err := json.Unmarshal(data, note); err != nil {
+		return nil, err
+	}
+	return note, nil
 }
+
+var ErrNoteMismatch = errors.New("note payload does not match its id or type")
@@ -0,0 +1,1605 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/google/uuid"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/logger"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func Test_unmarshalNote(t *testing.T) {
+	key, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	note1 := &models.TextNote{Text: "Hello World", BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "Test Note", Type: models.TEXT}}
+	note2 := &models.TextNote{Text: "Other", BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "Other Note", Type: models.TEXT}}
+	dto1, err := marshalNote(context.Background(), key, note1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dto2, err := marshalNote(context.Background(), key, note2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed1, err := marshalNote(context.Background(), key, note1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacyPayload := []byte(`{"text":"Hello World","data":{"id":"` + note1.Id.String() + `","name_record":"Test Note","created":0,"type":"text"}}`)
+	legacy, err := util.Encrypt(context.Background(), key, legacyPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		noteDto *pb.Note
+		want    models.Noteable
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			noteDto: dto1,
+			want:    note1,
+			wantErr: false,
+		},
+		{
+			name:    "swapped secret data",
+			noteDto: &pb.Note{Id: dto1.Id, Name: dto1.Name, Type: dto1.Type, SecretData: dto2.SecretData},
+			wantErr: true,
+		},
+		{
+			name:    "changed type",
+			noteDto: &pb.Note{Id: dto1.Id, Name: dto1.Name, Type: models.CREDENTIAL.String(), SecretData: dto1.SecretData},
+			wantErr: true,
+		},
+		{
+			name:    "sealed",
+			noteDto: sealed1,
+			want:    note1,
+			wantErr: false,
+		},
+		{
+			name:    "sealed moved to another id",
+			noteDto: &pb.Note{Id: dto2.Id, SecretData: sealed1.SecretData, Sealed: true},
+			wantErr: true,
+		},
+		{
+			name:    "sealed flag dropped",
+			noteDto: &pb.Note{Id: dto1.Id, Name: dto1.Name, Type: dto1.Type, SecretData: sealed1.SecretData},
+			wantErr: true,
+		},
+		{
+			name:    "legacy format",
+			noteDto: &pb.Note{Id: note1.Id.String(), Name: "Test Note", Type: models.TEXT.String(), SecretData: legacy},
+			want:    note1,
+			wantErr: false,
+		},
+		{
+			name:    "legacy format swapped",
+			noteDto: &pb.Note{Id: note2.Id.String(), Name: "Other Note", Type: models.TEXT.String(), SecretData: legacy},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unmarshalNote(context.Background(), key, tt.noteDto)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unmarshalNote() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unmarshalNote() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("sealed hides metadata", func(t *testing.T) {
+		if sealed1.Name != "" || sealed1.Type != "" || len(sealed1.SecretData) < util.PadBlock {
+			t.Errorf("marshalNote() sealed note leaks metadata: %v", sealed1)
+		}
+	})
+
+	t.Run("attached file id", func(t *testing.T) {
+		file := &models.BinaryNote{BaseNote: models.BaseNote{Id: uuid.New(), Type: models.BINARY}, Blob: &models.BlobRef{ID: uuid.New()}}
+		for _, sealed := range []bool{false, true} {
+			dto, err := marshalNote(context.Background(), key, file, sealed)
+			if err != nil || dto.BlobId != file.Blob.ID.String() {
+				t.Errorf("marshalNote() sealed = %v, blob id = %q, error = %v", sealed, dto.GetBlobId(), err)
+			}
+		}
+		if dto1.BlobId != "" {
+			t.Errorf("marshalNote() blob id of a text note = %q", dto1.BlobId)
+		}
+	})
+
+	t.Run("mismatch error", func(t *testing.T) {
+		_, err := unmarshalNote(context.Background(), key, &pb.Note{Id: note2.Id.String(), Type: models.TEXT.String(), SecretData: legacy})
+		if !errors.Is(err, ErrNoteMismatch) {
+			t.Errorf("unmarshalNote() error = %v, want %v", err, ErrNoteMismatch)
+		}
+	})
+}
+
+type fakeUserClient struct {
+	pb.UserServicesClient
+	refreshed []string
+}
+
+func (f *fakeUserClient) Refresh(_ context.Context, in *pb.RefreshRequest, _ ...grpc.CallOption) (*pb.JwtToken, error) {
+	f.refreshed = append(f.refreshed, in.RefreshToken)
+	return &pb.JwtToken{Token: "new token", RefreshToken: "new refresh", ExpiresAt: time.Now().Add(15 * time.Minute).Unix()}, nil
+}
+
+func TestService_addToken(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	tests := []struct {
+		name        string
+		expires     time.Time
+		wantToken   string
+		wantRefresh []string
+	}{
+		{
+			name:      "token still valid",
+			expires:   time.Now().Add(10 * time.Minute),
+			wantToken: "old token",
+		},
+		{
+			name:        "token about to expire",
+			expires:     time.Now().Add(30 * time.Second),
+			wantToken:   "new token",
+			wantRefresh: []string{"old refresh"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &fakeUserClient{}
+			cn := &Service{uc: uc, jwt: "old token", refreshToken: "old refresh", jwtExpires: tt.expires}
+			ctx := cn.addToken(context.Background())
+			md, _ := metadata.FromOutgoingContext(ctx)
+			if got := md.Get("token"); len(got) != 1 || got[0] != tt.wantToken {
+				t.Errorf("addToken() token = %v, want %v", got, tt.wantToken)
+			}
+			if !reflect.DeepEqual(uc.refreshed, tt.wantRefresh) {
+				t.Errorf("addToken() refreshed = %v, want %v", uc.refreshed, tt.wantRefresh)
+			}
+		})
+	}
+}
+
+// fakeSrpClient answers SRP logins for one account.
+type fakeSrpClient struct {
+	pb.UserServicesClient
+	kdf      models.KdfParams
+	verifier []byte
+	wrapped  []byte
+	forgeM2  bool
+	mfa      bool
+	server   *util.SrpServer
+}
+
+func (f *fakeSrpClient) LoginStart(_ context.Context, in *pb.SrpStart, _ ...grpc.CallOption) (*pb.SrpChallenge, error) {
+	var err error
+	if f.server, err = util.NewSrpServer(in.Email, f.kdf.Salt, f.verifier, in.A); err != nil {
+		return nil, err
+	}
+	return &pb.SrpChallenge{HandshakeId: "handshake", Kdf: interfaces.KdfToDto(f.kdf), B: f.server.B()}, nil
+}
+
+func (f *fakeSrpClient) LoginFinish(_ context.Context, in *pb.SrpProof, _ ...grpc.CallOption) (*pb.SrpSession, error) {
+	m2, err := f.server.Verify(in.M1)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if f.forgeM2 {
+		m2[0] ^= 1
+	}
+	if f.mfa {
+		return &pb.SrpSession{M2: m2, Token: &pb.JwtToken{MfaToken: "mfa"}}, nil
+	}
+	token := &pb.JwtToken{Token: "token", Kdf: interfaces.KdfToDto(f.kdf), WrappedKey: f.wrapped}
+	return &pb.SrpSession{M2: m2, Token: token}, nil
+}
+
+func (f *fakeSrpClient) VerifyMfa(_ context.Context, in *pb.MfaRequest, _ ...grpc.CallOption) (*pb.JwtToken, error) {
+	if in.MfaToken != "mfa" || in.Code != "123456" {
+		return nil, status.Error(codes.Unauthenticated, "invalid code")
+	}
+	return &pb.JwtToken{Token: "token", Kdf: interfaces.KdfToDto(f.kdf), WrappedKey: f.wrapped}, nil
+}
+
+func TestService_Login(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	kdf := models.KdfParams{Algorithm: util.KdfArgon2id, Salt: []byte("0123456789abcdef"), Time: 1, Memory: 1024, Threads: 1}
+	kek, err := util.DeriveKey("Test Password", kdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := util.WrapKey(context.Background(), kek, dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		forgeM2  bool
+		mfaCode  string
+		floor    *models.KdfParams
+		memory   uint32
+		wantErr  bool
+	}{
+		{name: "Success", password: "Test Password"},
+		{name: "Server kdf below the defaults", password: "Test Password", floor: &models.KdfParams{}, wantErr: true},
+		{name: "Server kdf above the maximum", password: "Test Password", memory: math.MaxUint32, wantErr: true},
+		{name: "Wrong password", password: "password", wantErr: true},
+		{name: "Server without verifier", password: "Test Password", forgeM2: true, wantErr: true},
+		{name: "Second factor", password: "Test Password", mfaCode: "123456"},
+		{name: "Wrong second factor", password: "Test Password", mfaCode: "654321", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &fakeSrpClient{kdf: kdf, verifier: util.SrpVerifier(util.SrpX(kek)), wrapped: wrapped, forgeM2: tt.forgeM2, mfa: tt.mfaCode != ""}
+			if tt.memory != 0 {
+				uc.kdf.Memory = tt.memory
+			}
+			floor := models.KdfParams{Time: kdf.Time, Memory: kdf.Memory, Threads: kdf.Threads}
+			if tt.floor != nil {
+				floor = *tt.floor
+			}
+			cn := &Service{uc: uc, kdfCost: floor}
+			err := cn.Login(&pb.User{Email: "user1@test.com", Password: tt.password})
+			if tt.mfaCode != "" {
+				if !errors.Is(err, ErrMfaRequired) || cn.jwt != "" {
+					t.Fatalf("Login() error = %v, want %v", err, ErrMfaRequired)
+				}
+				err = cn.VerifyMfa(tt.mfaCode)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Login() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if cn.jwt != "" || cn.dataKey != nil {
+					t.Errorf("Login() kept a session after an error")
+				}
+				return
+			}
+			if !cn.srp || !reflect.DeepEqual(cn.dataKey, dataKey) {
+				t.Errorf("Login() srp = %v, data key restored = %v", cn.srp, reflect.DeepEqual(cn.dataKey, dataKey))
+			}
+		})
+	}
+}
+
+// fakeLegacyClient is a server without an SRP verifier for the account; it
+// counts the password logins it receives.
+type fakeLegacyClient struct {
+	pb.UserServicesClient
+	logins int
+}
+
+func (f *fakeLegacyClient) LoginStart(_ context.Context, _ *pb.SrpStart, _ ...grpc.CallOption) (*pb.SrpChallenge, error) {
+	return nil, status.Error(codes.FailedPrecondition, "account has no SRP verifier")
+}
+
+func (f *fakeLegacyClient) Login(_ context.Context, _ *pb.User, _ ...grpc.CallOption) (*pb.JwtToken, error) {
+	f.logins++
+	return nil, status.Error(codes.Unauthenticated, "invalid password")
+}
+
+func TestService_LoginPassword(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	tests := []struct {
+		name          string
+		passwordLogin bool
+		wantErr       error
+		wantLogins    int
+	}{
+		{name: "Password login not allowed", wantErr: ErrPasswordLogin},
+		{name: "Password login allowed", passwordLogin: true, wantLogins: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &fakeLegacyClient{}
+			cn := &Service{uc: uc}
+			cn.SetPasswordLogin(tt.passwordLogin)
+			err := cn.Login(&pb.User{Email: "user1@test.com", Password: "Test Password"})
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("Login() error = %v, want %v", err, tt.wantErr)
+			}
+			if uc.logins != tt.wantLogins {
+				t.Errorf("Login() sent the password %d times, want %d", uc.logins, tt.wantLogins)
+			}
+		})
+	}
+}
+
+// fakeNoteClient stores notes by id and rejects writes based on an outdated
+// revision the way the server does.
+type fakeNoteClient struct {
+	pb.NoteServicesClient
+	notes map[string]*pb.Note
+}
+
+func (f *fakeNoteClient) UpdateNote(_ context.Context, in *pb.Note, _ ...grpc.CallOption) (*empty.Empty, error) {
+	current, ok := f.notes[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, in.Id)
+	}
+	if current.Revision != in.Revision {
+		st, _ := status.New(codes.Aborted, "note was changed on another device").WithDetails(current)
+		return nil, st.Err()
+	}
+	in.Revision++
+	f.notes[in.Id] = in
+	return &empty.Empty{}, nil
+}
+
+func TestService_AddNoteConflict(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	key, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := models.BaseNote{Id: uuid.New(), NameRecord: "Test Note", Type: models.TEXT}
+	theirs := &models.TextNote{Text: "theirs", BaseNote: base}
+	mine := &models.TextNote{Text: "mine", BaseNote: base}
+
+	tests := []struct {
+		name      string
+		keepLocal bool
+		want      models.Noteable
+		wantSent  int64
+	}{
+		{name: "keep mine", keepLocal: true, want: mine, wantSent: 3},
+		{name: "use theirs", want: theirs, wantSent: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote, err := marshalNote(context.Background(), key, theirs, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			remote.Revision = 2
+			nc := &fakeNoteClient{notes: map[string]*pb.Note{base.Id.String(): remote}}
+			var stored models.Noteable = &models.TextNote{Text: "loaded", BaseNote: base}
+			cn := &Service{
+				nc:        nc,
+				uc:        &fakeUserClient{},
+				jwt:       "token",
+				dataKey:   key,
+				storage:   map[uuid.UUID]*models.Noteable{base.Id: &stored},
+				sealed:    map[uuid.UUID]bool{},
+				revisions: map[uuid.UUID]int64{base.Id: 1},
+			}
+			cn.jwtExpires = time.Now().Add(time.Hour)
+
+			_, err = cn.AddNote(mine)
+			var conflict *ConflictError
+			if !errors.As(err, &conflict) {
+				t.Fatalf("AddNote() error = %v, want *ConflictError", err)
+			}
+			if !reflect.DeepEqual(conflict.Remote, theirs) {
+				t.Errorf("AddNote() remote = %v, want %v", conflict.Remote, theirs)
+			}
+
+			if _, err = cn.ResolveConflict(conflict, tt.keepLocal); err != nil {
+				t.Fatalf("ResolveConflict() error = %v", err)
+			}
+			if got := *cn.storage[base.Id]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveConflict() note = %v, want %v", got, tt.want)
+			}
+			if got := nc.notes[base.Id.String()].Revision; got != tt.wantSent {
+				t.Errorf("ResolveConflict() server revision = %d, want %d", got, tt.wantSent)
+			}
+			if cn.revisions[base.Id] != tt.wantSent {
+				t.Errorf("ResolveConflict() local revision = %d, want %d", cn.revisions[base.Id], tt.wantSent)
+			}
+		})
+	}
+}
+
+// fakeSyncClient answers SyncNotes from a list of prepared responses and
+// records the cursors it was asked for.
+type fakeSyncClient struct {
+	pb.NoteServicesClient
+	responses []*pb.SyncResponse
+	cursors   []int64
+}
+
+func (f *fakeSyncClient) SyncNotes(_ context.Context, in *pb.SyncRequest, _ ...grpc.CallOption) (*pb.SyncResponse, error) {
+	f.cursors = append(f.cursors, in.Cursor)
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	return resp, nil
+}
+
+func TestService_LoadNote(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	key, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	noteA := &models.TextNote{Text: "a", BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "A", Type: models.TEXT}}
+	noteB := &models.TextNote{Text: "b", BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "B", Type: models.TEXT}}
+	dtoA, err := marshalNote(context.Background(), key, noteA, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtoB, err := marshalNote(context.Background(), key, noteB, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachePath := filepath.Join(t.TempDir(), "vault.db")
+
+	newService := func(nc pb.NoteServicesClient) *Service {
+		cn := &Service{nc: nc, uc: &fakeUserClient{}, jwt: "token", jwtExpires: time.Now().Add(time.Hour), email: "user1@test.com", dataKey: key}
+		cn.resetVault()
+		if err := cn.SetCache(cachePath); err != nil {
+			t.Fatal(err)
+		}
+		return cn
+	}
+
+	first := &fakeSyncClient{responses: []*pb.SyncResponse{{Notes: []*pb.Note{dtoA, dtoB}, Cursor: 2, Full: true}}}
+	list, err := newService(first).LoadNote()
+	if err != nil {
+		t.Fatalf("LoadNote() error = %v", err)
+	}
+	if len(*list) != 2 || !reflect.DeepEqual(first.cursors, []int64{0}) {
+		t.Fatalf("LoadNote() notes = %d, cursors = %v", len(*list), first.cursors)
+	}
+
+	// a restart continues from the cached cursor and applies the tombstone
+	second := &fakeSyncClient{responses: []*pb.SyncResponse{{Deleted: []string{noteB.Id.String()}, Cursor: 3}}}
+	cn := newService(second)
+	list, err = cn.LoadNote()
+	if err != nil {
+		t.Fatalf("LoadNote() error = %v", err)
+	}
+	if !reflect.DeepEqual(second.cursors, []int64{2}) {
+		t.Errorf("LoadNote() cursors = %v, want [2]", second.cursors)
+	}
+	if !reflect.DeepEqual(*list, []models.Noteable{noteA}) {
+		t.Errorf("LoadNote() notes = %v, want %v", *list, []models.Noteable{noteA})
+	}
+	if cn.cursor != 3 {
+		t.Errorf("LoadNote() cursor = %d, want 3", cn.cursor)
+	}
+}
+
+// fakeResealClient records the notes written back after a sync.
+type fakeResealClient struct {
+	fakeSyncClient
+	updated map[string]*pb.Note
+}
+
+func (f *fakeResealClient) UpdateNote(_ context.Context, in *pb.Note, _ ...grpc.CallOption) (*empty.Empty, error) {
+	f.updated[in.Id] = in
+	return &empty.Empty{}, nil
+}
+
+func TestService_LoadNoteLegacy(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	ctx := context.Background()
+	key, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyNote := func(text string, nonce []byte) (*models.TextNote, *pb.Note) {
+		note := &models.TextNote{Text: text, BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: text, Type: models.TEXT}}
+		plain, err := json.Marshal(note)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce = append(nonce, make([]byte, gcm.NonceSize()-len(nonce))...)
+		dto := &pb.Note{Id: note.Id.String(), Name: text, Type: models.TEXT.String(), SecretData: gcm.Seal(nonce, nonce, plain, nil), Revision: 1}
+		return note, dto
+	}
+	sealedNote := &models.TextNote{Text: "sealed", BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "sealed", Type: models.TEXT}}
+	sealedDto, err := marshalNote(ctx, key, sealedNote, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealedDto.Revision = 1
+	legacy, legacyDto := legacyNote("legacy", []byte{1})
+	// legacy data whose nonce looks like an envelope header
+	lookalike, lookalikeDto := legacyNote("lookalike", []byte{'G', 'K', util.EnvelopeV1})
+
+	tests := []struct {
+		name       string
+		notes      []*pb.Note
+		wantLoaded []uuid.UUID
+	}{
+		{
+			name:       "vault with envelopes",
+			notes:      []*pb.Note{lookalikeDto, sealedDto, legacyDto},
+			wantLoaded: []uuid.UUID{sealedNote.Id, legacy.Id},
+		},
+		{
+			name:       "vault without envelopes",
+			notes:      []*pb.Note{lookalikeDto, legacyDto},
+			wantLoaded: []uuid.UUID{lookalike.Id, legacy.Id},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nc := &fakeResealClient{
+				fakeSyncClient: fakeSyncClient{responses: []*pb.SyncResponse{{Notes: tt.notes, Cursor: 1, Full: true}}},
+				updated:        map[string]*pb.Note{},
+			}
+			cn := &Service{nc: nc, jwt: "token", jwtExpires: time.Now().Add(time.Hour), dataKey: key}
+			cn.resetVault()
+			if _, err := cn.LoadNote(); err != nil {
+				t.Fatalf("LoadNote() error = %v", err)
+			}
+			if len(cn.storage) != len(tt.wantLoaded) {
+				t.Errorf("LoadNote() loaded %d notes, want %d", len(cn.storage), len(tt.wantLoaded))
+			}
+			for _, id := range tt.wantLoaded {
+				if cn.storage[id] == nil {
+					t.Errorf("LoadNote() did not load %s", id)
+				}
+				if id == sealedNote.Id {
+					continue
+				}
+				// legacy notes are written back as envelopes
+				dto := nc.updated[id.String()]
+				if dto == nil || dto.Revision != 1 || cn.revisions[id] != 2 || cn.legacy[id] {
+					t.Errorf("LoadNote() did not seal %s again: %v", id, dto)
+					continue
+				}
+				if _, header, err := util.Open(ctx, key, dto.SecretData, util.NoteAAD(dto.Id, dto.Type)); err != nil || header.Version != util.EnvelopeV1 {
+					t.Errorf("LoadNote() sealed %s as version %d, error = %v", id, header.Version, err)
+				}
+			}
+			if _, ok := nc.updated[sealedNote.Id.String()]; ok {
+				t.Errorf("LoadNote() wrote back a note that is already sealed")
+			}
+		})
+	}
+}
+
+// fakeFlakyClient is a fakeNoteClient that can be taken offline.
+type fakeFlakyClient struct {
+	fakeNoteClient
+	down bool
+}
+
+func (f *fakeFlakyClient) AddNote(_ context.Context, in *pb.Note, _ ...grpc.CallOption) (*empty.Empty, error) {
+	if f.down {
+		return nil, status.Error(codes.Unavailable, "connection refused")
+	}
+	in.Revision = 1
+	f.notes[in.Id] = in
+	return &empty.Empty{}, nil
+}
+
+func (f *fakeFlakyClient) UpdateNote(ctx context.Context, in *pb.Note, opts ...grpc.CallOption) (*empty.Empty, error) {
+	if f.down {
+		return nil, status.Error(codes.Unavailable, "connection refused")
+	}
+	return f.fakeNoteClient.UpdateNote(ctx, in, opts...)
+}
+
+func (f *fakeFlakyClient) DeleteNote(_ context.Context, in *pb.NoteRequest, _ ...grpc.CallOption) (*empty.Empty, error) {
+	if f.down {
+		return nil, status.Error(codes.Unavailable, "connection refused")
+	}
+	delete(f.notes, in.IdNote)
+	return &empty.Empty{}, nil
+}
+
+func (f *fakeFlakyClient) SyncNotes(_ context.Context, _ *pb.SyncRequest, _ ...grpc.CallOption) (*pb.SyncResponse, error) {
+	if f.down {
+		return nil, status.Error(codes.Unavailable, "connection refused")
+	}
+	resp := &pb.SyncResponse{Cursor: 1, Full: true}
+	for _, note := range f.notes {
+		resp.Notes = append(resp.Notes, note)
+	}
+	return resp, nil
+}
+
+func TestService_Offline(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	ctx := context.Background()
+	key, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := &models.TextNote{Text: "loaded", BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "A", Type: models.TEXT}}
+	edited := &models.TextNote{Text: "edited offline", BaseNote: loaded.BaseNote}
+	added := &models.TextNote{Text: "added offline", BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "B", Type: models.TEXT}}
+	dropped := &models.TextNote{Text: "deleted offline", BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "C", Type: models.TEXT}}
+	dto, err := marshalNote(ctx, key, loaded, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dto.Revision = 1
+	nc := &fakeFlakyClient{fakeNoteClient: fakeNoteClient{notes: map[string]*pb.Note{dto.Id: dto}}}
+	cachePath := filepath.Join(t.TempDir(), "vault.db")
+
+	newService := func() *Service {
+		cn := &Service{nc: nc, uc: &fakeUserClient{}, jwt: "token", jwtExpires: time.Now().Add(time.Hour), email: "user1@test.com", dataKey: key}
+		cn.resetVault()
+		if err := cn.SetCache(cachePath); err != nil {
+			t.Fatal(err)
+		}
+		return cn
+	}
+	notes := func(list *[]models.Noteable) map[uuid.UUID]models.Noteable {
+		got := make(map[uuid.UUID]models.Noteable)
+		for _, note := range *list {
+			got[note.GetID()] = note
+		}
+		return got
+	}
+
+	cn := newService()
+	if _, err = cn.LoadNote(); err != nil {
+		t.Fatalf("LoadNote() error = %v", err)
+	}
+
+	// writes are queued while the server is down
+	nc.down = true
+	for _, note := range []models.Noteable{edited, added, dropped} {
+		if _, err = cn.AddNote(note); err != nil {
+			t.Fatalf("AddNote() offline error = %v", err)
+		}
+	}
+	if _, err = cn.DeleteNote(dropped.Id); err != nil {
+		t.Fatalf("DeleteNote() offline error = %v", err)
+	}
+	if !cn.Offline() {
+		t.Error("Offline() = false, want true")
+	}
+
+	// a restart shows the cached notes with the queue applied
+	cn = newService()
+	list, err := cn.LoadNote()
+	if err != nil {
+		t.Fatalf("LoadNote() offline error = %v", err)
+	}
+	want := map[uuid.UUID]models.Noteable{edited.Id: edited, added.Id: added}
+	if got := notes(list); !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadNote() offline = %v, want %v", got, want)
+	}
+
+	// the queue is replayed once the server is back
+	nc.down = false
+	list, err = cn.LoadNote()
+	if err != nil {
+		t.Fatalf("LoadNote() error = %v", err)
+	}
+	if got := notes(list); !reflect.DeepEqual(got, want) || cn.Offline() {
+		t.Errorf("LoadNote() = %v, offline %v, want %v", got, cn.Offline(), want)
+	}
+	if len(nc.notes) != 2 || nc.notes[dto.Id].Revision != 2 {
+		t.Errorf("server notes = %d, revision = %d, want 2 notes at revision 2", len(nc.notes), nc.notes[dto.Id].Revision)
+	}
+	if edits, _ := cn.cache.pending(ctx, key, cn.email); len(edits) != 0 {
+		t.Errorf("pending() = %d edits, want 0", len(edits))
+	}
+
+	// a queued edit based on an outdated revision comes back as a conflict
+	nc.down = true
+	mine := &models.TextNote{Text: "mine", BaseNote: loaded.BaseNote}
+	if _, err = cn.AddNote(mine); err != nil {
+		t.Fatalf("AddNote() offline error = %v", err)
+	}
+	theirs, err := marshalNote(ctx, key, &models.TextNote{Text: "theirs", BaseNote: loaded.BaseNote}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirs.Revision = 3
+	nc.notes[dto.Id] = theirs
+	nc.down = false
+	_, err = cn.LoadNote()
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("LoadNote() error = %v, want *ConflictError", err)
+	}
+	if _, err = cn.ResolveConflict(conflict, true); err != nil {
+		t.Fatalf("ResolveConflict() error = %v", err)
+	}
+	if got := nc.notes[dto.Id].Revision; got != 4 {
+		t.Errorf("server revision = %d, want 4", got)
+	}
+	if edits, _ := cn.cache.pending(ctx, key, cn.email); len(edits) != 0 {
+		t.Errorf("pending() = %d edits, want 0", len(edits))
+	}
+}
+
+// fakeDownClient fails every login as if the server were unreachable.
+type fakeDownClient struct {
+	pb.UserServicesClient
+}
+
+func (f *fakeDownClient) LoginStart(_ context.Context, _ *pb.SrpStart, _ ...grpc.CallOption) (*pb.SrpChallenge, error) {
+	return nil, status.Error(codes.Unavailable, "connection refused")
+}
+
+func TestService_LoginOffline(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	kdf, err := util.NewKdfParams(1, 64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kek, err := util.DeriveKey("secret", kdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := util.WrapKey(context.Background(), kek, dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachePath := filepath.Join(t.TempDir(), "vault.db")
+
+	tests := []struct {
+		name    string
+		email   string
+		pass    string
+		memory  uint32
+		wantErr bool
+	}{
+		{name: "Remembered account", email: "user1@test.com", pass: "secret"},
+		{name: "Wrong password", email: "user1@test.com", pass: "wrong", wantErr: true},
+		{name: "Unknown account", email: "user2@test.com", pass: "secret", wantErr: true},
+		{name: "Cached kdf above the maximum", email: "user1@test.com", pass: "secret", memory: math.MaxUint32, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cn := &Service{uc: &fakeDownClient{}, email: "user1@test.com"}
+			if err := cn.SetCache(cachePath); err != nil {
+				t.Fatal(err)
+			}
+			cached := kdf
+			if tt.memory != 0 {
+				cached.Memory = tt.memory
+			}
+			cn.rememberAccount(cached, wrapped)
+			cn.email = ""
+
+			err := cn.Login(&pb.User{Email: tt.email, Password: tt.pass})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Login() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !bytes.Equal(cn.dataKey, dataKey) || !cn.Offline() || cn.jwt != "" {
+				t.Errorf("Login() offline = %v, jwt = %q, data key restored = %v", cn.Offline(), cn.jwt, bytes.Equal(cn.dataKey, dataKey))
+			}
+		})
+	}
+}
+
+func TestService_SaveSession(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	kdf, err := util.NewKdfParams(1, 64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kek, err := util.DeriveKey("secret", kdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := util.WrapKey(context.Background(), kek, dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionKey, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cn := &Service{email: "user1@test.com", dataKey: dataKey}
+	cn.setTokens(&pb.JwtToken{Token: "jwt", RefreshToken: "refresh", ExpiresAt: 1700000000})
+	cn.rememberAccount(kdf, wrapped)
+	if _, err = cn.SaveSession(sessionKey); !errors.Is(err, ErrNoSavedSession) {
+		t.Fatalf("SaveSession() of a vault without a wrapped key error = %v", err)
+	}
+	cn.enveloped = true
+	saved, err := cn.SaveSession(sessionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		sessionKey []byte
+		password   string
+		wantKey    []byte
+		wantErr    bool
+	}{
+		{name: "Session key", sessionKey: sessionKey, wantKey: dataKey},
+		{name: "Password", password: "secret", wantKey: dataKey},
+		{name: "Locked", wantKey: nil},
+		{name: "Other session key", sessionKey: kek, wantErr: true},
+		{name: "Wrong password", password: "wrong", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resumed := &Service{}
+			err := resumed.ResumeSession(*saved, tt.sessionKey, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResumeSession() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !bytes.Equal(resumed.dataKey, tt.wantKey) || resumed.jwt != "jwt" || resumed.refreshToken != "refresh" || resumed.email != "user1@test.com" {
+				t.Errorf("ResumeSession() jwt = %q, email = %q, data key restored = %v", resumed.jwt, resumed.email, bytes.Equal(resumed.dataKey, tt.wantKey))
+			}
+			again, err := resumed.SaveSession(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.SealedKey != nil || !bytes.Equal(again.WrappedKey, wrapped) {
+				t.Errorf("SaveSession(nil) = %+v", again)
+			}
+		})
+	}
+
+	costly := *saved
+	costly.Kdf.Memory = math.MaxUint32
+	if err = (&Service{}).ResumeSession(costly, nil, "secret"); !errors.Is(err, util.ErrCostlyKdf) {
+		t.Errorf("ResumeSession() of a session file asking for %d KiB error = %v", costly.Kdf.Memory, err)
+	}
+}
+
+// fakeWatchClient fails the first WatchNotes call; the next stream delivers
+// events and later ones none.
+type fakeWatchClient struct {
+	pb.NoteServicesClient
+	calls  int
+	tokens []string
+	events []*pb.NoteEvent
+}
+
+func (f *fakeWatchClient) WatchNotes(ctx context.Context, _ *empty.Empty, _ ...grpc.CallOption) (grpc.ServerStreamingClient[pb.NoteEvent], error) {
+	f.calls++
+	md, _ := metadata.FromOutgoingContext(ctx)
+	f.tokens = append(f.tokens, md.Get("token")...)
+	if f.calls == 1 {
+		return nil, status.Error(codes.Unavailable, "connection refused")
+	}
+	stream := &fakeWatchStream{events: f.events}
+	f.events = nil
+	return stream, nil
+}
+
+type fakeWatchStream struct {
+	grpc.ClientStream
+	events []*pb.NoteEvent
+}
+
+func (f *fakeWatchStream) Header() (metadata.MD, error) {
+	return metadata.Pairs("watching", "true"), nil
+}
+
+func (f *fakeWatchStream) Recv() (*pb.NoteEvent, error) {
+	if len(f.events) == 0 {
+		return nil, status.Error(codes.Unavailable, "server stopped")
+	}
+	event := f.events[0]
+	f.events = f.events[1:]
+	return event, nil
+}
+
+func TestService_Watch(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	prevMin, prevMax := watchBackoffMin, watchBackoffMax
+	watchBackoffMin, watchBackoffMax = time.Millisecond, 2*time.Millisecond
+	defer func() { watchBackoffMin, watchBackoffMax = prevMin, prevMax }()
+
+	nc := &fakeWatchClient{events: []*pb.NoteEvent{{Kind: pb.NoteEvent_UPDATED, Id: uuid.NewString()}}}
+	cn := &Service{nc: nc, jwt: "token"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// one call after connecting, one per event, one after reconnecting
+	changed := 0
+	done := make(chan struct{})
+	go func() {
+		cn.Watch(ctx, func() {
+			changed++
+			if changed == 3 {
+				cancel()
+			}
+		})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() did not stop")
+	}
+	if nc.calls != 3 || changed != 3 {
+		t.Errorf("Watch() calls = %d, changed = %d, want 3 and 3", nc.calls, changed)
+	}
+	if !reflect.DeepEqual(nc.tokens, []string{"token", "token", "token"}) {
+		t.Errorf("Watch() tokens = %v", nc.tokens)
+	}
+}
+
+// fakeBlobClient keeps one blob in memory. The upload stream of chunk cutAt
+// is cut off once, as a dropped connection would.
+type fakeBlobClient struct {
+	pb.NoteServicesClient
+	chunks   [][]byte
+	complete bool
+	cutAt    int64
+	cut      bool
+	uploads  int
+	sent     int
+}
+
+func (f *fakeBlobClient) GetBlobStatus(_ context.Context, in *pb.BlobRequest, _ ...grpc.CallOption) (*pb.BlobStatus, error) {
+	if len(f.chunks) == 0 {
+		return nil, status.Error(codes.NotFound, in.BlobId)
+	}
+	return &pb.BlobStatus{BlobId: in.BlobId, NextIndex: int64(len(f.chunks)), Complete: f.complete}, nil
+}
+
+func (f *fakeBlobClient) UploadBlob(_ context.Context, _ ...grpc.CallOption) (grpc.ClientStreamingClient[pb.BlobChunk, pb.BlobStatus], error) {
+	f.uploads++
+	return &fakeBlobUpload{f: f}, nil
+}
+
+func (f *fakeBlobClient) DownloadBlob(_ context.Context, _ *pb.BlobRequest, _ ...grpc.CallOption) (grpc.ServerStreamingClient[pb.BlobChunk], error) {
+	return &fakeBlobDownload{chunks: f.chunks}, nil
+}
+
+type fakeBlobUpload struct {
+	grpc.ClientStream
+	f   *fakeBlobClient
+	cut bool
+}
+
+func (u *fakeBlobUpload) Send(chunk *pb.BlobChunk) error {
+	if chunk.Index == u.f.cutAt && !u.f.cut {
+		u.f.cut, u.cut = true, true
+	}
+	if u.cut {
+		return io.EOF
+	}
+	if chunk.Index != int64(len(u.f.chunks)) {
+		return fmt.Errorf("chunk %d sent after %d", chunk.Index, len(u.f.chunks))
+	}
+	u.f.chunks = append(u.f.chunks, chunk.Data)
+	u.f.complete = chunk.Last
+	u.f.sent++
+	return nil
+}
+
+func (u *fakeBlobUpload) CloseAndRecv() (*pb.BlobStatus, error) {
+	if u.cut {
+		return nil, status.Error(codes.Unavailable, "connection reset")
+	}
+	return &pb.BlobStatus{NextIndex: int64(len(u.f.chunks)), Complete: u.f.complete}, nil
+}
+
+type fakeBlobDownload struct {
+	grpc.ClientStream
+	chunks [][]byte
+	index  int64
+}
+
+func (d *fakeBlobDownload) Recv() (*pb.BlobChunk, error) {
+	if d.index == int64(len(d.chunks)) {
+		return nil, io.EOF
+	}
+	chunk := &pb.BlobChunk{Index: d.index, Data: d.chunks[d.index], Last: d.index == int64(len(d.chunks))-1}
+	d.index++
+	return chunk, nil
+}
+
+func TestService_Blob(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	prev := uploadRetryDelay
+	uploadRetryDelay = 0
+	defer func() { uploadRetryDelay = prev }()
+
+	dir := t.TempDir()
+	data := make([]byte, 2*blobChunkSize+100)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	nc := &fakeBlobClient{cutAt: 1}
+	cn := &Service{nc: nc, jwt: "token"}
+	ref, err := cn.UploadFile(path)
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if ref.FileName != "photo.jpg" || ref.Size != int64(len(data)) {
+		t.Errorf("UploadFile() ref = %s, %d bytes", ref.FileName, ref.Size)
+	}
+	// the second upload resumes after chunk 0
+	if nc.uploads != 2 || nc.sent != 3 || !nc.complete {
+		t.Errorf("UploadFile() uploads = %d, chunks sent = %d, complete = %v", nc.uploads, nc.sent, nc.complete)
+	}
+
+	out := filepath.Join(dir, "copy.jpg")
+	if err = cn.DownloadFile(*ref, out); err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("DownloadFile() content differs, err = %v", err)
+	}
+
+	chunks := nc.chunks
+	otherKey, _ := util.NewDataKey()
+	tests := []struct {
+		name   string
+		chunks [][]byte
+		key    []byte
+	}{
+		{name: "Reordered", chunks: [][]byte{chunks[1], chunks[0], chunks[2]}, key: ref.Key},
+		{name: "Truncated", chunks: chunks[:2], key: ref.Key},
+		{name: "Last dropped", chunks: [][]byte{chunks[0], chunks[1], chunks[1]}, key: ref.Key},
+		{name: "Wrong key", chunks: chunks, key: otherKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nc.chunks = tt.chunks
+			bad := *ref
+			bad.Key = tt.key
+			target := filepath.Join(dir, tt.name)
+			if err := cn.DownloadFile(bad, target); !errors.Is(err, ErrBlobCorrupt) {
+				t.Errorf("DownloadFile() error = %v, want ErrBlobCorrupt", err)
+			}
+			if _, err := os.Stat(target); !os.IsNotExist(err) {
+				t.Errorf("DownloadFile() left %s behind", target)
+			}
+		})
+	}
+
+	empty := filepath.Join(dir, "empty")
+	if err = os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	nc = &fakeBlobClient{cutAt: -1}
+	cn.nc = nc
+	ref, err = cn.UploadFile(empty)
+	if err != nil || len(nc.chunks) != 1 {
+		t.Fatalf("UploadFile() of an empty file error = %v, chunks = %d", err, len(nc.chunks))
+	}
+	var buf bytes.Buffer
+	if err = cn.DownloadBlob(*ref, &buf); err != nil || buf.Len() != 0 {
+		t.Errorf("DownloadBlob() of an empty file error = %v, %d bytes", err, buf.Len())
+	}
+}
+
+// fakeHistoryClient keeps the replaced revisions of the notes it stores,
+// newest first.
+type fakeHistoryClient struct {
+	fakeNoteClient
+	history map[string][]*pb.Note
+}
+
+func (f *fakeHistoryClient) UpdateNote(ctx context.Context, in *pb.Note, opts ...grpc.CallOption) (*empty.Empty, error) {
+	previous := f.notes[in.Id]
+	resp, err := f.fakeNoteClient.UpdateNote(ctx, in, opts...)
+	if err == nil {
+		f.history[in.Id] = append([]*pb.Note{previous}, f.history[in.Id]...)
+	}
+	return resp, err
+}
+
+func (f *fakeHistoryClient) ListNoteRevisions(_ context.Context, in *pb.NoteRequest, _ ...grpc.CallOption) (*pb.NoteRevisionList, error) {
+	list := &pb.NoteRevisionList{}
+	for _, note := range f.history[in.IdNote] {
+		list.Revisions = append(list.Revisions, &pb.NoteRevision{Note: note, WrittenAt: 1700000000})
+	}
+	return list, nil
+}
+
+func (f *fakeHistoryClient) RestoreNoteRevision(ctx context.Context, in *pb.RestoreRequest, _ ...grpc.CallOption) (*pb.Note, error) {
+	for _, note := range f.history[in.IdNote] {
+		if note.Revision != in.Revision {
+			continue
+		}
+		restored := proto.Clone(note).(*pb.Note)
+		restored.Revision = in.Current
+		if _, err := f.UpdateNote(ctx, restored); err != nil {
+			return nil, err
+		}
+		return f.notes[in.IdNote], nil
+	}
+	return nil, status.Error(codes.NotFound, in.IdNote)
+}
+
+func TestService_History(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	key, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := models.BaseNote{Id: uuid.New(), NameRecord: "Test Note", Type: models.TEXT}
+	version := func(text string, revision int64) *pb.Note {
+		dto, err := marshalNote(context.Background(), key, &models.TextNote{Text: text, BaseNote: base}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dto.Revision = revision
+		return dto
+	}
+	nc := &fakeHistoryClient{
+		fakeNoteClient: fakeNoteClient{notes: map[string]*pb.Note{base.Id.String(): version("v3", 3)}},
+		history:        map[string][]*pb.Note{base.Id.String(): {version("v2", 2), version("v1", 1)}},
+	}
+	var stored models.Noteable = &models.TextNote{Text: "v3", BaseNote: base}
+	cn := &Service{
+		nc:        nc,
+		uc:        &fakeUserClient{},
+		jwt:       "token",
+		dataKey:   key,
+		storage:   map[uuid.UUID]*models.Noteable{base.Id: &stored},
+		sealed:    map[uuid.UUID]bool{},
+		revisions: map[uuid.UUID]int64{base.Id: 3},
+	}
+	cn.jwtExpires = time.Now().Add(time.Hour)
+
+	revisions, err := cn.ListRevisions(base.Id)
+	if err != nil {
+		t.Fatalf("ListRevisions() error = %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Revision != 2 || revisions[0].Note.(*models.TextNote).Text != "v2" || revisions[1].WrittenAt.Unix() != 1700000000 {
+		t.Fatalf("ListRevisions() = %+v", revisions)
+	}
+
+	if _, err = cn.RestoreRevision(revisions[1]); err != nil {
+		t.Fatalf("RestoreRevision() error = %v", err)
+	}
+	if got := (*cn.storage[base.Id]).(*models.TextNote).Text; got != "v1" || cn.revisions[base.Id] != 4 {
+		t.Errorf("RestoreRevision() note = %q at %d, want v1 at 4", got, cn.revisions[base.Id])
+	}
+
+	// another device wrote meanwhile: keeping the revision writes it on top
+	nc.notes[base.Id.String()] = version("theirs", 5)
+	_, err = cn.RestoreRevision(revisions[0])
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Local != revisions[0].Note {
+		t.Fatalf("RestoreRevision() error = %v, want *ConflictError with the revision", err)
+	}
+	if _, err = cn.ResolveConflict(conflict, true); err != nil {
+		t.Fatalf("ResolveConflict() error = %v", err)
+	}
+	if got := (*cn.storage[base.Id]).(*models.TextNote).Text; got != "v2" || nc.notes[base.Id.String()].Revision != 6 {
+		t.Errorf("ResolveConflict() note = %q at %d, want v2 at 6", got, nc.notes[base.Id.String()].Revision)
+	}
+
+	// a revision of another note is rejected
+	other := models.BaseNote{Id: uuid.New(), NameRecord: "Other", Type: models.TEXT}
+	forged, err := marshalNote(context.Background(), key, &models.TextNote{Text: "forged", BaseNote: other}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nc.history[base.Id.String()] = append(nc.history[base.Id.String()], forged)
+	if _, err = cn.ListRevisions(base.Id); !errors.Is(err, ErrNoteMismatch) {
+		t.Errorf("ListRevisions() of a forged revision error = %v, want ErrNoteMismatch", err)
+	}
+}
+
+// fakeTrashClient keeps deleted notes until they are restored or purged.
+type fakeTrashClient struct {
+	fakeNoteClient
+	trash []*pb.Note
+}
+
+func (f *fakeTrashClient) ListTrash(_ context.Context, _ *empty.Empty, _ ...grpc.CallOption) (*pb.TrashList, error) {
+	list := &pb.TrashList{}
+	for _, note := range f.trash {
+		list.Notes = append(list.Notes, &pb.TrashedNote{Note: note, DeletedAt: 1700000000})
+	}
+	return list, nil
+}
+
+func (f *fakeTrashClient) RestoreNote(_ context.Context, in *pb.NoteRequest, _ ...grpc.CallOption) (*pb.Note, error) {
+	for i, note := range f.trash {
+		if note.Id == in.IdNote {
+			f.trash = append(f.trash[:i], f.trash[i+1:]...)
+			restored := proto.Clone(note).(*pb.Note)
+			restored.Revision++
+			f.notes[in.IdNote] = restored
+			return restored, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, in.IdNote)
+}
+
+func (f *fakeTrashClient) EmptyTrash(_ context.Context, _ *empty.Empty, _ ...grpc.CallOption) (*pb.TrashCount, error) {
+	count := &pb.TrashCount{Purged: int64(len(f.trash))}
+	f.trash = nil
+	return count, nil
+}
+
+func TestService_Trash(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	key, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted := func(name string) (uuid.UUID, *pb.Note) {
+		base := models.BaseNote{Id: uuid.New(), NameRecord: name, Type: models.TEXT}
+		dto, err := marshalNote(context.Background(), key, &models.TextNote{Text: name, BaseNote: base}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dto.Revision = 2
+		return base.Id, dto
+	}
+	idA, noteA := deleted("A")
+	_, noteB := deleted("B")
+	nc := &fakeTrashClient{fakeNoteClient: fakeNoteClient{notes: map[string]*pb.Note{}}, trash: []*pb.Note{noteA, noteB}}
+	cn := &Service{
+		nc:        nc,
+		uc:        &fakeUserClient{},
+		dataKey:   key,
+		storage:   map[uuid.UUID]*models.Noteable{},
+		sealed:    map[uuid.UUID]bool{},
+		revisions: map[uuid.UUID]int64{},
+	}
+
+	if _, err = cn.ListTrash(); err == nil {
+		t.Error("ListTrash() without signing in")
+	}
+	cn.jwt = "token"
+	cn.jwtExpires = time.Now().Add(time.Hour)
+
+	trash, err := cn.ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash() error = %v", err)
+	}
+	if len(trash) != 2 || trash[0].Note.GetName() != "A" || trash[1].DeletedAt.Unix() != 1700000000 {
+		t.Errorf("ListTrash() = %+v", trash)
+	}
+
+	notes, err := cn.RestoreNote(idA)
+	if err != nil {
+		t.Fatalf("RestoreNote() error = %v", err)
+	}
+	if len(*notes) != 1 || (*notes)[0].GetID() != idA || cn.revisions[idA] != 3 {
+		t.Errorf("RestoreNote() = %v, revision %d", *notes, cn.revisions[idA])
+	}
+	if _, err = cn.RestoreNote(idA); status.Code(err) != codes.NotFound {
+		t.Errorf("RestoreNote() of a restored note error = %v", err)
+	}
+
+	purged, err := cn.EmptyTrash()
+	if err != nil || purged != 1 {
+		t.Errorf("EmptyTrash() = %d, %v, want 1", purged, err)
+	}
+}
+
+// fakeRekeyClient serves a vault with trashed notes and kept revisions.
+type fakeRekeyClient struct {
+	fakeTrashClient
+	history map[string][]*pb.Note
+}
+
+func (f *fakeRekeyClient) GetNotes(_ context.Context, _ *pb.NoteRequest, _ ...grpc.CallOption) (*pb.NoteList, error) {
+	list := &pb.NoteList{}
+	for _, note := range f.notes {
+		list.Notes = append(list.Notes, note)
+	}
+	return list, nil
+}
+
+func (f *fakeRekeyClient) ListNoteRevisions(_ context.Context, in *pb.NoteRequest, _ ...grpc.CallOption) (*pb.NoteRevisionList, error) {
+	list := &pb.NoteRevisionList{}
+	for _, note := range f.history[in.IdNote] {
+		list.Revisions = append(list.Revisions, &pb.NoteRevision{Note: note, WrittenAt: 1700000000})
+	}
+	return list, nil
+}
+
+func TestService_reencryptVault(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	ctx := context.Background()
+	oldKey := util.LegacyKey("user1@test.com", "Test Password")
+	newKey, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := func(text string, revision int64, id uuid.UUID) *pb.Note {
+		base := models.BaseNote{Id: id, NameRecord: "note", Type: models.TEXT}
+		dto, err := marshalNote(ctx, oldKey, &models.TextNote{Text: text, BaseNote: base}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dto.Revision = revision
+		return dto
+	}
+	live, trashed := uuid.New(), uuid.New()
+	nc := &fakeRekeyClient{
+		fakeTrashClient: fakeTrashClient{
+			fakeNoteClient: fakeNoteClient{notes: map[string]*pb.Note{live.String(): note("live", 3, live)}},
+			trash:          []*pb.Note{note("trashed", 2, trashed)},
+		},
+		history: map[string][]*pb.Note{
+			live.String():    {note("live 2", 2, live), note("live 1", 1, live)},
+			trashed.String(): {note("trashed 1", 1, trashed)},
+		},
+	}
+	cn := &Service{nc: nc, dataKey: oldKey}
+
+	notes, history, err := cn.reencryptVault(ctx, newKey)
+	if err != nil {
+		t.Fatalf("reencryptVault() error = %v", err)
+	}
+	texts := func(notes []*pb.Note) map[string]int64 {
+		got := make(map[string]int64, len(notes))
+		for _, dto := range notes {
+			plain, _, _, err := openNote(ctx, newKey, dto, false)
+			if err != nil {
+				t.Fatalf("reencryptVault() note %s is not sealed with the new key: %v", dto.Id, err)
+			}
+			var text models.TextNote
+			if err = json.Unmarshal(plain, &text); err != nil {
+				t.Fatal(err)
+			}
+			got[text.Text] = dto.Revision
+		}
+		return got
+	}
+	if got := texts(notes); !reflect.DeepEqual(got, map[string]int64{"live": 3, "trashed": 2}) {
+		t.Errorf("reencryptVault() notes = %v", got)
+	}
+	revisions := make([]*pb.Note, 0, len(history))
+	for _, revision := range history {
+		revisions = append(revisions, revision.Note)
+	}
+	if got := texts(revisions); !reflect.DeepEqual(got, map[string]int64{"live 2": 2, "live 1": 1, "trashed 1": 1}) {
+		t.Errorf("reencryptVault() revisions = %v", got)
+	}
+}
+
+// fakeExportClient keeps a whole vault in memory: notes served by full syncs
+// and any number of blobs.
+type fakeExportClient struct {
+	fakeNoteClient
+	blobs map[string][][]byte
+}
+
+func newFakeExportClient() *fakeExportClient {
+	return &fakeExportClient{fakeNoteClient: fakeNoteClient{notes: map[string]*pb.Note{}}, blobs: map[string][][]byte{}}
+}
+
+func (f *fakeExportClient) AddNote(_ context.Context, in *pb.Note, _ ...grpc.CallOption) (*empty.Empty, error) {
+	if _, ok := f.notes[in.Id]; ok {
+		return nil, status.Error(codes.AlreadyExists, in.Id)
+	}
+	in.Revision = 1
+	f.notes[in.Id] = in
+	return &empty.Empty{}, nil
+}
+
+func (f *fakeExportClient) SyncNotes(_ context.Context, _ *pb.SyncRequest, _ ...grpc.CallOption) (*pb.SyncResponse, error) {
+	resp := &pb.SyncResponse{Cursor: 1, Full: true}
+	for _, note := range f.notes {
+		resp.Notes = append(resp.Notes, proto.Clone(note).(*pb.Note))
+	}
+	return resp, nil
+}
+
+func (f *fakeExportClient) GetBlobStatus(_ context.Context, in *pb.BlobRequest, _ ...grpc.CallOption) (*pb.BlobStatus, error) {
+	return nil, status.Error(codes.NotFound, in.BlobId)
+}
+
+func (f *fakeExportClient) UploadBlob(_ context.Context, _ ...grpc.CallOption) (grpc.ClientStreamingClient[pb.BlobChunk, pb.BlobStatus], error) {
+	return &fakeExportUpload{f: f}, nil
+}
+
+func (f *fakeExportClient) DownloadBlob(_ context.Context, in *pb.BlobRequest, _ ...grpc.CallOption) (grpc.ServerStreamingClient[pb.BlobChunk], error) {
+	return &fakeBlobDownload{chunks: f.blobs[in.BlobId]}, nil
+}
+
+type fakeExportUpload struct {
+	grpc.ClientStream
+	f    *fakeExportClient
+	sent int64
+}
+
+func (u *fakeExportUpload) Send(chunk *pb.BlobChunk) error {
+	u.f.blobs[chunk.BlobId] = append(u.f.blobs[chunk.BlobId], chunk.Data)
+	u.sent++
+	return nil
+}
+
+func (u *fakeExportUpload) CloseAndRecv() (*pb.BlobStatus, error) {
+	return &pb.BlobStatus{NextIndex: u.sent, Complete: true}, nil
+}
+
+func newExportService(t *testing.T) (*Service, *fakeExportClient) {
+	key, err := util.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nc := newFakeExportClient()
+	cn := &Service{nc: nc, jwt: "token", dataKey: key}
+	cn.SetKdfCost(1, 64, 1)
+	cn.resetVault()
+	return cn, nc
+}
+
+func TestService_ExportVault(t *testing.T) {
+	log = logger.NewLogger(logrus.New())
+	source, sourceClient := newExportService(t)
+	path := filepath.Join(t.TempDir(), "scan.pdf")
+	if err := os.WriteFile(path, []byte("%PDF scan"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := source.UploadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := []models.Noteable{
+		&models.TextNote{Text: "<b>&</b>", BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "html", Type: models.TEXT}},
+		&models.CredentialNote{Username: "me", Password: "pw", BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "mail", Type: models.CREDENTIAL}},
+		&models.BinaryNote{Blob: ref, BaseNote: models.BaseNote{Id: uuid.New(), NameRecord: "scan", Type: models.BINARY}},
+	}
+	for _, note := range notes {
+		if _, err = source.AddNote(note); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = source.ExportVault(&bytes.Buffer{}, ""); !errors.Is(err, ErrEmptyPassphrase) {
+		t.Errorf("ExportVault() without passphrase error = %v", err)
+	}
+	var export bytes.Buffer
+	if count, err := source.ExportVault(&export, "correct horse"); err != nil || count != 3 {
+		t.Fatalf("ExportVault() = %d, %v", count, err)
+	}
+	if bytes.Contains(export.Bytes(), []byte("mail")) || bytes.Contains(export.Bytes(), []byte("PDF")) {
+		t.Error("ExportVault() wrote plaintext")
+	}
+
+	target, targetClient := newExportService(t)
+	steps := []struct {
+		policy ImportPolicy
+		want   ImportResult
+		total  int
+	}{
+		{policy: ImportSkip, want: ImportResult{Added: 3}, total: 3},
+		{policy: ImportSkip, want: ImportResult{Skipped: 3}, total: 3},
+		{policy: ImportOverwrite, want: ImportResult{Overwritten: 3}, total: 3},
+		{policy: ImportDuplicate, want: ImportResult{Duplicated: 3}, total: 6},
+	}
+	for _, step := range steps {
+		result, err := target.ImportVault(bytes.NewReader(export.Bytes()), "correct horse", step.policy)
+		if err != nil || *result != step.want || len(targetClient.notes) != step.total {
+			t.Fatalf("ImportVault(%s) = %+v, %v, %d notes", step.policy, result, err, len(targetClient.notes))
+		}
+	}
+	for _, note := range notes {
+		imported, ok := target.storage[note.GetID()]
+		if !ok {
+			t.Fatalf("ImportVault() lost %s", note.GetName())
+		}
+		if binary, ok := (*imported).(*models.BinaryNote); ok {
+			var file bytes.Buffer
+			if binary.Blob.ID == ref.ID || target.DownloadBlob(*binary.Blob, &file) != nil || file.String() != "%PDF scan" {
+				t.Errorf("ImportVault() file = %q, blob %s", file.String(), binary.Blob.ID)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(*imported, note) {
+			t.Errorf("ImportVault() note = %+v, want %+v", *imported, note)
+		}
+	}
+	if len(sourceClient.blobs) != 1 || len(targetClient.blobs) != 3 {
+		t.Errorf("blobs: source %d, target %d", len(sourceClient.blobs), len(targetClient.blobs))
+	}
+
+	var file exportFile
+	if err = json.Unmarshal(export.Bytes(), &file); err != nil {
+		t.Fatal(err)
+	}
+	reencode := func(change func(*exportFile)) []byte {
+		changed := file
+		change(&changed)
+		data, _ := json.Marshal(changed)
+		return data
+	}
+	tampered := func(change func(*exportPayload)) []byte {
+		payload := exportPayload{Manifest: exportManifest{Notes: map[string]string{}}}
+		for _, note := range notes[:2] {
+			data, _ := json.Marshal(note)
+			payload.Notes = append(payload.Notes, exportedNote{Type: note.GetType().String(), Note: data})
+			payload.Manifest.Notes[note.GetID().String()] = digest(data)
+		}
+		change(&payload)
+		var buf bytes.Buffer
+		if err := writeExport(&buf, payload, "correct horse", target.kdfCost); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		wantErr    error
+	}{
+		{name: "Wrong passphrase", data: export.Bytes(), passphrase: "wrong horse", wantErr: ErrExportPassphrase},
+		{name: "Not an export", data: []byte(`{"notes":[]}`), passphrase: "correct horse", wantErr: ErrExportFormat},
+		{name: "Newer version", data: reencode(func(f *exportFile) { f.Version = exportVersion + 1 }), passphrase: "correct horse", wantErr: ErrExportVersion},
+		{name: "Oversized memory", data: reencode(func(f *exportFile) { f.Kdf.Memory = math.MaxUint32 }), passphrase: "correct horse", wantErr: ErrExportFormat},
+		{name: "Header changed", data: reencode(func(f *exportFile) { f.CreatedAt++ }), passphrase: "correct horse", wantErr: ErrExportPassphrase},
+		{name: "Note changed", data: tampered(func(p *exportPayload) {
+			p.Notes[1].Note = bytes.Replace(p.Notes[1].Note, []byte(`"pw"`), []byte(`"pwned"`), 1)
+		}), passphrase: "correct horse", wantErr: ErrExportManifest},
+		{name: "Note dropped", data: tampered(func(p *exportPayload) { p.Notes = p.Notes[1:] }), passphrase: "correct horse", wantErr: ErrExportManifest},
+		{name: "File missing", data: tampered(func(p *exportPayload) {
+			data, _ := json.Marshal(notes[2])
+			p.Notes = append(p.Notes, exportedNote{Type: models.BINARY.String(), Note: data})
+			p.Manifest.Notes[notes[2].GetID().String()] = digest(data)
+		}), passphrase: "correct horse", wantErr: ErrExportManifest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cn, nc := newExportService(t)
+			_, err := cn.ImportVault(bytes.NewReader(tt.data), tt.passphrase, ImportSkip)
+			if !errors.Is(err, tt.wantErr) || len(nc.notes) != 0 {
+				t.Errorf("ImportVault() error = %v, want %v, %d notes imported", err, tt.wantErr, len(nc.notes))
+			}
+		})
+	}
+}
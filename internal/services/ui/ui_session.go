@@ -0,0 +1,224 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+)
+
+// refreshMargin is how long before expiry the access token is renewed.
+const refreshMargin = time.Minute
+
+// Logout revokes the current session on the server and forgets the tokens,
+// the vault key and the loaded notes. Queued offline edits stay in the cache
+// until the next online login sends them.
+func (cn *Service) Logout() error {
+	log := log.WithFields(logrus.Fields{
+		"method": "Logout",
+	})
+
+	if cn.jwt == "" && cn.offline {
+		cn.dataKey = nil
+		cn.enveloped = false
+		cn.offline = false
+		cn.resetVault()
+		log.Info("offline user signed out")
+		return nil
+	}
+	if cn.jwt == "" {
+		log.Warning("Logout: jwt not found")
+		return fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
+	defer cancelFunc()
+	_, err := cn.uc.Logout(cn.addToken(ctx), &empty.Empty{})
+
+	cn.clearTokens()
+	cn.dataKey = nil
+	cn.enveloped = false
+	cn.offline = false
+	cn.resetVault()
+	if err != nil {
+		log.WithError(err).Warning("session is not revoked on the server")
+		return err
+	}
+	log.Info("user signed out")
+	return nil
+}
+
+func (cn *Service) ListSessions() ([]*pb.Session, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "ListSessions",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("ListSessions: jwt not found")
+		return nil, fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
+	defer cancelFunc()
+	list, err := cn.uc.ListSessions(cn.addToken(ctx), &empty.Empty{})
+	if err != nil {
+		log.WithError(err).Error("Error listing sessions")
+		return nil, err
+	}
+	return list.Sessions, nil
+}
+
+func (cn *Service) RevokeSession(id string) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "RevokeSession",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("RevokeSession: jwt not found")
+		return fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
+	defer cancelFunc()
+	if _, err := cn.uc.RevokeSession(cn.addToken(ctx), &pb.SessionRequest{Id: id}); err != nil {
+		log.WithError(err).Error("Error revoking session")
+		return err
+	}
+	log.WithField("session", id).Info("session revoked")
+	return nil
+}
+
+func (cn *Service) setTokens(token *pb.JwtToken) {
+	cn.tokenMu.Lock()
+	defer cn.tokenMu.Unlock()
+	cn.jwt = token.Token
+	cn.refreshToken = token.RefreshToken
+	cn.jwtExpires = time.Unix(token.ExpiresAt, 0)
+}
+
+func (cn *Service) clearTokens() {
+	cn.tokenMu.Lock()
+	defer cn.tokenMu.Unlock()
+	cn.jwt = ""
+	cn.refreshToken = ""
+	cn.jwtExpires = time.Time{}
+}
+
+// refreshIfExpiring exchanges the refresh token for a new token pair. A
+// failed refresh keeps the old access token; the server rejects it once it
+// expires.
+func (cn *Service) refreshIfExpiring(ctx context.Context) {
+	if cn.refreshToken == "" || time.Until(cn.jwtExpires) > refreshMargin {
+		return
+	}
+	token, err := cn.uc.Refresh(ctx, &pb.RefreshRequest{RefreshToken: cn.refreshToken})
+	if err != nil {
+		log.WithField("method", "refreshIfExpiring").WithError(err).Warning("could not refresh token")
+		return
+	}
+	cn.setTokens(token)
+}
+
+// withDevice names this client in the session list of the account.
+func withDevice(ctx context.Context) context.Context {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "device", host)
+}
+
+// SavedSession lets a client that does not keep running, such as the CLI,
+// resume a login. The vault key is in it twice: sealed with a session key
+// that the caller keeps apart from the file, and wrapped with the
+// password-derived key as on the server.
+type SavedSession struct {
+	Email        string           `json:"email"`
+	Token        string           `json:"token"`
+	RefreshToken string           `json:"refresh_token"`
+	ExpiresAt    int64            `json:"expires_at"`
+	Kdf          models.KdfParams `json:"kdf"`
+	WrappedKey   []byte           `json:"wrapped_key"`
+	SealedKey    []byte           `json:"sealed_key,omitempty"`
+}
+
+// SaveSession returns the current session for ResumeSession. A nil
+// sessionKey leaves SealedKey empty. Vaults without a wrapped data key cannot
+// be saved.
+func (cn *Service) SaveSession(sessionKey []byte) (*SavedSession, error) {
+	if cn.jwt == "" {
+		return nil, fmt.Errorf("You need sigin to app")
+	}
+	if !cn.enveloped || cn.keys == nil || cn.keys.Account != cn.email {
+		return nil, ErrNoSavedSession
+	}
+	cn.tokenMu.RLock()
+	saved := &SavedSession{
+		Email:        cn.email,
+		Token:        cn.jwt,
+		RefreshToken: cn.refreshToken,
+		ExpiresAt:    cn.jwtExpires.Unix(),
+		Kdf:          cn.keys.Kdf,
+		WrappedKey:   cn.keys.WrappedKey,
+	}
+	cn.tokenMu.RUnlock()
+	if sessionKey != nil {
+		sealed, err := util.WrapKey(context.Background(), sessionKey, cn.dataKey)
+		if err != nil {
+			return nil, err
+		}
+		saved.SealedKey = sealed
+	}
+	return saved, nil
+}
+
+// ResumeSession continues a saved session. The vault is unlocked with
+// sessionKey if it is set, else with password; with neither it stays locked
+// and only calls that need no notes, such as Logout, work.
+func (cn *Service) ResumeSession(saved SavedSession, sessionKey []byte, password string) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "ResumeSession",
+	})
+
+	ctx := context.Background()
+	var dataKey []byte
+	switch {
+	case sessionKey != nil:
+		key, err := util.UnwrapKey(ctx, sessionKey, saved.SealedKey)
+		if err != nil {
+			return ErrSessionKey
+		}
+		dataKey = key
+	case password != "":
+		if err := util.CheckKdfLimits(saved.Kdf); err != nil {
+			return err
+		}
+		kek, err := util.DeriveKey(password, saved.Kdf)
+		if err != nil {
+			return err
+		}
+		if dataKey, err = util.UnwrapKey(ctx, kek, saved.WrappedKey); err != nil {
+			return err
+		}
+	}
+	cn.resetVault()
+	cn.setTokens(&pb.JwtToken{Token: saved.Token, RefreshToken: saved.RefreshToken, ExpiresAt: saved.ExpiresAt})
+	cn.email = saved.Email
+	cn.srp = true
+	cn.offline = false
+	cn.dataKey = dataKey
+	cn.enveloped = true
+	cn.keys = &cachedAccount{Account: saved.Email, Kdf: saved.Kdf, WrappedKey: saved.WrappedKey}
+	log.Infof("session resumed: %s", saved.Email)
+	return nil
+}
+
+var (
+	ErrNoSavedSession = errors.New("this vault has no wrapped data key yet, sign in with the TUI once")
+	ErrSessionKey     = errors.New("session key does not match the saved session")
+)
@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"context"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/util"
+	"github.com/sirupsen/logrus"
+)
+
+// loginSrp signs in without sending the password. It returns the tokens and
+// the password-derived key, so the vault key is not derived twice. The
+// server is trusted only after it proves that it holds the verifier.
+func (cn *Service) loginSrp(ctx context.Context, email, password string) (*pb.JwtToken, []byte, error) {
+	client, proof, kek, err := cn.srpProof(ctx, email, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := cn.uc.LoginFinish(withDevice(ctx), proof)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = client.VerifyServer(session.M2); err != nil {
+		return nil, nil, err
+	}
+	return session.Token, kek, nil
+}
+
+// srpProof runs the first half of a handshake and proves password.
+func (cn *Service) srpProof(ctx context.Context, email, password string) (*util.SrpClient, *pb.SrpProof, []byte, error) {
+	client, err := util.NewSrpClient(email)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	challenge, err := cn.uc.LoginStart(ctx, &pb.SrpStart{Email: email, A: client.A()})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	params, err := cn.serverKdf(challenge.Kdf)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	kek, err := util.DeriveKey(password, params)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	m1, err := client.Proof(params.Salt, util.SrpX(kek), challenge.B)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return client, &pb.SrpProof{HandshakeId: challenge.HandshakeId, M1: m1}, kek, nil
+}
+
+// serverKdf returns the KDF parameters sent by the server if they cost at
+// least as much as the ones this client uses for new keys and no more than
+// the maximum.
+func (cn *Service) serverKdf(dto *pb.KdfParams) (models.KdfParams, error) {
+	params := interfaces.DtoToKdf(dto)
+	if err := util.CheckKdfCost(params, cn.kdfCost); err != nil {
+		return models.KdfParams{}, err
+	}
+	if err := util.CheckKdfLimits(params); err != nil {
+		return models.KdfParams{}, err
+	}
+	return params, nil
+}
+
+// upgradeToSrp replaces the password stored on the server with an SRP
+// verifier for the current KDF parameters.
+func (cn *Service) upgradeToSrp(ctx context.Context, password string, params models.KdfParams) error {
+	log := log.WithFields(logrus.Fields{
+		"method": "upgradeToSrp",
+	})
+
+	kek, err := util.DeriveKey(password, params)
+	if err != nil {
+		return err
+	}
+	req := &pb.SrpUpgrade{Password: password, Verifier: util.SrpVerifier(util.SrpX(kek))}
+	if _, err = cn.uc.UpgradeToSrp(cn.addToken(ctx), req); err != nil {
+		return err
+	}
+	cn.srp = true
+	log.Info("account moved to SRP login")
+	return nil
+}
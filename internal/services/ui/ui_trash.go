@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/google/uuid"
+	pb "github.com/katvixlab/go-diplom-gophkeeper/internal/interfaces/proto"
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// TrashedNote is a deleted note the server has not purged yet, decrypted.
+type TrashedNote struct {
+	DeletedAt time.Time
+	Note      models.Noteable
+}
+
+// ListTrash fetches the deleted notes, most recently deleted first, and
+// decrypts them. The trash is only kept on the server.
+func (cn *Service) ListTrash() ([]TrashedNote, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "ListTrash",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("ListTrash: jwt not found")
+		return nil, fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
+	defer cancelFunc()
+
+	resp, err := cn.nc.ListTrash(cn.addToken(ctx), &empty.Empty{})
+	if err != nil {
+		log.WithError(err).Error("Error listing trash")
+		return nil, err
+	}
+	trash := make([]TrashedNote, 0, len(resp.Notes))
+	for _, trashed := range resp.Notes {
+		note, err := unmarshalNote(ctx, cn.dataKey, trashed.Note)
+		if err != nil {
+			log.WithError(err).Errorf("Error decrypting trashed note %s", trashed.GetNote().GetId())
+			return nil, err
+		}
+		trash = append(trash, TrashedNote{DeletedAt: time.Unix(trashed.DeletedAt, 0), Note: note})
+	}
+	return trash, nil
+}
+
+// RestoreNote takes a deleted note out of the trash and back into the vault.
+func (cn *Service) RestoreNote(id uuid.UUID) (*[]models.Noteable, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "RestoreNote",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("RestoreNote: jwt not found")
+		return nil, fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
+	defer cancelFunc()
+
+	noteDto, err := cn.nc.RestoreNote(cn.addToken(ctx), &pb.NoteRequest{IdNote: id.String()})
+	if err != nil {
+		log.WithError(err).Error("Error restoring note")
+		return nil, err
+	}
+	if noteDto.Id != id.String() {
+		return nil, ErrNoteMismatch
+	}
+	note, err := unmarshalNote(ctx, cn.dataKey, noteDto)
+	if err != nil {
+		log.WithError(err).Error("Error decrypting restored note")
+		return nil, err
+	}
+	cn.storage[id] = &note
+	cn.sealed[id] = noteDto.Sealed
+	cn.revisions[id] = noteDto.Revision
+	log.WithField("note", note.GetName()).Info("Restored from the trash")
+	return toNotableList(cn.storage), nil
+}
+
+// EmptyTrash deletes the notes in the trash for good and returns how many
+// there were.
+func (cn *Service) EmptyTrash() (int64, error) {
+	log := log.WithFields(logrus.Fields{
+		"method": "EmptyTrash",
+	})
+
+	if cn.jwt == "" {
+		log.Warning("EmptyTrash: jwt not found")
+		return 0, fmt.Errorf("You need sigin to app")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 2000*time.Millisecond)
+	defer cancelFunc()
+
+	count, err := cn.nc.EmptyTrash(cn.addToken(ctx), &empty.Empty{})
+	if err != nil {
+		log.WithError(err).Error("Error emptying trash")
+		return 0, err
+	}
+	log.Infof("Purged %d notes", count.Purged)
+	return count.Purged, nil
+}
@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+)
+
+// Bounds of the delay between two attempts of Watch to reconnect. The delay
+// doubles after every failed attempt.
+var (
+	watchBackoffMin = time.Second
+	watchBackoffMax = 30 * time.Second
+)
+
+// Watch follows the note changes made on other devices until ctx is done.
+// changed is called from the Watch goroutine after every change and after
+// every (re)connect, since changes may have been missed meanwhile; it is
+// expected to hand a LoadNote over to the goroutine that uses the Service.
+func (cn *Service) Watch(ctx context.Context, changed func()) {
+	log := log.WithFields(logrus.Fields{
+		"method": "Watch",
+	})
+
+	backoff := watchBackoffMin
+	for {
+		connected, err := cn.watch(ctx, changed)
+		if ctx.Err() != nil {
+			log.Info("watch stopped")
+			return
+		}
+		if connected {
+			backoff = watchBackoffMin
+		}
+		log.WithError(err).Warningf("watch interrupted, reconnecting in %s", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watchBackoffMax)
+	}
+}
+
+// watch runs one stream. It reports whether the stream was established.
+func (cn *Service) watch(ctx context.Context, changed func()) (bool, error) {
+	cn.tokenMu.RLock()
+	md := metadata.New(map[string]string{"token": cn.jwt})
+	cn.tokenMu.RUnlock()
+	stream, err := cn.nc.WatchNotes(metadata.NewOutgoingContext(ctx, md), &empty.Empty{})
+	if err != nil {
+		return false, err
+	}
+	// the server sends the header once it is subscribed; a call that fails
+	// right away has none
+	header, err := stream.Header()
+	if err != nil {
+		return false, err
+	}
+	if len(header.Get("watching")) == 0 {
+		_, err = stream.Recv()
+		return false, err
+	}
+	changed()
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return true, err
+		}
+		log.WithField("method", "watch").Debugf("note %s %s", event.Id, event.Kind)
+		changed()
+	}
+}
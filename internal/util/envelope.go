@@ -0,0 +1,188 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"strconv"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// Envelope layout, version 1:
+//
+//	magic "GK" | version | algorithm | kdf version | key id (8) | nonce | ciphertext
+//
+// The whole header is authenticated together with the caller's associated
+// data. Data without the magic prefix is treated as the legacy
+// nonce||ciphertext format produced by Encrypt.
+const (
+	EnvelopeLegacy byte = 0
+	EnvelopeV1     byte = 1
+
+	AlgAES256GCM byte = 1
+
+	KdfVersionNone     byte = 0
+	KdfVersionLegacy   byte = 1
+	KdfVersionArgon2id byte = 2
+
+	keyIDSize          = 8
+	envelopeHeaderSize = 5 + keyIDSize
+)
+
+var envelopeMagic = []byte("GK")
+
+type EnvelopeHeader struct {
+	Version    byte
+	Algorithm  byte
+	KdfVersion byte
+	KeyID      [keyIDSize]byte
+}
+
+func (h EnvelopeHeader) marshal() []byte {
+	b := make([]byte, 0, envelopeHeaderSize)
+	b = append(b, envelopeMagic...)
+	b = append(b, h.Version, h.Algorithm, h.KdfVersion)
+	return append(b, h.KeyID[:]...)
+}
+
+// KeyID returns a short fingerprint of key that is stored in the envelope
+// header to tell keys apart without revealing them.
+func KeyID(key []byte) [keyIDSize]byte {
+	sum := sha256.Sum256(append([]byte("gophkeeper key id\x00"), key...))
+	var id [keyIDSize]byte
+	copy(id[:], sum[:keyIDSize])
+	return id
+}
+
+// NoteAAD binds a ciphertext to the note it belongs to.
+func NoteAAD(id string, typeNote string) []byte {
+	return []byte("note\x00" + id + "\x00" + typeNote)
+}
+
+// SealedNoteAAD binds a sealed note ciphertext to its id. The type is kept
+// inside the payload and is authenticated by the AEAD itself.
+func SealedNoteAAD(id string) []byte {
+	return []byte("sealed note\x00" + id)
+}
+
+// BlobChunkAAD binds a blob chunk to its blob, its position and whether it
+// is the final one, so chunks cannot be reordered, swapped between blobs or
+// cut off at the end.
+func BlobChunkAAD(blobID string, index int64, last bool) []byte {
+	aad := []byte("blob chunk\x00" + blobID + "\x00" + strconv.FormatInt(index, 10))
+	if last {
+		aad = append(aad, "\x00last"...)
+	}
+	return aad
+}
+
+// Seal encrypts data into a version 1 envelope authenticated with aad.
+func Seal(ctx context.Context, key []byte, kdfVersion byte, aad []byte, data []byte) ([]byte, error) {
+	log := logger.FromContext(ctx).WithFields(logrus.Fields{
+		"method": "Seal",
+	})
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	nonce, err := generateRandom(gcm.NonceSize())
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	header := EnvelopeHeader{Version: EnvelopeV1, Algorithm: AlgAES256GCM, KdfVersion: kdfVersion, KeyID: KeyID(key)}
+	out := append(header.marshal(), nonce...)
+	return gcm.Seal(out, nonce, data, append(out[:envelopeHeaderSize:envelopeHeaderSize], aad...)), nil
+}
+
+// Open decrypts an envelope produced by Seal and verifies aad. Legacy data is
+// decrypted without associated data and reported with version EnvelopeLegacy.
+// A version 1 envelope that does not open is an error.
+func Open(ctx context.Context, key []byte, data []byte, aad []byte) ([]byte, EnvelopeHeader, error) {
+	header, ok := parseHeader(data)
+	if !ok {
+		plain, err := Decrypt(ctx, key, data)
+		return plain, EnvelopeHeader{Version: EnvelopeLegacy}, err
+	}
+	plain, err := openV1(ctx, key, header, data, aad)
+	if err != nil {
+		return nil, header, err
+	}
+	return plain, header, nil
+}
+
+// OpenLegacy is Open for data that may predate envelopes. A legacy nonce may
+// start with the magic prefix by chance, so a version 1 envelope that does not
+// open is retried as legacy data. The retry drops the associated data; use
+// Open once the data is known to be sealed.
+func OpenLegacy(ctx context.Context, key []byte, data []byte, aad []byte) ([]byte, EnvelopeHeader, error) {
+	plain, header, err := Open(ctx, key, data, aad)
+	if err != nil && header.Version == EnvelopeV1 {
+		if legacy, legacyErr := Decrypt(ctx, key, data); legacyErr == nil {
+			return legacy, EnvelopeHeader{Version: EnvelopeLegacy}, nil
+		}
+	}
+	return plain, header, err
+}
+
+func openV1(ctx context.Context, key []byte, header EnvelopeHeader, data []byte, aad []byte) ([]byte, error) {
+	log := logger.FromContext(ctx).WithFields(logrus.Fields{
+		"method": "Open",
+	})
+
+	if header.Algorithm != AlgAES256GCM {
+		return nil, ErrUnknownAlgorithm
+	}
+	if header.KeyID != KeyID(key) {
+		log.Warn(ErrKeyMismatch)
+		return nil, ErrKeyMismatch
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	if len(data) < envelopeHeaderSize+gcm.NonceSize() {
+		return nil, ErrMalformedEnvelope
+	}
+	nonce := data[envelopeHeaderSize : envelopeHeaderSize+gcm.NonceSize()]
+	ciphertext := data[envelopeHeaderSize+gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, append(data[:envelopeHeaderSize:envelopeHeaderSize], aad...))
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	return plain, nil
+}
+
+func parseHeader(data []byte) (EnvelopeHeader, bool) {
+	if len(data) < envelopeHeaderSize || !bytes.HasPrefix(data, envelopeMagic) || data[2] != EnvelopeV1 {
+		return EnvelopeHeader{}, false
+	}
+	header := EnvelopeHeader{Version: data[2], Algorithm: data[3], KdfVersion: data[4]}
+	copy(header.KeyID[:], data[5:envelopeHeaderSize])
+	return header, true
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+var (
+	ErrUnknownAlgorithm  = errors.New("unknown envelope algorithm")
+	ErrKeyMismatch       = errors.New("envelope was sealed with another key")
+	ErrMalformedEnvelope = errors.New("malformed envelope")
+)
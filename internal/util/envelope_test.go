@@ -0,0 +1,140 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSeal(t *testing.T) {
+	key1 := LegacyKey("user1@test.com", "Test Password")
+	key2 := LegacyKey("user1@test.com", "Test Password2")
+	id1 := uuid.New().String()
+	id2 := uuid.New().String()
+	data := []byte("TEST TEST TEST TEST TEST TEST TEST TEST TEST TEST")
+
+	sealed, err := Seal(context.Background(), key1, KdfVersionNone, NoteAAD(id1, "text"), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := Encrypt(context.Background(), key1, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte{}, sealed...)
+	tampered[4] = KdfVersionArgon2id
+
+	tests := []struct {
+		name        string
+		key         []byte
+		data        []byte
+		aad         []byte
+		wantVersion byte
+		wantErr     error
+	}{
+		{
+			name:        "success",
+			key:         key1,
+			data:        sealed,
+			aad:         NoteAAD(id1, "text"),
+			wantVersion: EnvelopeV1,
+		},
+		{
+			name:    "swapped note id",
+			key:     key1,
+			data:    sealed,
+			aad:     NoteAAD(id2, "text"),
+			wantErr: errors.New("cipher: message authentication failed"),
+		},
+		{
+			name:    "changed note type",
+			key:     key1,
+			data:    sealed,
+			aad:     NoteAAD(id1, "credential"),
+			wantErr: errors.New("cipher: message authentication failed"),
+		},
+		{
+			name:    "tampered header",
+			key:     key1,
+			data:    tampered,
+			aad:     NoteAAD(id1, "text"),
+			wantErr: errors.New("cipher: message authentication failed"),
+		},
+		{
+			name:    "another key",
+			key:     key2,
+			data:    sealed,
+			aad:     NoteAAD(id1, "text"),
+			wantErr: ErrKeyMismatch,
+		},
+		{
+			name:        "legacy format",
+			key:         key1,
+			data:        legacy,
+			aad:         NoteAAD(id1, "text"),
+			wantVersion: EnvelopeLegacy,
+		},
+		{
+			name:    "too short",
+			key:     key1,
+			data:    []byte("GK"),
+			aad:     NoteAAD(id1, "text"),
+			wantErr: ErrMalformedEnvelope,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, header, err := Open(context.Background(), tt.key, tt.data, tt.aad)
+			if (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("Open() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				if err.Error() != tt.wantErr.Error() {
+					t.Errorf("Open() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if header.Version != tt.wantVersion {
+				t.Errorf("Open() version = %v, want %v", header.Version, tt.wantVersion)
+			}
+			if !reflect.DeepEqual(got, data) {
+				t.Errorf("Open() got = %v, want %v", got, data)
+			}
+		})
+	}
+}
+
+func TestOpenLegacy(t *testing.T) {
+	key := LegacyKey("user1@test.com", "Test Password")
+	data := []byte("TEST TEST TEST TEST TEST TEST TEST TEST TEST TEST")
+	gcm, err := newGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a legacy ciphertext whose nonce happens to start like an envelope
+	nonce := append([]byte{'G', 'K', EnvelopeV1}, make([]byte, gcm.NonceSize()-3)...)
+	legacy := gcm.Seal(nonce, nonce, data, nil)
+
+	if _, _, err = Open(context.Background(), key, legacy, NoteAAD("id", "text")); err == nil {
+		t.Error("Open() accepted legacy data behind an envelope header")
+	}
+	got, header, err := OpenLegacy(context.Background(), key, legacy, NoteAAD("id", "text"))
+	if err != nil {
+		t.Fatalf("OpenLegacy() error = %v", err)
+	}
+	if header.Version != EnvelopeLegacy || !reflect.DeepEqual(got, data) {
+		t.Errorf("OpenLegacy() = %q, version %v", got, header.Version)
+	}
+
+	sealed, err := Seal(context.Background(), key, KdfVersionNone, NoteAAD("id", "text"), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = OpenLegacy(context.Background(), key, sealed, NoteAAD("other", "text")); err == nil {
+		t.Error("OpenLegacy() ignored the associated data")
+	}
+}
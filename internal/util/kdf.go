@@ -0,0 +1,136 @@
+package util
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	KdfArgon2id = "argon2id"
+
+	DefaultKdfTime    uint32 = 3
+	DefaultKdfMemory  uint32 = 64 * 1024
+	DefaultKdfThreads uint8  = 4
+
+	// The maximum cost accepted from parameters the client did not choose
+	// itself, 16 times the defaults. A server or a file asking for more could
+	// make the client run out of memory or time before anything is checked.
+	MaxKdfTime    uint32 = 16 * DefaultKdfTime
+	MaxKdfMemory  uint32 = 16 * DefaultKdfMemory
+	MaxKdfThreads uint8  = 16 * DefaultKdfThreads
+
+	kdfSaltSize = 16
+	kdfKeySize  = 32
+)
+
+// NewKdfParams returns Argon2id parameters with a fresh random salt.
+// Zero cost values are replaced with the package defaults.
+func NewKdfParams(time, memory uint32, threads uint8) (models.KdfParams, error) {
+	salt, err := generateRandom(kdfSaltSize)
+	if err != nil {
+		return models.KdfParams{}, err
+	}
+	if time == 0 {
+		time = DefaultKdfTime
+	}
+	if memory == 0 {
+		memory = DefaultKdfMemory
+	}
+	if threads == 0 {
+		threads = DefaultKdfThreads
+	}
+	return models.KdfParams{
+		Algorithm: KdfArgon2id,
+		Salt:      salt,
+		Time:      time,
+		Memory:    memory,
+		Threads:   threads,
+	}, nil
+}
+
+// DeriveKey derives the AES-256 vault key from the master password.
+func DeriveKey(password string, params models.KdfParams) ([]byte, error) {
+	if params.Algorithm != KdfArgon2id {
+		return nil, ErrUnknownKdf
+	}
+	if len(params.Salt) == 0 || params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+		return nil, ErrInvalidKdfParams
+	}
+	return argon2.IDKey([]byte(password), params.Salt, params.Time, params.Memory, params.Threads, kdfKeySize), nil
+}
+
+// CheckKdfCost rejects parameters that are cheaper than floor, so a server
+// cannot make the client derive its key with a cost that is easy to brute
+// force. Zero values of floor stand for the package defaults.
+func CheckKdfCost(params, floor models.KdfParams) error {
+	if floor.Time == 0 {
+		floor.Time = DefaultKdfTime
+	}
+	if floor.Memory == 0 {
+		floor.Memory = DefaultKdfMemory
+	}
+	if floor.Threads == 0 {
+		floor.Threads = DefaultKdfThreads
+	}
+	if params.Time < floor.Time || params.Memory < floor.Memory || params.Threads < floor.Threads {
+		return fmt.Errorf("%w: t=%d m=%d p=%d, at least t=%d m=%d p=%d are required", ErrWeakKdf,
+			params.Time, params.Memory, params.Threads, floor.Time, floor.Memory, floor.Threads)
+	}
+	return nil
+}
+
+// CheckKdfLimits rejects parameters that cost more than the maximum. It is
+// checked before deriving a key with parameters read from the server, the
+// cache or a file.
+func CheckKdfLimits(params models.KdfParams) error {
+	if params.Time > MaxKdfTime || params.Memory > MaxKdfMemory || params.Threads > MaxKdfThreads {
+		return fmt.Errorf("%w: t=%d m=%d p=%d, at most t=%d m=%d p=%d are allowed", ErrCostlyKdf,
+			params.Time, params.Memory, params.Threads, MaxKdfTime, MaxKdfMemory, MaxKdfThreads)
+	}
+	return nil
+}
+
+// LegacyKey reproduces the unsalted key used before per-user KDF parameters
+// were introduced. It is only needed to migrate old vaults.
+func LegacyKey(email, password string) []byte {
+	sum := sha256.Sum256([]byte(email + password))
+	return sum[:]
+}
+
+// NewDataKey returns a random AES-256 key used to encrypt the notes of a vault.
+func NewDataKey() ([]byte, error) {
+	return generateRandom(kdfKeySize)
+}
+
+// WrapKey encrypts the vault data key with the password-derived key.
+func WrapKey(ctx context.Context, kek []byte, dataKey []byte) ([]byte, error) {
+	return Seal(ctx, kek, KdfVersionArgon2id, dataKeyAAD, dataKey)
+}
+
+// UnwrapKey recovers the vault data key. It fails when kek was derived from a
+// wrong password.
+func UnwrapKey(ctx context.Context, kek []byte, wrapped []byte) ([]byte, error) {
+	dataKey, _, err := OpenLegacy(ctx, kek, wrapped, dataKeyAAD)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataKey) != kdfKeySize {
+		return nil, ErrInvalidDataKey
+	}
+	return dataKey, nil
+}
+
+var dataKeyAAD = []byte("vault data key")
+
+var (
+	ErrInvalidDataKey   = errors.New("invalid vault data key")
+	ErrUnknownKdf       = errors.New("unknown kdf algorithm")
+	ErrInvalidKdfParams = errors.New("invalid kdf parameters")
+	ErrWeakKdf          = errors.New("kdf parameters are below the minimum cost")
+	ErrCostlyKdf        = errors.New("kdf parameters are above the maximum cost")
+)
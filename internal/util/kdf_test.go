@@ -0,0 +1,191 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/katvixlab/go-diplom-gophkeeper/internal/models"
+)
+
+func TestNewKdfParams(t *testing.T) {
+	t.Run("defaults and random salt", func(t *testing.T) {
+		got1, err := NewKdfParams(0, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got2, err := NewKdfParams(0, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got1.Algorithm != KdfArgon2id || got1.Time != DefaultKdfTime || got1.Memory != DefaultKdfMemory || got1.Threads != DefaultKdfThreads {
+			t.Errorf("NewKdfParams() = %v, want default cost", got1)
+		}
+		if len(got1.Salt) != kdfSaltSize || reflect.DeepEqual(got1.Salt, got2.Salt) {
+			t.Errorf("NewKdfParams() salt must be random. got1 = %v, got2 = %v", got1.Salt, got2.Salt)
+		}
+	})
+}
+
+func TestDeriveKey(t *testing.T) {
+	params := models.KdfParams{Algorithm: KdfArgon2id, Salt: []byte("0123456789abcdef"), Time: 1, Memory: 1024, Threads: 1}
+	otherSalt := params
+	otherSalt.Salt = []byte("fedcba9876543210")
+
+	type args struct {
+		password string
+		params   models.KdfParams
+	}
+	tests := []struct {
+		name     string
+		args     args
+		sameAs   *args
+		wantSame bool
+		wantErr  bool
+	}{
+		{
+			name:     "deterministic",
+			args:     args{password: "DemoPass123!", params: params},
+			sameAs:   &args{password: "DemoPass123!", params: params},
+			wantSame: true,
+		},
+		{
+			name:     "salt changes key",
+			args:     args{password: "DemoPass123!", params: params},
+			sameAs:   &args{password: "DemoPass123!", params: otherSalt},
+			wantSame: false,
+		},
+		{
+			name:    "unknown algorithm",
+			args:    args{password: "DemoPass123!", params: models.KdfParams{Algorithm: "md5", Salt: params.Salt, Time: 1, Memory: 1024, Threads: 1}},
+			wantErr: true,
+		},
+		{
+			name:    "empty salt",
+			args:    args{password: "DemoPass123!", params: models.KdfParams{Algorithm: KdfArgon2id, Time: 1, Memory: 1024, Threads: 1}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DeriveKey(tt.args.password, tt.args.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DeriveKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != kdfKeySize {
+				t.Errorf("DeriveKey() key size = %d, want %d", len(got), kdfKeySize)
+			}
+			other, err := DeriveKey(tt.sameAs.password, tt.sameAs.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reflect.DeepEqual(got, other) != tt.wantSame {
+				t.Errorf("DeriveKey() got = %v, other = %v, wantSame %v", got, other, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestCheckKdfCost(t *testing.T) {
+	defaults := models.KdfParams{Time: DefaultKdfTime, Memory: DefaultKdfMemory, Threads: DefaultKdfThreads}
+	tests := []struct {
+		name    string
+		params  models.KdfParams
+		floor   models.KdfParams
+		wantErr bool
+	}{
+		{name: "defaults", params: defaults},
+		{name: "above the defaults", params: models.KdfParams{Time: 4, Memory: 128 * 1024, Threads: 8}},
+		{name: "cheap time", params: models.KdfParams{Time: 1, Memory: DefaultKdfMemory, Threads: DefaultKdfThreads}, wantErr: true},
+		{name: "cheap memory", params: models.KdfParams{Time: DefaultKdfTime, Memory: 1024, Threads: DefaultKdfThreads}, wantErr: true},
+		{name: "one thread", params: models.KdfParams{Time: DefaultKdfTime, Memory: DefaultKdfMemory, Threads: 1}, wantErr: true},
+		{name: "lowered floor", params: models.KdfParams{Time: 1, Memory: 1024, Threads: 1}, floor: models.KdfParams{Time: 1, Memory: 1024, Threads: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckKdfCost(tt.params, tt.floor)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckKdfCost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrWeakKdf) {
+				t.Errorf("CheckKdfCost() error = %v, want ErrWeakKdf", err)
+			}
+		})
+	}
+}
+
+func TestCheckKdfLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  models.KdfParams
+		wantErr bool
+	}{
+		{name: "defaults", params: models.KdfParams{Time: DefaultKdfTime, Memory: DefaultKdfMemory, Threads: DefaultKdfThreads}},
+		{name: "maximum", params: models.KdfParams{Time: MaxKdfTime, Memory: MaxKdfMemory, Threads: MaxKdfThreads}},
+		{name: "huge time", params: models.KdfParams{Time: math.MaxUint32, Memory: DefaultKdfMemory, Threads: DefaultKdfThreads}, wantErr: true},
+		{name: "huge memory", params: models.KdfParams{Time: DefaultKdfTime, Memory: math.MaxUint32, Threads: DefaultKdfThreads}, wantErr: true},
+		{name: "many threads", params: models.KdfParams{Time: DefaultKdfTime, Memory: DefaultKdfMemory, Threads: math.MaxUint8}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckKdfLimits(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckKdfLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrCostlyKdf) {
+				t.Errorf("CheckKdfLimits() error = %v, want ErrCostlyKdf", err)
+			}
+		})
+	}
+}
+
+func TestWrapKey(t *testing.T) {
+	kek1 := LegacyKey("user1@test.com", "Test Password")
+	kek2 := LegacyKey("user1@test.com", "Test Password2")
+	dataKey, err := NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		wrapKey []byte
+		openKey []byte
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			wrapKey: kek1,
+			openKey: kek1,
+			wantErr: false,
+		},
+		{
+			name:    "wrong password",
+			wrapKey: kek1,
+			openKey: kek2,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapped, err := WrapKey(context.Background(), tt.wrapKey, dataKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := UnwrapKey(context.Background(), tt.openKey, wrapped)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UnwrapKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, dataKey) {
+				t.Errorf("UnwrapKey() got = %v, want %v", got, dataKey)
+			}
+		})
+	}
+}
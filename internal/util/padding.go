@@ -0,0 +1,34 @@
+package util
+
+import "errors"
+
+// PadBlock is the granularity sealed note payloads are padded to, so the
+// ciphertext size only reveals a coarse bucket of the note size.
+const PadBlock = 256
+
+// Pad appends ISO/IEC 7816-4 padding (0x80 followed by zeros) up to the next
+// multiple of block. At least one byte is always added.
+func Pad(data []byte, block int) []byte {
+	n := block - len(data)%block
+	out := make([]byte, len(data), len(data)+n)
+	copy(out, data)
+	out = append(out, 0x80)
+	return append(out, make([]byte, n-1)...)
+}
+
+// Unpad removes padding added by Pad.
+func Unpad(data []byte) ([]byte, error) {
+	for i := len(data) - 1; i >= 0; i-- {
+		switch data[i] {
+		case 0:
+			continue
+		case 0x80:
+			return data[:i], nil
+		default:
+			return nil, ErrInvalidPadding
+		}
+	}
+	return nil, ErrInvalidPadding
+}
+
+var ErrInvalidPadding = errors.New("invalid padding")
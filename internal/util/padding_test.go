@@ -0,0 +1,77 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantLen int
+	}{
+		{
+			name:    "empty",
+			data:    []byte{},
+			wantLen: 16,
+		},
+		{
+			name:    "short",
+			data:    []byte(`{"text":"hi"}`),
+			wantLen: 16,
+		},
+		{
+			name:    "full block",
+			data:    make([]byte, 16),
+			wantLen: 32,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			padded := Pad(tt.data, 16)
+			if len(padded) != tt.wantLen {
+				t.Errorf("Pad() len = %d, want %d", len(padded), tt.wantLen)
+			}
+			got, err := Unpad(padded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.data) {
+				t.Errorf("Unpad() got = %v, want %v", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestUnpad(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{
+			name:    "no marker",
+			data:    []byte{1, 2, 0, 0},
+			wantErr: true,
+		},
+		{
+			name:    "only zeros",
+			data:    []byte{0, 0, 0},
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			data:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Unpad(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Unpad() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"math/big"
+)
+
+// SRP-6a (RFC 5054) with the 2048-bit group and SHA-256. The password never
+// leaves the client: x is derived from the Argon2id vault key, so the KDF
+// salt doubles as the SRP salt, and the server stores only v = g^x.
+var (
+	srpN = mustHex("AC6BDB41324A9A9BF166DE5E1389582FAF72B6651987EE07FC3192943DB56050A37329CBB4A099ED8193E0757767A13DD52312AB4B03310DCD7F48A9DA04FD50E8083969EDB767B0CF6095179A163AB3661A05FBD5FAAAE82918A9962F0B93B855F97993EC975EEAA80D740ADBF4FF747359D041D5C33EA71D281E446B14773BCA97B43A23FB801676BD207A436C6481F1D2B9078717461A5B9D32E688F87748544523B524B0D57D5EA77A2775D2ECFA032CFBDBF52FB3786160279004E57AE6AF874E7303CE53299CCC041C7BC308D82A5698F3A8D0C38271AE35F8E9DBFBB694B5C803D89F7AE435DE236D525F54759B65E372FCD68EF20FA7111F9E4AFF73")
+	srpG = big.NewInt(2)
+	srpK = new(big.Int).SetBytes(srpHash(srpN.Bytes(), srpPad(srpG)))
+)
+
+const srpSecretSize = 32
+
+// SrpX derives the private SRP value from the password-derived vault key.
+// The vault key itself cannot be recovered from x.
+func SrpX(kek []byte) []byte {
+	sum := sha256.Sum256(append([]byte("gophkeeper srp x\x00"), kek...))
+	return sum[:]
+}
+
+// SrpVerifier returns v = g^x mod N, the only password-dependent value the
+// server stores.
+func SrpVerifier(x []byte) []byte {
+	return srpPad(new(big.Int).Exp(srpG, new(big.Int).SetBytes(x), srpN))
+}
+
+// SrpClient is the client side of one handshake.
+type SrpClient struct {
+	email string
+	a     *big.Int
+	pubA  *big.Int
+	m1, k []byte
+}
+
+func NewSrpClient(email string) (*SrpClient, error) {
+	a, err := srpSecret()
+	if err != nil {
+		return nil, err
+	}
+	return &SrpClient{email: email, a: a, pubA: new(big.Int).Exp(srpG, a, srpN)}, nil
+}
+
+// A is the public ephemeral value sent with the first message.
+func (c *SrpClient) A() []byte {
+	return srpPad(c.pubA)
+}
+
+// Proof computes the session key from the server's challenge and returns the
+// client proof M1.
+func (c *SrpClient) Proof(salt, x, serverB []byte) ([]byte, error) {
+	B := new(big.Int).SetBytes(serverB)
+	if new(big.Int).Mod(B, srpN).Sign() == 0 {
+		return nil, ErrSrpBadPublic
+	}
+	u := new(big.Int).SetBytes(srpHash(srpPad(c.pubA), srpPad(B)))
+	if u.Sign() == 0 {
+		return nil, ErrSrpBadPublic
+	}
+	xi := new(big.Int).SetBytes(x)
+
+	// S = (B - k * g^x) ^ (a + u * x) mod N
+	kgx := new(big.Int).Mul(srpK, new(big.Int).Exp(srpG, xi, srpN))
+	base := new(big.Int).Sub(B, kgx)
+	base.Mod(base, srpN)
+	exp := new(big.Int).Add(c.a, new(big.Int).Mul(u, xi))
+	S := new(big.Int).Exp(base, exp, srpN)
+
+	c.k = srpHash(srpPad(S))
+	c.m1 = srpClientProof(c.email, salt, c.pubA, B, c.k)
+	return c.m1, nil
+}
+
+// VerifyServer checks the server proof M2, which shows that the server knows
+// the verifier.
+func (c *SrpClient) VerifyServer(m2 []byte) error {
+	if c.m1 == nil || subtle.ConstantTimeCompare(m2, srpHash(srpPad(c.pubA), c.m1, c.k)) != 1 {
+		return ErrSrpProof
+	}
+	return nil
+}
+
+// SrpServer is the server side of one handshake.
+type SrpServer struct {
+	email string
+	salt  []byte
+	v     *big.Int
+	b     *big.Int
+	pubA  *big.Int
+	pubB  *big.Int
+}
+
+// NewSrpServer starts a handshake for a stored verifier and the client's A.
+func NewSrpServer(email string, salt, verifier, clientA []byte) (*SrpServer, error) {
+	A := new(big.Int).SetBytes(clientA)
+	if new(big.Int).Mod(A, srpN).Sign() == 0 {
+		return nil, ErrSrpBadPublic
+	}
+	b, err := srpSecret()
+	if err != nil {
+		return nil, err
+	}
+	v := new(big.Int).SetBytes(verifier)
+
+	// B = k * v + g^b mod N
+	B := new(big.Int).Mul(srpK, v)
+	B.Add(B, new(big.Int).Exp(srpG, b, srpN))
+	B.Mod(B, srpN)
+	return &SrpServer{email: email, salt: salt, v: v, b: b, pubA: A, pubB: B}, nil
+}
+
+// B is the public ephemeral value returned in the challenge.
+func (s *SrpServer) B() []byte {
+	return srpPad(s.pubB)
+}
+
+// Verify checks the client proof M1 and returns the server proof M2.
+func (s *SrpServer) Verify(m1 []byte) ([]byte, error) {
+	u := new(big.Int).SetBytes(srpHash(srpPad(s.pubA), srpPad(s.pubB)))
+	if u.Sign() == 0 {
+		return nil, ErrSrpBadPublic
+	}
+
+	// S = (A * v^u) ^ b mod N
+	base := new(big.Int).Mul(s.pubA, new(big.Int).Exp(s.v, u, srpN))
+	S := new(big.Int).Exp(base.Mod(base, srpN), s.b, srpN)
+	k := srpHash(srpPad(S))
+
+	if subtle.ConstantTimeCompare(m1, srpClientProof(s.email, s.salt, s.pubA, s.pubB, k)) != 1 {
+		return nil, ErrSrpProof
+	}
+	return srpHash(srpPad(s.pubA), m1, k), nil
+}
+
+// srpClientProof is M1 = H(H(N) xor H(g) | H(I) | s | A | B | K).
+func srpClientProof(email string, salt []byte, A, B *big.Int, k []byte) []byte {
+	hn := srpHash(srpN.Bytes())
+	hg := srpHash(srpG.Bytes())
+	for i := range hn {
+		hn[i] ^= hg[i]
+	}
+	return srpHash(hn, srpHash([]byte(email)), salt, srpPad(A), srpPad(B), k)
+}
+
+func srpHash(parts ...[]byte) []byte {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+// srpPad left-pads n to the length of N.
+func srpPad(n *big.Int) []byte {
+	return n.FillBytes(make([]byte, (srpN.BitLen()+7)/8))
+}
+
+func srpSecret() (*big.Int, error) {
+	buf := make([]byte, srpSecretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(buf), nil
+}
+
+func mustHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("srp: bad group constant")
+	}
+	return n
+}
+
+var (
+	ErrSrpBadPublic = errors.New("srp: invalid public value")
+	ErrSrpProof     = errors.New("srp: proof mismatch")
+)
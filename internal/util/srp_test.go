@@ -0,0 +1,73 @@
+package util
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSrpHandshake(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	x := SrpX([]byte("vault key derived from the password"))
+	verifier := SrpVerifier(x)
+
+	tests := []struct {
+		name     string
+		clientX  []byte
+		wantErr  error
+		tamperM2 bool
+	}{
+		{name: "same password", clientX: x},
+		{name: "wrong password", clientX: SrpX([]byte("another key")), wantErr: ErrSrpProof},
+		{name: "forged server proof", clientX: x, tamperM2: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewSrpClient("user1@test.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			server, err := NewSrpServer("user1@test.com", salt, verifier, client.A())
+			if err != nil {
+				t.Fatal(err)
+			}
+			m1, err := client.Proof(salt, tt.clientX, server.B())
+			if err != nil {
+				t.Fatal(err)
+			}
+			m2, err := server.Verify(m1)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.tamperM2 {
+				m2[0] ^= 1
+				if err = client.VerifyServer(m2); !errors.Is(err, ErrSrpProof) {
+					t.Errorf("VerifyServer() error = %v, want %v", err, ErrSrpProof)
+				}
+				return
+			}
+			if err = client.VerifyServer(m2); err != nil {
+				t.Errorf("VerifyServer() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSrpBadPublic(t *testing.T) {
+	verifier := SrpVerifier(SrpX([]byte("key")))
+	for _, a := range [][]byte{nil, srpN.Bytes(), srpPad(srpN)} {
+		if _, err := NewSrpServer("user1@test.com", nil, verifier, a); !errors.Is(err, ErrSrpBadPublic) {
+			t.Errorf("NewSrpServer(%x) error = %v, want %v", a, err, ErrSrpBadPublic)
+		}
+	}
+
+	client, err := NewSrpClient("user1@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Proof(nil, SrpX([]byte("key")), srpN.Bytes()); !errors.Is(err, ErrSrpBadPublic) {
+		t.Errorf("Proof() error = %v, want %v", err, ErrSrpBadPublic)
+	}
+}
@@ -59,6 +59,10 @@ func Decrypt(ctx context.Context, key []byte, data []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	if len(data) < aesgcm.NonceSize() {
+		log.Error(ErrMalformedEnvelope)
+		return nil, ErrMalformedEnvelope
+	}
 	nonce := data[:aesgcm.NonceSize()]
 	ciphertext := data[aesgcm.NonceSize():]
 
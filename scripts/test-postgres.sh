@@ -0,0 +1,26 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+
+cd "$ROOT_DIR"
+
+# Runs the tests against SQLite and a throwaway PostgreSQL container.
+CONTAINER="gophkeeper-test-pg-$$"
+PORT="${GOPHKEEPER_TEST_PG_PORT:-55432}"
+
+echo "Starting PostgreSQL in container $CONTAINER on port $PORT"
+docker run -d --rm --name "$CONTAINER" -p "$PORT:5432" -e POSTGRES_PASSWORD=test postgres:16 >/dev/null
+trap 'docker stop "$CONTAINER" >/dev/null' EXIT
+
+for _ in $(seq 1 30); do
+  if docker exec "$CONTAINER" pg_isready -U postgres >/dev/null 2>&1; then
+    break
+  fi
+  sleep 1
+done
+
+GOPHKEEPER_TEST_PG_DSN="host=localhost port=$PORT user=postgres password=test sslmode=disable" \
+GOPHKEEPER_TEST_PG_REQUIRED=1 \
+GOCACHE="$ROOT_DIR/.cache/go-build" GOMODCACHE="$ROOT_DIR/.cache/go-mod" \
+  go test "${@:-./...}"